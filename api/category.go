@@ -1,12 +1,15 @@
 package api
 
 import (
+	"github.com/jinzhu/gorm"
 	"github.com/labstack/echo/v4"
 	"github.com/shopicano/shopicano-backend/app"
 	"github.com/shopicano/shopicano-backend/core"
 	"github.com/shopicano/shopicano-backend/data"
 	"github.com/shopicano/shopicano-backend/errors"
 	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
 	"github.com/shopicano/shopicano-backend/utils"
 	"github.com/shopicano/shopicano-backend/validators"
 	"net/http"
@@ -50,12 +53,19 @@ func createCategory(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
-	db := app.DB()
+	db := app.DB().Begin()
 
 	c.StoreID = storeID
 
+	if err := placeCategory(db, c, c.ParentID, c.TaxonomyID); err != nil {
+		db.Rollback()
+		return serveCategoryPlacementFailed(ctx, err)
+	}
+
 	cu := data.NewCategoryRepository()
 	if err := cu.Create(db, c); err != nil {
+		db.Rollback()
+
 		msg, ok := errors.IsDuplicateKeyError(err)
 		if ok {
 			resp.Title = msg
@@ -72,6 +82,10 @@ func createCategory(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
 	resp.Status = http.StatusCreated
 	resp.Data = c
 	return resp.ServerJSON(ctx)
@@ -179,13 +193,25 @@ func fetchCategories(ctx echo.Context, page, limit int64, isPublic bool) (interf
 
 func deleteCategory(ctx echo.Context) error {
 	storeID := ctx.Get(utils.StoreID).(string)
-	categoryID := ctx.Param("id")
+	categoryID := ctx.Param("category_id")
 
 	resp := core.Response{}
 
 	db := app.DB()
 
 	cu := data.NewCategoryRepository()
+
+	children, err := cu.CountChildren(db, categoryID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	if children > 0 {
+		resp.Title = "Category has subcategories"
+		resp.Status = http.StatusConflict
+		resp.Code = errors.CategoryHasChildren
+		return resp.ServerJSON(ctx)
+	}
+
 	if err := cu.Delete(db, storeID, categoryID); err != nil {
 		if errors.IsRecordNotFoundError(err) {
 			resp.Title = "Category not found"
@@ -222,11 +248,13 @@ func updateCategory(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
-	db := app.DB()
+	db := app.DB().Begin()
 	cu := data.NewCategoryRepository()
 
 	c, err := cu.GetAsStoreOwner(db, storeID, categoryID)
 	if err != nil {
+		db.Rollback()
+
 		if errors.IsRecordNotFoundError(err) {
 			resp.Title = "Category not found"
 			resp.Status = http.StatusNotFound
@@ -255,9 +283,30 @@ func updateCategory(ctx echo.Context) error {
 		c.IsPublished = *pld.IsPublished
 	}
 
+	parentID, taxonomyID := c.ParentID, c.TaxonomyID
+	if pld.ParentID != nil {
+		parentID = pld.ParentID
+		if *parentID == "" {
+			parentID = nil
+		}
+	}
+	if pld.TaxonomyID != nil {
+		taxonomyID = pld.TaxonomyID
+		if *taxonomyID == "" {
+			taxonomyID = nil
+		}
+	}
+
+	if err := placeCategory(db, c, parentID, taxonomyID); err != nil {
+		db.Rollback()
+		return serveCategoryPlacementFailed(ctx, err)
+	}
+
 	c.UpdatedAt = time.Now().UTC()
 
 	if err := cu.Update(db, c); err != nil {
+		db.Rollback()
+
 		resp.Title = "Database query failed"
 		resp.Status = http.StatusInternalServerError
 		resp.Code = errors.DatabaseQueryFailed
@@ -265,6 +314,10 @@ func updateCategory(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
 	resp.Status = http.StatusOK
 	resp.Data = c
 	return resp.ServerJSON(ctx)
@@ -330,3 +383,70 @@ func getCategoryAsOwner(ctx echo.Context) error {
 	resp.Data = c
 	return resp.ServerJSON(ctx)
 }
+
+// categoryPlacementError tells which of the parent and the taxonomy node of the category couldn't be loaded
+type categoryPlacementError struct {
+	err      error
+	taxonomy bool
+}
+
+func (e *categoryPlacementError) Error() string {
+	return e.err.Error()
+}
+
+// placeCategory maps the category into the taxonomy node and places it below the parent category of
+// the same store, moving its subcategories along
+func placeCategory(db *gorm.DB, c *models.Category, parentID, taxonomyID *string) error {
+	if taxonomyID != nil {
+		tu := data.NewTaxonomyRepository()
+		n, err := tu.Get(db, *taxonomyID)
+		if err != nil {
+			return &categoryPlacementError{err: err, taxonomy: true}
+		}
+		taxonomyID = &n.ID
+	}
+	c.TaxonomyID = taxonomyID
+
+	var parent *models.Category
+	if parentID != nil {
+		cu := data.NewCategoryRepository()
+		p, err := cu.GetAsStoreOwner(db, c.StoreID, *parentID)
+		if err != nil {
+			return &categoryPlacementError{err: err}
+		}
+		parent = p
+	}
+
+	return services.PlaceCategory(db, c, parent)
+}
+
+func serveCategoryPlacementFailed(ctx echo.Context, err error) error {
+	resp := core.Response{}
+
+	if pe, ok := err.(*categoryPlacementError); ok {
+		if !errors.IsRecordNotFoundError(pe.err) {
+			return serveDatabaseQueryFailed(ctx, pe.err)
+		}
+
+		resp.Status = http.StatusNotFound
+		resp.Errors = pe.err
+		if pe.taxonomy {
+			resp.Title = "Taxonomy node not found"
+			resp.Code = errors.TaxonomyNodeNotFound
+		} else {
+			resp.Title = "Parent category not found"
+			resp.Code = errors.CategoryNotFound
+		}
+		return resp.ServerJSON(ctx)
+	}
+
+	if _, ok := err.(*errors.UndefinedError); ok {
+		resp.Title = "Category can't be placed there"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.CategoryMoveNotAllowed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	return serveDatabaseQueryFailed(ctx, err)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"net/http"
+	"strconv"
+)
+
+func listCommissionInvoices(ctx echo.Context) error {
+	return serveCommissionInvoices(ctx, utils.GetStoreID(ctx))
+}
+
+func listCommissionInvoicesByMarketplace(ctx echo.Context) error {
+	return serveCommissionInvoices(ctx, ctx.Param("store_id"))
+}
+
+func downloadCommissionInvoicePDF(ctx echo.Context) error {
+	return serveCommissionInvoicePDF(ctx, utils.GetStoreID(ctx))
+}
+
+func downloadCommissionInvoicePDFByMarketplace(ctx echo.Context) error {
+	return serveCommissionInvoicePDF(ctx, ctx.Param("store_id"))
+}
+
+func serveCommissionInvoices(ctx echo.Context, storeID string) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 12
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+	cr := data.NewCommissionInvoiceRepository()
+
+	invoices, err := cr.List(db, storeID, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = invoices
+	return resp.ServerJSON(ctx)
+}
+
+func serveCommissionInvoicePDF(ctx echo.Context, storeID string) error {
+	invoiceID := ctx.Param("invoice_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	cr := data.NewCommissionInvoiceRepository()
+
+	ci, err := cr.Get(db, storeID, invoiceID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Commission invoice not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.CommissionInvoiceNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	f, err := services.ServeAsStreamFromMinio(ci.PDFPath)
+	if err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	return resp.ServeStreamFromMinioAsDownload(ctx, f)
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func createCommissionRule(ctx echo.Context) error {
+	req, err := validators.ValidateCreateCommissionRule(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.CommissionRuleDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	m := &models.CommissionRule{
+		ID:              utils.NewUUID(),
+		Name:            req.Name,
+		ProductID:       emptyAsNil(req.ProductID),
+		CategoryID:      emptyAsNil(req.CategoryID),
+		StoreTier:       emptyAsNil(req.StoreTier),
+		MinMonthlySales: req.MinMonthlySales,
+		MaxMonthlySales: req.MaxMonthlySales,
+		Rate:            req.Rate,
+		FixedFee:        req.FixedFee,
+		Priority:        req.Priority,
+		IsActive:        req.IsActive,
+		EffectiveFrom:   time.Now().UTC(),
+		EffectiveTo:     req.EffectiveTo,
+		CreatedAt:       time.Now().UTC(),
+		UpdatedAt:       time.Now().UTC(),
+	}
+	if req.EffectiveFrom != nil {
+		m.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+
+	db := app.DB()
+
+	if res := checkCommissionRule(db, m); res != nil {
+		return res.ServerJSON(ctx)
+	}
+
+	au := data.NewMarketplaceRepository()
+	if err := au.CreateCommissionRule(db, m); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = m
+	return resp.ServerJSON(ctx)
+}
+
+func updateCommissionRule(ctx echo.Context) error {
+	ruleID := ctx.Param("rule_id")
+
+	req, err := validators.ValidateUpdateCommissionRule(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.CommissionRuleDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+
+	au := data.NewMarketplaceRepository()
+	m, err := au.GetCommissionRule(db, ruleID)
+	if err != nil {
+		return serveCommissionRuleQueryFailed(ctx, err)
+	}
+
+	if req.Name != nil {
+		m.Name = *req.Name
+	}
+	if req.ProductID != nil {
+		m.ProductID = emptyAsNil(req.ProductID)
+	}
+	if req.CategoryID != nil {
+		m.CategoryID = emptyAsNil(req.CategoryID)
+	}
+	if req.StoreTier != nil {
+		m.StoreTier = emptyAsNil(req.StoreTier)
+	}
+	if req.MinMonthlySales != nil {
+		m.MinMonthlySales = req.MinMonthlySales
+	}
+	if req.MaxMonthlySales != nil {
+		m.MaxMonthlySales = req.MaxMonthlySales
+	}
+	if req.ClearMonthlySales {
+		m.MinMonthlySales = nil
+		m.MaxMonthlySales = nil
+	}
+	if req.Rate != nil {
+		m.Rate = *req.Rate
+	}
+	if req.FixedFee != nil {
+		m.FixedFee = *req.FixedFee
+	}
+	if req.Priority != nil {
+		m.Priority = *req.Priority
+	}
+	if req.IsActive != nil {
+		m.IsActive = *req.IsActive
+	}
+	if req.EffectiveFrom != nil {
+		m.EffectiveFrom = req.EffectiveFrom.UTC()
+	}
+	if req.EffectiveTo != nil {
+		m.EffectiveTo = req.EffectiveTo
+	}
+	if req.ClearEffectiveTo {
+		m.EffectiveTo = nil
+	}
+
+	m.UpdatedAt = time.Now().UTC()
+
+	if res := checkCommissionRule(db, m); res != nil {
+		return res.ServerJSON(ctx)
+	}
+
+	if err := au.UpdateCommissionRule(db, m); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = m
+	return resp.ServerJSON(ctx)
+}
+
+func deleteCommissionRule(ctx echo.Context) error {
+	ruleID := ctx.Param("rule_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	au := data.NewMarketplaceRepository()
+	if err := au.DeleteCommissionRule(db, ruleID); err != nil {
+		// Rules already snapshotted on ordered items can't be deleted, they should be deactivated instead
+		resp.Title = "Failed to delete commission rule, deactivate it if it's used by orders"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+func listCommissionRules(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+	au := data.NewMarketplaceRepository()
+
+	rules, err := au.ListCommissionRules(db, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = rules
+	return resp.ServerJSON(ctx)
+}
+
+func getCommissionRule(ctx echo.Context) error {
+	ruleID := ctx.Param("rule_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	au := data.NewMarketplaceRepository()
+
+	m, err := au.GetCommissionRule(db, ruleID)
+	if err != nil {
+		return serveCommissionRuleQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = m
+	return resp.ServerJSON(ctx)
+}
+
+// checkCommissionRule makes sure the ranges of the rule are consistent and the product and
+// category it refers to exist
+func checkCommissionRule(db *gorm.DB, m *models.CommissionRule) *core.Response {
+	ve := errors.ValidationError{}
+
+	if m.MinMonthlySales != nil && m.MaxMonthlySales != nil && *m.MaxMonthlySales <= *m.MinMonthlySales {
+		ve.Add("max_monthly_sales", "must be greater than min_monthly_sales")
+	}
+	if m.EffectiveTo != nil && !m.EffectiveTo.After(m.EffectiveFrom) {
+		ve.Add("effective_to", "must be after effective_from")
+	}
+
+	if m.ProductID != nil {
+		pu := data.NewProductRepository()
+		if _, err := pu.Get(db, *m.ProductID); err != nil {
+			if !errors.IsRecordNotFoundError(err) {
+				return &core.Response{
+					Title:  "Database query failed",
+					Status: http.StatusInternalServerError,
+					Code:   errors.DatabaseQueryFailed,
+					Errors: err,
+				}
+			}
+			ve.Add("product_id", "product not found")
+		}
+	}
+	if m.CategoryID != nil {
+		cu := data.NewCategoryRepository()
+		if _, err := cu.Get(db, *m.CategoryID); err != nil {
+			if !errors.IsRecordNotFoundError(err) {
+				return &core.Response{
+					Title:  "Database query failed",
+					Status: http.StatusInternalServerError,
+					Code:   errors.DatabaseQueryFailed,
+					Errors: err,
+				}
+			}
+			ve.Add("category_id", "category not found")
+		}
+	}
+
+	if len(ve) > 0 {
+		return &core.Response{
+			Title:  "Invalid data",
+			Status: http.StatusUnprocessableEntity,
+			Code:   errors.CommissionRuleDataInvalid,
+			Errors: &ve,
+		}
+	}
+	return nil
+}
+
+func serveCommissionRuleQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Commission rule not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.CommissionRuleNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+func emptyAsNil(v *string) *string {
+	if v == nil || *v == "" {
+		return nil
+	}
+	return v
+}
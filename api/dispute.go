@@ -0,0 +1,499 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	payment_gateways "github.com/shopicano/shopicano-backend/payment-gateways"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+	"github.com/shopicano/shopicano-backend/values"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+func RegisterDisputeRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	disputesPath := platformEndpoints.Group("/disputes")
+
+	func(g echo.Group) {
+		g.Use(middlewares.IsPlatformAdmin)
+		g.POST("/", createDispute)
+		g.GET("/", listDisputes)
+		g.GET("/:dispute_id/", getDispute)
+		g.PATCH("/:dispute_id/", updateDispute)
+		g.POST("/:dispute_id/evidences/", uploadDisputeEvidence)
+		g.GET("/:dispute_id/evidences/:evidence_id/", downloadDisputeEvidence)
+	}(*disputesPath)
+}
+
+func createDispute(ctx echo.Context) error {
+	req, err := validators.ValidateCreateDispute(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.DisputeDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	// The order stays locked until the dispute is created, so the order gets a single open dispute
+	// when admins or the dispute webhook open one at the same time
+	ou := data.NewOrderRepository()
+	o, err := ou.GetForUpdate(db, req.OrderID)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Order not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.OrderNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if o.PaymentStatus != models.PaymentCompleted {
+		db.Rollback()
+
+		ve := errors.ValidationError{}
+		ve.Add("order_id", "payment isn't completed")
+
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.DisputeDataInvalid
+		resp.Errors = &ve
+		return resp.ServerJSON(ctx)
+	}
+
+	dr := data.NewDisputeRepository()
+
+	open, err := dr.HasOpenDispute(db, o.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	if open {
+		db.Rollback()
+
+		resp.Title = "Order already has an open dispute"
+		resp.Status = http.StatusConflict
+		resp.Code = errors.DisputeAlreadyOpen
+		return resp.ServerJSON(ctx)
+	}
+
+	userID := utils.GetUserID(ctx)
+
+	d := &models.Dispute{
+		ID:               utils.NewUUID(),
+		GatewayDisputeID: emptyAsNil(req.GatewayDisputeID),
+		Amount:           req.Amount,
+		Fee:              req.Fee,
+		Reason:           req.Reason,
+		Status:           models.DisputeStatusOpen,
+		EvidenceDueBy:    req.EvidenceDueBy,
+		Note:             req.Note,
+		CreatedByUserID:  &userID,
+	}
+
+	if err := services.CreateDispute(db, o, d); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
+
+func listDisputes(ctx echo.Context) error {
+	return serveDisputes(ctx, ctx.QueryParam("store_id"))
+}
+
+func listDisputesOfStore(ctx echo.Context) error {
+	return serveDisputes(ctx, utils.GetStoreID(ctx))
+}
+
+func serveDisputes(ctx echo.Context, storeID string) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+	status := models.DisputeStatus(ctx.Request().URL.Query().Get("status"))
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	disputes, err := dr.List(db, storeID, status, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = disputes
+	return resp.ServerJSON(ctx)
+}
+
+func getDispute(ctx echo.Context) error {
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.Get(db, ctx.Param("dispute_id"))
+	if err != nil {
+		return serveDisputeQueryFailed(ctx, err)
+	}
+	return serveDisputeDetails(ctx, d)
+}
+
+func getDisputeOfStore(ctx echo.Context) error {
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("dispute_id"))
+	if err != nil {
+		return serveDisputeQueryFailed(ctx, err)
+	}
+	return serveDisputeDetails(ctx, d)
+}
+
+func serveDisputeDetails(ctx echo.Context, d *models.Dispute) error {
+	resp := core.Response{}
+
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	evidences, err := dr.ListEvidences(db, d.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = models.DisputeDetails{
+		Dispute:   *d,
+		Evidences: evidences,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func updateDispute(ctx echo.Context) error {
+	req, err := validators.ValidateUpdateDispute(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.DisputeDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.Get(db, ctx.Param("dispute_id"))
+	if err != nil {
+		db.Rollback()
+		return serveDisputeQueryFailed(ctx, err)
+	}
+
+	statusChanged := req.Status != nil && *req.Status != d.Status
+	if statusChanged {
+		if err := services.CheckDisputeTransition(d.Status, *req.Status); err != nil {
+			db.Rollback()
+
+			resp.Title = "Dispute status transition not allowed"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.DisputeStatusTransitionNotAllowed
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+	}
+
+	if req.Amount != nil {
+		d.Amount = *req.Amount
+	}
+	if req.Fee != nil {
+		d.Fee = *req.Fee
+	}
+	if req.Reason != nil {
+		d.Reason = *req.Reason
+	}
+	if req.EvidenceDueBy != nil {
+		d.EvidenceDueBy = req.EvidenceDueBy
+	}
+	if req.Note != nil {
+		d.Note = *req.Note
+	}
+
+	if statusChanged {
+		err = services.UpdateDisputeStatus(db, d, *req.Status)
+	} else {
+		d.UpdatedAt = time.Now().UTC()
+		if err = dr.Update(db, d); err == nil {
+			// The fee of a lost dispute may still be corrected
+			err = services.PostDisputeLedgerEntries(db, d)
+		}
+	}
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
+
+func uploadDisputeEvidence(ctx echo.Context) error {
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.Get(db, ctx.Param("dispute_id"))
+	if err != nil {
+		return serveDisputeQueryFailed(ctx, err)
+	}
+	return serveDisputeEvidenceUpload(ctx, d)
+}
+
+func uploadDisputeEvidenceByStore(ctx echo.Context) error {
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("dispute_id"))
+	if err != nil {
+		return serveDisputeQueryFailed(ctx, err)
+	}
+	return serveDisputeEvidenceUpload(ctx, d)
+}
+
+// serveDisputeEvidenceUpload keeps the uploaded file as evidence of the dispute in the reserved bucket
+func serveDisputeEvidenceUpload(ctx echo.Context, d *models.Dispute) error {
+	resp := core.Response{}
+
+	if err := ctx.Request().ParseMultipartForm(32 << 20); err != nil {
+		resp.Title = "Couldn't parse multipart form"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.InvalidMultiPartBody
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	r := ctx.Request()
+	r.Body = http.MaxBytesReader(ctx.Response(), r.Body, 32<<20) // 32 Mb
+
+	f, h, e := r.FormFile("file")
+	if e != nil {
+		resp.Title = "No multipart file"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.InvalidMultiPartBody
+		resp.Errors = e
+		return resp.ServerJSON(ctx)
+	}
+	defer f.Close()
+
+	body, errR := ioutil.ReadAll(f)
+	if errR != nil {
+		resp.Title = "Unable to read multipart data"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.UnableToReadMultiPartData
+		resp.Errors = errR
+		return resp.ServerJSON(ctx)
+	}
+
+	evidence := &models.DisputeEvidence{
+		ID:               utils.NewUUID(),
+		DisputeID:        d.ID,
+		FileName:         h.Filename,
+		ContentType:      h.Header.Get("Content-Type"),
+		Size:             int64(len(body)),
+		Description:      r.FormValue("description"),
+		UploadedByUserID: utils.GetUserID(ctx),
+		CreatedAt:        time.Now().UTC(),
+	}
+	if evidence.ContentType == "" {
+		evidence.ContentType = http.DetectContentType(body)
+	}
+	evidence.Path = fmt.Sprintf("%s/disputes/%s/%s%s", values.ReservedBucketName, d.ID, evidence.ID, filepath.Ext(h.Filename))
+
+	if err := services.UploadToMinio(evidence.Path, evidence.ContentType, bytes.NewReader(body), evidence.Size); err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	dr := data.NewDisputeRepository()
+	if err := dr.CreateEvidence(app.DB(), evidence); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = evidence
+	return resp.ServerJSON(ctx)
+}
+
+func downloadDisputeEvidence(ctx echo.Context) error {
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.Get(db, ctx.Param("dispute_id"))
+	if err != nil {
+		return serveDisputeQueryFailed(ctx, err)
+	}
+	return serveDisputeEvidenceFile(ctx, d)
+}
+
+func downloadDisputeEvidenceByStore(ctx echo.Context) error {
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("dispute_id"))
+	if err != nil {
+		return serveDisputeQueryFailed(ctx, err)
+	}
+	return serveDisputeEvidenceFile(ctx, d)
+}
+
+func serveDisputeEvidenceFile(ctx echo.Context, d *models.Dispute) error {
+	resp := core.Response{}
+
+	db := app.DB()
+	dr := data.NewDisputeRepository()
+
+	e, err := dr.GetEvidence(db, d.ID, ctx.Param("evidence_id"))
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Dispute evidence not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.DisputeEvidenceNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	f, err := services.ServeAsStreamFromMinio(e.Path)
+	if err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	return resp.ServeStreamFromMinioAsDownload(ctx, f)
+}
+
+// receiveDisputeWebhook records the disputes reported by the active payment gateway. Events which
+// aren't about a dispute and disputes of unknown transactions are acknowledged, so the gateway
+// doesn't retry them.
+func receiveDisputeWebhook(ctx echo.Context) error {
+	resp := core.Response{}
+
+	pg := payment_gateways.GetActivePaymentGateway()
+
+	dw, err := payment_gateways.GetDisputeWebhook(pg)
+	if err != nil {
+		resp.Title = "Dispute webhooks not supported"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.DisputeWebhookInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Response(), ctx.Request().Body, 1<<20))
+	if err != nil {
+		resp.Title = "Unable to read webhook body"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.DisputeWebhookInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	n, err := dw.ParseDisputeWebhook(ctx.Request().Header, body)
+	if err == payment_gateways.ErrDisputeWebhookIgnored {
+		resp.Status = http.StatusOK
+		return resp.ServerJSON(ctx)
+	}
+	if err != nil {
+		log.Log().Errorln("Invalid dispute webhook : ", err)
+
+		resp.Title = "Invalid dispute webhook"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.DisputeWebhookInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	d, err := services.IngestDisputeNotification(db, pg.GetName(), n)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			log.Log().Errorln("Order of dispute ", n.DisputeID, " not found for transaction ", n.TransactionID)
+
+			resp.Status = http.StatusOK
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
+
+func serveDisputeQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Dispute not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.DisputeNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
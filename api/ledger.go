@@ -0,0 +1,209 @@
+package api
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/queue"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func RegisterLedgerRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	ledgerPath := platformEndpoints.Group("/ledger")
+
+	func(g echo.Group) {
+		g.Use(middlewares.IsPlatformAdmin)
+		g.GET("/accounts/", listLedgerAccounts)
+		g.GET("/accounts/:code/statement/", getLedgerAccountStatement)
+		g.GET("/entries/", listLedgerEntries)
+		g.POST("/sync/", runLedgerSync)
+	}(*ledgerPath)
+}
+
+// postOrderLedgerEntries records the payment status of the order in the ledger within the given transaction.
+// The caller is responsible for rolling back on failure.
+func postOrderLedgerEntries(db *gorm.DB, orderID string) *core.Response {
+	if err := services.PostOrderLedgerEntries(db, orderID); err != nil {
+		return &core.Response{
+			Title:  "Failed to post ledger entries",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+	return nil
+}
+
+// postPayoutLedgerEntries records the status of the payout entry in the ledger within the given transaction.
+// The caller is responsible for rolling back on failure.
+func postPayoutLedgerEntries(db *gorm.DB, p *models.PayoutSend) *core.Response {
+	if err := services.PostPayoutLedgerEntries(db, p); err != nil {
+		return &core.Response{
+			Title:  "Failed to post ledger entries",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+	return nil
+}
+
+func listLedgerAccounts(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+	lu := data.NewLedgerRepository()
+
+	accounts, err := lu.ListAccountBalances(db, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = accounts
+	return resp.ServerJSON(ctx)
+}
+
+func getLedgerAccountStatement(ctx echo.Context) error {
+	return serveLedgerStatement(ctx, ctx.Param("code"))
+}
+
+func getStoreLedgerStatement(ctx echo.Context) error {
+	return serveLedgerStatement(ctx, models.StoreLedgerAccountCode(utils.GetStoreID(ctx)))
+}
+
+func getStoreLedgerStatementByMarketplace(ctx echo.Context) error {
+	return serveLedgerStatement(ctx, models.StoreLedgerAccountCode(ctx.Param("store_id")))
+}
+
+// serveLedgerStatement serves the balance and the journal lines of the account between the
+// optional from and to dates, most recent first
+func serveLedgerStatement(ctx echo.Context, code string) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+	fromQ := ctx.Request().URL.Query().Get("from")
+	toQ := ctx.Request().URL.Query().Get("to")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	start := time.Time{}
+	end := time.Now().UTC()
+
+	if fromQ != "" {
+		start, err = time.Parse(utils.DateFormat, fromQ)
+		if err != nil {
+			resp.Title = "Invalid statement period"
+			resp.Status = http.StatusUnprocessableEntity
+			resp.Code = errors.LedgerStatementQueryInvalid
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+	}
+	if toQ != "" {
+		end, err = time.Parse(utils.DateFormat, toQ)
+		if err != nil {
+			resp.Title = "Invalid statement period"
+			resp.Status = http.StatusUnprocessableEntity
+			resp.Code = errors.LedgerStatementQueryInvalid
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		end = end.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	db := app.DB()
+	lu := data.NewLedgerRepository()
+
+	account, err := lu.GetAccountBalance(db, code)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Ledger account not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.LedgerAccountNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	lines, err := lu.ListStatement(db, code, start, end, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"account": account,
+		"lines":   lines,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func listLedgerEntries(ctx echo.Context) error {
+	referenceType := ctx.QueryParam("reference_type")
+	referenceID := ctx.QueryParam("reference_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	lu := data.NewLedgerRepository()
+
+	entries, err := lu.ListEntries(db, models.JournalReferenceType(referenceType), referenceID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = entries
+	return resp.ServerJSON(ctx)
+}
+
+func runLedgerSync(ctx echo.Context) error {
+	resp := core.Response{}
+
+	if err := queue.RunLedgerSync(); err != nil {
+		resp.Title = "Failed to enqueue task"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.FailedToEnqueueTask
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	resp.Status = http.StatusAccepted
+	return resp.ServerJSON(ctx)
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/queue"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+)
+
+func getLicenseKeyPool(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	pool, err := services.GetLicenseKeyPoolDetails(db, p.StoreID, p.ID)
+	if err != nil {
+		return serveLicenseKeyPoolQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = pool
+	return resp.ServerJSON(ctx)
+}
+
+// updateLicenseKeyPool sets the low threshold of the pool, creating it makes the product hand out license keys
+func updateLicenseKeyPool(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateLicenseKeyPool(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.LicenseKeyDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	if !p.IsDigital {
+		db.Rollback()
+
+		resp.Title = "License keys are only for digital products"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.LicenseKeysNotAllowed
+		return resp.ServerJSON(ctx)
+	}
+
+	lu := data.NewLicenseKeyRepository()
+
+	pool, err := lu.GetPool(db, p.StoreID, p.ID)
+	if err != nil && !errors.IsRecordNotFoundError(err) {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if pool == nil {
+		pool = &models.LicenseKeyPool{
+			ProductID:    p.ID,
+			StoreID:      p.StoreID,
+			LowThreshold: req.LowThreshold,
+			CreatedAt:    time.Now().UTC(),
+			UpdatedAt:    time.Now().UTC(),
+		}
+		if err := lu.CreatePool(db, pool); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	} else {
+		// The store is warned again against the new threshold
+		if req.LowThreshold != pool.LowThreshold {
+			pool.WarnedAt = nil
+		}
+		pool.LowThreshold = req.LowThreshold
+		pool.UpdatedAt = time.Now().UTC()
+
+		if err := lu.UpdatePool(db, pool); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	details, err := services.GetLicenseKeyPoolDetails(db, p.StoreID, p.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = details
+	return resp.ServerJSON(ctx)
+}
+
+// uploadLicenseKeys adds keys to the pool of the product, creating the pool on the first upload. The keys
+// already in the pool are reported as duplicates, the paid orders waiting for keys get theirs right away.
+func uploadLicenseKeys(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateLicenseKeyUpload(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.LicenseKeyDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	if !p.IsDigital {
+		db.Rollback()
+
+		resp.Title = "License keys are only for digital products"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.LicenseKeysNotAllowed
+		return resp.ServerJSON(ctx)
+	}
+
+	lu := data.NewLicenseKeyRepository()
+
+	pool, err := lu.GetPool(db, p.StoreID, p.ID)
+	if err != nil {
+		if !errors.IsRecordNotFoundError(err) {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+
+		pool = &models.LicenseKeyPool{
+			ProductID:    p.ID,
+			StoreID:      p.StoreID,
+			LowThreshold: services.DefaultLicenseKeyLowThreshold,
+			CreatedAt:    time.Now().UTC(),
+			UpdatedAt:    time.Now().UTC(),
+		}
+		if err := lu.CreatePool(db, pool); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	result, err := services.AddLicenseKeys(db, pool, req.Keys)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	// The buyers waiting for keys get the order email again, now with their keys
+	for _, orderID := range result.FulfilledOrderIDs {
+		if err := queue.SendOrderDetailsEmail(orderID, "Your license keys are ready"); err != nil {
+			db.Rollback()
+
+			resp.Title = "Failed to queue send order details"
+			resp.Status = http.StatusInternalServerError
+			resp.Code = errors.FailedToEnqueueTask
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = result
+	return resp.ServerJSON(ctx)
+}
+
+func listLicenseKeys(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+	statusQ := ctx.Request().URL.Query().Get("status")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	var status *models.LicenseKeyStatus
+	if statusQ != "" {
+		s := models.LicenseKeyStatus(statusQ)
+		if !s.IsValid() {
+			resp.Title = "Invalid license key status"
+			resp.Status = http.StatusUnprocessableEntity
+			resp.Code = errors.LicenseKeyDataInvalid
+			return resp.ServerJSON(ctx)
+		}
+		status = &s
+	}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	lu := data.NewLicenseKeyRepository()
+	keys, err := lu.ListKeys(db, p.ID, status, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = keys
+	return resp.ServerJSON(ctx)
+}
+
+// deleteLicenseKey removes a key uploaded by mistake, only the keys not allocated yet can be removed
+func deleteLicenseKey(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	lu := data.NewLicenseKeyRepository()
+	if err := lu.DeleteKey(db, p.ID, ctx.Param("key_id")); err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Available license key not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.LicenseKeyNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+// syncOrderLicenseKeys allocates or revokes the license keys of the order for its payment status within the
+// given transaction. The caller is responsible for rolling back on failure.
+func syncOrderLicenseKeys(db *gorm.DB, orderID string) *core.Response {
+	if err := services.SyncOrderLicenseKeys(db, orderID); err != nil {
+		return &core.Response{
+			Title:  "Failed to sync license keys",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+	return nil
+}
+
+func serveLicenseKeyPoolQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "License key pool not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.LicenseKeyPoolNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
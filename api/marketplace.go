@@ -44,6 +44,12 @@ func RegisterPlatformRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 	func(g echo.Group) {
 		g.Use(middlewares.IsPlatformAdmin)
 		g.PATCH("/settings/", updateSettings)
+
+		g.POST("/commission-rules/", createCommissionRule)
+		g.PUT("/commission-rules/:rule_id/", updateCommissionRule)
+		g.DELETE("/commission-rules/:rule_id/", deleteCommissionRule)
+		g.GET("/commission-rules/", listCommissionRules)
+		g.GET("/commission-rules/:rule_id/", getCommissionRule)
 	}(*platformEndpoints)
 
 	func(g echo.Group) {
@@ -119,6 +125,9 @@ func updateSettings(ctx echo.Context) error {
 	if req.DefaultCommissionRate != nil {
 		s.DefaultCommissionRate = *req.DefaultCommissionRate
 	}
+	if req.DefaultReservePeriodDays != nil {
+		s.DefaultReservePeriodDays = *req.DefaultReservePeriodDays
+	}
 	if req.EnabledAutoStoreConfirmation != nil {
 		s.EnabledAutoStoreConfirmation = *req.EnabledAutoStoreConfirmation
 	}
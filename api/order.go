@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"github.com/jinzhu/gorm"
 	"github.com/labstack/echo/v4"
 	"github.com/shopicano/shopicano-backend/app"
 	"github.com/shopicano/shopicano-backend/core"
@@ -40,7 +41,9 @@ func RegisterOrderRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 		g.GET("/", listOrders)
 		g.GET("/:order_id/", getOrder)
 		g.POST("/:order_id/nonce/", generatePayNonce)
+		g.PATCH("/:order_id/payment-method/", changeOrderPaymentMethod)
 		g.POST("/:order_id/review/", createReview)
+		g.POST("/:order_id/items/:item_id/review/", createProductReview)
 		g.GET("/:order_id/products/:product_id/download/", downloadProductAsUser)
 		g.GET("/:order_id/nonce/", generatePayNonce)
 	}(*ordersPublicPath)
@@ -95,6 +98,7 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 	o.PaymentStatus = models.PaymentPending
 
 	pu := data.NewProductRepository()
+	vu := data.NewProductVariantRepository()
 	ou := data.NewOrderRepository()
 	au := data.NewMarketplaceRepository()
 	cu := data.NewCouponRepository()
@@ -140,13 +144,31 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 
 	var availableItems []*models.OrderedItem
 	var productAttributes []*models.OrderedItemAttribute
+	itemCategories := map[string]*string{}
 
 	var storeID *string
 
 	for _, v := range pld.Items {
 		orderedItemID := utils.NewUUID()
 
-		item, err := pu.GetForOrder(db, v.ID, v.Quantity)
+		if v.VariantID == nil {
+			hasVariants, err := vu.HasActiveVariants(db, v.ID)
+			if err != nil {
+				db.Rollback()
+				return serveDatabaseQueryFailed(ctx, err)
+			}
+
+			if hasVariants {
+				db.Rollback()
+
+				resp.Title = fmt.Sprintf("Product %s requires a variant", v.ID)
+				resp.Status = http.StatusBadRequest
+				resp.Code = errors.ProductVariantRequired
+				return resp.ServerJSON(ctx)
+			}
+		}
+
+		item, variant, err := pu.GetForOrder(db, v.ID, v.VariantID, v.Quantity)
 		if err != nil {
 			db.Rollback()
 
@@ -198,6 +220,28 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 			})
 		}
 
+		price := item.Price
+		var variantID *string
+
+		if variant != nil {
+			price = variant.EffectivePrice(item)
+			variantID = &variant.ID
+
+			options, err := vu.ListVariantOptions(db, variant.ID)
+			if err != nil {
+				db.Rollback()
+				return serveDatabaseQueryFailed(ctx, err)
+			}
+
+			for _, op := range options {
+				productAttributes = append(productAttributes, &models.OrderedItemAttribute{
+					OrderedItemID:  orderedItemID,
+					AttributeKey:   op.Option,
+					AttributeValue: op.Value,
+				})
+			}
+		}
+
 		if storeID == nil {
 			storeID = &item.StoreID
 			o.StoreID = *storeID
@@ -227,13 +271,15 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 			ID:          orderedItemID,
 			OrderID:     o.ID,
 			ProductID:   item.ID,
+			VariantID:   variantID,
 			Quantity:    v.Quantity,
-			Price:       item.Price,
+			Price:       price,
 			ProductCost: item.ProductCost,
 		}
-		oi.SubTotal = int64(v.Quantity) * item.Price
+		oi.SubTotal = int64(v.Quantity) * price
 
 		availableItems = append(availableItems, oi)
+		itemCategories[oi.ID] = item.CategoryID
 
 		o.SubTotal += oi.SubTotal
 	}
@@ -411,8 +457,10 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 	}
 
 	o.ActualEarnings = actualEarningsFromOrder
-	o.PlatformEarnings = s.CalculateCommission(o.ActualEarnings)
-	o.SellerEarnings = o.ActualEarnings - o.PlatformEarnings
+	if err := services.ApplyOrderCommission(db, s, &o, availableItems, itemCategories); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
 
 	err = ou.Create(db, &o)
 	if err != nil {
@@ -443,6 +491,20 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 		}
 	}
 
+	if err := services.AllocateOrderStock(db, &o, availableItems); err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Products are unavailable at any single location"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.ProductUnavailable
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
 	for _, v := range availableItems {
 		if err := ou.AddOrderedItem(db, v); err != nil {
 			db.Rollback()
@@ -573,6 +635,15 @@ func createNewOrder(ctx echo.Context, pld *validators.ReqOrderCreate) error {
 			db.Rollback()
 			return serveDatabaseQueryFailed(ctx, err)
 		}
+
+		if res := postOrderLedgerEntries(db, o.ID); res != nil {
+			db.Rollback()
+			return res.ServerJSON(ctx)
+		}
+		if res := syncOrderLicenseKeys(db, o.ID); res != nil {
+			db.Rollback()
+			return res.ServerJSON(ctx)
+		}
 	}
 
 	m, err := ou.GetDetailsAsUser(db, o.UserID, o.ID)
@@ -820,56 +891,72 @@ func orderPaymentStatusUpdate(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
-	r.PaymentStatus = pld.Status
-
-	if err := ou.UpdateStatus(db, r); err != nil {
+	if res := updateOrderPaymentStatus(db, r, pld.Status, fmt.Sprintf("Order payment status updated by %s", utils.GetUserID(ctx))); res != nil {
 		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
 
-		resp.Title = "Failed to update payment info"
+	if err := db.Commit().Error; err != nil {
+		resp.Title = "Database query failed"
 		resp.Status = http.StatusInternalServerError
 		resp.Code = errors.DatabaseQueryFailed
 		resp.Errors = err
 		return resp.ServerJSON(ctx)
 	}
 
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+// updateOrderPaymentStatus updates the payment status of the order in the given transaction,
+// logs the change and notifies the customer. The caller is responsible for rolling back on failure.
+func updateOrderPaymentStatus(db *gorm.DB, o *models.Order, status models.PaymentStatus, details string) *core.Response {
+	ou := data.NewOrderRepository()
+
+	o.PaymentStatus = status
+
+	if err := ou.UpdatePaymentStatus(db, o); err != nil {
+		return &core.Response{
+			Title:  "Failed to update payment info",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+
+	if res := postOrderLedgerEntries(db, o.ID); res != nil {
+		return res
+	}
+	if res := syncOrderLicenseKeys(db, o.ID); res != nil {
+		return res
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
-		OrderID:   r.ID,
-		Action:    string(r.PaymentStatus),
-		Details:   fmt.Sprintf("Order payment status updated by %s", utils.GetUserID(ctx)),
+		OrderID:   o.ID,
+		Action:    string(o.PaymentStatus),
+		Details:   details,
 		CreatedAt: time.Now().UTC(),
 	}
 	if err := ou.CreateLog(db, &ol); err != nil {
-		db.Rollback()
-
-		resp.Title = "Database query failed"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.DatabaseQueryFailed
-		resp.Errors = err
-		return resp.ServerJSON(ctx)
-	}
-
-	if err := queue.SendOrderDetailsEmail(r.ID, "Order payment status updated"); err != nil {
-		db.Rollback()
-
-		resp.Title = "Failed to enqueue task"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.FailedToEnqueueTask
-		resp.Errors = err
-		return resp.ServerJSON(ctx)
+		return &core.Response{
+			Title:  "Database query failed",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
 	}
 
-	if err := db.Commit().Error; err != nil {
-		resp.Title = "Database query failed"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.DatabaseQueryFailed
-		resp.Errors = err
-		return resp.ServerJSON(ctx)
+	if err := queue.SendOrderDetailsEmail(o.ID, "Order payment status updated"); err != nil {
+		return &core.Response{
+			Title:  "Failed to enqueue task",
+			Status: http.StatusInternalServerError,
+			Code:   errors.FailedToEnqueueTask,
+			Errors: err,
+		}
 	}
-
-	resp.Status = http.StatusOK
-	resp.Data = r
-	return resp.ServerJSON(ctx)
+	return nil
 }
 
 func listOrders(ctx echo.Context) error {
@@ -1084,3 +1171,15 @@ func serveDatabaseQueryFailed(ctx echo.Context, err error) error {
 	resp.Errors = err
 	return resp.ServerJSON(ctx)
 }
+
+func serveOrderQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Order not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.OrderNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
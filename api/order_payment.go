@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"github.com/jinzhu/gorm"
 	"github.com/labstack/echo/v4"
 	"github.com/shopicano/shopicano-backend/app"
 	"github.com/shopicano/shopicano-backend/config"
@@ -13,6 +14,7 @@ import (
 	payment_gateways "github.com/shopicano/shopicano-backend/payment-gateways"
 	"github.com/shopicano/shopicano-backend/queue"
 	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
 	"io/ioutil"
 	"net/http"
 	"time"
@@ -69,10 +71,59 @@ func payOrder(ctx echo.Context) error {
 		return processPayOrderForSSL(ctx, m)
 	case payment_gateways.PaddlePaymentGatewayName:
 		return processPayOrderForPaddle(ctx, m)
+	case payment_gateways.BKashPaymentGatewayName:
+		return processPayOrderForBKash(ctx, m)
+	case payment_gateways.MockPaymentGatewayName:
+		return processPayOrderForMock(ctx, m)
 	}
 	return serveInvalidPaymentRequest(ctx)
 }
 
+// lockUnpaidOrder locks the order for the payment within the given transaction, so the payments of the same
+// order run one after the other and only the first one charges. The caller is responsible for rolling back on failure.
+func lockUnpaidOrder(db *gorm.DB, orderID string) *core.Response {
+	ou := data.NewOrderRepository()
+	o, err := ou.GetForUpdate(db, orderID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			return &core.Response{
+				Title:  "Order not found",
+				Status: http.StatusNotFound,
+				Code:   errors.OrderNotFound,
+				Errors: err,
+			}
+		}
+		return &core.Response{
+			Title:  "Database query failed",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+
+	switch {
+	case o.Status == models.OrderCancelled:
+		return &core.Response{
+			Title:  "Order already cancelled",
+			Status: http.StatusBadRequest,
+			Code:   errors.OrderAlreadyCancelled,
+		}
+	case o.PaymentStatus == models.PaymentCompleted:
+		return &core.Response{
+			Title:  "Order already paid",
+			Status: http.StatusConflict,
+			Code:   errors.PaymentAlreadyProcessed,
+		}
+	case o.PaymentStatus == models.PaymentReverted:
+		return &core.Response{
+			Title:  "Order payment already reverted",
+			Status: http.StatusBadRequest,
+			Code:   errors.OrderPaymentAlreadyReverted,
+		}
+	}
+	return nil
+}
+
 type reqBrainTreeNonce struct {
 	Nonce *string `json:"nonce"`
 }
@@ -107,6 +158,20 @@ func processPayOrderForBrainTree(ctx echo.Context, o *models.OrderDetailsView) e
 		return resp.ServerJSON(ctx)
 	}
 
+	if o.Nonce == nil {
+		db.Rollback()
+
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.OrderPaymentDataInvalid
+		return resp.ServerJSON(ctx)
+	}
+
+	if res := lockUnpaidOrder(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	res, err := pg.Pay(o)
 	if err != nil {
 		db.Rollback()
@@ -138,6 +203,15 @@ func processPayOrderForBrainTree(ctx echo.Context, o *models.OrderDetailsView) e
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := postOrderLedgerEntries(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
 		OrderID:   o.ID,
@@ -199,6 +273,11 @@ func processPayOrderForStripe(ctx echo.Context, o *models.OrderDetailsView) erro
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := lockUnpaidOrder(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	if err := pg.ValidateTransaction(o); err != nil {
 		log.Log().Errorln(err)
 
@@ -217,6 +296,15 @@ func processPayOrderForStripe(ctx echo.Context, o *models.OrderDetailsView) erro
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := postOrderLedgerEntries(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
 		OrderID:   o.ID,
@@ -326,6 +414,15 @@ func processPayOrderFor2Checkout(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := postOrderLedgerEntries(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
 		OrderID:   m.ID,
@@ -405,6 +502,15 @@ func processPayOrderForSSL(ctx echo.Context, m *models.OrderDetailsView) error {
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := postOrderLedgerEntries(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
 		OrderID:   m.ID,
@@ -496,6 +602,15 @@ func processPayOrderForPaddle(ctx echo.Context, m *models.OrderDetailsView) erro
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := postOrderLedgerEntries(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
 		OrderID:   m.ID,
@@ -536,6 +651,202 @@ func processPayOrderForPaddle(ctx echo.Context, m *models.OrderDetailsView) erro
 	return ctx.JSON(http.StatusOK, nil)
 }
 
+func processPayOrderForMock(ctx echo.Context, m *models.OrderDetailsView) error {
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	if res := lockUnpaidOrder(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	if m.PaymentGateway != payment_gateways.MockPaymentGatewayName {
+		db.Rollback()
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(m.PaymentGateway)
+	if err != nil {
+		db.Rollback()
+
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	if err := pg.ValidateTransaction(m); err != nil {
+		log.Log().Errorln(err)
+
+		m.PaymentStatus = models.PaymentFailed
+	} else {
+		m.PaymentStatus = models.PaymentCompleted
+	}
+
+	or := data.NewOrderRepository()
+
+	if err := or.UpdatePaymentInfo(db, m); err != nil {
+		db.Rollback()
+
+		resp.Title = "Failed to update payment info"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if res := postOrderLedgerEntries(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	ol := models.OrderLog{
+		ID:        utils.NewUUID(),
+		OrderID:   m.ID,
+		Action:    string(m.PaymentStatus),
+		Details:   fmt.Sprintf("Payment has been updated using %s", pg.DisplayName()),
+		CreatedAt: time.Now(),
+	}
+	if err := or.CreateLog(db, &ol); err != nil {
+		db.Rollback()
+
+		resp.Title = "Database query failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if m.PaymentStatus == models.PaymentCompleted {
+		if err := queue.SendPaymentConfirmationEmail(m.ID); err != nil {
+			db.Rollback()
+
+			resp.Title = "Failed to enqueue task"
+			resp.Status = http.StatusInternalServerError
+			resp.Code = errors.FailedToEnqueueTask
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		resp.Title = "Database query failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	orderPath := fmt.Sprintf(config.PathMappingCfg()["after_payment_completed"], m.ID)
+	paymentCompletedCallback := fmt.Sprintf("%s%s", config.App().FrontStoreUrl, orderPath)
+	return ctx.Redirect(http.StatusPermanentRedirect, paymentCompletedCallback)
+}
+
+func processPayOrderForBKash(ctx echo.Context, m *models.OrderDetailsView) error {
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	if res := lockUnpaidOrder(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	if m.PaymentGateway != payment_gateways.BKashPaymentGatewayName {
+		db.Rollback()
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(m.PaymentGateway)
+	if err != nil {
+		db.Rollback()
+
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	if m.TransactionID == nil || ctx.QueryParam("paymentID") != *m.TransactionID {
+		db.Rollback()
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	// The payment is executed only when the buyer has authorized it in the bKash checkout
+	if ctx.QueryParam("status") != "success" {
+		log.Log().Errorln("bKash payment wasn't authorized : ", ctx.QueryParam("status"))
+
+		m.PaymentStatus = models.PaymentFailed
+	} else if err := pg.ValidateTransaction(m); err != nil {
+		log.Log().Errorln(err)
+
+		m.PaymentStatus = models.PaymentFailed
+	} else {
+		m.PaymentStatus = models.PaymentCompleted
+	}
+
+	or := data.NewOrderRepository()
+
+	if err := or.UpdatePaymentInfo(db, m); err != nil {
+		db.Rollback()
+
+		resp.Title = "Failed to update payment info"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if res := postOrderLedgerEntries(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, m.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	ol := models.OrderLog{
+		ID:        utils.NewUUID(),
+		OrderID:   m.ID,
+		Action:    string(m.PaymentStatus),
+		Details:   fmt.Sprintf("Payment has been updated using %s", pg.DisplayName()),
+		CreatedAt: time.Now(),
+	}
+	if err := or.CreateLog(db, &ol); err != nil {
+		db.Rollback()
+
+		resp.Title = "Database query failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if m.PaymentStatus == models.PaymentCompleted {
+		if err := queue.SendPaymentConfirmationEmail(m.ID); err != nil {
+			db.Rollback()
+
+			resp.Title = "Failed to enqueue task"
+			resp.Status = http.StatusInternalServerError
+			resp.Code = errors.FailedToEnqueueTask
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		resp.Title = "Database query failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	orderPath := fmt.Sprintf(config.PathMappingCfg()["after_payment_completed"], m.ID)
+	paymentCompletedCallback := fmt.Sprintf("%s%s", config.App().FrontStoreUrl, orderPath)
+	return ctx.Redirect(http.StatusPermanentRedirect, paymentCompletedCallback)
+}
+
 // generatePayNonce create payment reference / nonce
 func generatePayNonce(ctx echo.Context) error {
 	orderID := ctx.Param("order_id")
@@ -546,7 +857,7 @@ func generatePayNonce(ctx echo.Context) error {
 
 	ou := data.NewOrderRepository()
 	m, err := ou.GetDetails(db, orderID)
-	if err != nil {
+	if err != nil || m.UserID != utils.GetUserID(ctx) {
 		resp.Title = "Order not found"
 		resp.Status = http.StatusNotFound
 		resp.Code = errors.OrderNotFound
@@ -562,6 +873,23 @@ func generatePayNonce(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
+	req, err := validators.ValidatePayNonce(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.OrderPaymentDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if req.SavedPaymentMethodID != nil {
+		return payWithSavedPaymentMethod(ctx, m, *req.SavedPaymentMethodID)
+	}
+	return issuePayNonce(ctx, m)
+}
+
+// issuePayNonce generates the nonce or the pay url of the order for its payment gateway
+func issuePayNonce(ctx echo.Context, m *models.OrderDetailsView) error {
 	switch m.PaymentGateway {
 	case payment_gateways.StripePaymentGatewayName:
 		return generateStripePayNonce(ctx, m)
@@ -571,10 +899,203 @@ func generatePayNonce(ctx echo.Context) error {
 		return generateSSLPayUrl(ctx, m)
 	case payment_gateways.PaddlePaymentGatewayName:
 		return generatePaddlePayUrl(ctx, m)
+	case payment_gateways.BKashPaymentGatewayName:
+		return generateBKashPayUrl(ctx, m)
+	case payment_gateways.MockPaymentGatewayName:
+		return generateMockPayUrl(ctx, m)
 	}
 	return serveInvalidPaymentRequest(ctx)
 }
 
+// changeOrderPaymentMethod switches the payment method of an unpaid order or retries its failed payment.
+// The processing fee and the grand total are recomputed and a fresh nonce or pay url is issued.
+func changeOrderPaymentMethod(ctx echo.Context) error {
+	orderID := ctx.Param("order_id")
+
+	pld, err := validators.ValidateChangeOrderPaymentMethod(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.OrderPaymentDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	ou := data.NewOrderRepository()
+	o, err := ou.GetAsUser(db, utils.GetUserID(ctx), orderID)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Order not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.OrderNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	// The order is read again once locked, a payment may have completed in between
+	if res := lockUnpaidOrder(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	o, err = ou.GetAsUser(db, utils.GetUserID(ctx), orderID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	au := data.NewMarketplaceRepository()
+	pm, err := au.GetPaymentMethodForUser(db, pld.PaymentMethodID)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Payment method not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.PaymentMethodNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if o.IsAllDigitalProducts && pm.IsOfflinePayment {
+		db.Rollback()
+
+		resp.Title = "Payment method must be online for digital products"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.PaymentMethodMustBeOnlineForDigitalProducts
+		return resp.ServerJSON(ctx)
+	}
+
+	if res := voidPreviousPaymentSession(db, o); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	previousPaymentMethodID := o.PaymentMethodID
+	previousGrandTotal := o.GrandTotal
+	previousTransactionID := "none"
+	if o.TransactionID != nil {
+		previousTransactionID = *o.TransactionID
+	}
+
+	// The processing fee is charged on the bill before the discount, same as when the order was created
+	bill := o.OriginalGrandTotal - o.PaymentProcessingFee
+
+	pgName := payment_gateways.GetActivePaymentGateway().GetName()
+
+	o.PaymentMethodID = pm.ID
+	o.PaymentGateway = &pgName
+	o.PaymentProcessingFee = pm.CalculateProcessingFee(bill)
+	o.OriginalGrandTotal = bill + o.PaymentProcessingFee
+	o.GrandTotal = o.OriginalGrandTotal - o.DiscountedAmount
+	o.Nonce = nil
+	o.TransactionID = nil
+	o.PaymentStatus = models.PaymentPending
+	o.UpdatedAt = time.Now().UTC()
+
+	if err := ou.UpdatePaymentMethod(db, o); err != nil {
+		db.Rollback()
+
+		resp.Title = "Failed to update payment info"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	ol := models.OrderLog{
+		ID:      utils.NewUUID(),
+		OrderID: o.ID,
+		Action:  string(o.PaymentStatus),
+		Details: fmt.Sprintf("Payment method changed from %s to %s by %s, grand total changed from %d to %d, previous transaction %s",
+			previousPaymentMethodID, o.PaymentMethodID, utils.GetUserID(ctx), previousGrandTotal, o.GrandTotal, previousTransactionID),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := ou.CreateLog(db, &ol); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	m, err := ou.GetDetails(app.DB(), o.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	// Offline payments and BrainTree don't need a nonce from the server
+	if pm.IsOfflinePayment || m.PaymentGateway == payment_gateways.BrainTreePaymentGatewayName {
+		resp.Status = http.StatusOK
+		resp.Data = m
+		return resp.ServerJSON(ctx)
+	}
+	return issuePayNonce(ctx, m)
+}
+
+// voidPreviousPaymentSession makes sure the payment session the order is switched away from can't charge
+// the buyer. A session the buyer completed meanwhile is voided, so the order is only paid through the new one.
+func voidPreviousPaymentSession(db *gorm.DB, o *models.Order) *core.Response {
+	if o.TransactionID == nil || o.PaymentGateway == nil {
+		return nil
+	}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(*o.PaymentGateway)
+	if err != nil {
+		return &core.Response{
+			Title:  "Invalid payment gateway",
+			Status: http.StatusInternalServerError,
+			Code:   errors.PaymentGatewayFailed,
+			Errors: err,
+		}
+	}
+
+	ou := data.NewOrderRepository()
+	details, err := ou.GetDetails(db, o.ID)
+	if err != nil {
+		return &core.Response{
+			Title:  "Database query failed",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+
+	// Sessions the gateway doesn't validate were never paid
+	if err := pg.ValidateTransaction(details); err != nil {
+		return nil
+	}
+
+	if err := pg.VoidTransaction(details, map[string]interface{}{
+		"reason": "Payment method changed",
+		"type":   1,
+	}); err != nil {
+		log.Log().Errorln(err)
+
+		return &core.Response{
+			Title:  "Failed to void the previous payment",
+			Status: http.StatusInternalServerError,
+			Code:   errors.PaymentGatewayFailed,
+			Errors: err,
+		}
+	}
+	return nil
+}
+
 func generateStripePayNonce(ctx echo.Context, o *models.OrderDetailsView) error {
 	resp := core.Response{}
 
@@ -612,7 +1133,8 @@ func generateStripePayNonce(ctx echo.Context, o *models.OrderDetailsView) error
 
 	resp.Status = http.StatusOK
 	resp.Data = map[string]interface{}{
-		"nonce": res.Nonce,
+		"nonce":          res.Nonce,
+		"transaction_id": res.Result,
 	}
 	return resp.ServerJSON(ctx)
 }
@@ -722,6 +1244,95 @@ func generatePaddlePayUrl(ctx echo.Context, o *models.OrderDetailsView) error {
 	return resp.ServerJSON(ctx)
 }
 
+func generateMockPayUrl(ctx echo.Context, o *models.OrderDetailsView) error {
+	resp := core.Response{}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(o.PaymentGateway)
+	if err != nil {
+		resp.Title = "Invalid payment gateway"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentProcessingFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		log.Log().Infoln(err)
+
+		resp.Title = "Failed to process payment"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentProcessingFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+	or := data.NewOrderRepository()
+
+	o.TransactionID = &res.Result
+	o.Nonce = &res.Nonce
+
+	if err := or.UpdatePaymentInfo(db, o); err != nil {
+		resp.Title = "Failed to update payment info"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	url := res.Nonce
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"url": url,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func generateBKashPayUrl(ctx echo.Context, o *models.OrderDetailsView) error {
+	resp := core.Response{}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(o.PaymentGateway)
+	if err != nil {
+		resp.Title = "Invalid payment gateway"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentProcessingFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		log.Log().Infoln(err)
+
+		resp.Title = "Failed to process payment"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentProcessingFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+	or := data.NewOrderRepository()
+
+	o.TransactionID = &res.Result
+	o.Nonce = &res.Nonce
+
+	if err := or.UpdatePaymentInfo(db, o); err != nil {
+		resp.Title = "Failed to update payment info"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.DatabaseQueryFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	url := res.Nonce
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"url": url,
+	}
+	return resp.ServerJSON(ctx)
+}
 func serveInvalidPaymentRequest(ctx echo.Context) error {
 	resp := core.Response{}
 	resp.Title = "Invalid payment request"
@@ -772,6 +1383,10 @@ func revertOrderPayment(ctx echo.Context) error {
 		return revertOrderPaymentForAny(ctx, m)
 	case payment_gateways.SSLCommerzPaymentGatewayName:
 		return revertOrderPaymentForAny(ctx, m)
+	case payment_gateways.BKashPaymentGatewayName:
+		return revertOrderPaymentForAny(ctx, m)
+	case payment_gateways.MockPaymentGatewayName:
+		return revertOrderPaymentForAny(ctx, m)
 	}
 	return serveInvalidPaymentRequest(ctx)
 }
@@ -834,6 +1449,15 @@ func revertOrderPaymentForAny(ctx echo.Context, details *models.OrderDetailsView
 		return resp.ServerJSON(ctx)
 	}
 
+	if res := postOrderLedgerEntries(db, details.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, details.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
 	ol := models.OrderLog{
 		ID:        utils.NewUUID(),
 		OrderID:   details.ID,
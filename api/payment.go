@@ -1,10 +1,13 @@
 package api
 
 import (
+	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/shopicano/shopicano-backend/core"
 	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
 	gateway "github.com/shopicano/shopicano-backend/payment-gateways"
+	"github.com/shopicano/shopicano-backend/templates"
 	"net/http"
 )
 
@@ -13,6 +16,9 @@ func RegisterPaymentRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 
 	paymentsPublicPath.GET("/configs/", getPaymentGatewayConfig)
 	paymentsPublicPath.GET("/confirm/", processPayOrderFor2Checkout)
+	paymentsPublicPath.GET("/mock/:transaction_id/", serveMockPayPage)
+	paymentsPublicPath.POST("/mock/:transaction_id/", submitMockPayPage)
+	paymentsPublicPath.POST("/webhooks/disputes/", receiveDisputeWebhook)
 }
 
 func getPaymentGatewayConfig(ctx echo.Context) error {
@@ -31,3 +37,82 @@ func getPaymentGatewayConfig(ctx echo.Context) error {
 	resp.Data = config
 	return resp.ServerJSON(ctx)
 }
+
+// serveMockPayPage renders the hosted pay page of the mock payment gateway
+func serveMockPayPage(ctx echo.Context) error {
+	if gateway.GetActivePaymentGateway().GetName() != gateway.MockPaymentGatewayName {
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	resp := core.Response{}
+
+	trx, err := gateway.GetMockTransaction(ctx.Param("transaction_id"))
+	if err != nil {
+		resp.Title = "Transaction not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.PaymentProcessingFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	body, err := templates.GenerateMockPayPageHTML(map[string]interface{}{
+		"orderHash":     trx.OrderHash,
+		"amount":        fmt.Sprintf("%.2f", float64(trx.Amount)/100),
+		"transactionID": trx.ID,
+		"action":        ctx.Request().URL.Path,
+	})
+	if err != nil {
+		log.Log().Errorln(err)
+
+		resp.Title = "Failed to render pay page"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return ctx.HTML(http.StatusOK, body)
+}
+
+// submitMockPayPage records the outcome chosen on the mock pay page and
+// redirects the buyer to the payment callback of the order
+func submitMockPayPage(ctx echo.Context) error {
+	if gateway.GetActivePaymentGateway().GetName() != gateway.MockPaymentGatewayName {
+		return serveInvalidPaymentRequest(ctx)
+	}
+
+	resp := core.Response{}
+
+	outcome := gateway.MockOutcome(ctx.FormValue("outcome"))
+
+	trx, err := gateway.SetMockTransactionOutcome(ctx.Param("transaction_id"), outcome)
+	if err != nil {
+		if err == gateway.ErrMockTransactionNotFound {
+			resp.Title = "Transaction not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.PaymentProcessingFailed
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		resp.Title = "Invalid outcome"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.OrderPaymentDataInvalid
+		resp.Errors = errors.NewError(err.Error())
+		return resp.ServerJSON(ctx)
+	}
+
+	cfg, err := gateway.GetActivePaymentGateway().GetConfig()
+	if err != nil {
+		resp.Title = "Failed to get payment gateway config"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	callback := cfg["success_callback_url"].(string)
+	if outcome != gateway.MockOutcomeSuccess {
+		callback = cfg["failure_callback_url"].(string)
+	}
+	return ctx.Redirect(http.StatusSeeOther, fmt.Sprintf(callback, trx.OrderID))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/shopicano/shopicano-backend/data"
 	"github.com/shopicano/shopicano-backend/errors"
 	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
 	"github.com/shopicano/shopicano-backend/utils"
 	"github.com/shopicano/shopicano-backend/validators"
 	"net/http"
@@ -29,54 +30,26 @@ func createPayoutEntry(ctx echo.Context) error {
 
 	db := app.DB().Begin()
 	au := data.NewMarketplaceRepository()
-	su := data.NewStoreRepository()
 
-	sv, err := su.GetStoreFinanceSummary(db, utils.GetStoreID(ctx))
+	available, err := services.LockStoreAvailableBalance(db, utils.GetStoreID(ctx))
 	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		sv = &models.StoreFinanceSummaryView{
-			StoreID:         utils.GetStoreID(ctx),
-			TotalCommission: 0,
-			TotalEarnings:   0,
-			TotalIncome:     0,
-		}
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
 	}
 
-	pv, err := su.GetStorePayoutSummary(db, utils.GetStoreID(ctx))
-	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		pv = &models.StorePayoutSummaryView{
-			StoreID:        utils.GetStoreID(ctx),
-			TotalEarnings:  sv.TotalEarnings,
-			TotalAvailable: sv.TotalEarnings,
-			TotalPaid:      0,
-		}
-	}
+	if available-req.Amount < 0 {
+		db.Rollback()
 
-	if pv.TotalAvailable-req.Amount < 0 {
 		resp.Title = "Payout amount is invalid"
 		resp.Status = http.StatusBadRequest
 		resp.Code = errors.PayoutAmountInvalid
-		resp.Errors = err
 		return resp.ServerJSON(ctx)
 	}
 
 	psd, err := au.GetPayoutSettingsDetails(db, utils.GetStoreID(ctx))
 	if err != nil {
+		db.Rollback()
+
 		if errors.IsRecordNotFoundError(err) {
 			resp.Title = "Payout settings not found"
 			resp.Status = http.StatusNotFound
@@ -109,11 +82,19 @@ func createPayoutEntry(ctx echo.Context) error {
 	}
 
 	if err := au.CreatePayoutEntry(db, m); err != nil {
-		resp.Title = "Database query failed"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.DatabaseQueryFailed
-		resp.Errors = err
-		return resp.ServerJSON(ctx)
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if res := postPayoutLedgerEntries(db, m); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	actorID := utils.GetUserID(ctx)
+	if _, err := services.RecordPayoutCreated(db, m, &actorID, m.Note); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
 	}
 
 	if err := db.Commit().Error; err != nil {
@@ -146,54 +127,26 @@ func createPayoutEntryByMarketplace(ctx echo.Context) error {
 
 	db := app.DB().Begin()
 	au := data.NewMarketplaceRepository()
-	su := data.NewStoreRepository()
 
-	sv, err := su.GetStoreFinanceSummary(db, storeID)
+	available, err := services.LockStoreAvailableBalance(db, storeID)
 	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		sv = &models.StoreFinanceSummaryView{
-			StoreID:         storeID,
-			TotalCommission: 0,
-			TotalEarnings:   0,
-			TotalIncome:     0,
-		}
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
 	}
 
-	pv, err := su.GetStorePayoutSummary(db, storeID)
-	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		pv = &models.StorePayoutSummaryView{
-			StoreID:        storeID,
-			TotalEarnings:  sv.TotalEarnings,
-			TotalAvailable: sv.TotalEarnings,
-			TotalPaid:      0,
-		}
-	}
+	if available-req.Amount < 0 {
+		db.Rollback()
 
-	if pv.TotalAvailable-req.Amount < 0 {
 		resp.Title = "Payout amount is invalid"
 		resp.Status = http.StatusBadRequest
 		resp.Code = errors.PayoutAmountInvalid
-		resp.Errors = err
 		return resp.ServerJSON(ctx)
 	}
 
 	psd, err := au.GetPayoutSettingsDetails(db, storeID)
 	if err != nil {
+		db.Rollback()
+
 		if errors.IsRecordNotFoundError(err) {
 			resp.Title = "Payout settings not found"
 			resp.Status = http.StatusNotFound
@@ -226,11 +179,19 @@ func createPayoutEntryByMarketplace(ctx echo.Context) error {
 	}
 
 	if err := au.CreatePayoutEntry(db, m); err != nil {
-		resp.Title = "Database query failed"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.DatabaseQueryFailed
-		resp.Errors = err
-		return resp.ServerJSON(ctx)
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if res := postPayoutLedgerEntries(db, m); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	actorID := utils.GetUserID(ctx)
+	if _, err := services.RecordPayoutCreated(db, m, &actorID, m.Note); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
 	}
 
 	if err := db.Commit().Error; err != nil {
@@ -250,43 +211,10 @@ func getStorePayoutSummary(ctx echo.Context) error {
 	resp := core.Response{}
 
 	db := app.DB()
-	su := data.NewStoreRepository()
 
-	sv, err := su.GetStoreFinanceSummary(db, utils.GetStoreID(ctx))
+	sv, err := services.GetStoreLedgerSummary(db, utils.GetStoreID(ctx))
 	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		sv = &models.StoreFinanceSummaryView{
-			StoreID:         utils.GetStoreID(ctx),
-			TotalCommission: 0,
-			TotalEarnings:   0,
-			TotalIncome:     0,
-		}
-	}
-
-	pv, err := su.GetStorePayoutSummary(db, utils.GetStoreID(ctx))
-	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		pv = &models.StorePayoutSummaryView{
-			StoreID:        utils.GetStoreID(ctx),
-			TotalEarnings:  sv.TotalEarnings,
-			TotalAvailable: sv.TotalEarnings,
-			TotalPaid:      0,
-			TotalRequested: 0,
-		}
+		return serveDatabaseQueryFailed(ctx, err)
 	}
 
 	resp.Status = http.StatusOK
@@ -294,9 +222,11 @@ func getStorePayoutSummary(ctx echo.Context) error {
 		"total_income":     sv.TotalIncome,
 		"total_earnings":   sv.TotalEarnings,
 		"total_commission": sv.TotalCommission,
-		"total_requested":  pv.TotalRequested,
-		"total_available":  pv.TotalAvailable,
-		"total_paid":       pv.TotalPaid,
+		"total_requested":  sv.TotalRequested,
+		"total_on_hold":    sv.TotalOnHold,
+		"total_frozen":     sv.TotalFrozen,
+		"total_available":  sv.TotalAvailable,
+		"total_paid":       sv.TotalPaid,
 	}
 	return resp.ServerJSON(ctx)
 }
@@ -304,46 +234,11 @@ func getStorePayoutSummary(ctx echo.Context) error {
 func getStorePayoutSummaryByMarketplace(ctx echo.Context) error {
 	resp := core.Response{}
 
-	storeID := ctx.Param("store_id")
-
 	db := app.DB()
-	su := data.NewStoreRepository()
 
-	sv, err := su.GetStoreFinanceSummary(db, storeID)
+	sv, err := services.GetStoreLedgerSummary(db, ctx.Param("store_id"))
 	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		sv = &models.StoreFinanceSummaryView{
-			StoreID:         utils.GetStoreID(ctx),
-			TotalCommission: 0,
-			TotalEarnings:   0,
-			TotalIncome:     0,
-		}
-	}
-
-	pv, err := su.GetStorePayoutSummary(db, storeID)
-	if err != nil {
-		if !errors.IsRecordNotFoundError(err) {
-			resp.Title = "Database query failed"
-			resp.Status = http.StatusInternalServerError
-			resp.Code = errors.DatabaseQueryFailed
-			resp.Errors = err
-			return resp.ServerJSON(ctx)
-		}
-
-		pv = &models.StorePayoutSummaryView{
-			StoreID:        utils.GetStoreID(ctx),
-			TotalEarnings:  sv.TotalEarnings,
-			TotalAvailable: sv.TotalEarnings,
-			TotalPaid:      0,
-			TotalRequested: 0,
-		}
+		return serveDatabaseQueryFailed(ctx, err)
 	}
 
 	resp.Status = http.StatusOK
@@ -351,9 +246,11 @@ func getStorePayoutSummaryByMarketplace(ctx echo.Context) error {
 		"total_income":     sv.TotalIncome,
 		"total_earnings":   sv.TotalEarnings,
 		"total_commission": sv.TotalCommission,
-		"total_requested":  pv.TotalRequested,
-		"total_available":  pv.TotalAvailable,
-		"total_paid":       pv.TotalPaid,
+		"total_requested":  sv.TotalRequested,
+		"total_on_hold":    sv.TotalOnHold,
+		"total_frozen":     sv.TotalFrozen,
+		"total_available":  sv.TotalAvailable,
+		"total_paid":       sv.TotalPaid,
 	}
 	return resp.ServerJSON(ctx)
 }
@@ -488,6 +385,44 @@ func getPayoutEntryByMarketplace(ctx echo.Context) error {
 	return resp.ServerJSON(ctx)
 }
 
+func listPayoutEvents(ctx echo.Context) error {
+	return servePayoutEvents(ctx, utils.GetStoreID(ctx))
+}
+
+func listPayoutEventsByMarketplace(ctx echo.Context) error {
+	return servePayoutEvents(ctx, ctx.Param("store_id"))
+}
+
+// servePayoutEvents serves the status history of the payout entry, oldest first
+func servePayoutEvents(ctx echo.Context, storeID string) error {
+	entryID := ctx.Param("entry_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	au := data.NewMarketplaceRepository()
+
+	if _, err := au.GetPayoutEntry(db, storeID, entryID); err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Payout entry not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.PayoutEntryNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	events, err := au.ListPayoutEvents(db, storeID, entryID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = events
+	return resp.ServerJSON(ctx)
+}
+
 func updatePayoutEntryByMarketplace(ctx echo.Context) error {
 	entryID := ctx.Param("entry_id")
 	storeID := ctx.Param("store_id")
@@ -503,11 +438,23 @@ func updatePayoutEntryByMarketplace(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
-	db := app.DB()
+	db := app.DB().Begin()
 	au := data.NewMarketplaceRepository()
 
-	entry, err := au.GetPayoutEntry(db, storeID, entryID)
+	// The store balance is locked before the entry, like the payouts being created
+	var available int64
+	if pld.Amount != nil {
+		available, err = services.LockStoreAvailableBalance(db, storeID)
+		if err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	entry, err := au.GetPayoutEntryForUpdate(db, storeID, entryID)
 	if err != nil {
+		db.Rollback()
+
 		if errors.IsRecordNotFoundError(err) {
 			resp.Title = "Payout entry not found"
 			resp.Status = http.StatusNotFound
@@ -523,26 +470,82 @@ func updatePayoutEntryByMarketplace(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
-	if pld.Status != nil {
-		entry.Status = *pld.Status
+	statusChanged := pld.Status != nil && *pld.Status != entry.Status
+	if statusChanged {
+		if err := services.CheckPayoutTransition(entry.Status, *pld.Status); err != nil {
+			db.Rollback()
+
+			resp.Title = "Payout status transition not allowed"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.PayoutStatusTransitionNotAllowed
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
 	}
+
 	if pld.FailureReason != nil {
 		entry.FailureReason = *pld.FailureReason
 	}
 	if pld.Highlights != nil {
 		entry.Highlights = *pld.Highlights
 	}
-	if pld.Amount != nil {
+	// Only pending payouts can be changed, the available balance already has the current amount taken out
+	if pld.Amount != nil && *pld.Amount != entry.Amount {
+		if entry.Status != models.PayoutSendStatusPending {
+			db.Rollback()
+
+			resp.Title = "Payout amount can only be changed while pending"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.PayoutAmountInvalid
+			return resp.ServerJSON(ctx)
+		}
+
+		if available-(*pld.Amount-entry.Amount) < 0 {
+			db.Rollback()
+
+			resp.Title = "Payout amount is invalid"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.PayoutAmountInvalid
+			return resp.ServerJSON(ctx)
+		}
+
 		entry.Amount = *pld.Amount
 	}
+	entry.UpdatedAt = time.Now().UTC()
 
-	err = au.UpdatePayoutEntry(db, entry)
-	if err != nil {
-		resp.Title = "Database query failed"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.DatabaseQueryFailed
-		resp.Errors = err
-		return resp.ServerJSON(ctx)
+	var event *models.PayoutEvent
+
+	if statusChanged {
+		note := ""
+		if pld.Note != nil {
+			note = *pld.Note
+		}
+
+		actorID := utils.GetUserID(ctx)
+		event, err = services.UpdatePayoutStatus(db, entry, *pld.Status, entry.FailureReason, &actorID, note)
+		if err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	} else {
+		err = au.UpdatePayoutEntry(db, entry)
+		if err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+
+		if res := postPayoutLedgerEntries(db, entry); res != nil {
+			db.Rollback()
+			return res.ServerJSON(ctx)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if event != nil {
+		sendPayoutStatusEmails([]*models.PayoutEvent{event})
 	}
 
 	resp.Status = http.StatusOK
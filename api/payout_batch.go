@@ -0,0 +1,415 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/queue"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+	"github.com/shopicano/shopicano-backend/values"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func RegisterPayoutBatchRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	batchesPath := platformEndpoints.Group("/payout-batches")
+
+	func(g echo.Group) {
+		g.Use(middlewares.IsPlatformAdmin)
+		g.POST("/", createPayoutBatch)
+		g.GET("/", listPayoutBatches)
+		g.GET("/:batch_id/", getPayoutBatch)
+		g.GET("/:batch_id/file/", downloadPayoutBatchFile)
+		g.POST("/:batch_id/report/", uploadPayoutBatchReport)
+	}(*batchesPath)
+}
+
+// createPayoutBatch exports the selected confirmed payout entries as a batch file for the bank
+// and moves them to processing
+func createPayoutBatch(ctx echo.Context) error {
+	resp := core.Response{}
+
+	pld, err := validators.ValidateCreatePayoutBatch(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.PayoutBatchDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+	mu := data.NewMarketplaceRepository()
+
+	entries, err := mu.ListPayoutEntriesByIDsForUpdate(db, pld.EntryIDs)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	found := map[string]bool{}
+	for _, e := range entries {
+		found[e.ID] = true
+	}
+
+	ve := errors.ValidationError{}
+	for _, id := range pld.EntryIDs {
+		if !found[id] {
+			ve.Add(id, "payout entry not found")
+		}
+	}
+
+	b := &models.PayoutBatch{
+		ID:              utils.NewUUID(),
+		Format:          pld.Format,
+		Status:          models.PayoutBatchExported,
+		Currency:        config.Payout().Currency,
+		TotalEntries:    len(entries),
+		CreatedByUserID: utils.GetUserID(ctx),
+		CreatedAt:       time.Now().UTC(),
+		UpdatedAt:       time.Now().UTC(),
+	}
+
+	recipients := map[string]*services.PayoutRecipient{}
+	for _, e := range entries {
+		if e.Status != models.PayoutSendStatusConfirmed || e.BatchID != nil {
+			ve.Add(e.ID, "payout entry is not confirmed")
+			continue
+		}
+
+		r, err := services.ParsePayoutRecipient(e.PayoutMethodDetails)
+		if err != nil {
+			ve.Add(e.ID, err.Error())
+			continue
+		}
+		recipients[e.ID] = r
+		b.TotalAmount += e.Amount
+	}
+
+	if len(ve) > 0 {
+		db.Rollback()
+
+		resp.Title = "Payout entries can't be exported"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.PayoutEntryNotExportable
+		resp.Errors = &ve
+		return resp.ServerJSON(ctx)
+	}
+
+	body, ext, contentType, err := services.GeneratePayoutBatchFile(b, entries, recipients)
+	if err != nil {
+		db.Rollback()
+
+		resp.Title = "Failed to generate payout batch file"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PayoutEntryNotExportable
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	b.FilePath = fmt.Sprintf("%s/payout-batches/%s.%s", values.ReservedBucketName, b.ID, ext)
+
+	if err := mu.CreatePayoutBatch(db, b); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	actorID := utils.GetUserID(ctx)
+	var events []*models.PayoutEvent
+
+	for i := range entries {
+		entries[i].BatchID = &b.ID
+
+		e, err := services.UpdatePayoutStatus(db, &entries[i], models.PayoutSendStatusProcessing, "", &actorID,
+			fmt.Sprintf("Exported in payout batch %s", b.ID))
+		if err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+		events = append(events, e)
+	}
+
+	if err := services.UploadToMinio(b.FilePath, contentType, bytes.NewReader(body), int64(len(body))); err != nil {
+		db.Rollback()
+
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	// The file of a batch that was never saved is removed, the entries can be exported again
+	if err := db.Commit().Error; err != nil {
+		if err := services.RemoveFromMinio(b.FilePath); err != nil {
+			log.Log().Errorln("Failed to remove payout batch file ", b.FilePath, " : ", err)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	sendPayoutStatusEmails(events)
+
+	resp.Status = http.StatusCreated
+	resp.Data = models.PayoutBatchDetails{
+		PayoutBatch: *b,
+		Entries:     entries,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func listPayoutBatches(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+	mu := data.NewMarketplaceRepository()
+
+	batches, err := mu.ListPayoutBatches(db, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = batches
+	return resp.ServerJSON(ctx)
+}
+
+func getPayoutBatch(ctx echo.Context) error {
+	batchID := ctx.Param("batch_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	mu := data.NewMarketplaceRepository()
+
+	b, err := mu.GetPayoutBatch(db, batchID)
+	if err != nil {
+		return servePayoutBatchQueryFailed(ctx, err)
+	}
+
+	entries, err := mu.ListPayoutEntriesByBatch(db, b.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = models.PayoutBatchDetails{
+		PayoutBatch: *b,
+		Entries:     entries,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func downloadPayoutBatchFile(ctx echo.Context) error {
+	batchID := ctx.Param("batch_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	mu := data.NewMarketplaceRepository()
+
+	b, err := mu.GetPayoutBatch(db, batchID)
+	if err != nil {
+		return servePayoutBatchQueryFailed(ctx, err)
+	}
+
+	f, err := services.ServeAsStreamFromMinio(b.FilePath)
+	if err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	return resp.ServeStreamFromMinioAsDownload(ctx, f)
+}
+
+// uploadPayoutBatchReport applies the outcome of the payouts in the bank report to the entries of the batch.
+// Entries reported as settled are completed and the rejected ones are failed with the reason given by the bank.
+func uploadPayoutBatchReport(ctx echo.Context) error {
+	batchID := ctx.Param("batch_id")
+
+	resp := core.Response{}
+
+	if err := ctx.Request().ParseMultipartForm(32 << 20); err != nil {
+		resp.Title = "Couldn't parse multipart form"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.InvalidMultiPartBody
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	r := ctx.Request()
+	r.Body = http.MaxBytesReader(ctx.Response(), r.Body, 32<<20) // 32 Mb
+
+	f, _, e := r.FormFile("file")
+	if e != nil {
+		resp.Title = "No multipart file"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.InvalidMultiPartBody
+		resp.Errors = e
+		return resp.ServerJSON(ctx)
+	}
+	defer f.Close()
+
+	body, errR := ioutil.ReadAll(f)
+	if errR != nil {
+		resp.Title = "Unable to read multipart data"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.UnableToReadMultiPartData
+		resp.Errors = errR
+		return resp.ServerJSON(ctx)
+	}
+
+	lines, err := services.ParseBankReport(body)
+	if err != nil {
+		resp.Title = "Invalid bank report"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.PayoutBankReportInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+	mu := data.NewMarketplaceRepository()
+
+	b, err := mu.GetPayoutBatch(db, batchID)
+	if err != nil {
+		db.Rollback()
+		return servePayoutBatchQueryFailed(ctx, err)
+	}
+
+	entries, err := mu.ListPayoutEntriesByBatchForUpdate(db, b.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	// The report may refer to an entry either by its ID or by the end to end ID used in the batch file
+	entriesByRef := map[string]*models.PayoutSend{}
+	for i := range entries {
+		entriesByRef[entries[i].ID] = &entries[i]
+		entriesByRef[services.PayoutEndToEndID(entries[i].ID)] = &entries[i]
+	}
+
+	actorID := utils.GetUserID(ctx)
+	var events []*models.PayoutEvent
+	var unmatched []string
+	completed, failed := 0, 0
+
+	for _, l := range lines {
+		var matched []*models.PayoutSend
+
+		// A line about the whole batch applies to all of its entries
+		if l.Reference == b.ID || l.Reference == services.PayoutEndToEndID(b.ID) {
+			for i := range entries {
+				matched = append(matched, &entries[i])
+			}
+		} else if entry, ok := entriesByRef[l.Reference]; ok {
+			matched = append(matched, entry)
+		} else {
+			unmatched = append(unmatched, l.Reference)
+			continue
+		}
+
+		for _, entry := range matched {
+			if entry.Status != models.PayoutSendStatusProcessing {
+				continue
+			}
+
+			e, err := services.UpdatePayoutStatus(db, entry, l.Status, l.Reason, &actorID,
+				fmt.Sprintf("Reported by the bank for payout batch %s", b.ID))
+			if err != nil {
+				db.Rollback()
+				return serveDatabaseQueryFailed(ctx, err)
+			}
+			events = append(events, e)
+
+			if l.Status == models.PayoutSendStatusCompleted {
+				completed++
+			} else {
+				failed++
+			}
+		}
+	}
+
+	processing := 0
+	for _, e := range entries {
+		if e.Status == models.PayoutSendStatusProcessing {
+			processing++
+		}
+	}
+
+	if processing == 0 && b.Status != models.PayoutBatchReconciled {
+		b.Status = models.PayoutBatchReconciled
+		b.UpdatedAt = time.Now().UTC()
+
+		if err := mu.UpdatePayoutBatch(db, b); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	sendPayoutStatusEmails(events)
+
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"batch":                b,
+		"completed":            completed,
+		"failed":               failed,
+		"processing":           processing,
+		"unmatched_references": unmatched,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+// sendPayoutStatusEmails queues the status email of each event, failing to queue doesn't fail the request
+func sendPayoutStatusEmails(events []*models.PayoutEvent) {
+	for _, e := range events {
+		if err := queue.SendPayoutStatusEmail(e.ID); err != nil {
+			log.Log().Errorln("Failed to enqueue payout status email : ", err)
+		}
+	}
+}
+
+func servePayoutBatchQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Payout batch not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.PayoutBatchNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
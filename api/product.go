@@ -30,6 +30,9 @@ func RegisterProductRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 	func(g echo.Group) {
 		g.GET("/", listProducts)
 		g.GET("/:product_id/", getProduct)
+		g.GET("/:product_id/reviews/", listProductReviews)
+		g.GET("/:product_id/related/", listRelatedProducts)
+		g.GET("/:product_id/bought-together/", listProductsBoughtTogether)
 	}(*productsPublicPath)
 
 	func(g echo.Group) {
@@ -37,12 +40,42 @@ func RegisterProductRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 		g.Use(middlewares.IsStoreActive())
 		g.Use(middlewares.IsStoreManager())
 		g.POST("/", createProduct)
+		g.GET("/export/", exportProducts)
+		g.POST("/imports/", importProducts)
+		g.GET("/imports/", listProductImports)
+		g.GET("/imports/:import_id/", getProductImport)
+		g.GET("/imports/:import_id/report/", downloadProductImportReport)
 		g.PATCH("/:product_id/", updateProduct)
 		g.DELETE("/:product_id/", deleteProduct)
 		g.GET("/:product_id/", getProductAsStoreOwner)
 		g.GET("/", listProductsAsStoreOwner)
 		g.PUT("/:product_id/attributes/", addProductAttribute)
 		g.DELETE("/:product_id/attributes/:attribute_id/", deleteProductAttribute)
+		g.GET("/:product_id/variants/", listProductVariants)
+		g.PATCH("/:product_id/variants/:variant_id/", updateProductVariant)
+		g.GET("/:product_id/stock/", listProductStock)
+		g.GET("/:product_id/stock/adjustments/", listStockAdjustments)
+		g.POST("/:product_id/stock/adjustments/", adjustProductStock)
+		g.GET("/:product_id/license-keys/", listLicenseKeys)
+		g.POST("/:product_id/license-keys/", uploadLicenseKeys)
+		g.DELETE("/:product_id/license-keys/:key_id/", deleteLicenseKey)
+		g.GET("/:product_id/license-keys/pool/", getLicenseKeyPool)
+		g.PUT("/:product_id/license-keys/pool/", updateLicenseKeyPool)
+		g.POST("/:product_id/options/", createProductOption)
+		g.DELETE("/:product_id/options/:option_id/", deleteProductOption)
+		g.PUT("/:product_id/options/:option_id/values/", addProductOptionValues)
+		g.DELETE("/:product_id/options/:option_id/values/:value_id/", deleteProductOptionValue)
+		g.GET("/:product_id/revisions/", listProductRevisions)
+		g.POST("/:product_id/revisions/", createProductRevision)
+		g.GET("/:product_id/revisions/:revision_id/", getProductRevision)
+		g.PATCH("/:product_id/revisions/:revision_id/", updateProductRevision)
+		g.DELETE("/:product_id/revisions/:revision_id/", deleteProductRevision)
+		g.GET("/:product_id/revisions/:revision_id/preview/", previewProductRevision)
+		g.POST("/:product_id/revisions/:revision_id/publish/", publishProductRevision)
+		g.DELETE("/:product_id/revisions/:revision_id/schedule/", unscheduleProductRevision)
+		g.POST("/:product_id/revisions/:revision_id/revert/", revertToProductRevision)
+		g.GET("/:product_id/reviews/", listProductReviewsAsStoreOwner)
+		g.PUT("/:product_id/reviews/:review_id/reply/", replyToProductReview)
 		g.GET("/:product_id/download/", downloadProduct)
 		g.POST("/:product_id/upload/", saveDownloadableProduct)
 	}(*productsPlatformPath)
@@ -72,12 +105,12 @@ func createProduct(ctx echo.Context) error {
 		StoreID:          storeID,
 		Price:            req.Price,
 		ProductCost:      req.ProductCost,
-		Stock:            req.Stock,
 		Name:             req.Name,
 		Slug:             slug.Make(req.Name),
 		IsShippable:      req.IsShippable,
 		CategoryID:       req.CategoryID,
 		IsPublished:      req.IsPublished,
+		UnpublishAt:      req.UnpublishAt,
 		IsDigital:        req.IsDigital,
 		MaxQuantityCount: req.MaxQuantityCount,
 		SKU:              req.SKU,
@@ -127,6 +160,18 @@ func createProduct(ctx echo.Context) error {
 		}
 	}
 
+	userID := utils.GetUserID(ctx)
+	if err := services.SetProductStock(db, &p, nil, 0, req.Stock, models.StockReceived, &userID); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	p.Stock = req.Stock
+
+	if _, err := services.RecordProductRevision(db, &p, &userID); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
 	if err := db.Commit().Error; err != nil {
 		resp.Title = "Failed to commit data"
 		resp.Status = http.StatusInternalServerError
@@ -195,8 +240,11 @@ func updateProduct(ctx echo.Context) error {
 	if req.IsPublished != nil {
 		p.IsPublished = *req.IsPublished
 	}
-	if req.Stock != nil {
-		p.Stock = *req.Stock
+	if req.UnpublishAt != nil {
+		p.UnpublishAt = req.UnpublishAt
+	}
+	if req.ClearUnpublishAt {
+		p.UnpublishAt = nil
 	}
 	if req.CategoryID != nil {
 		p.CategoryID = req.CategoryID
@@ -270,6 +318,20 @@ func updateProduct(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
+	userID := utils.GetUserID(ctx)
+	if req.Stock != nil {
+		if err := services.SetProductStock(db, p, nil, p.Stock, *req.Stock, models.StockCorrection, &userID); err != nil {
+			db.Rollback()
+			return serveStockAdjustmentFailed(ctx, err)
+		}
+		p.Stock = *req.Stock
+	}
+
+	if _, err := services.RecordProductRevision(db, p, &userID); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
 	if err := db.Commit().Error; err != nil {
 		resp.Title = "Failed to commit data"
 		resp.Status = http.StatusInternalServerError
@@ -409,10 +471,11 @@ func getProductAsStoreOwner(ctx echo.Context) error {
 	return resp.ServerJSON(ctx)
 }
 
+// listProducts serves the published products matching the filter, along with their total count
+// and the facet counts for narrowing the filter down further
 func listProducts(ctx echo.Context) error {
 	pageQ := ctx.Request().URL.Query().Get("page")
 	limitQ := ctx.Request().URL.Query().Get("limit")
-	query := ctx.Request().URL.Query().Get("query")
 
 	page, err := strconv.ParseInt(pageQ, 10, 64)
 	if err != nil {
@@ -425,24 +488,39 @@ func listProducts(ctx echo.Context) error {
 
 	resp := core.Response{}
 
-	var r interface{}
-
-	if query == "" {
-		r, err = fetchProducts(ctx, page, limit, true)
-	} else {
-		r, err = searchProducts(ctx, query, page, limit, true)
-	}
-
+	f, err := validators.ValidateProductFilter(ctx)
 	if err != nil {
-		resp.Title = "Database query failed"
-		resp.Status = http.StatusInternalServerError
-		resp.Code = errors.DatabaseQueryFailed
+		resp.Title = "Invalid filter"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductFilterInvalid
 		resp.Errors = err
 		return resp.ServerJSON(ctx)
 	}
 
+	from := (page - 1) * limit
+
+	db := app.DB()
+	pu := data.NewProductRepository()
+
+	products, err := pu.Filter(db, f, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	total, err := pu.CountFiltered(db, f)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	facets, err := pu.Facets(db, f)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
 	resp.Status = http.StatusOK
-	resp.Data = r
+	resp.Data = map[string]interface{}{
+		"products": products,
+		"total":    total,
+		"facets":   facets,
+	}
 	return resp.ServerJSON(ctx)
 }
 
@@ -465,9 +543,9 @@ func listProductsAsStoreOwner(ctx echo.Context) error {
 	var r interface{}
 
 	if query == "" {
-		r, err = fetchProducts(ctx, page, limit, false)
+		r, err = fetchProducts(ctx, page, limit)
 	} else {
-		r, err = searchProducts(ctx, query, page, limit, false)
+		r, err = searchProducts(ctx, query, page, limit)
 	}
 
 	if err != nil {
@@ -483,27 +561,21 @@ func listProductsAsStoreOwner(ctx echo.Context) error {
 	return resp.ServerJSON(ctx)
 }
 
-func fetchProducts(ctx echo.Context, page int64, limit int64, isPublic bool) (interface{}, error) {
+func fetchProducts(ctx echo.Context, page int64, limit int64) (interface{}, error) {
 	from := (page - 1) * limit
 	pu := data.NewProductRepository()
 
 	db := app.DB()
 
-	if isPublic {
-		return pu.List(db, int(from), int(limit))
-	}
 	return pu.ListAsStoreStuff(db, ctx.Get(utils.StoreID).(string), int(from), int(limit))
 }
 
-func searchProducts(ctx echo.Context, query string, page int64, limit int64, isPublic bool) (interface{}, error) {
+func searchProducts(ctx echo.Context, query string, page int64, limit int64) (interface{}, error) {
 	from := (page - 1) * limit
 	pu := data.NewProductRepository()
 
 	db := app.DB()
 
-	if isPublic {
-		return pu.Search(db, query, int(from), int(limit))
-	}
 	return pu.SearchAsStoreStuff(db, ctx.Get(utils.StoreID).(string), query, int(from), int(limit))
 }
 
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/queue"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/values"
+)
+
+// importProducts keeps the uploaded CSV in the reserved bucket and leaves the rows to the worker.
+// Only the header is checked here, the rows are reported once the import is completed.
+func importProducts(ctx echo.Context) error {
+	storeID := ctx.Get(utils.StoreID).(string)
+
+	resp := core.Response{}
+
+	if err := ctx.Request().ParseMultipartForm(32 << 20); err != nil {
+		resp.Title = "Couldn't parse multipart form"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.InvalidMultiPartBody
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	r := ctx.Request()
+	r.Body = http.MaxBytesReader(ctx.Response(), r.Body, 32<<20) // 32 Mb
+
+	f, h, e := r.FormFile("file")
+	if e != nil {
+		resp.Title = "No multipart file"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.InvalidMultiPartBody
+		resp.Errors = e
+		return resp.ServerJSON(ctx)
+	}
+	defer f.Close()
+
+	body, errR := ioutil.ReadAll(f)
+	if errR != nil {
+		resp.Title = "Unable to read multipart data"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.UnableToReadMultiPartData
+		resp.Errors = errR
+		return resp.ServerJSON(ctx)
+	}
+
+	header, err := csv.NewReader(bytes.NewReader(body)).Read()
+	if err == nil {
+		_, err = services.ParseProductCSVHeader(header)
+	}
+	if err != nil {
+		resp.Title = "Invalid product CSV"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.ProductImportFileInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	pi := &models.ProductImport{
+		ID:        utils.NewUUID(),
+		StoreID:   storeID,
+		UserID:    utils.GetUserID(ctx),
+		FileName:  h.Filename,
+		Status:    models.ProductImportPending,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	pi.FilePath = fmt.Sprintf("%s/imports/%s/%s.csv", values.ReservedBucketName, storeID, pi.ID)
+
+	if err := services.UploadToMinio(pi.FilePath, "text/csv", bytes.NewReader(body), int64(len(body))); err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	iu := data.NewProductImportRepository()
+	if err := iu.Create(app.DB(), pi); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := queue.ImportProducts(pi.ID); err != nil {
+		resp.Title = "Failed to enqueue task"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.FailedToEnqueueTask
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = pi
+	return resp.ServerJSON(ctx)
+}
+
+func listProductImports(ctx echo.Context) error {
+	storeID := ctx.Get(utils.StoreID).(string)
+
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	iu := data.NewProductImportRepository()
+	imports, err := iu.List(app.DB(), storeID, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = imports
+	return resp.ServerJSON(ctx)
+}
+
+func getProductImport(ctx echo.Context) error {
+	storeID := ctx.Get(utils.StoreID).(string)
+
+	resp := core.Response{}
+
+	iu := data.NewProductImportRepository()
+	pi, err := iu.GetAsStoreStuff(app.DB(), storeID, ctx.Param("import_id"))
+	if err != nil {
+		return serveProductImportQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = pi
+	return resp.ServerJSON(ctx)
+}
+
+func downloadProductImportReport(ctx echo.Context) error {
+	storeID := ctx.Get(utils.StoreID).(string)
+
+	resp := core.Response{}
+
+	iu := data.NewProductImportRepository()
+	pi, err := iu.GetAsStoreStuff(app.DB(), storeID, ctx.Param("import_id"))
+	if err != nil {
+		return serveProductImportQueryFailed(ctx, err)
+	}
+
+	if pi.ReportPath == "" {
+		resp.Title = "Import report not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.ProductImportReportNotFound
+		return resp.ServerJSON(ctx)
+	}
+
+	f, err := services.ServeAsStreamFromMinio(pi.ReportPath)
+	if err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	return resp.ServeStreamFromMinioAsDownload(ctx, f)
+}
+
+// exportProducts serves the products of the store in the format accepted by the import
+func exportProducts(ctx echo.Context) error {
+	storeID := ctx.Get(utils.StoreID).(string)
+
+	resp := core.Response{}
+
+	body, err := services.GenerateProductCSV(app.DB(), storeID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	fileName := fmt.Sprintf("products-%s.csv", time.Now().UTC().Format("20060102"))
+	return resp.ServeAsDownload(ctx, fileName, "text/csv", body)
+}
+
+func serveProductImportQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Product import not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.ProductImportNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
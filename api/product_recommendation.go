@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
+)
+
+func listRelatedProducts(ctx echo.Context) error {
+	return serveProductRecommendations(ctx, models.RecommendationRelated)
+}
+
+func listProductsBoughtTogether(ctx echo.Context) error {
+	return serveProductRecommendations(ctx, models.RecommendationBoughtTogether)
+}
+
+// serveProductRecommendations serves the recommendations computed by the worker for a published product
+func serveProductRecommendations(ctx echo.Context, kind models.RecommendationKind) error {
+	limit, err := strconv.Atoi(ctx.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > services.ProductRecommendationLimit {
+		limit = services.ProductRecommendationLimit
+	}
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.Get(db, ctx.Param("product_id"))
+	if err == nil && !p.IsPublished {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	ru := data.NewProductRecommendationRepository()
+	products, err := ru.ListRecommended(db, p.ID, kind, limit)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = products
+	return resp.ServerJSON(ctx)
+}
@@ -0,0 +1,490 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+)
+
+func listProductRevisions(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	ru := data.NewProductRevisionRepository()
+	revisions, err := ru.List(db, p.ID, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = revisions
+	return resp.ServerJSON(ctx)
+}
+
+// createProductRevision starts a draft from the current content of the product with the changes applied
+func createProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	userID := utils.GetUserID(ctx)
+
+	resp := core.Response{}
+
+	req, err := validators.ValidateProductRevision(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductRevisionDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, ctx.Param("product_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	images, err := pu.GetImages(db, p.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	r := services.NewProductRevision(p, images)
+	r.CreatedByUserID = &userID
+
+	if err := applyProductRevisionChanges(db, storeID, r, req); err != nil {
+		db.Rollback()
+		return serveProductRevisionCategoryFailed(ctx, err)
+	}
+
+	if err := services.CreateProductRevision(db, r); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+func getProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	resp := core.Response{}
+
+	r, err := getStoreProductRevision(app.DB(), storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+// updateProductRevision edits a draft or a scheduled revision, a scheduled one stays scheduled
+func updateProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	resp := core.Response{}
+
+	req, err := validators.ValidateProductRevision(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductRevisionDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	r, err := getStoreProductRevision(db, storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	if !r.Status.IsEditable() {
+		db.Rollback()
+		return serveProductRevisionNotEditable(ctx)
+	}
+
+	if err := applyProductRevisionChanges(db, storeID, r, req); err != nil {
+		db.Rollback()
+		return serveProductRevisionCategoryFailed(ctx, err)
+	}
+
+	r.UpdatedAt = time.Now().UTC()
+
+	ru := data.NewProductRevisionRepository()
+	if err := ru.Update(db, r); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	if req.AdditionalImages != nil {
+		if err := ru.SetImages(db, r.ID, r.AdditionalImages); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+// deleteProductRevision discards a draft or a scheduled revision, the published history is kept
+func deleteProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	r, err := getStoreProductRevision(db, storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	if !r.Status.IsEditable() {
+		db.Rollback()
+		return serveProductRevisionNotEditable(ctx)
+	}
+
+	ru := data.NewProductRevisionRepository()
+	if err := ru.Delete(db, r); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+// previewProductRevision serves the product as the customers would see it once the revision is published
+func previewProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	r, err := getStoreProductRevision(db, storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	pu := data.NewProductRepository()
+	d, err := pu.GetDetailsAsStoreStuff(db, storeID, r.ProductID)
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	services.PreviewProductRevision(d, r)
+
+	if d.CategoryID != "" && d.CategoryName == "" {
+		cu := data.NewCategoryRepository()
+		c, err := cu.GetAsStoreOwner(db, storeID, d.CategoryID)
+		if err != nil && !errors.IsRecordNotFoundError(err) {
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+		if c != nil {
+			d.CategoryName = c.Name
+		}
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
+
+// publishProductRevision publishes the revision right away or schedules it for the worker
+func publishProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	userID := utils.GetUserID(ctx)
+
+	resp := core.Response{}
+
+	req, err := validators.ValidatePublishProductRevision(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductRevisionDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	r, err := getStoreProductRevision(db, storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	if !r.Status.IsEditable() {
+		db.Rollback()
+		return serveProductRevisionNotEditable(ctx)
+	}
+
+	if req.PublishAt != nil {
+		publishAt := req.PublishAt.UTC()
+		r.Status = models.RevisionScheduled
+		r.PublishAt = &publishAt
+		r.UpdatedAt = time.Now().UTC()
+
+		ru := data.NewProductRevisionRepository()
+		if err := ru.Update(db, r); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	} else {
+		if _, err := services.PublishProductRevision(db, r, &userID); err != nil {
+			db.Rollback()
+			return serveProductPublishFailed(ctx, err)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+// unscheduleProductRevision turns a scheduled revision back into a draft
+func unscheduleProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	r, err := getStoreProductRevision(db, storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	if r.Status != models.RevisionScheduled {
+		return serveProductRevisionNotEditable(ctx)
+	}
+
+	r.Status = models.RevisionDraft
+	r.PublishAt = nil
+	r.UpdatedAt = time.Now().UTC()
+
+	ru := data.NewProductRevisionRepository()
+	if err := ru.Update(db, r); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+// revertToProductRevision publishes a copy of an archived revision, keeping the history in order
+func revertToProductRevision(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	userID := utils.GetUserID(ctx)
+
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	r, err := getStoreProductRevision(db, storeID, ctx.Param("product_id"), ctx.Param("revision_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductRevisionQueryFailed(ctx, err)
+	}
+
+	if r.Status != models.RevisionArchived {
+		db.Rollback()
+
+		resp.Title = "Only archived revisions can be reverted to"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.ProductRevisionNotRevertible
+		return resp.ServerJSON(ctx)
+	}
+
+	c := services.CopyProductRevision(r)
+	c.RevertedFromID = &r.ID
+	c.CreatedByUserID = &userID
+	c.Note = "Reverted to revision " + strconv.Itoa(r.Number)
+
+	if err := services.CreateProductRevision(db, c); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if _, err := services.PublishProductRevision(db, c, &userID); err != nil {
+		db.Rollback()
+		return serveProductPublishFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = c
+	return resp.ServerJSON(ctx)
+}
+
+// getStoreProductRevision finds the revision of a product of the store, the product may be given by slug
+func getStoreProductRevision(db *gorm.DB, storeID, productID, revisionID string) (*models.ProductRevision, error) {
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	ru := data.NewProductRevisionRepository()
+	return ru.Get(db, p.ID, revisionID)
+}
+
+// applyProductRevisionChanges fails when the category isn't one of the store
+func applyProductRevisionChanges(db *gorm.DB, storeID string, r *models.ProductRevision, req *validators.ReqProductRevision) error {
+	if req.CategoryID != nil {
+		r.CategoryID = nil
+		if *req.CategoryID != "" {
+			cu := data.NewCategoryRepository()
+			c, err := cu.GetAsStoreOwner(db, storeID, *req.CategoryID)
+			if err != nil {
+				return err
+			}
+			r.CategoryID = &c.ID
+		}
+	}
+
+	if req.Note != nil {
+		r.Note = *req.Note
+	}
+	if req.Name != nil {
+		r.Name = *req.Name
+	}
+	if req.Description != nil {
+		r.Description = *req.Description
+	}
+	if req.Image != nil {
+		r.Image = *req.Image
+	}
+	if req.Unit != nil {
+		r.Unit = *req.Unit
+	}
+	if req.Price != nil {
+		r.Price = *req.Price
+	}
+	if req.ProductCost != nil {
+		r.ProductCost = *req.ProductCost
+	}
+	if req.MaxQuantityCount != nil {
+		r.MaxQuantityCount = *req.MaxQuantityCount
+	}
+	if req.IsShippable != nil {
+		r.IsShippable = *req.IsShippable
+	}
+	if req.IsDigital != nil {
+		r.IsDigital = *req.IsDigital
+	}
+	if req.AdditionalImages != nil {
+		r.AdditionalImages = req.AdditionalImages
+	}
+	return nil
+}
+
+func serveProductRevisionCategoryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Category not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.CategoryNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+func serveProductRevisionNotEditable(ctx echo.Context) error {
+	resp := core.Response{}
+	resp.Title = "Revision is already published"
+	resp.Status = http.StatusBadRequest
+	resp.Code = errors.ProductRevisionNotEditable
+	return resp.ServerJSON(ctx)
+}
+
+// serveProductPublishFailed answers the product names already taken in the store with a conflict
+func serveProductPublishFailed(ctx echo.Context, err error) error {
+	if msg, ok := errors.IsDuplicateKeyError(err); ok {
+		resp := core.Response{}
+		resp.Title = msg
+		resp.Status = http.StatusConflict
+		resp.Code = errors.ProductAlreadyExists
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+func serveProductRevisionQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Product revision not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.ProductRevisionNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
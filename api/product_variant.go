@@ -0,0 +1,427 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+)
+
+func listProductVariants(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	productID := ctx.Param("product_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	options, err := vu.ListOptions(db, p.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	variants, err := vu.ListVariants(db, p.ID, false)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"options":  options,
+		"variants": variants,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func createProductOption(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	productID := ctx.Param("product_id")
+
+	req, err := validators.ValidateCreateProductOption(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductVariantDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	position, err := vu.CountOptions(db, p.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	o := models.ProductOption{
+		ID:        utils.NewUUID(),
+		ProductID: p.ID,
+		Name:      req.Name,
+		Position:  position,
+	}
+	if err := vu.CreateOption(db, &o); err != nil {
+		db.Rollback()
+		return serveProductOptionConflict(ctx, err)
+	}
+
+	for i, value := range req.Values {
+		v := models.ProductOptionValue{
+			ID:       utils.NewUUID(),
+			OptionID: o.ID,
+			Value:    value,
+			Position: i,
+		}
+		if err := vu.CreateOptionValue(db, &v); err != nil {
+			db.Rollback()
+			return serveProductOptionConflict(ctx, err)
+		}
+	}
+
+	return commitProductOptionChange(ctx, db, p, http.StatusCreated, "Product option created")
+}
+
+func deleteProductOption(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	productID := ctx.Param("product_id")
+	optionID := ctx.Param("option_id")
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	o, err := vu.GetOption(db, p.ID, optionID)
+	if err != nil {
+		db.Rollback()
+		return serveProductOptionQueryFailed(ctx, err)
+	}
+
+	options, err := vu.ListOptions(db, p.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	var valueIDs []string
+	for _, op := range options {
+		if op.ID != o.ID {
+			continue
+		}
+		for _, v := range op.Values {
+			valueIDs = append(valueIDs, v.ID)
+		}
+	}
+
+	if err := vu.DetachOptionValues(db, valueIDs); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	if err := vu.DeleteOption(db, p.ID, o.ID); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	return commitProductOptionChange(ctx, db, p, http.StatusOK, "Product option deleted")
+}
+
+func addProductOptionValues(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	productID := ctx.Param("product_id")
+	optionID := ctx.Param("option_id")
+
+	req, err := validators.ValidateAddProductOptionValues(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductVariantDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	o, err := vu.GetOption(db, p.ID, optionID)
+	if err != nil {
+		db.Rollback()
+		return serveProductOptionQueryFailed(ctx, err)
+	}
+
+	options, err := vu.ListOptions(db, p.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	position := 0
+	for _, op := range options {
+		if op.ID == o.ID {
+			position = len(op.Values)
+		}
+	}
+
+	for i, value := range req.Values {
+		v := models.ProductOptionValue{
+			ID:       utils.NewUUID(),
+			OptionID: o.ID,
+			Value:    value,
+			Position: position + i,
+		}
+		if err := vu.CreateOptionValue(db, &v); err != nil {
+			db.Rollback()
+			return serveProductOptionConflict(ctx, err)
+		}
+	}
+
+	return commitProductOptionChange(ctx, db, p, http.StatusOK, "Product option values added")
+}
+
+func deleteProductOptionValue(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	productID := ctx.Param("product_id")
+	optionID := ctx.Param("option_id")
+	valueID := ctx.Param("value_id")
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	o, err := vu.GetOption(db, p.ID, optionID)
+	if err != nil {
+		db.Rollback()
+		return serveProductOptionQueryFailed(ctx, err)
+	}
+
+	v, err := vu.GetOptionValue(db, o.ID, valueID)
+	if err != nil {
+		db.Rollback()
+		return serveProductOptionQueryFailed(ctx, err)
+	}
+
+	if err := vu.DetachOptionValues(db, []string{v.ID}); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	if err := vu.DeleteOptionValue(db, o.ID, v.ID); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	return commitProductOptionChange(ctx, db, p, http.StatusOK, "Product option value deleted")
+}
+
+func updateProductVariant(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	productID := ctx.Param("product_id")
+	variantID := ctx.Param("variant_id")
+
+	req, err := validators.ValidateUpdateProductVariant(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ProductVariantDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, productID)
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	v, err := vu.GetVariant(db, p.ID, variantID)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Product variant not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.ProductVariantNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if req.SKU != nil {
+		v.SKU = *req.SKU
+	}
+	if req.Price != nil {
+		v.Price = req.Price
+	}
+	if req.UseProductPrice {
+		v.Price = nil
+	}
+	if req.Weight != nil {
+		v.Weight = *req.Weight
+	}
+	if req.Image != nil {
+		v.Image = *req.Image
+	}
+	v.UpdatedAt = time.Now().UTC()
+
+	if err := vu.UpdateVariant(db, v); err != nil {
+		db.Rollback()
+
+		msg, ok := errors.IsDuplicateKeyError(err)
+		if ok {
+			resp.Title = msg
+			resp.Status = http.StatusConflict
+			resp.Code = errors.ProductVariantAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if req.Stock != nil {
+		userID := utils.GetUserID(ctx)
+		if err := services.SetProductStock(db, p, &v.ID, v.Stock, *req.Stock, models.StockCorrection, &userID); err != nil {
+			db.Rollback()
+			return serveStockAdjustmentFailed(ctx, err)
+		}
+		v.Stock = *req.Stock
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Title = "Product variant updated"
+	resp.Data = v
+	return resp.ServerJSON(ctx)
+}
+
+// commitProductOptionChange regenerates the variants of the product after its options changed,
+// commits the transaction and serves the options along with the variants
+func commitProductOptionChange(ctx echo.Context, db *gorm.DB, p *models.Product, status int, title string) error {
+	resp := core.Response{}
+
+	variants, err := services.GenerateProductVariants(db, p)
+	if err != nil {
+		db.Rollback()
+
+		msg, ok := errors.IsDuplicateKeyError(err)
+		if ok {
+			resp.Title = msg
+			resp.Status = http.StatusConflict
+			resp.Code = errors.ProductVariantAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	vu := data.NewProductVariantRepository()
+	options, err := vu.ListOptions(db, p.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = status
+	resp.Title = title
+	resp.Data = map[string]interface{}{
+		"options":  options,
+		"variants": variants,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func serveProductQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Product not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.ProductNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+func serveProductOptionQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Product option not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.ProductOptionNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+func serveProductOptionConflict(ctx echo.Context, err error) error {
+	msg, ok := errors.IsDuplicateKeyError(err)
+	if ok {
+		resp := core.Response{}
+		resp.Title = msg
+		resp.Status = http.StatusConflict
+		resp.Code = errors.ProductOptionAlreadyExists
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/queue"
+	"github.com/shopicano/shopicano-backend/utils"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func RegisterReconciliationRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	reconciliationsPath := platformEndpoints.Group("/reconciliations")
+
+	func(g echo.Group) {
+		g.Use(middlewares.IsPlatformAdmin)
+		g.GET("/", listReconciliationReports)
+		g.POST("/", runPaymentReconciliation)
+		g.GET("/:report_id/", getReconciliationReport)
+		g.POST("/discrepancies/:discrepancy_id/fix/", fixPaymentDiscrepancy)
+		g.POST("/discrepancies/:discrepancy_id/dismiss/", dismissPaymentDiscrepancy)
+	}(*reconciliationsPath)
+}
+
+func listReconciliationReports(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	ru := data.NewReconciliationRepository()
+	reports, err := ru.ListReports(db, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = reports
+	return resp.ServerJSON(ctx)
+}
+
+func runPaymentReconciliation(ctx echo.Context) error {
+	resp := core.Response{}
+
+	if err := queue.RunPaymentReconciliation(); err != nil {
+		resp.Title = "Failed to enqueue task"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.FailedToEnqueueTask
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	resp.Status = http.StatusAccepted
+	return resp.ServerJSON(ctx)
+}
+
+func getReconciliationReport(ctx echo.Context) error {
+	reportID := ctx.Param("report_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	ru := data.NewReconciliationRepository()
+	r, err := ru.GetReport(db, reportID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Reconciliation report not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.ReconciliationReportNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	discrepancies, err := ru.ListDiscrepancies(db, reportID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = models.ReconciliationReportDetails{
+		ReconciliationReport: *r,
+		Discrepancies:        discrepancies,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func fixPaymentDiscrepancy(ctx echo.Context) error {
+	return resolvePaymentDiscrepancy(ctx, true)
+}
+
+func dismissPaymentDiscrepancy(ctx echo.Context) error {
+	return resolvePaymentDiscrepancy(ctx, false)
+}
+
+// resolvePaymentDiscrepancy marks the discrepancy as resolved,
+// when fix is set the suggested payment status is applied to the order first
+func resolvePaymentDiscrepancy(ctx echo.Context, fix bool) error {
+	discrepancyID := ctx.Param("discrepancy_id")
+
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	ru := data.NewReconciliationRepository()
+	d, err := ru.GetDiscrepancy(db, discrepancyID)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Payment discrepancy not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.PaymentDiscrepancyNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if d.IsResolved {
+		db.Rollback()
+
+		resp.Title = "Payment discrepancy already resolved"
+		resp.Status = http.StatusConflict
+		resp.Code = errors.PaymentDiscrepancyAlreadyResolved
+		return resp.ServerJSON(ctx)
+	}
+
+	if fix {
+		if d.OrderID == nil || d.SuggestedPaymentStatus == nil {
+			db.Rollback()
+
+			resp.Title = "Payment discrepancy can't be fixed automatically"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.PaymentDiscrepancyNotFixable
+			return resp.ServerJSON(ctx)
+		}
+
+		ou := data.NewOrderRepository()
+		o, err := ou.GetForUpdate(db, *d.OrderID)
+		if err != nil {
+			db.Rollback()
+
+			if errors.IsRecordNotFoundError(err) {
+				resp.Title = "Order not found"
+				resp.Status = http.StatusNotFound
+				resp.Code = errors.OrderNotFound
+				resp.Errors = err
+				return resp.ServerJSON(ctx)
+			}
+
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+
+		// The order may have been fixed already, through another report or the payment callback
+		if o.PaymentStatus != *d.SuggestedPaymentStatus {
+			details := fmt.Sprintf("Order payment status updated by %s from reconciliation report %s", utils.GetUserID(ctx), d.ReportID)
+			if res := updateOrderPaymentStatus(db, o, *d.SuggestedPaymentStatus, details); res != nil {
+				db.Rollback()
+				return res.ServerJSON(ctx)
+			}
+		}
+	}
+
+	userID := utils.GetUserID(ctx)
+	now := time.Now().UTC()
+
+	d.IsResolved = true
+	d.ResolvedByUserID = &userID
+	d.ResolvedAt = &now
+
+	if err := ru.ResolveDiscrepancy(db, d); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
@@ -1,15 +1,19 @@
 package api
 
 import (
+	"github.com/jinzhu/gorm"
 	"github.com/labstack/echo/v4"
 	"github.com/shopicano/shopicano-backend/app"
 	"github.com/shopicano/shopicano-backend/core"
 	"github.com/shopicano/shopicano-backend/data"
 	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/middlewares"
 	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
 	"github.com/shopicano/shopicano-backend/utils"
 	"github.com/shopicano/shopicano-backend/validators"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -68,3 +72,330 @@ func createReview(ctx echo.Context) error {
 	resp.Data = rv
 	return resp.ServerJSON(ctx)
 }
+
+func RegisterReviewRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	reviewsPath := platformEndpoints.Group("/reviews")
+
+	func(g echo.Group) {
+		g.Use(middlewares.IsPlatformAdmin)
+		g.GET("/", listProductReviewsForModeration)
+		g.PATCH("/:review_id/moderation/", moderateProductReview)
+	}(*reviewsPath)
+}
+
+// createProductReview lets the buyer review an item of a paid order, the review is public once approved
+func createProductReview(ctx echo.Context) error {
+	orderID := ctx.Param("order_id")
+	itemID := ctx.Param("item_id")
+	userID := utils.GetUserID(ctx)
+
+	resp := core.Response{}
+
+	pld, err := validators.ValidateCreateProductReview(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ReviewDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	ou := data.NewOrderRepository()
+	o, err := ou.GetAsUser(db, userID, orderID)
+	if err != nil {
+		db.Rollback()
+		return serveOrderQueryFailed(ctx, err)
+	}
+
+	oi, err := ou.GetOrderedItemByID(db, o.ID, itemID)
+	if err != nil {
+		db.Rollback()
+		return serveOrderQueryFailed(ctx, err)
+	}
+
+	ru := data.NewProductReviewRepository()
+
+	reviewed, err := ru.ExistsForOrderedItem(db, oi.ID)
+	if err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := services.CheckProductReviewEligibility(userID, o, oi, reviewed); err != nil {
+		db.Rollback()
+
+		if err == services.ErrProductReviewExists {
+			resp.Title = "Item already reviewed"
+			resp.Status = http.StatusConflict
+			resp.Code = errors.ProductReviewAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		resp.Title = "Review not allowed"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.ProductReviewNotAllowed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	now := time.Now().UTC()
+	r := &models.ProductReview{
+		ID:            utils.NewUUID(),
+		OrderedItemID: oi.ID,
+		OrderID:       o.ID,
+		ProductID:     oi.ProductID,
+		StoreID:       o.StoreID,
+		UserID:        userID,
+		Rating:        pld.Rating,
+		Title:         pld.Title,
+		Description:   pld.Description,
+		Status:        models.ReviewPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if err := ru.Create(db, r); err != nil {
+		db.Rollback()
+
+		if _, ok := errors.IsDuplicateKeyError(err); ok {
+			resp.Title = "Item already reviewed"
+			resp.Status = http.StatusConflict
+			resp.Code = errors.ProductReviewAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	for i, path := range pld.Photos {
+		if err := ru.AddPhoto(db, &models.ProductReviewPhoto{
+			ID:        utils.NewUUID(),
+			ReviewID:  r.ID,
+			Path:      path,
+			Position:  i,
+			CreatedAt: now,
+		}); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = models.ProductReviewDetails{
+		ProductReview: *r,
+		Photos:        pld.Photos,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+// listProductReviews lists the approved reviews of the product without the order of the buyer or the moderation
+func listProductReviews(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.Get(db, ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+	if !p.IsPublished {
+		return serveProductQueryFailed(ctx, gorm.ErrRecordNotFound)
+	}
+
+	ru := data.NewProductReviewRepository()
+
+	reviews, err := ru.ListPublic(db, p.ID, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	total, err := ru.Count(db, p.ID, models.ReviewApproved)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"reviews": reviews,
+		"total":   total,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func listProductReviewsAsStoreOwner(ctx echo.Context) error {
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	return serveProductReviews(ctx, p.ID, models.ReviewStatus(ctx.QueryParam("status")))
+}
+
+// listProductReviewsForModeration lists the reviews of every product, the pending ones unless asked otherwise
+func listProductReviewsForModeration(ctx echo.Context) error {
+	status := models.ReviewStatus(ctx.QueryParam("status"))
+	if status == "" {
+		status = models.ReviewPending
+	}
+	return serveProductReviews(ctx, "", status)
+}
+
+func serveProductReviews(ctx echo.Context, productID string, status models.ReviewStatus) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	if status != "" && !status.IsValid() {
+		ve := errors.ValidationError{}
+		ve.Add("status", "is invalid")
+
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ReviewDataInvalid
+		resp.Errors = &ve
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+	ru := data.NewProductReviewRepository()
+
+	reviews, err := ru.List(db, productID, status, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	total, err := ru.Count(db, productID, status)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"reviews": reviews,
+		"total":   total,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+// replyToProductReview sets the public answer of the store, replying again replaces it
+func replyToProductReview(ctx echo.Context) error {
+	resp := core.Response{}
+
+	pld, err := validators.ValidateProductReviewReply(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ReviewDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+	ru := data.NewProductReviewRepository()
+
+	r, err := ru.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("review_id"))
+	if err == nil && r.ProductID != ctx.Param("product_id") {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		return serveProductReviewQueryFailed(ctx, err)
+	}
+
+	now := time.Now().UTC()
+	userID := utils.GetUserID(ctx)
+	r.Reply = pld.Reply
+	r.RepliedAt = &now
+	r.RepliedByUserID = &userID
+	r.UpdatedAt = now
+
+	if err := ru.Update(db, r); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+// moderateProductReview approves or rejects the review, a decision can be revisited
+func moderateProductReview(ctx echo.Context) error {
+	resp := core.Response{}
+
+	pld, err := validators.ValidateModerateProductReview(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ReviewDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+	ru := data.NewProductReviewRepository()
+
+	r, err := ru.Get(db, ctx.Param("review_id"))
+	if err != nil {
+		return serveProductReviewQueryFailed(ctx, err)
+	}
+
+	if err := services.ModerateProductReview(r, pld.Status, pld.Note, utils.GetUserID(ctx), time.Now().UTC()); err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.ReviewDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if err := ru.Update(db, r); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = r
+	return resp.ServerJSON(ctx)
+}
+
+func serveProductReviewQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Review not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.ProductReviewNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
@@ -0,0 +1,359 @@
+package api
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	payment_gateways "github.com/shopicano/shopicano-backend/payment-gateways"
+	"github.com/shopicano/shopicano-backend/queue"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+	"net/http"
+	"time"
+)
+
+func listSavedPaymentMethods(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	uu := data.NewUserRepository()
+	methods, err := uu.ListSavedPaymentMethods(db, utils.GetUserID(ctx), payment_gateways.GetActivePaymentGateway().GetName())
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = methods
+	return resp.ServerJSON(ctx)
+}
+
+func savePaymentMethod(ctx echo.Context) error {
+	req, err := validators.ValidateSavePaymentMethod(ctx)
+
+	resp := core.Response{}
+
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.SavedPaymentMethodDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	pg := payment_gateways.GetActivePaymentGateway()
+	cv, err := payment_gateways.GetCustomerVault(pg)
+	if err != nil {
+		return serveSavedPaymentMethodNotSupported(ctx, err)
+	}
+
+	db := app.DB().Begin()
+
+	pc, err := getOrCreatePaymentCustomer(db, cv, pg.GetName(), utils.GetUserID(ctx))
+	if err != nil {
+		db.Rollback()
+		log.Log().Errorln(err)
+
+		resp.Title = "Failed to create payment customer"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	vpm, err := cv.SavePaymentMethod(pc.CustomerID, req.Token)
+	if err != nil {
+		db.Rollback()
+		log.Log().Errorln(err)
+
+		resp.Title = "Failed to save payment method"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	m := &models.SavedPaymentMethod{
+		ID:             utils.NewUUID(),
+		UserID:         pc.UserID,
+		PaymentGateway: pc.PaymentGateway,
+		CustomerID:     pc.CustomerID,
+		Token:          vpm.Token,
+		Brand:          vpm.Brand,
+		Last4:          vpm.Last4,
+		ExpMonth:       vpm.ExpMonth,
+		ExpYear:        vpm.ExpYear,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	uu := data.NewUserRepository()
+	if err := uu.CreateSavedPaymentMethod(db, m); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = m
+	return resp.ServerJSON(ctx)
+}
+
+func deleteSavedPaymentMethod(ctx echo.Context) error {
+	savedPaymentMethodID := ctx.Param("saved_payment_method_id")
+
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	uu := data.NewUserRepository()
+	m, err := uu.GetSavedPaymentMethod(db, utils.GetUserID(ctx), savedPaymentMethodID)
+	if err != nil {
+		db.Rollback()
+
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Saved payment method not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.SavedPaymentMethodNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := uu.DeleteSavedPaymentMethod(db, m.UserID, m.ID); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(m.PaymentGateway)
+	if err != nil {
+		db.Rollback()
+
+		resp.Title = "Invalid payment gateway"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	cv, err := payment_gateways.GetCustomerVault(pg)
+	if err != nil {
+		db.Rollback()
+		return serveSavedPaymentMethodNotSupported(ctx, err)
+	}
+
+	if err := cv.DeletePaymentMethod(m.Token); err != nil {
+		db.Rollback()
+		log.Log().Errorln(err)
+
+		resp.Title = "Failed to delete payment method"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+// getOrCreatePaymentCustomer returns the vault customer of the user, creating it in the gateway on first use
+func getOrCreatePaymentCustomer(db *gorm.DB, cv payment_gateways.CustomerVault, paymentGateway, userID string) (*models.PaymentCustomer, error) {
+	uu := data.NewUserRepository()
+
+	pc, err := uu.GetPaymentCustomer(db, userID, paymentGateway)
+	if err == nil {
+		return pc, nil
+	}
+	if !errors.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	u, err := uu.Get(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	customerID, err := cv.CreateCustomer(u)
+	if err != nil {
+		return nil, err
+	}
+
+	pc = &models.PaymentCustomer{
+		ID:             utils.NewUUID(),
+		UserID:         userID,
+		PaymentGateway: paymentGateway,
+		CustomerID:     customerID,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := uu.CreatePaymentCustomer(db, pc); err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
+
+// payWithSavedPaymentMethod charges a payment method saved by the order owner and completes the payment right away.
+// The order is locked meanwhile, so it's charged once even when paid another way at the same time.
+func payWithSavedPaymentMethod(ctx echo.Context, o *models.OrderDetailsView, savedPaymentMethodID string) error {
+	resp := core.Response{}
+
+	pg, err := payment_gateways.GetPaymentGatewayByName(o.PaymentGateway)
+	if err != nil {
+		resp.Title = "Invalid payment gateway"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.PaymentGatewayFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+	ou := data.NewOrderRepository()
+
+	if res := lockUnpaidOrder(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	res, errResp := chargeSavedPaymentMethod(db, pg, o, savedPaymentMethodID)
+	if errResp != nil {
+		db.Rollback()
+		return errResp.ServerJSON(ctx)
+	}
+
+	o.TransactionID = &res.Result
+	o.Nonce = &res.Nonce
+
+	if err := pg.ValidateTransaction(o); err != nil {
+		log.Log().Errorln(err)
+
+		o.PaymentStatus = models.PaymentFailed
+	} else {
+		o.PaymentStatus = models.PaymentCompleted
+	}
+
+	if err := ou.UpdatePaymentInfo(db, o); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if res := postOrderLedgerEntries(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+	if res := syncOrderLicenseKeys(db, o.ID); res != nil {
+		db.Rollback()
+		return res.ServerJSON(ctx)
+	}
+
+	ol := models.OrderLog{
+		ID:        utils.NewUUID(),
+		OrderID:   o.ID,
+		Action:    string(o.PaymentStatus),
+		Details:   fmt.Sprintf("Payment has been updated using the saved payment method %s", savedPaymentMethodID),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := ou.CreateLog(db, &ol); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if o.PaymentStatus == models.PaymentCompleted {
+		if err := queue.SendPaymentConfirmationEmail(o.ID); err != nil {
+			db.Rollback()
+
+			resp.Title = "Failed to enqueue task"
+			resp.Status = http.StatusInternalServerError
+			resp.Code = errors.FailedToEnqueueTask
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = map[string]interface{}{
+		"transaction_id": res.Result,
+		"payment_status": o.PaymentStatus,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+// chargeSavedPaymentMethod pays the order using a payment method saved by the order owner
+func chargeSavedPaymentMethod(db *gorm.DB, pg payment_gateways.PaymentGateway, o *models.OrderDetailsView, savedPaymentMethodID string) (*payment_gateways.PaymentGatewayResponse, *core.Response) {
+	cv, err := payment_gateways.GetCustomerVault(pg)
+	if err != nil {
+		return nil, &core.Response{
+			Title:  "Saved payment methods aren't supported",
+			Status: http.StatusBadRequest,
+			Code:   errors.SavedPaymentMethodNotSupported,
+			Errors: err,
+		}
+	}
+
+	uu := data.NewUserRepository()
+	m, err := uu.GetSavedPaymentMethod(db, o.UserID, savedPaymentMethodID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			return nil, &core.Response{
+				Title:  "Saved payment method not found",
+				Status: http.StatusNotFound,
+				Code:   errors.SavedPaymentMethodNotFound,
+				Errors: err,
+			}
+		}
+
+		return nil, &core.Response{
+			Title:  "Database query failed",
+			Status: http.StatusInternalServerError,
+			Code:   errors.DatabaseQueryFailed,
+			Errors: err,
+		}
+	}
+
+	if m.PaymentGateway != pg.GetName() {
+		return nil, &core.Response{
+			Title:  "Saved payment method not found",
+			Status: http.StatusNotFound,
+			Code:   errors.SavedPaymentMethodNotFound,
+		}
+	}
+
+	res, err := cv.PayWithSavedPaymentMethod(o, m.CustomerID, m.Token)
+	if err != nil {
+		log.Log().Errorln(err)
+
+		return nil, &core.Response{
+			Title:  "Failed to process payment",
+			Status: http.StatusInternalServerError,
+			Code:   errors.PaymentProcessingFailed,
+			Errors: err,
+		}
+	}
+	return res, nil
+}
+
+func serveSavedPaymentMethodNotSupported(ctx echo.Context, err error) error {
+	resp := core.Response{}
+	resp.Title = "Saved payment methods aren't supported"
+	resp.Status = http.StatusBadRequest
+	resp.Code = errors.SavedPaymentMethodNotSupported
+	resp.Errors = err
+	return resp.ServerJSON(ctx)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"net/http"
+	"strconv"
+)
+
+func listSellerStatements(ctx echo.Context) error {
+	return serveSellerStatements(ctx, utils.GetStoreID(ctx))
+}
+
+func listSellerStatementsByMarketplace(ctx echo.Context) error {
+	return serveSellerStatements(ctx, ctx.Param("store_id"))
+}
+
+func downloadSellerStatementPDF(ctx echo.Context) error {
+	return serveSellerStatementFile(ctx, utils.GetStoreID(ctx), false)
+}
+
+func downloadSellerStatementCSV(ctx echo.Context) error {
+	return serveSellerStatementFile(ctx, utils.GetStoreID(ctx), true)
+}
+
+func downloadSellerStatementPDFByMarketplace(ctx echo.Context) error {
+	return serveSellerStatementFile(ctx, ctx.Param("store_id"), false)
+}
+
+func downloadSellerStatementCSVByMarketplace(ctx echo.Context) error {
+	return serveSellerStatementFile(ctx, ctx.Param("store_id"), true)
+}
+
+func serveSellerStatements(ctx echo.Context, storeID string) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 12
+	}
+
+	from := (page - 1) * limit
+
+	resp := core.Response{}
+
+	db := app.DB()
+	sr := data.NewSellerStatementRepository()
+
+	statements, err := sr.List(db, storeID, int(from), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = statements
+	return resp.ServerJSON(ctx)
+}
+
+func serveSellerStatementFile(ctx echo.Context, storeID string, asCSV bool) error {
+	statementID := ctx.Param("statement_id")
+
+	resp := core.Response{}
+
+	db := app.DB()
+	sr := data.NewSellerStatementRepository()
+
+	s, err := sr.Get(db, storeID, statementID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Statement not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.SellerStatementNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	path := s.PDFPath
+	if asCSV {
+		path = s.CSVPath
+	}
+
+	f, err := services.ServeAsStreamFromMinio(path)
+	if err != nil {
+		resp.Title = "Minio service failed"
+		resp.Status = http.StatusInternalServerError
+		resp.Code = errors.MinioServiceFailed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	return resp.ServeStreamFromMinioAsDownload(ctx, f)
+}
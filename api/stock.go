@@ -0,0 +1,525 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+)
+
+func RegisterStockRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	stockPlatformPath := platformEndpoints.Group("/stock")
+
+	func(g echo.Group) {
+		g.Use(middlewares.HasStore())
+		g.Use(middlewares.IsStoreActive())
+		g.Use(middlewares.IsStoreManager())
+		g.POST("/locations/", createStockLocation)
+		g.GET("/locations/", listStockLocations)
+		g.GET("/locations/:location_id/", getStockLocation)
+		g.PATCH("/locations/:location_id/", updateStockLocation)
+		g.GET("/locations/:location_id/levels/", listStockLocationLevels)
+		g.PUT("/levels/", bulkUpdateStock)
+	}(*stockPlatformPath)
+}
+
+func createStockLocation(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateCreateStockLocation(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.StockLocationDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	l := &models.StockLocation{
+		ID:        utils.NewUUID(),
+		StoreID:   utils.GetStoreID(ctx),
+		Name:      req.Name,
+		Code:      req.Code,
+		Address:   req.Address,
+		IsActive:  true,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	db := app.DB().Begin()
+
+	if err := services.CreateStockLocation(db, l); err != nil {
+		db.Rollback()
+
+		if msg, ok := errors.IsDuplicateKeyError(err); ok {
+			resp.Title = msg
+			resp.Status = http.StatusConflict
+			resp.Code = errors.StockLocationAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = l
+	return resp.ServerJSON(ctx)
+}
+
+func listStockLocations(ctx echo.Context) error {
+	resp := core.Response{}
+
+	su := data.NewStockRepository()
+	locations, err := su.ListLocations(app.DB(), utils.GetStoreID(ctx))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = locations
+	return resp.ServerJSON(ctx)
+}
+
+func getStockLocation(ctx echo.Context) error {
+	resp := core.Response{}
+
+	su := data.NewStockRepository()
+	l, err := su.GetLocation(app.DB(), utils.GetStoreID(ctx), ctx.Param("location_id"))
+	if err != nil {
+		return serveStockLocationQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = l
+	return resp.ServerJSON(ctx)
+}
+
+// updateStockLocation changes the location. Only empty locations can be deactivated, and never the default
+// one, so the stock of the products is always at locations the orders allocate from.
+func updateStockLocation(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateUpdateStockLocation(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.StockLocationDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	su := data.NewStockRepository()
+	l, err := su.GetLocation(db, utils.GetStoreID(ctx), ctx.Param("location_id"))
+	if err != nil {
+		db.Rollback()
+		return serveStockLocationQueryFailed(ctx, err)
+	}
+
+	if req.Name != nil {
+		l.Name = *req.Name
+	}
+	if req.Code != nil {
+		l.Code = *req.Code
+	}
+	if req.Address != nil {
+		l.Address = *req.Address
+	}
+	if req.IsActive != nil {
+		l.IsActive = *req.IsActive
+	}
+
+	if !l.IsActive {
+		if l.IsDefault || req.IsDefault {
+			db.Rollback()
+
+			resp.Title = "Default location can't be deactivated"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.StockLocationNotDeactivatable
+			return resp.ServerJSON(ctx)
+		}
+
+		quantity, err := su.CountLocationStock(db, l.ID)
+		if err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+		if quantity > 0 {
+			db.Rollback()
+
+			resp.Title = "Location still has stock"
+			resp.Status = http.StatusBadRequest
+			resp.Code = errors.StockLocationNotDeactivatable
+			return resp.ServerJSON(ctx)
+		}
+	}
+
+	l.UpdatedAt = time.Now().UTC()
+
+	if err := su.UpdateLocation(db, l); err != nil {
+		db.Rollback()
+
+		if msg, ok := errors.IsDuplicateKeyError(err); ok {
+			resp.Title = msg
+			resp.Status = http.StatusConflict
+			resp.Code = errors.StockLocationAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if req.IsDefault && !l.IsDefault {
+		if err := su.SetDefaultLocation(db, l.StoreID, l.ID); err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+		l.IsDefault = true
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = l
+	return resp.ServerJSON(ctx)
+}
+
+func listStockLocationLevels(ctx echo.Context) error {
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	su := data.NewStockRepository()
+	l, err := su.GetLocation(db, utils.GetStoreID(ctx), ctx.Param("location_id"))
+	if err != nil {
+		return serveStockLocationQueryFailed(ctx, err)
+	}
+
+	levels, err := su.ListLevelsByLocation(db, l.ID, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = levels
+	return resp.ServerJSON(ctx)
+}
+
+// bulkUpdateStock sets the quantities of the SKUs at a location for the ERPs syncing their stock levels.
+// The differences are recorded as corrections, the SKUs not found or of digital products are reported and skipped.
+func bulkUpdateStock(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	userID := utils.GetUserID(ctx)
+
+	resp := core.Response{}
+
+	req, err := validators.ValidateBulkStockUpdate(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.StockAdjustmentDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	su := data.NewStockRepository()
+
+	var l *models.StockLocation
+	switch {
+	case req.LocationID != nil:
+		l, err = su.GetLocation(db, storeID, *req.LocationID)
+	case req.LocationCode != nil:
+		l, err = su.GetLocationByCode(db, storeID, *req.LocationCode)
+	default:
+		l, err = services.DefaultStockLocation(db, storeID)
+	}
+	if err != nil {
+		db.Rollback()
+		return serveStockLocationQueryFailed(ctx, err)
+	}
+
+	if !l.IsActive {
+		db.Rollback()
+
+		resp.Title = "Location is inactive"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.StockAdjustmentNotAllowed
+		return resp.ServerJSON(ctx)
+	}
+
+	results := []models.StockSyncResult{}
+	for _, item := range req.Items {
+		res := models.StockSyncResult{
+			SKU:      item.SKU,
+			Quantity: item.Quantity,
+		}
+
+		si, err := su.FindSKU(db, storeID, item.SKU)
+		if err != nil {
+			if !errors.IsRecordNotFoundError(err) {
+				db.Rollback()
+				return serveDatabaseQueryFailed(ctx, err)
+			}
+
+			res.Status = models.StockSyncNotFound
+			results = append(results, res)
+			continue
+		}
+
+		if si.IsDigital {
+			res.Status = models.StockSyncNotTracked
+			results = append(results, res)
+			continue
+		}
+
+		changed, err := services.SetStockLevel(db, &models.StockAdjustment{
+			StoreID:    storeID,
+			LocationID: l.ID,
+			ProductID:  si.ProductID,
+			VariantID:  si.VariantID,
+			Reason:     models.StockCorrection,
+			Note:       req.Note,
+			UserID:     &userID,
+		}, item.Quantity)
+		if err != nil {
+			db.Rollback()
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+
+		res.Status = models.StockSyncUnchanged
+		if changed {
+			res.Status = models.StockSyncUpdated
+		}
+		results = append(results, res)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = results
+	return resp.ServerJSON(ctx)
+}
+
+// listProductStock lists the levels of the product and its variants at every location
+func listProductStock(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, utils.GetStoreID(ctx), ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	su := data.NewStockRepository()
+	levels, err := su.ListLevelsByProduct(db, p.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = levels
+	return resp.ServerJSON(ctx)
+}
+
+func listStockAdjustments(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+
+	pageQ := ctx.Request().URL.Query().Get("page")
+	limitQ := ctx.Request().URL.Query().Get("limit")
+
+	page, err := strconv.ParseInt(pageQ, 10, 64)
+	if err != nil {
+		page = 1
+	}
+	limit, err := strconv.ParseInt(limitQ, 10, 64)
+	if err != nil {
+		limit = 10
+	}
+
+	resp := core.Response{}
+
+	db := app.DB()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, ctx.Param("product_id"))
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	su := data.NewStockRepository()
+	adjustments, err := su.ListAdjustments(db, storeID, p.ID, int((page-1)*limit), int(limit))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = adjustments
+	return resp.ServerJSON(ctx)
+}
+
+// adjustProductStock records a stock movement of the product, or of one of its variants, at a location
+func adjustProductStock(ctx echo.Context) error {
+	storeID := utils.GetStoreID(ctx)
+	userID := utils.GetUserID(ctx)
+
+	resp := core.Response{}
+
+	req, err := validators.ValidateStockAdjustment(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.StockAdjustmentDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	if err := services.CheckStockAdjustment(req.Reason, req.Quantity); err != nil {
+		resp.Title = "Stock adjustment not allowed"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.StockAdjustmentNotAllowed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+
+	pu := data.NewProductRepository()
+	p, err := pu.GetAsStoreStuff(db, storeID, ctx.Param("product_id"))
+	if err != nil {
+		db.Rollback()
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	if p.IsDigital {
+		db.Rollback()
+
+		resp.Title = "Digital products have unlimited stock"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.StockAdjustmentNotAllowed
+		return resp.ServerJSON(ctx)
+	}
+
+	if req.VariantID != nil {
+		vu := data.NewProductVariantRepository()
+		if _, err := vu.GetVariant(db, p.ID, *req.VariantID); err != nil {
+			db.Rollback()
+
+			if errors.IsRecordNotFoundError(err) {
+				resp.Title = "Product variant not found"
+				resp.Status = http.StatusNotFound
+				resp.Code = errors.ProductVariantNotFound
+				resp.Errors = err
+				return resp.ServerJSON(ctx)
+			}
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	su := data.NewStockRepository()
+	l, err := su.GetLocation(db, storeID, req.LocationID)
+	if err != nil {
+		db.Rollback()
+		return serveStockLocationQueryFailed(ctx, err)
+	}
+
+	if !l.IsActive {
+		db.Rollback()
+
+		resp.Title = "Location is inactive"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.StockAdjustmentNotAllowed
+		return resp.ServerJSON(ctx)
+	}
+
+	if req.OrderID != nil {
+		ou := data.NewOrderRepository()
+		if _, err := ou.GetAsStoreStuff(db, storeID, *req.OrderID); err != nil {
+			db.Rollback()
+			return serveOrderQueryFailed(ctx, err)
+		}
+	}
+
+	a := &models.StockAdjustment{
+		StoreID:    storeID,
+		LocationID: l.ID,
+		ProductID:  p.ID,
+		VariantID:  req.VariantID,
+		Reason:     req.Reason,
+		Quantity:   req.Quantity,
+		Note:       req.Note,
+		OrderID:    req.OrderID,
+		UserID:     &userID,
+	}
+	if err := services.AdjustStock(db, a); err != nil {
+		db.Rollback()
+		return serveStockAdjustmentFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = a
+	return resp.ServerJSON(ctx)
+}
+
+func serveStockLocationQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Stock location not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.StockLocationNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+// serveStockAdjustmentFailed reports taking more than the location holds, which the adjustments fail as not found
+func serveStockAdjustmentFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Not enough stock at the location"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.StockNotAvailableAtLocation
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
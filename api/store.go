@@ -44,7 +44,18 @@ func RegisterStoreRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 		g.POST("/:store_id/payouts/entries/", createPayoutEntry)
 		g.GET("/:store_id/payouts/entries/", listPayoutEntries)
 		g.GET("/:store_id/payouts/entries/:entry_id/", getPayoutEntry)
+		g.GET("/:store_id/payouts/entries/:entry_id/events/", listPayoutEvents)
 		g.GET("/:store_id/payouts/summary/", getStorePayoutSummary)
+		g.GET("/:store_id/ledger/statement/", getStoreLedgerStatement)
+		g.GET("/:store_id/statements/", listSellerStatements)
+		g.GET("/:store_id/statements/:statement_id/pdf/", downloadSellerStatementPDF)
+		g.GET("/:store_id/statements/:statement_id/csv/", downloadSellerStatementCSV)
+		g.GET("/:store_id/invoices/", listCommissionInvoices)
+		g.GET("/:store_id/invoices/:invoice_id/pdf/", downloadCommissionInvoicePDF)
+		g.GET("/:store_id/disputes/", listDisputesOfStore)
+		g.GET("/:store_id/disputes/:dispute_id/", getDisputeOfStore)
+		g.POST("/:store_id/disputes/:dispute_id/evidences/", uploadDisputeEvidenceByStore)
+		g.GET("/:store_id/disputes/:dispute_id/evidences/:evidence_id/", downloadDisputeEvidenceByStore)
 	}(*storesPublicPath)
 
 	func(g echo.Group) {
@@ -61,7 +72,14 @@ func RegisterStoreRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 		g.GET("/:store_id/payouts/entries/", listPayoutEntriesByMarketplace)
 		g.GET("/:store_id/payouts/entries/:entry_id/", getPayoutEntryByMarketplace)
 		g.PATCH("/:store_id/payouts/entries/:entry_id/", updatePayoutEntryByMarketplace)
+		g.GET("/:store_id/payouts/entries/:entry_id/events/", listPayoutEventsByMarketplace)
 		g.GET("/:store_id/payouts/summary/", getStorePayoutSummaryByMarketplace)
+		g.GET("/:store_id/ledger/statement/", getStoreLedgerStatementByMarketplace)
+		g.GET("/:store_id/statements/", listSellerStatementsByMarketplace)
+		g.GET("/:store_id/statements/:statement_id/pdf/", downloadSellerStatementPDFByMarketplace)
+		g.GET("/:store_id/statements/:statement_id/csv/", downloadSellerStatementCSVByMarketplace)
+		g.GET("/:store_id/invoices/", listCommissionInvoicesByMarketplace)
+		g.GET("/:store_id/invoices/:invoice_id/pdf/", downloadCommissionInvoicePDFByMarketplace)
 	}(*storesPlatformPath)
 }
 
@@ -560,7 +578,7 @@ func listStores(ctx echo.Context) error {
 func updateStoreAsPlatformOwner(ctx echo.Context) error {
 	storeID := ctx.Param("store_id")
 
-	status, commissionRate, err := validators.ValidateUpdateStoreStatus(ctx)
+	req, err := validators.ValidateUpdateStoreStatus(ctx)
 
 	resp := core.Response{}
 
@@ -594,11 +612,20 @@ func updateStoreAsPlatformOwner(ctx echo.Context) error {
 		return resp.ServerJSON(ctx)
 	}
 
-	if status != nil {
-		store.Status = *status
+	if req.Status != nil {
+		store.Status = *req.Status
 	}
-	if commissionRate != nil {
-		store.CommissionRate = *commissionRate
+	if req.CommissionRate != nil {
+		store.CommissionRate = *req.CommissionRate
+	}
+	if req.Tier != nil {
+		store.Tier = *req.Tier
+	}
+	if req.ReservePeriodDays != nil {
+		store.ReservePeriodDays = req.ReservePeriodDays
+	}
+	if req.UseDefaultReservePeriod {
+		store.ReservePeriodDays = nil
 	}
 
 	if err := su.UpdateStoreStatus(db, store); err != nil {
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/services"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+	"net/http"
+	"time"
+)
+
+func RegisterTaxonomyRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	taxonomyPublicPath := publicEndpoints.Group("/taxonomy")
+	taxonomyPlatformPath := platformEndpoints.Group("/taxonomy")
+
+	func(g echo.Group) {
+		g.GET("/", getTaxonomyTree)
+		g.GET("/:node_id/", getTaxonomyNode)
+	}(*taxonomyPublicPath)
+
+	func(g echo.Group) {
+		g.Use(middlewares.IsPlatformAdmin)
+		g.POST("/", createTaxonomyNode)
+		g.PATCH("/:node_id/", updateTaxonomyNode)
+		g.DELETE("/:node_id/", deleteTaxonomyNode)
+	}(*taxonomyPlatformPath)
+}
+
+func getTaxonomyTree(ctx echo.Context) error {
+	resp := core.Response{}
+
+	tu := data.NewTaxonomyRepository()
+	nodes, err := tu.List(app.DB())
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = services.BuildTaxonomyTree(nodes)
+	return resp.ServerJSON(ctx)
+}
+
+// getTaxonomyNode returns the node by ID or slug with its breadcrumb and its children
+func getTaxonomyNode(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+	tu := data.NewTaxonomyRepository()
+
+	n, err := tu.Get(db, ctx.Param("node_id"))
+	if err != nil {
+		return serveTaxonomyNodeQueryFailed(ctx, err)
+	}
+
+	var ancestorIDs []string
+	if ids := models.PathIDs(n.Path); len(ids) > 1 {
+		ancestorIDs = ids[:len(ids)-1]
+	}
+	ancestors, err := tu.ListByIDs(db, ancestorIDs)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	children, err := tu.ListChildren(db, n.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = models.TaxonomyNodeDetails{
+		TaxonomyNode: *n,
+		Ancestors:    ancestors,
+		Children:     children,
+	}
+	return resp.ServerJSON(ctx)
+}
+
+func createTaxonomyNode(ctx echo.Context) error {
+	resp := core.Response{}
+
+	pld, err := validators.ValidateCreateTaxonomyNode(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.TaxonomyNodeDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+	tu := data.NewTaxonomyRepository()
+
+	n := &models.TaxonomyNode{
+		ID:          utils.NewUUID(),
+		Name:        pld.Name,
+		Slug:        pld.Slug,
+		Description: pld.Description,
+		Image:       pld.Image,
+		Position:    pld.Position,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	if err := placeTaxonomyNode(db, n, pld.ParentID); err != nil {
+		db.Rollback()
+		return serveTaxonomyNodePlacementFailed(ctx, err)
+	}
+
+	if err := tu.Create(db, n); err != nil {
+		db.Rollback()
+		return serveTaxonomyNodeSaveFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = n
+	return resp.ServerJSON(ctx)
+}
+
+func updateTaxonomyNode(ctx echo.Context) error {
+	resp := core.Response{}
+
+	pld, err := validators.ValidateUpdateTaxonomyNode(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.TaxonomyNodeDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB().Begin()
+	tu := data.NewTaxonomyRepository()
+
+	n, err := tu.Get(db, ctx.Param("node_id"))
+	if err != nil {
+		db.Rollback()
+		return serveTaxonomyNodeQueryFailed(ctx, err)
+	}
+
+	if pld.Name != nil {
+		n.Name = *pld.Name
+	}
+	if pld.Slug != nil {
+		n.Slug = *pld.Slug
+	}
+	if pld.Description != nil {
+		n.Description = *pld.Description
+	}
+	if pld.Image != nil {
+		n.Image = *pld.Image
+	}
+	if pld.Position != nil {
+		n.Position = *pld.Position
+	}
+
+	parentID := n.ParentID
+	if pld.ParentID != nil {
+		parentID = pld.ParentID
+		if *parentID == "" {
+			parentID = nil
+		}
+	}
+
+	if err := placeTaxonomyNode(db, n, parentID); err != nil {
+		db.Rollback()
+		return serveTaxonomyNodePlacementFailed(ctx, err)
+	}
+
+	n.UpdatedAt = time.Now().UTC()
+
+	if err := tu.Update(db, n); err != nil {
+		db.Rollback()
+		return serveTaxonomyNodeSaveFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = n
+	return resp.ServerJSON(ctx)
+}
+
+// deleteTaxonomyNode only deletes leaves no store category is mapped into
+func deleteTaxonomyNode(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+	tu := data.NewTaxonomyRepository()
+
+	n, err := tu.Get(db, ctx.Param("node_id"))
+	if err != nil {
+		return serveTaxonomyNodeQueryFailed(ctx, err)
+	}
+
+	children, err := tu.CountChildren(db, n.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	categories, err := tu.CountCategories(db, n.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	if children > 0 || categories > 0 {
+		resp.Title = "Taxonomy node has children or mapped categories"
+		resp.Status = http.StatusConflict
+		resp.Code = errors.TaxonomyNodeInUse
+		return resp.ServerJSON(ctx)
+	}
+
+	if err := tu.Delete(db, n.ID); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+// placeTaxonomyNode places the node below the parent, moving its descendants along
+func placeTaxonomyNode(db *gorm.DB, n *models.TaxonomyNode, parentID *string) error {
+	var parent *models.TaxonomyNode
+	if parentID != nil {
+		tu := data.NewTaxonomyRepository()
+		p, err := tu.Get(db, *parentID)
+		if err != nil {
+			return err
+		}
+		parent = p
+	}
+	return services.PlaceTaxonomyNode(db, n, parent)
+}
+
+func serveTaxonomyNodePlacementFailed(ctx echo.Context, err error) error {
+	if _, ok := err.(*errors.UndefinedError); ok {
+		resp := core.Response{}
+		resp.Title = "Taxonomy node can't be placed there"
+		resp.Status = http.StatusBadRequest
+		resp.Code = errors.CategoryMoveNotAllowed
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveTaxonomyNodeQueryFailed(ctx, err)
+}
+
+func serveTaxonomyNodeSaveFailed(ctx echo.Context, err error) error {
+	if msg, ok := errors.IsDuplicateKeyError(err); ok {
+		resp := core.Response{}
+		resp.Title = msg
+		resp.Status = http.StatusConflict
+		resp.Code = errors.TaxonomyNodeAlreadyExists
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
+
+func serveTaxonomyNodeQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Taxonomy node not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.TaxonomyNodeNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
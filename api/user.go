@@ -23,6 +23,9 @@ func RegisterUserRoutes(publicEndpoints, platformEndpoints *echo.Group) {
 		g.Use(middlewares.JWTAuth())
 		g.PUT("/", update)
 		g.GET("/", get)
+		g.GET("/saved-payment-methods/", listSavedPaymentMethods)
+		g.POST("/saved-payment-methods/", savePaymentMethod)
+		g.DELETE("/saved-payment-methods/:saved_payment_method_id/", deleteSavedPaymentMethod)
 	}(*usersPublicPath)
 
 	func(g echo.Group) {
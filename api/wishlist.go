@@ -0,0 +1,452 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/core"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/middlewares"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+)
+
+func RegisterWishlistRoutes(publicEndpoints, platformEndpoints *echo.Group) {
+	wishlistsPublicPath := publicEndpoints.Group("/wishlists")
+	alertsPublicPath := publicEndpoints.Group("/product-alerts")
+
+	func(g echo.Group) {
+		g.GET("/shared/:share_token/", getSharedWishlist)
+	}(*wishlistsPublicPath)
+
+	func(g echo.Group) {
+		g.Use(middlewares.JWTAuth())
+		g.POST("/", createWishlist)
+		g.GET("/", listWishlists)
+		g.GET("/:wishlist_id/", getWishlist)
+		g.PATCH("/:wishlist_id/", updateWishlist)
+		g.DELETE("/:wishlist_id/", deleteWishlist)
+		g.POST("/:wishlist_id/items/", addWishlistItem)
+		g.DELETE("/:wishlist_id/items/:product_id/", removeWishlistItem)
+		g.PUT("/:wishlist_id/share/", shareWishlist)
+		g.DELETE("/:wishlist_id/share/", unshareWishlist)
+	}(*wishlistsPublicPath)
+
+	func(g echo.Group) {
+		g.Use(middlewares.JWTAuth())
+		g.POST("/", subscribeToProductAlerts)
+		g.GET("/", listProductAlertSubscriptions)
+		g.DELETE("/:product_id/", unsubscribeFromProductAlerts)
+	}(*alertsPublicPath)
+}
+
+func createWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateWishlist(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.WishlistDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	w := &models.Wishlist{
+		ID:        utils.NewUUID(),
+		UserID:    utils.GetUserID(ctx),
+		Name:      req.Name,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	wu := data.NewWishlistRepository()
+	if err := wu.Create(app.DB(), w); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = w
+	return resp.ServerJSON(ctx)
+}
+
+func listWishlists(ctx echo.Context) error {
+	resp := core.Response{}
+
+	wu := data.NewWishlistRepository()
+	wishlists, err := wu.List(app.DB(), utils.GetUserID(ctx))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = wishlists
+	return resp.ServerJSON(ctx)
+}
+
+func getWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	d, err := getWishlistDetails(db, w)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
+
+// getSharedWishlist serves a shared wishlist to anyone with its link, without the token it's shared by
+func getSharedWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.GetByShareToken(db, ctx.Param("share_token"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	d, err := getWishlistDetails(db, w)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+	d.ShareToken = nil
+	d.ShareURL = ""
+
+	resp.Status = http.StatusOK
+	resp.Data = d
+	return resp.ServerJSON(ctx)
+}
+
+func updateWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateWishlist(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.WishlistDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	w.Name = req.Name
+	w.UpdatedAt = time.Now().UTC()
+
+	if err := wu.Update(db, w); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = w
+	return resp.ServerJSON(ctx)
+}
+
+func deleteWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB().Begin()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		db.Rollback()
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	if err := wu.Delete(db, w); err != nil {
+		db.Rollback()
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	if err := db.Commit().Error; err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+func addWishlistItem(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateSaveProduct(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.WishlistDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	p, err := getPublishedProduct(db, req.ProductID)
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	au := data.NewProductAlertRepository()
+	stock, err := au.GetAvailableStock(db, p.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	wi := &models.WishlistItem{
+		WishlistID: w.ID,
+		ProductID:  p.ID,
+		SeenPrice:  p.Price,
+		SeenStock:  stock,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := wu.AddItem(db, wi); err != nil {
+		if msg, ok := errors.IsDuplicateKeyError(err); ok {
+			resp.Title = msg
+			resp.Status = http.StatusConflict
+			resp.Code = errors.WishlistItemAlreadyExists
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = wi
+	return resp.ServerJSON(ctx)
+}
+
+func removeWishlistItem(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	if err := wu.RemoveItem(db, w.ID, ctx.Param("product_id")); err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+// shareWishlist gives the wishlist a share link, sharing again keeps the link
+func shareWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	if w.ShareToken == nil {
+		token := utils.NewUUID()
+		w.ShareToken = &token
+		w.UpdatedAt = time.Now().UTC()
+
+		if err := wu.Update(db, w); err != nil {
+			return serveDatabaseQueryFailed(ctx, err)
+		}
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = models.WishlistDetails{
+		Wishlist: *w,
+		ShareURL: wishlistShareURL(w),
+		Items:    []models.SavedProductDetails{},
+	}
+	return resp.ServerJSON(ctx)
+}
+
+// unshareWishlist makes the wishlist private again, the previous link stops working
+func unshareWishlist(ctx echo.Context) error {
+	resp := core.Response{}
+
+	db := app.DB()
+
+	wu := data.NewWishlistRepository()
+	w, err := wu.Get(db, utils.GetUserID(ctx), ctx.Param("wishlist_id"))
+	if err != nil {
+		return serveWishlistQueryFailed(ctx, err)
+	}
+
+	w.ShareToken = nil
+	w.UpdatedAt = time.Now().UTC()
+
+	if err := wu.Update(db, w); err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = w
+	return resp.ServerJSON(ctx)
+}
+
+func subscribeToProductAlerts(ctx echo.Context) error {
+	resp := core.Response{}
+
+	req, err := validators.ValidateSaveProduct(ctx)
+	if err != nil {
+		resp.Title = "Invalid data"
+		resp.Status = http.StatusUnprocessableEntity
+		resp.Code = errors.WishlistDataInvalid
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+
+	db := app.DB()
+
+	p, err := getPublishedProduct(db, req.ProductID)
+	if err != nil {
+		return serveProductQueryFailed(ctx, err)
+	}
+
+	au := data.NewProductAlertRepository()
+	stock, err := au.GetAvailableStock(db, p.ID)
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	s := &models.ProductAlertSubscription{
+		UserID:    utils.GetUserID(ctx),
+		ProductID: p.ID,
+		SeenPrice: p.Price,
+		SeenStock: stock,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := au.Subscribe(db, s); err != nil {
+		if msg, ok := errors.IsDuplicateKeyError(err); ok {
+			resp.Title = msg
+			resp.Status = http.StatusConflict
+			resp.Code = errors.ProductAlertAlreadySubscribed
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusCreated
+	resp.Data = s
+	return resp.ServerJSON(ctx)
+}
+
+func listProductAlertSubscriptions(ctx echo.Context) error {
+	resp := core.Response{}
+
+	au := data.NewProductAlertRepository()
+	products, err := au.ListSubscriptions(app.DB(), utils.GetUserID(ctx))
+	if err != nil {
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusOK
+	resp.Data = products
+	return resp.ServerJSON(ctx)
+}
+
+func unsubscribeFromProductAlerts(ctx echo.Context) error {
+	resp := core.Response{}
+
+	au := data.NewProductAlertRepository()
+	if err := au.Unsubscribe(app.DB(), utils.GetUserID(ctx), ctx.Param("product_id")); err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			resp.Title = "Product alert subscription not found"
+			resp.Status = http.StatusNotFound
+			resp.Code = errors.ProductAlertSubscriptionNotFound
+			resp.Errors = err
+			return resp.ServerJSON(ctx)
+		}
+		return serveDatabaseQueryFailed(ctx, err)
+	}
+
+	resp.Status = http.StatusNoContent
+	return resp.ServerJSON(ctx)
+}
+
+func getWishlistDetails(db *gorm.DB, w *models.Wishlist) (*models.WishlistDetails, error) {
+	wu := data.NewWishlistRepository()
+	items, err := wu.ListItems(db, w.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WishlistDetails{
+		Wishlist: *w,
+		ShareURL: wishlistShareURL(w),
+		Items:    items,
+	}, nil
+}
+
+// wishlistShareURL is empty for private wishlists or when the front store has no page for them
+func wishlistShareURL(w *models.Wishlist) string {
+	path := config.PathMappingCfg()["shared_wishlist"]
+	if w.ShareToken == nil || path == "" {
+		return ""
+	}
+	return config.App().FrontStoreUrl + fmt.Sprintf(path, *w.ShareToken)
+}
+
+// getPublishedProduct finds the product by ID or slug, unpublished products are not found
+func getPublishedProduct(db *gorm.DB, productID string) (*models.Product, error) {
+	pu := data.NewProductRepository()
+	p, err := pu.Get(db, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.IsPublished {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return p, nil
+}
+
+func serveWishlistQueryFailed(ctx echo.Context, err error) error {
+	if errors.IsRecordNotFoundError(err) {
+		resp := core.Response{}
+		resp.Title = "Wishlist not found"
+		resp.Status = http.StatusNotFound
+		resp.Code = errors.WishlistNotFound
+		resp.Errors = err
+		return resp.ServerJSON(ctx)
+	}
+	return serveDatabaseQueryFailed(ctx, err)
+}
@@ -23,14 +23,28 @@ func auto(cmd *cobra.Command, args []string) {
 	tables = append(tables, &models.UserPermission{}, &models.User{}, &models.Session{})
 	tables = append(tables, &models.StorePermission{}, &models.Store{}, &models.Staff{})
 	tables = append(tables, &models.ShippingMethod{}, &models.PaymentMethod{}, &models.Settings{})
+	tables = append(tables, &models.TaxonomyNode{})
 	tables = append(tables, &models.Category{}, &models.Collection{}, &models.Product{}, &models.CollectionOfProduct{})
 	tables = append(tables, &models.ProductAttribute{}, &models.OrderLog{}, &models.ProductImage{})
-	tables = append(tables, &models.Order{}, &models.OrderedItem{})
+	tables = append(tables, &models.ProductOption{}, &models.ProductOptionValue{})
+	tables = append(tables, &models.ProductVariant{}, &models.ProductVariantOptionValue{}, &models.ProductImport{})
+	tables = append(tables, &models.ProductRevision{}, &models.ProductRevisionImage{}, &models.ProductRecommendation{})
+	tables = append(tables, &models.StockLocation{}, &models.StockLevel{})
+	tables = append(tables, &models.Order{}, &models.OrderedItem{}, &models.StockAdjustment{})
+	tables = append(tables, &models.LicenseKeyPool{}, &models.LicenseKey{})
 	tables = append(tables, &models.Coupon{}, &models.CouponFor{}, &models.CouponUsage{})
 	tables = append(tables, &models.Location{}, &models.Review{}, &models.OrderedItemAttribute{}, &models.Log{})
+	tables = append(tables, &models.ProductReview{}, &models.ProductReviewPhoto{})
+	tables = append(tables, &models.Wishlist{}, &models.WishlistItem{}, &models.ProductAlertSubscription{}, &models.ProductAlert{})
 	tables = append(tables, &models.Location{}, &models.ShippingForLocation{}, &models.PaymentForLocation{})
 	tables = append(tables, &models.BusinessAccountType{}, &models.PayoutMethod{}, &models.PayoutSettings{})
-	tables = append(tables, &models.PayoutSend{})
+	tables = append(tables, &models.PayoutBatch{}, &models.PayoutSend{}, &models.PayoutEvent{})
+	tables = append(tables, &models.CommissionRule{}, &models.SellerStatement{})
+	tables = append(tables, &models.InvoiceSequence{}, &models.CommissionInvoice{})
+	tables = append(tables, &models.Dispute{}, &models.DisputeEvidence{})
+	tables = append(tables, &models.ReconciliationReport{}, &models.PaymentDiscrepancy{})
+	tables = append(tables, &models.PaymentCustomer{}, &models.SavedPaymentMethod{})
+	tables = append(tables, &models.LedgerAccount{}, &models.JournalEntry{}, &models.JournalLine{})
 
 	for _, t := range tables {
 		if err := tx.AutoMigrate(t).Error; err != nil {
@@ -42,15 +56,27 @@ func auto(cmd *cobra.Command, args []string) {
 
 	var tForeignKeys []core.Model
 	tForeignKeys = append(tForeignKeys, &models.Address{}, &models.Category{}, &models.Collection{})
+	tForeignKeys = append(tForeignKeys, &models.TaxonomyNode{})
 	tForeignKeys = append(tForeignKeys, &models.Order{}, &models.OrderedItem{})
 	tForeignKeys = append(tForeignKeys, &models.Product{}, &models.CollectionOfProduct{})
 	tForeignKeys = append(tForeignKeys, &models.ProductAttribute{}, &models.OrderLog{}, &models.ProductImage{})
+	tForeignKeys = append(tForeignKeys, &models.ProductOption{}, &models.ProductOptionValue{})
+	tForeignKeys = append(tForeignKeys, &models.ProductVariant{}, &models.ProductVariantOptionValue{}, &models.ProductImport{})
+	tForeignKeys = append(tForeignKeys, &models.ProductRevision{}, &models.ProductRevisionImage{}, &models.ProductRecommendation{})
+	tForeignKeys = append(tForeignKeys, &models.StockLocation{}, &models.StockLevel{}, &models.StockAdjustment{})
+	tForeignKeys = append(tForeignKeys, &models.LicenseKeyPool{}, &models.LicenseKey{})
 	tForeignKeys = append(tForeignKeys, &models.Settings{}, &models.Store{}, &models.Staff{})
 	tForeignKeys = append(tForeignKeys, &models.User{}, &models.Session{})
 	tForeignKeys = append(tForeignKeys, &models.Coupon{}, &models.Coupon{}, &models.CouponUsage{})
 	tForeignKeys = append(tForeignKeys, &models.Review{}, &models.OrderedItemAttribute{}, &models.ShippingForLocation{})
+	tForeignKeys = append(tForeignKeys, &models.ProductReview{}, &models.ProductReviewPhoto{})
+	tForeignKeys = append(tForeignKeys, &models.Wishlist{}, &models.WishlistItem{}, &models.ProductAlertSubscription{}, &models.ProductAlert{})
 	tForeignKeys = append(tForeignKeys, &models.PaymentForLocation{}, &models.PayoutSettings{})
-	tForeignKeys = append(tForeignKeys, &models.PayoutSend{})
+	tForeignKeys = append(tForeignKeys, &models.PayoutBatch{}, &models.PayoutSend{}, &models.PayoutEvent{})
+	tForeignKeys = append(tForeignKeys, &models.CommissionRule{}, &models.SellerStatement{}, &models.CommissionInvoice{})
+	tForeignKeys = append(tForeignKeys, &models.PaymentDiscrepancy{}, &models.Dispute{}, &models.DisputeEvidence{})
+	tForeignKeys = append(tForeignKeys, &models.PaymentCustomer{}, &models.SavedPaymentMethod{})
+	tForeignKeys = append(tForeignKeys, &models.LedgerAccount{}, &models.JournalEntry{}, &models.JournalLine{})
 
 	for _, t := range tForeignKeys {
 		for _, fks := range t.ForeignKeys() {
@@ -76,6 +102,41 @@ func auto(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	pe := models.PayoutEvent{}
+	if err := pe.CreateImmutabilityTrigger(tx); err != nil {
+		tx.Rollback()
+		log.Log().Errorln(err)
+		return
+	}
+
+	p := models.Product{}
+	if err := p.CreateSearchIndex(tx); err != nil {
+		tx.Rollback()
+		log.Log().Errorln(err)
+		return
+	}
+
+	c := models.Category{}
+	if err := c.MigratePaths(tx); err != nil {
+		tx.Rollback()
+		log.Log().Errorln(err)
+		return
+	}
+
+	tn := models.TaxonomyNode{}
+	if err := tn.CreatePathIndex(tx); err != nil {
+		tx.Rollback()
+		log.Log().Errorln(err)
+		return
+	}
+
+	sl := models.StockLevel{}
+	if err := sl.CreateUniqueIndex(tx); err != nil {
+		tx.Rollback()
+		log.Log().Errorln(err)
+		return
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		log.Log().Errorln(err)
 		return
@@ -19,9 +19,17 @@ func drop(cmd *cobra.Command, args []string) {
 
 	var tables []core.Table
 	tables = append(tables, &models.CouponUsage{}, &models.CouponFor{}, &models.Coupon{}, &models.Review{}, &models.OrderedItemAttribute{})
+	tables = append(tables, &models.ProductReviewPhoto{}, &models.ProductReview{})
+	tables = append(tables, &models.ProductAlert{}, &models.ProductAlertSubscription{}, &models.WishlistItem{}, &models.Wishlist{})
 	tables = append(tables, &models.ProductAttribute{}, &models.OrderLog{}, &models.ProductImage{})
-	tables = append(tables, &models.OrderedItem{}, &models.Order{})
+	tables = append(tables, &models.LicenseKey{}, &models.LicenseKeyPool{})
+	tables = append(tables, &models.StockAdjustment{}, &models.OrderedItem{}, &models.Order{})
+	tables = append(tables, &models.StockLevel{}, &models.StockLocation{})
+	tables = append(tables, &models.ProductVariantOptionValue{}, &models.ProductVariant{})
+	tables = append(tables, &models.ProductRecommendation{}, &models.ProductRevisionImage{}, &models.ProductRevision{})
+	tables = append(tables, &models.ProductOptionValue{}, &models.ProductOption{})
 	tables = append(tables, &models.CollectionOfProduct{}, &models.Product{}, &models.Category{}, &models.Collection{})
+	tables = append(tables, &models.TaxonomyNode{})
 	tables = append(tables, &models.ShippingMethod{}, &models.PaymentMethod{}, &models.Settings{})
 	tables = append(tables, &models.Staff{}, &models.StorePermission{}, &models.Store{})
 	tables = append(tables, &models.Address{}, &models.Session{}, &models.User{}, &models.UserPermission{})
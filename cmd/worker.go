@@ -30,6 +30,12 @@ func preServeWorker(cmd *cobra.Command, args []string) {
 		log.Log().Errorln("Failed to register rabbitmq tasks : ", err)
 		os.Exit(-1)
 	}
+	if config.Scheduler().Enabled {
+		if err := machinery.RegisterScheduledTasks(); err != nil {
+			log.Log().Errorln("Failed to register scheduled tasks : ", err)
+			os.Exit(-1)
+		}
+	}
 }
 
 func serveWorker(cmd *cobra.Command, args []string) {
@@ -38,5 +44,9 @@ func serveWorker(cmd *cobra.Command, args []string) {
 		os.Exit(-1)
 	}
 
+	if config.Scheduler().Enabled {
+		go machinery.RunScheduler()
+	}
+
 	machinery.RunRabbitMQWorker()
 }
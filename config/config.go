@@ -46,6 +46,9 @@ func LoadConfig() error {
 	LoadRabbitMQ()
 	LoadEmailService()
 	LoadPathMapping()
+	LoadScheduler()
+	LoadPayout()
+	LoadInvoice()
 
 	return nil
 }
@@ -0,0 +1,30 @@
+package config
+
+import "github.com/spf13/viper"
+
+type InvoiceCfg struct {
+	NumberPrefix           string
+	CreditNoteNumberPrefix string
+}
+
+var invoice InvoiceCfg
+
+func LoadInvoice() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	invoice = InvoiceCfg{
+		NumberPrefix:           viper.GetString("invoice.number_prefix"),
+		CreditNoteNumberPrefix: viper.GetString("invoice.credit_note_number_prefix"),
+	}
+	if invoice.NumberPrefix == "" {
+		invoice.NumberPrefix = "INV"
+	}
+	if invoice.CreditNoteNumberPrefix == "" {
+		invoice.CreditNoteNumberPrefix = "CN"
+	}
+}
+
+func Invoice() InvoiceCfg {
+	return invoice
+}
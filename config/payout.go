@@ -0,0 +1,33 @@
+package config
+
+import "github.com/spf13/viper"
+
+type PayoutCfg struct {
+	FinanceTeamEmails []string
+	Currency          string
+	DebtorName        string
+	DebtorIBAN        string
+	DebtorBIC         string
+}
+
+var payout PayoutCfg
+
+func LoadPayout() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	payout = PayoutCfg{
+		FinanceTeamEmails: viper.GetStringSlice("payout.finance_team_emails"),
+		Currency:          viper.GetString("payout.currency"),
+		DebtorName:        viper.GetString("payout.debtor_name"),
+		DebtorIBAN:        viper.GetString("payout.debtor_iban"),
+		DebtorBIC:         viper.GetString("payout.debtor_bic"),
+	}
+	if payout.Currency == "" {
+		payout.Currency = "USD"
+	}
+}
+
+func Payout() PayoutCfg {
+	return payout
+}
@@ -0,0 +1,48 @@
+package config
+
+import "github.com/spf13/viper"
+
+// SchedulerCfg holds the configuration of the periodic tasks enqueued by the worker.
+// Only one worker instance should run with the scheduler enabled.
+type SchedulerCfg struct {
+	Enabled                        bool
+	PaymentReconciliationAt        string
+	PaymentReconciliationWindowHrs int
+	PayoutSchedule                 string
+	PayoutAt                       string
+	PayoutWeekday                  string
+	PayoutDayOfMonth               int
+	SellerStatementsAt             string
+	CommissionInvoicesAt           string
+	ProductPublishingIntervalMins  int
+	ProductRecommendationsAt       string
+	ProductAlertsIntervalMins      int
+	LicenseKeyPoolsIntervalMins    int
+}
+
+var scheduler SchedulerCfg
+
+func LoadScheduler() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	scheduler = SchedulerCfg{
+		Enabled:                        viper.GetBool("scheduler.enabled"),
+		PaymentReconciliationAt:        viper.GetString("scheduler.payment_reconciliation_at"),
+		PaymentReconciliationWindowHrs: viper.GetInt("scheduler.payment_reconciliation_window_hours"),
+		PayoutSchedule:                 viper.GetString("scheduler.payout_schedule"),
+		PayoutAt:                       viper.GetString("scheduler.payout_at"),
+		PayoutWeekday:                  viper.GetString("scheduler.payout_weekday"),
+		PayoutDayOfMonth:               viper.GetInt("scheduler.payout_day_of_month"),
+		SellerStatementsAt:             viper.GetString("scheduler.seller_statements_at"),
+		CommissionInvoicesAt:           viper.GetString("scheduler.commission_invoices_at"),
+		ProductPublishingIntervalMins:  viper.GetInt("scheduler.product_publishing_interval_minutes"),
+		ProductRecommendationsAt:       viper.GetString("scheduler.product_recommendations_at"),
+		ProductAlertsIntervalMins:      viper.GetInt("scheduler.product_alerts_interval_minutes"),
+		LicenseKeyPoolsIntervalMins:    viper.GetInt("scheduler.license_key_pools_interval_minutes"),
+	}
+}
+
+func Scheduler() SchedulerCfg {
+	return scheduler
+}
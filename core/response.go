@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -52,6 +53,16 @@ func (r *Response) ServeStreamFromMinioAsDownload(ctx echo.Context, object *mini
 	return nil
 }
 
+// ServeAsDownload sends a file generated on the fly as an attachment
+func (r *Response) ServeAsDownload(ctx echo.Context, fileName, contentType string, body []byte) error {
+	ctx.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	ctx.Response().Header().Set("X-Platform", "Shopicano")
+	ctx.Response().Header().Set("X-Platform-Developer", "www.codersgarage.com")
+	ctx.Response().Header().Set("X-Platform-Connect", "www.shopicano.com")
+
+	return ctx.Blob(http.StatusOK, contentType, body)
+}
+
 func (r *Response) ServeStreamFromMinio(ctx echo.Context, object *minio.Object) error {
 	s, _ := object.Stat()
 
@@ -15,7 +15,11 @@ type CategoryRepository interface {
 	Delete(db *gorm.DB, storeID, categoryID string) error
 	Get(db *gorm.DB, categoryID string) (*models.Category, error)
 	GetAsStoreOwner(db *gorm.DB, storeID, categoryID string) (*models.Category, error)
+	GetByNameAsStoreOwner(db *gorm.DB, storeID, name string) (*models.Category, error)
 	Update(db *gorm.DB, c *models.Category) error
 	Stats(db *gorm.DB, from, limit int) ([]helpers.CategoryStats, error)
 	StatsAsStoreStuff(db *gorm.DB, storeID string, from, limit int) ([]helpers.CategoryStats, error)
+	CountChildren(db *gorm.DB, categoryID string) (int, error)
+	MoveSubtree(db *gorm.DB, oldPath, newPath string, depthDelta int) error
+	SubtreeHeight(db *gorm.DB, c *models.Category) (int, error)
 }
@@ -32,9 +32,9 @@ func (cu *CategoryRepositoryImpl) List(db *gorm.DB, from, limit int) ([]models.R
 	var cols []models.ResCategorySearch
 	col := models.Category{}
 	if err := db.Table(fmt.Sprintf("%s AS c", col.TableName())).
-		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id").
+		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id, c.parent_id, c.depth, c.taxonomy_id").
 		Joins("LEFT JOIN products AS p ON p.category_id = c.id").
-		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id").
+		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id, c.parent_id, c.depth, c.taxonomy_id").
 		Where("c.is_published = ?", true).
 		Offset(from).Limit(limit).
 		Order("c.updated_at DESC").Find(&cols).Error; err != nil {
@@ -47,9 +47,9 @@ func (cu *CategoryRepositoryImpl) ListAsStoreStuff(db *gorm.DB, storeID string,
 	var cols []models.ResCategorySearchInternal
 	col := models.Category{}
 	if err := db.Table(fmt.Sprintf("%s AS c", col.TableName())).
-		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id, c.created_at, c.is_published, c.updated_at").
+		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id, c.parent_id, c.path, c.depth, c.taxonomy_id, c.created_at, c.is_published, c.updated_at").
 		Joins("LEFT JOIN products AS p ON p.category_id = c.id").
-		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id, c.is_published, c.created_at").
+		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id, c.parent_id, c.path, c.depth, c.taxonomy_id, c.is_published, c.created_at").
 		Where("c.store_id = ?", storeID).
 		Offset(from).Limit(limit).
 		Order("c.updated_at DESC").Find(&cols).Error; err != nil {
@@ -62,9 +62,9 @@ func (cu *CategoryRepositoryImpl) Search(db *gorm.DB, query string, from, limit
 	var cols []models.ResCategorySearch
 	col := models.Category{}
 	if err := db.Table(fmt.Sprintf("%s AS c", col.TableName())).
-		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id").
+		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id, c.parent_id, c.depth, c.taxonomy_id").
 		Joins("LEFT JOIN products AS p ON p.category_id = c.id").
-		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id").
+		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id, c.parent_id, c.depth, c.taxonomy_id").
 		Where("c.is_published = ? AND LOWER(c.name) LIKE ?", true, "%"+strings.ToLower(query)+"%").
 		Offset(from).Limit(limit).
 		Order("c.updated_at DESC").Find(&cols).Error; err != nil {
@@ -77,9 +77,9 @@ func (cu *CategoryRepositoryImpl) SearchAsStoreStuff(db *gorm.DB, storeID, query
 	var cols []models.ResCategorySearchInternal
 	col := models.Category{}
 	if err := db.Table(fmt.Sprintf("%s AS c", col.TableName())).
-		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id, c.created_at, c.is_published, c.updated_at").
+		Select("COUNT(p.id) AS count, c.id, c.name, c.description, c.image, c.store_id, c.parent_id, c.path, c.depth, c.taxonomy_id, c.created_at, c.is_published, c.updated_at").
 		Joins("LEFT JOIN products AS p ON p.category_id = c.id").
-		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id, c.is_published, c.created_at").
+		Group("c.name, c.description, c.image, c.id, c.updated_at, c.store_id, c.parent_id, c.path, c.depth, c.taxonomy_id, c.is_published, c.created_at").
 		Where("c.store_id = ? AND LOWER(c.name) LIKE ?", storeID, "%"+strings.ToLower(query)+"%").
 		Offset(from).Limit(limit).
 		Order("c.updated_at DESC").Find(&cols).Error; err != nil {
@@ -118,16 +118,31 @@ func (cu *CategoryRepositoryImpl) GetAsStoreOwner(db *gorm.DB, storeID, category
 	return &col, nil
 }
 
+// GetByNameAsStoreOwner finds the category of the store by its name, ignoring the case
+func (cu *CategoryRepositoryImpl) GetByNameAsStoreOwner(db *gorm.DB, storeID, name string) (*models.Category, error) {
+	col := models.Category{}
+	if err := db.Table(col.TableName()).
+		Where("store_id = ? AND LOWER(name) = LOWER(?)", storeID, name).
+		First(&col).Error; err != nil {
+		return nil, err
+	}
+	return &col, nil
+}
+
 func (cu *CategoryRepositoryImpl) Update(db *gorm.DB, c *models.Category) error {
 	col := models.Category{}
 	if err := db.Table(col.TableName()).
 		Where("store_id = ? AND id = ?", c.StoreID, c.ID).
-		Select("name", "description", "image", "is_published", "updated_at").
+		Select("name", "description", "image", "is_published", "parent_id", "path", "depth", "taxonomy_id", "updated_at").
 		Updates(map[string]interface{}{
 			"name":         c.Name,
 			"description":  c.Description,
 			"is_published": c.IsPublished,
 			"image":        c.Image,
+			"parent_id":    c.ParentID,
+			"path":         c.Path,
+			"depth":        c.Depth,
+			"taxonomy_id":  c.TaxonomyID,
 			"updated_at":   c.UpdatedAt,
 		}).Error; err != nil {
 		return err
@@ -135,6 +150,7 @@ func (cu *CategoryRepositoryImpl) Update(db *gorm.DB, c *models.Category) error
 	return nil
 }
 
+// Stats counts the products of every category, including the products of its descendants
 func (cu *CategoryRepositoryImpl) Stats(db *gorm.DB, from, limit int) ([]helpers.CategoryStats, error) {
 	var stats []helpers.CategoryStats
 
@@ -142,9 +158,10 @@ func (cu *CategoryRepositoryImpl) Stats(db *gorm.DB, from, limit int) ([]helpers
 	p := models.Product{}
 
 	if err := db.Table(fmt.Sprintf("%s AS c", c.TableName())).
-		Select("c.id AS id, c.name AS name, c.image AS image, c.description AS description, COALESCE(COUNT(p.id), 0) AS count").
-		Joins(fmt.Sprintf("LEFT JOIN %s AS p ON c.id = p.category_id", p.TableName())).
-		Group("c.id, c.name, c.image, c.description").
+		Select("c.id AS id, c.name AS name, c.image AS image, c.description AS description, c.parent_id AS parent_id, c.depth AS depth, COALESCE(COUNT(p.id), 0) AS count").
+		Joins(fmt.Sprintf("LEFT JOIN %s AS d ON d.path LIKE c.path || '%%'", c.TableName())).
+		Joins(fmt.Sprintf("LEFT JOIN %s AS p ON d.id = p.category_id", p.TableName())).
+		Group("c.id, c.name, c.image, c.description, c.parent_id, c.depth").
 		Order("count DESC").
 		Offset(from).
 		Limit(limit).
@@ -159,21 +176,25 @@ func (cu *CategoryRepositoryImpl) Stats(db *gorm.DB, from, limit int) ([]helpers
 	return stats, nil
 }
 
+// StatsAsStoreStuff sums the quantities sold per category of the store, including the sales of its descendants
 func (cu *CategoryRepositoryImpl) StatsAsStoreStuff(db *gorm.DB, storeID string, from, limit int) ([]helpers.CategoryStats, error) {
 	var stats []helpers.CategoryStats
 
+	c := models.Category{}
 	p := models.Product{}
 	oi := models.OrderedItem{}
 
-	if err := db.Table(fmt.Sprintf("%s AS p", p.TableName())).
-		Select("c.id AS id, c.name AS name, c.image AS image, c.description AS description, COALESCE(SUM(oi.quantity), 0) AS count").
-		Joins(fmt.Sprintf("LEFT JOIN %s AS p ON c.id = p.category_id", p.TableName())).
+	if err := db.Table(fmt.Sprintf("%s AS c", c.TableName())).
+		Select("c.id AS id, c.name AS name, c.image AS image, c.description AS description, c.parent_id AS parent_id, c.depth AS depth, COALESCE(SUM(oi.quantity), 0) AS count").
+		Joins(fmt.Sprintf("LEFT JOIN %s AS d ON d.path LIKE c.path || '%%'", c.TableName())).
+		Joins(fmt.Sprintf("LEFT JOIN %s AS p ON d.id = p.category_id", p.TableName())).
 		Joins(fmt.Sprintf("LEFT JOIN %s AS oi ON oi.product_id = p.id", oi.TableName())).
-		Group("c.id, c.name, c.image, c.description").
+		Where("c.store_id = ?", storeID).
+		Group("c.id, c.name, c.image, c.description, c.parent_id, c.depth").
 		Order("count DESC").
 		Offset(from).
 		Limit(limit).
-		Find(&stats, "p.store_id = ?", storeID).Error; err != nil {
+		Find(&stats).Error; err != nil {
 		return nil, err
 	}
 
@@ -183,3 +204,17 @@ func (cu *CategoryRepositoryImpl) StatsAsStoreStuff(db *gorm.DB, storeID string,
 
 	return stats, nil
 }
+
+func (cu *CategoryRepositoryImpl) CountChildren(db *gorm.DB, categoryID string) (int, error) {
+	c := models.Category{}
+	return countChildren(db, c.TableName(), categoryID)
+}
+
+func (cu *CategoryRepositoryImpl) MoveSubtree(db *gorm.DB, oldPath, newPath string, depthDelta int) error {
+	c := models.Category{}
+	return moveSubtree(db, c.TableName(), oldPath, newPath, depthDelta)
+}
+
+func (cu *CategoryRepositoryImpl) SubtreeHeight(db *gorm.DB, c *models.Category) (int, error) {
+	return subtreeHeight(db, c.TableName(), c.Path, c.Depth)
+}
@@ -0,0 +1,15 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type CommissionInvoiceRepository interface {
+	NextNumber(db *gorm.DB, sequence string) (int64, error)
+	Create(db *gorm.DB, ci *models.CommissionInvoice) error
+	List(db *gorm.DB, storeID string, from, limit int) ([]models.CommissionInvoice, error)
+	Get(db *gorm.DB, storeID, invoiceID string) (*models.CommissionInvoice, error)
+	Exists(db *gorm.DB, storeID string, periodStart time.Time) (bool, error)
+}
@@ -0,0 +1,78 @@
+package data
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type CommissionInvoiceRepositoryImpl struct {
+}
+
+var commissionInvoiceRepository CommissionInvoiceRepository
+
+func NewCommissionInvoiceRepository() CommissionInvoiceRepository {
+	if commissionInvoiceRepository == nil {
+		commissionInvoiceRepository = &CommissionInvoiceRepositoryImpl{}
+	}
+	return commissionInvoiceRepository
+}
+
+// NextNumber increments the sequence and returns the new number. The row stays locked until the
+// transaction ends, so numbers are issued without gaps as long as it's called within the transaction
+// creating the invoice.
+func (cr *CommissionInvoiceRepositoryImpl) NextNumber(db *gorm.DB, sequence string) (int64, error) {
+	is := models.InvoiceSequence{}
+
+	var number int64
+	if err := db.Raw(fmt.Sprintf("INSERT INTO %s AS s (name, last_number, updated_at) VALUES (?, 1, ?) "+
+		"ON CONFLICT (name) DO UPDATE SET last_number = s.last_number + 1, updated_at = EXCLUDED.updated_at "+
+		"RETURNING last_number", is.TableName()), sequence, time.Now().UTC()).
+		Row().Scan(&number); err != nil {
+		return 0, err
+	}
+	return number, nil
+}
+
+func (cr *CommissionInvoiceRepositoryImpl) Create(db *gorm.DB, ci *models.CommissionInvoice) error {
+	if err := db.Table(ci.TableName()).Create(ci).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (cr *CommissionInvoiceRepositoryImpl) List(db *gorm.DB, storeID string, from, limit int) ([]models.CommissionInvoice, error) {
+	ci := models.CommissionInvoice{}
+	var invoices []models.CommissionInvoice
+	if err := db.Table(ci.TableName()).
+		Where("store_id = ?", storeID).
+		Order("period_start DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+func (cr *CommissionInvoiceRepositoryImpl) Get(db *gorm.DB, storeID, invoiceID string) (*models.CommissionInvoice, error) {
+	ci := models.CommissionInvoice{}
+	if err := db.Table(ci.TableName()).
+		Where("store_id = ? AND id = ?", storeID, invoiceID).
+		First(&ci).Error; err != nil {
+		return nil, err
+	}
+	return &ci, nil
+}
+
+func (cr *CommissionInvoiceRepositoryImpl) Exists(db *gorm.DB, storeID string, periodStart time.Time) (bool, error) {
+	ci := models.CommissionInvoice{}
+	count := 0
+	if err := db.Table(ci.TableName()).
+		Where("store_id = ? AND period_start = ?", storeID, periodStart).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
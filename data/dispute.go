@@ -0,0 +1,20 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type DisputeRepository interface {
+	Create(db *gorm.DB, d *models.Dispute) error
+	Update(db *gorm.DB, d *models.Dispute) error
+	Get(db *gorm.DB, disputeID string) (*models.Dispute, error)
+	GetAsStoreStuff(db *gorm.DB, storeID, disputeID string) (*models.Dispute, error)
+	GetByGatewayDisputeID(db *gorm.DB, paymentGateway, gatewayDisputeID string) (*models.Dispute, error)
+	HasOpenDispute(db *gorm.DB, orderID string) (bool, error)
+	GetOpenByOrderID(db *gorm.DB, orderID string) (*models.Dispute, error)
+	List(db *gorm.DB, storeID string, status models.DisputeStatus, from, limit int) ([]models.Dispute, error)
+	CreateEvidence(db *gorm.DB, e *models.DisputeEvidence) error
+	ListEvidences(db *gorm.DB, disputeID string) ([]models.DisputeEvidence, error)
+	GetEvidence(db *gorm.DB, disputeID, evidenceID string) (*models.DisputeEvidence, error)
+}
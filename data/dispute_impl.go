@@ -0,0 +1,150 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type DisputeRepositoryImpl struct {
+}
+
+var disputeRepository DisputeRepository
+
+func NewDisputeRepository() DisputeRepository {
+	if disputeRepository == nil {
+		disputeRepository = &DisputeRepositoryImpl{}
+	}
+	return disputeRepository
+}
+
+func (dr *DisputeRepositoryImpl) Create(db *gorm.DB, d *models.Dispute) error {
+	if err := db.Table(d.TableName()).Create(d).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DisputeRepositoryImpl) Update(db *gorm.DB, d *models.Dispute) error {
+	if err := db.Table(d.TableName()).
+		Where("id = ?", d.ID).
+		Select("gateway_dispute_id, amount, fee, reason, status, evidence_due_by, note, resolved_at, updated_at").
+		Updates(map[string]interface{}{
+			"gateway_dispute_id": d.GatewayDisputeID,
+			"amount":             d.Amount,
+			"fee":                d.Fee,
+			"reason":             d.Reason,
+			"status":             d.Status,
+			"evidence_due_by":    d.EvidenceDueBy,
+			"note":               d.Note,
+			"resolved_at":        d.ResolvedAt,
+			"updated_at":         d.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DisputeRepositoryImpl) Get(db *gorm.DB, disputeID string) (*models.Dispute, error) {
+	d := models.Dispute{}
+	if err := db.Table(d.TableName()).
+		Where("id = ?", disputeID).
+		First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (dr *DisputeRepositoryImpl) GetAsStoreStuff(db *gorm.DB, storeID, disputeID string) (*models.Dispute, error) {
+	d := models.Dispute{}
+	if err := db.Table(d.TableName()).
+		Where("store_id = ? AND id = ?", storeID, disputeID).
+		First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (dr *DisputeRepositoryImpl) GetByGatewayDisputeID(db *gorm.DB, paymentGateway, gatewayDisputeID string) (*models.Dispute, error) {
+	d := models.Dispute{}
+	if err := db.Table(d.TableName()).
+		Where("payment_gateway = ? AND gateway_dispute_id = ?", paymentGateway, gatewayDisputeID).
+		First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (dr *DisputeRepositoryImpl) HasOpenDispute(db *gorm.DB, orderID string) (bool, error) {
+	d := models.Dispute{}
+	count := 0
+	if err := db.Table(d.TableName()).
+		Where("order_id = ? AND status IN (?)", orderID, models.OpenDisputeStatuses()).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetOpenByOrderID returns the latest open dispute of the order
+func (dr *DisputeRepositoryImpl) GetOpenByOrderID(db *gorm.DB, orderID string) (*models.Dispute, error) {
+	d := models.Dispute{}
+	if err := db.Table(d.TableName()).
+		Where("order_id = ? AND status IN (?)", orderID, models.OpenDisputeStatuses()).
+		Order("created_at DESC").
+		First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// List returns the disputes of the store, or of every store when the store is empty,
+// optionally filtered by status, latest first
+func (dr *DisputeRepositoryImpl) List(db *gorm.DB, storeID string, status models.DisputeStatus, from, limit int) ([]models.Dispute, error) {
+	d := models.Dispute{}
+
+	q := db.Table(d.TableName())
+	if storeID != "" {
+		q = q.Where("store_id = ?", storeID)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	var disputes []models.Dispute
+	if err := q.Order("created_at DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&disputes).Error; err != nil {
+		return nil, err
+	}
+	return disputes, nil
+}
+
+func (dr *DisputeRepositoryImpl) CreateEvidence(db *gorm.DB, e *models.DisputeEvidence) error {
+	if err := db.Table(e.TableName()).Create(e).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DisputeRepositoryImpl) ListEvidences(db *gorm.DB, disputeID string) ([]models.DisputeEvidence, error) {
+	e := models.DisputeEvidence{}
+	var evidences []models.DisputeEvidence
+	if err := db.Table(e.TableName()).
+		Where("dispute_id = ?", disputeID).
+		Order("created_at ASC").
+		Find(&evidences).Error; err != nil {
+		return nil, err
+	}
+	return evidences, nil
+}
+
+func (dr *DisputeRepositoryImpl) GetEvidence(db *gorm.DB, disputeID, evidenceID string) (*models.DisputeEvidence, error) {
+	e := models.DisputeEvidence{}
+	if err := db.Table(e.TableName()).
+		Where("dispute_id = ? AND id = ?", disputeID, evidenceID).
+		First(&e).Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,25 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type LedgerRepository interface {
+	GetOrCreateAccount(db *gorm.DB, a *models.LedgerAccount) (*models.LedgerAccount, error)
+	LockReference(db *gorm.DB, referenceType models.JournalReferenceType, referenceID string) error
+	LockStore(db *gorm.DB, storeID string) error
+	GetAccountBalance(db *gorm.DB, code string) (*models.LedgerAccountBalance, error)
+	ListAccountBalances(db *gorm.DB, from, limit int) ([]models.LedgerAccountBalance, error)
+
+	CreateEntry(db *gorm.DB, e *models.JournalEntry, lines []models.JournalLine) error
+	ListEntries(db *gorm.DB, referenceType models.JournalReferenceType, referenceID string) ([]models.JournalEntryDetails, error)
+	ListReferenceBalances(db *gorm.DB, referenceType models.JournalReferenceType, referenceID string) ([]models.LedgerAccountBalance, error)
+	ListStatement(db *gorm.DB, code string, start, end time.Time, from, limit int) ([]models.StatementLine, error)
+	GetStoreSummary(db *gorm.DB, storeID string) (*models.StoreLedgerSummary, error)
+	GetStoreHeldEarnings(db *gorm.DB, storeID string, paidAfter time.Time) (int64, error)
+	GetStoreFrozenEarnings(db *gorm.DB, storeID string) (int64, error)
+	GetStoreBalanceAt(db *gorm.DB, storeID string, at time.Time) (int64, error)
+	ListStoreMovements(db *gorm.DB, storeID string, start, end time.Time) ([]models.StoreLedgerMovement, error)
+}
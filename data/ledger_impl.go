@@ -0,0 +1,329 @@
+package data
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type LedgerRepositoryImpl struct {
+}
+
+var ledgerRepository LedgerRepository
+
+func NewLedgerRepository() LedgerRepository {
+	if ledgerRepository == nil {
+		ledgerRepository = &LedgerRepositoryImpl{}
+	}
+	return ledgerRepository
+}
+
+// GetOrCreateAccount returns the account with the code of the given one, creating it first when missing.
+// Concurrent postings creating the same account end up with the one created first.
+func (lr *LedgerRepositoryImpl) GetOrCreateAccount(db *gorm.DB, a *models.LedgerAccount) (*models.LedgerAccount, error) {
+	if err := db.Exec(fmt.Sprintf("INSERT INTO %s (id, code, name, type, store_id, created_at) "+
+		"VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (code) DO NOTHING", a.TableName()),
+		a.ID, a.Code, a.Name, a.Type, a.StoreID, a.CreatedAt).Error; err != nil {
+		return nil, err
+	}
+
+	m := models.LedgerAccount{}
+	if err := db.Table(m.TableName()).Find(&m, "code = ?", a.Code).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// LockReference holds a lock on the reference until the transaction ends, so the postings of the
+// reference are computed one at a time
+func (lr *LedgerRepositoryImpl) LockReference(db *gorm.DB, referenceType models.JournalReferenceType, referenceID string) error {
+	if err := db.Exec("SELECT pg_advisory_xact_lock(hashtext(?))",
+		fmt.Sprintf("%s:%s", referenceType, referenceID)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// LockStore holds a lock on the balance of the store until the transaction ends, so the payouts of the
+// store are checked against its balance one at a time
+func (lr *LedgerRepositoryImpl) LockStore(db *gorm.DB, storeID string) error {
+	if err := db.Exec("SELECT pg_advisory_xact_lock(hashtext(?))",
+		fmt.Sprintf("store:%s", storeID)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lr *LedgerRepositoryImpl) accountBalances(db *gorm.DB) *gorm.DB {
+	la := models.LedgerAccount{}
+	jl := models.JournalLine{}
+
+	return db.Table(fmt.Sprintf("%s AS la", la.TableName())).
+		Select("la.id AS id, la.code AS code, la.name AS name, la.type AS type, la.store_id AS store_id, " +
+			"COALESCE(SUM(jl.debit), 0) AS total_debit, COALESCE(SUM(jl.credit), 0) AS total_credit").
+		Joins(fmt.Sprintf("LEFT JOIN %s AS jl ON la.id = jl.account_id", jl.TableName())).
+		Group("la.id")
+}
+
+func (lr *LedgerRepositoryImpl) GetAccountBalance(db *gorm.DB, code string) (*models.LedgerAccountBalance, error) {
+	var balances []models.LedgerAccountBalance
+	if err := lr.accountBalances(db).
+		Where("la.code = ?", code).
+		Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+	if len(balances) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	b := &balances[0]
+	setLedgerBalance(b)
+	return b, nil
+}
+
+func (lr *LedgerRepositoryImpl) ListAccountBalances(db *gorm.DB, from, limit int) ([]models.LedgerAccountBalance, error) {
+	var balances []models.LedgerAccountBalance
+	if err := lr.accountBalances(db).
+		Order("la.code ASC").
+		Offset(from).
+		Limit(limit).
+		Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range balances {
+		setLedgerBalance(&balances[i])
+	}
+	return balances, nil
+}
+
+func setLedgerBalance(b *models.LedgerAccountBalance) {
+	if b.Type == models.LedgerAccountAsset {
+		b.Balance = b.TotalDebit - b.TotalCredit
+		return
+	}
+	b.Balance = b.TotalCredit - b.TotalDebit
+}
+
+func (lr *LedgerRepositoryImpl) CreateEntry(db *gorm.DB, e *models.JournalEntry, lines []models.JournalLine) error {
+	var debit, credit int64
+	for _, l := range lines {
+		debit += l.Debit
+		credit += l.Credit
+	}
+	if debit != credit {
+		return fmt.Errorf("unbalanced journal entry : debit %d, credit %d", debit, credit)
+	}
+
+	if err := db.Table(e.TableName()).Create(e).Error; err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		l.JournalEntryID = e.ID
+		if err := db.Table(l.TableName()).Create(&l).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lr *LedgerRepositoryImpl) ListEntries(db *gorm.DB, referenceType models.JournalReferenceType, referenceID string) ([]models.JournalEntryDetails, error) {
+	je := models.JournalEntry{}
+	var entries []models.JournalEntry
+	if err := db.Table(je.TableName()).
+		Where("reference_type = ? AND reference_id = ?", referenceType, referenceID).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	var details []models.JournalEntryDetails
+	for _, e := range entries {
+		jl := models.JournalLine{}
+		var lines []models.JournalLine
+		if err := db.Table(jl.TableName()).
+			Where("journal_entry_id = ?", e.ID).
+			Find(&lines).Error; err != nil {
+			return nil, err
+		}
+
+		details = append(details, models.JournalEntryDetails{
+			JournalEntry: e,
+			Lines:        lines,
+		})
+	}
+	return details, nil
+}
+
+func (lr *LedgerRepositoryImpl) ListReferenceBalances(db *gorm.DB, referenceType models.JournalReferenceType, referenceID string) ([]models.LedgerAccountBalance, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+
+	var balances []models.LedgerAccountBalance
+	if err := db.Table(fmt.Sprintf("%s AS jl", jl.TableName())).
+		Select("la.id AS id, la.code AS code, la.name AS name, la.type AS type, la.store_id AS store_id, "+
+			"SUM(jl.debit) AS total_debit, SUM(jl.credit) AS total_credit").
+		Joins(fmt.Sprintf("JOIN %s AS je ON jl.journal_entry_id = je.id", je.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS la ON jl.account_id = la.id", la.TableName())).
+		Where("je.reference_type = ? AND je.reference_id = ?", referenceType, referenceID).
+		Group("la.id").
+		Scan(&balances).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range balances {
+		setLedgerBalance(&balances[i])
+	}
+	return balances, nil
+}
+
+func (lr *LedgerRepositoryImpl) ListStatement(db *gorm.DB, code string, start, end time.Time, from, limit int) ([]models.StatementLine, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+
+	// The running balance covers every line of the account, before the period is filtered
+	sql := fmt.Sprintf("SELECT * FROM (SELECT je.id AS journal_entry_id, je.reference_type AS reference_type, "+
+		"je.reference_id AS reference_id, je.event AS event, je.description AS description, jl.debit AS debit, "+
+		"jl.credit AS credit, je.created_at AS created_at, "+
+		"SUM(CASE WHEN la.type = ? THEN jl.debit - jl.credit ELSE jl.credit - jl.debit END) "+
+		"OVER (ORDER BY je.created_at, je.id) AS balance "+
+		"FROM %s AS jl JOIN %s AS je ON jl.journal_entry_id = je.id JOIN %s AS la ON jl.account_id = la.id "+
+		"WHERE la.code = ?) AS st WHERE st.created_at >= ? AND st.created_at <= ? "+
+		"ORDER BY st.created_at DESC, st.journal_entry_id DESC OFFSET ? LIMIT ?",
+		jl.TableName(), je.TableName(), la.TableName())
+
+	var statement []models.StatementLine
+	if err := db.Raw(sql, models.LedgerAccountAsset, code, start, end, from, limit).
+		Scan(&statement).Error; err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+func (lr *LedgerRepositoryImpl) GetStoreSummary(db *gorm.DB, storeID string) (*models.StoreLedgerSummary, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+
+	storeAccount := models.StoreLedgerAccountCode(storeID)
+
+	summary := models.StoreLedgerSummary{}
+	if err := db.Table(fmt.Sprintf("%s AS jl", jl.TableName())).
+		Select("COALESCE(SUM(jl.credit - jl.debit) FILTER (WHERE la.code = ? AND je.reference_type = ?), 0) AS total_earnings, "+
+			"COALESCE(SUM(jl.credit - jl.debit) FILTER (WHERE la.code = ?), 0) AS total_commission, "+
+			"COALESCE(SUM(jl.debit - jl.credit) FILTER (WHERE la.code = ? AND je.reference_type = ?), 0) AS total_requested, "+
+			"COALESCE(SUM(jl.credit - jl.debit) FILTER (WHERE la.code = ?), 0) AS total_paid, "+
+			"COALESCE(SUM(jl.credit - jl.debit) FILTER (WHERE la.code = ?), 0) AS total_available",
+			storeAccount, models.JournalReferenceOrder,
+			models.LedgerAccountPlatformCommission,
+			storeAccount, models.JournalReferencePayout,
+			models.LedgerAccountPayoutsSent,
+			storeAccount).
+		Joins(fmt.Sprintf("JOIN %s AS je ON jl.journal_entry_id = je.id", je.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS la ON jl.account_id = la.id", la.TableName())).
+		Where("je.store_id = ?", storeID).
+		Scan(&summary).Error; err != nil {
+		return nil, err
+	}
+
+	summary.StoreID = storeID
+	summary.TotalIncome = summary.TotalEarnings + summary.TotalCommission
+	return &summary, nil
+}
+
+// GetStoreHeldEarnings returns the earnings of the store from orders that are not delivered yet
+// and were paid after the given time. Orders with an open dispute are left out as they are frozen.
+// Orders paid before the time of payment was kept on them fall back to their payment posting.
+func (lr *LedgerRepositoryImpl) GetStoreHeldEarnings(db *gorm.DB, storeID string, paidAfter time.Time) (int64, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+	o := models.Order{}
+	d := models.Dispute{}
+
+	sql := fmt.Sprintf("SELECT COALESCE(SUM(r.net), 0) AS held FROM (SELECT je.reference_id AS order_id, "+
+		"SUM(jl.credit - jl.debit) AS net, MAX(je.created_at) FILTER (WHERE je.event = ?) AS paid_at "+
+		"FROM %s AS jl JOIN %s AS je ON jl.journal_entry_id = je.id JOIN %s AS la ON jl.account_id = la.id "+
+		"WHERE la.code = ? AND je.reference_type = ? GROUP BY je.reference_id) AS r "+
+		"JOIN %s AS o ON r.order_id = o.id WHERE r.net > 0 AND COALESCE(o.paid_at, r.paid_at) > ? AND o.status != ? "+
+		"AND r.order_id NOT IN (SELECT order_id FROM %s WHERE status IN (?))",
+		jl.TableName(), je.TableName(), la.TableName(), o.TableName(), d.TableName())
+
+	var held int64
+	if err := db.Raw(sql, models.PaymentCompleted, models.StoreLedgerAccountCode(storeID), models.JournalReferenceOrder,
+		paidAfter, models.OrderDelivered, models.OpenDisputeStatuses()).
+		Row().Scan(&held); err != nil {
+		return 0, err
+	}
+	return held, nil
+}
+
+// GetStoreFrozenEarnings returns the earnings of the store from orders with an open dispute
+func (lr *LedgerRepositoryImpl) GetStoreFrozenEarnings(db *gorm.DB, storeID string) (int64, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+	d := models.Dispute{}
+
+	sql := fmt.Sprintf("SELECT COALESCE(SUM(r.net), 0) AS frozen FROM (SELECT je.reference_id AS order_id, "+
+		"SUM(jl.credit - jl.debit) AS net "+
+		"FROM %s AS jl JOIN %s AS je ON jl.journal_entry_id = je.id JOIN %s AS la ON jl.account_id = la.id "+
+		"WHERE la.code = ? AND je.reference_type = ? GROUP BY je.reference_id) AS r "+
+		"WHERE r.net > 0 AND r.order_id IN (SELECT order_id FROM %s WHERE store_id = ? AND status IN (?))",
+		jl.TableName(), je.TableName(), la.TableName(), d.TableName())
+
+	var frozen int64
+	if err := db.Raw(sql, models.StoreLedgerAccountCode(storeID), models.JournalReferenceOrder,
+		storeID, models.OpenDisputeStatuses()).
+		Row().Scan(&frozen); err != nil {
+		return 0, err
+	}
+	return frozen, nil
+}
+
+// GetStoreBalanceAt returns the balance owed to the store from the journal entries posted before the given time
+func (lr *LedgerRepositoryImpl) GetStoreBalanceAt(db *gorm.DB, storeID string, at time.Time) (int64, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+
+	var balance int64
+	if err := db.Table(fmt.Sprintf("%s AS jl", jl.TableName())).
+		Select("COALESCE(SUM(jl.credit - jl.debit), 0)").
+		Joins(fmt.Sprintf("JOIN %s AS je ON jl.journal_entry_id = je.id", je.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS la ON jl.account_id = la.id", la.TableName())).
+		Where("la.code = ? AND je.created_at < ?", models.StoreLedgerAccountCode(storeID), at).
+		Row().Scan(&balance); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// ListStoreMovements returns the journal entries of the store posted between start (inclusive) and end (exclusive)
+// with their effect on the store account and on the platform commission, oldest first
+func (lr *LedgerRepositoryImpl) ListStoreMovements(db *gorm.DB, storeID string, start, end time.Time) ([]models.StoreLedgerMovement, error) {
+	jl := models.JournalLine{}
+	je := models.JournalEntry{}
+	la := models.LedgerAccount{}
+
+	var movements []models.StoreLedgerMovement
+	if err := db.Table(fmt.Sprintf("%s AS jl", jl.TableName())).
+		Select("je.id AS journal_entry_id, je.reference_type AS reference_type, je.reference_id AS reference_id, "+
+			"je.event AS event, je.description AS description, je.created_at AS created_at, "+
+			"COALESCE(SUM(jl.credit - jl.debit) FILTER (WHERE la.code = ?), 0) AS store_amount, "+
+			"COALESCE(SUM(jl.credit - jl.debit) FILTER (WHERE la.code = ?), 0) AS commission_amount",
+			models.StoreLedgerAccountCode(storeID), models.LedgerAccountPlatformCommission).
+		Joins(fmt.Sprintf("JOIN %s AS je ON jl.journal_entry_id = je.id", je.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS la ON jl.account_id = la.id", la.TableName())).
+		Where("je.store_id = ? AND je.created_at >= ? AND je.created_at < ?", storeID, start, end).
+		Group("je.id").
+		Order("je.created_at ASC, je.id ASC").
+		Scan(&movements).Error; err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
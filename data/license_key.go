@@ -0,0 +1,24 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type LicenseKeyRepository interface {
+	CreatePool(db *gorm.DB, p *models.LicenseKeyPool) error
+	UpdatePool(db *gorm.DB, p *models.LicenseKeyPool) error
+	SetPoolWarnedAt(db *gorm.DB, productID string, warnedAt *time.Time) error
+	GetPool(db *gorm.DB, storeID, productID string) (*models.LicenseKeyPool, error)
+	GetPoolDetails(db *gorm.DB, storeID, productID string) (*models.LicenseKeyPoolDetails, error)
+	ListLowPools(db *gorm.DB) ([]models.LicenseKeyPoolDetails, error)
+	AddKey(db *gorm.DB, k *models.LicenseKey) (bool, error)
+	DeleteKey(db *gorm.DB, productID, keyID string) error
+	ListKeys(db *gorm.DB, productID string, status *models.LicenseKeyStatus, from, limit int) ([]models.LicenseKey, error)
+	ListKeysByOrder(db *gorm.DB, orderID string) ([]models.LicenseKey, error)
+	ListPendingItems(db *gorm.DB, orderID, productID *string) ([]models.PendingLicenseKeyItem, error)
+	AllocateKeys(db *gorm.DB, item *models.PendingLicenseKeyItem, allocatedAt time.Time) (int, error)
+	RevokeKeys(db *gorm.DB, orderID string, revokedAt time.Time) error
+}
@@ -0,0 +1,239 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type LicenseKeyRepositoryImpl struct {
+}
+
+var licenseKeyRepository LicenseKeyRepository
+
+func NewLicenseKeyRepository() LicenseKeyRepository {
+	if licenseKeyRepository == nil {
+		licenseKeyRepository = &LicenseKeyRepositoryImpl{}
+	}
+	return licenseKeyRepository
+}
+
+func (lu *LicenseKeyRepositoryImpl) CreatePool(db *gorm.DB, p *models.LicenseKeyPool) error {
+	if err := db.Table(p.TableName()).Create(p).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lu *LicenseKeyRepositoryImpl) UpdatePool(db *gorm.DB, p *models.LicenseKeyPool) error {
+	if err := db.Table(p.TableName()).
+		Where("store_id = ? AND product_id = ?", p.StoreID, p.ProductID).
+		Select("low_threshold, warned_at, updated_at").
+		Updates(map[string]interface{}{
+			"low_threshold": p.LowThreshold,
+			"warned_at":     p.WarnedAt,
+			"updated_at":    p.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lu *LicenseKeyRepositoryImpl) SetPoolWarnedAt(db *gorm.DB, productID string, warnedAt *time.Time) error {
+	p := models.LicenseKeyPool{}
+	if err := db.Table(p.TableName()).
+		Where("product_id = ?", productID).
+		UpdateColumn("warned_at", warnedAt).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lu *LicenseKeyRepositoryImpl) GetPool(db *gorm.DB, storeID, productID string) (*models.LicenseKeyPool, error) {
+	p := models.LicenseKeyPool{}
+	if err := db.Table(p.TableName()).
+		Where("store_id = ? AND product_id = ?", storeID, productID).
+		First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// licenseKeyPoolDetails selects the pools with their product name and the number of keys in every status
+func licenseKeyPoolDetails(db *gorm.DB) *gorm.DB {
+	kp := models.LicenseKeyPool{}
+	lk := models.LicenseKey{}
+	p := models.Product{}
+
+	countKeys := func(status models.LicenseKeyStatus) string {
+		return fmt.Sprintf("(SELECT COUNT(*) FROM %s AS lk WHERE lk.product_id = kp.product_id AND lk.status = '%s')",
+			lk.TableName(), status)
+	}
+
+	return db.Table(fmt.Sprintf("%s AS kp", kp.TableName())).
+		Select(fmt.Sprintf("kp.product_id, kp.store_id, p.name AS product_name, kp.low_threshold, kp.warned_at, "+
+			"%s AS available, %s AS allocated, %s AS revoked",
+			countKeys(models.LicenseKeyAvailable), countKeys(models.LicenseKeyAllocated), countKeys(models.LicenseKeyRevoked))).
+		Joins(fmt.Sprintf("JOIN %s AS p ON p.id = kp.product_id", p.TableName()))
+}
+
+func (lu *LicenseKeyRepositoryImpl) GetPoolDetails(db *gorm.DB, storeID, productID string) (*models.LicenseKeyPoolDetails, error) {
+	var pools []models.LicenseKeyPoolDetails
+	if err := licenseKeyPoolDetails(db).
+		Where("kp.store_id = ? AND kp.product_id = ?", storeID, productID).
+		Scan(&pools).Error; err != nil {
+		return nil, err
+	}
+	if len(pools) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &pools[0], nil
+}
+
+// ListLowPools returns the pools at or below their low threshold the stores haven't been warned about yet
+func (lu *LicenseKeyRepositoryImpl) ListLowPools(db *gorm.DB) ([]models.LicenseKeyPoolDetails, error) {
+	lk := models.LicenseKey{}
+
+	var pools []models.LicenseKeyPoolDetails
+	if err := licenseKeyPoolDetails(db).
+		Where("kp.warned_at IS NULL").
+		Where(fmt.Sprintf("(SELECT COUNT(*) FROM %s AS lk WHERE lk.product_id = kp.product_id AND lk.status = ?) <= kp.low_threshold",
+			lk.TableName()), models.LicenseKeyAvailable).
+		Order("kp.store_id ASC, p.name ASC").
+		Scan(&pools).Error; err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// AddKey adds the key to the pool of its product and tells whether it was added, keys already in the pool are skipped
+func (lu *LicenseKeyRepositoryImpl) AddKey(db *gorm.DB, k *models.LicenseKey) (bool, error) {
+	q := db.Exec(fmt.Sprintf("INSERT INTO %s (id, store_id, product_id, key, status, created_at) "+
+		"VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (product_id, key) DO NOTHING", k.TableName()),
+		k.ID, k.StoreID, k.ProductID, k.Key, k.Status, k.CreatedAt)
+	if q.Error != nil {
+		return false, q.Error
+	}
+	return q.RowsAffected > 0, nil
+}
+
+// DeleteKey removes an available key from the pool, allocated and revoked keys are kept and reported as not found
+func (lu *LicenseKeyRepositoryImpl) DeleteKey(db *gorm.DB, productID, keyID string) error {
+	k := models.LicenseKey{}
+	q := db.Table(k.TableName()).
+		Where("product_id = ? AND id = ? AND status = ?", productID, keyID, models.LicenseKeyAvailable).
+		Delete(&k)
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (lu *LicenseKeyRepositoryImpl) ListKeys(db *gorm.DB, productID string, status *models.LicenseKeyStatus, from, limit int) ([]models.LicenseKey, error) {
+	k := models.LicenseKey{}
+
+	q := db.Table(k.TableName()).Where("product_id = ?", productID)
+	if status != nil {
+		q = q.Where("status = ?", *status)
+	}
+
+	var keys []models.LicenseKey
+	if err := q.Order("created_at DESC").
+		Offset(from).Limit(limit).
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ListKeysByOrder returns the keys allocated to the items of the order
+func (lu *LicenseKeyRepositoryImpl) ListKeysByOrder(db *gorm.DB, orderID string) ([]models.LicenseKey, error) {
+	k := models.LicenseKey{}
+
+	var keys []models.LicenseKey
+	if err := db.Table(k.TableName()).
+		Where("order_id = ? AND status = ?", orderID, models.LicenseKeyAllocated).
+		Order("allocated_at ASC, key ASC").
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// ListPendingItems returns the items of the paid orders with fewer allocated keys than units, oldest orders
+// first. The items are those of the order or of the product when given, orders placed before the pool of
+// the product was created are left out.
+func (lu *LicenseKeyRepositoryImpl) ListPendingItems(db *gorm.DB, orderID, productID *string) ([]models.PendingLicenseKeyItem, error) {
+	oi := models.OrderedItem{}
+	o := models.Order{}
+	kp := models.LicenseKeyPool{}
+	lk := models.LicenseKey{}
+
+	q := db.Table(fmt.Sprintf("%s AS oi", oi.TableName())).
+		Select("oi.id AS ordered_item_id, oi.order_id, oi.product_id, oi.quantity - COUNT(lk.id) AS missing").
+		Joins(fmt.Sprintf("JOIN %s AS o ON o.id = oi.order_id", o.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS kp ON kp.product_id = oi.product_id", kp.TableName())).
+		Joins(fmt.Sprintf("LEFT JOIN %s AS lk ON lk.ordered_item_id = oi.id AND lk.status = ?", lk.TableName()),
+			models.LicenseKeyAllocated).
+		Where("o.payment_status = ? AND o.created_at >= kp.created_at", models.PaymentCompleted)
+	if orderID != nil {
+		q = q.Where("oi.order_id = ?", *orderID)
+	}
+	if productID != nil {
+		q = q.Where("oi.product_id = ?", *productID)
+	}
+
+	var items []models.PendingLicenseKeyItem
+	if err := q.Group("oi.id, oi.order_id, oi.product_id, oi.quantity, o.created_at").
+		Having("oi.quantity > COUNT(lk.id)").
+		Order("o.created_at ASC").
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AllocateKeys gives the item available keys up to its units, the oldest keys first, and returns how many it got.
+// The item is locked and its keys counted again, so concurrent allocations for the same item wait for each
+// other rather than giving it more keys than units. Keys locked by another allocation are skipped.
+func (lu *LicenseKeyRepositoryImpl) AllocateKeys(db *gorm.DB, item *models.PendingLicenseKeyItem, allocatedAt time.Time) (int, error) {
+	k := models.LicenseKey{}
+	oi := models.OrderedItem{}
+
+	if err := db.Exec(fmt.Sprintf("SELECT id FROM %s WHERE id = ? FOR UPDATE", oi.TableName()),
+		item.OrderedItemID).Error; err != nil {
+		return 0, err
+	}
+
+	q := db.Exec(fmt.Sprintf("UPDATE %[1]s SET status = ?, order_id = ?, ordered_item_id = ?, allocated_at = ? "+
+		"WHERE id IN (SELECT id FROM %[1]s WHERE product_id = ? AND status = ? ORDER BY created_at ASC "+
+		"LIMIT GREATEST((SELECT quantity FROM %[2]s WHERE id = ?) - "+
+		"(SELECT COUNT(*) FROM %[1]s WHERE ordered_item_id = ? AND status = ?), 0) FOR UPDATE SKIP LOCKED)",
+		k.TableName(), oi.TableName()),
+		models.LicenseKeyAllocated, item.OrderID, item.OrderedItemID, allocatedAt,
+		item.ProductID, models.LicenseKeyAvailable,
+		item.OrderedItemID, item.OrderedItemID, models.LicenseKeyAllocated)
+	if q.Error != nil {
+		return 0, q.Error
+	}
+	return int(q.RowsAffected), nil
+}
+
+// RevokeKeys revokes the keys allocated to the order, they aren't given back to the pool
+func (lu *LicenseKeyRepositoryImpl) RevokeKeys(db *gorm.DB, orderID string, revokedAt time.Time) error {
+	k := models.LicenseKey{}
+	if err := db.Table(k.TableName()).
+		Where("order_id = ? AND status = ?", orderID, models.LicenseKeyAllocated).
+		UpdateColumns(map[string]interface{}{
+			"status":     models.LicenseKeyRevoked,
+			"revoked_at": revokedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
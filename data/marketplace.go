@@ -3,6 +3,7 @@ package data
 import (
 	"github.com/jinzhu/gorm"
 	"github.com/shopicano/shopicano-backend/models"
+	"time"
 )
 
 type MarketplaceRepository interface {
@@ -46,12 +47,34 @@ type MarketplaceRepository interface {
 	UpdatePayoutSettings(db *gorm.DB, m *models.PayoutSettings) error
 	GetPayoutSettings(db *gorm.DB, storeID string) (*models.PayoutSettings, error)
 	GetPayoutSettingsDetails(db *gorm.DB, storeID string) (*models.PayoutSettingsDetails, error)
+	ListPayoutSettingsOfActiveStores(db *gorm.DB) ([]models.PayoutSettings, error)
 
 	CreatePayoutEntry(db *gorm.DB, m *models.PayoutSend) error
 	ListPayoutEntries(db *gorm.DB, storeID string, from, limit int) ([]models.PayoutSend, error)
+	ListAllPayoutEntries(db *gorm.DB) ([]models.PayoutSend, error)
 	GetPayoutEntry(db *gorm.DB, storeID, entryID string) (*models.PayoutSend, error)
 	GetPayoutEntryDetails(db *gorm.DB, storeID, entryID string) (*models.PayoutSendDetails, error)
 	UpdatePayoutEntry(db *gorm.DB, ps *models.PayoutSend) error
+	GetPayoutEntryForUpdate(db *gorm.DB, storeID, entryID string) (*models.PayoutSend, error)
+	ListPayoutEntriesByIDsForUpdate(db *gorm.DB, entryIDs []string) ([]models.PayoutSend, error)
+	ListPayoutEntriesByBatch(db *gorm.DB, batchID string) ([]models.PayoutSend, error)
+	ListPayoutEntriesByBatchForUpdate(db *gorm.DB, batchID string) ([]models.PayoutSend, error)
+
+	CreatePayoutBatch(db *gorm.DB, b *models.PayoutBatch) error
+	UpdatePayoutBatch(db *gorm.DB, b *models.PayoutBatch) error
+	ListPayoutBatches(db *gorm.DB, from, limit int) ([]models.PayoutBatch, error)
+	GetPayoutBatch(db *gorm.DB, batchID string) (*models.PayoutBatch, error)
+
+	CreatePayoutEvent(db *gorm.DB, e *models.PayoutEvent) error
+	ListPayoutEvents(db *gorm.DB, storeID, payoutID string) ([]models.PayoutEvent, error)
+	GetPayoutEvent(db *gorm.DB, eventID string) (*models.PayoutEvent, error)
+
+	CreateCommissionRule(db *gorm.DB, cr *models.CommissionRule) error
+	UpdateCommissionRule(db *gorm.DB, cr *models.CommissionRule) error
+	DeleteCommissionRule(db *gorm.DB, ID string) error
+	ListCommissionRules(db *gorm.DB, from, limit int) ([]models.CommissionRule, error)
+	ListEffectiveCommissionRules(db *gorm.DB, at time.Time) ([]models.CommissionRule, error)
+	GetCommissionRule(db *gorm.DB, ID string) (*models.CommissionRule, error)
 
 	GetSettings(db *gorm.DB) (*models.Settings, error)
 	GetSettingsDetails(db *gorm.DB) (*models.SettingsDetails, error)
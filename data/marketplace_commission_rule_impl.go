@@ -0,0 +1,80 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+func (au *MarketplaceRepositoryImpl) CreateCommissionRule(db *gorm.DB, cr *models.CommissionRule) error {
+	if err := db.Table(cr.TableName()).Create(cr).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *MarketplaceRepositoryImpl) UpdateCommissionRule(db *gorm.DB, cr *models.CommissionRule) error {
+	if err := db.Table(cr.TableName()).
+		Where("id = ?", cr.ID).
+		Select("name, product_id, category_id, store_tier, min_monthly_sales, max_monthly_sales, rate, fixed_fee," +
+			" priority, is_active, effective_from, effective_to, updated_at").
+		Updates(map[string]interface{}{
+			"name":              cr.Name,
+			"product_id":        cr.ProductID,
+			"category_id":       cr.CategoryID,
+			"store_tier":        cr.StoreTier,
+			"min_monthly_sales": cr.MinMonthlySales,
+			"max_monthly_sales": cr.MaxMonthlySales,
+			"rate":              cr.Rate,
+			"fixed_fee":         cr.FixedFee,
+			"priority":          cr.Priority,
+			"is_active":         cr.IsActive,
+			"effective_from":    cr.EffectiveFrom,
+			"effective_to":      cr.EffectiveTo,
+			"updated_at":        cr.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *MarketplaceRepositoryImpl) DeleteCommissionRule(db *gorm.DB, ID string) error {
+	cr := models.CommissionRule{}
+	if err := db.Table(cr.TableName()).Delete(&cr, "id = ?", ID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *MarketplaceRepositoryImpl) ListCommissionRules(db *gorm.DB, from, limit int) ([]models.CommissionRule, error) {
+	cr := models.CommissionRule{}
+	var rules []models.CommissionRule
+	if err := db.Table(cr.TableName()).
+		Order("effective_from DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (au *MarketplaceRepositoryImpl) ListEffectiveCommissionRules(db *gorm.DB, at time.Time) ([]models.CommissionRule, error) {
+	cr := models.CommissionRule{}
+	var rules []models.CommissionRule
+	if err := db.Table(cr.TableName()).
+		Where("is_active = ? AND effective_from <= ? AND (effective_to IS NULL OR effective_to > ?)", true, at, at).
+		Order("priority DESC, effective_from DESC").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (au *MarketplaceRepositoryImpl) GetCommissionRule(db *gorm.DB, ID string) (*models.CommissionRule, error) {
+	cr := models.CommissionRule{}
+	if err := db.Table(cr.TableName()).Find(&cr, "id = ?", ID).Error; err != nil {
+		return nil, err
+	}
+	return &cr, nil
+}
@@ -160,7 +160,7 @@ func (au *MarketplaceRepositoryImpl) GetSettingsDetails(db *gorm.DB) (*models.Se
 	a := models.Address{}
 	if err := db.Table(fmt.Sprintf("%s AS s", settings.TableName())).
 		Where("s.id = ?", "1").
-		Select("s.id AS id, s.name AS name, s.website AS website, s.status AS status, a.address AS address, a.city AS city, a.country AS country, a.postcode AS postcode, a.email AS email, a.phone AS phone, s.is_sign_up_enabled AS is_sign_up_enabled, s.enabled_auto_store_confirmation AS enabled_auto_store_confirmation, s.is_store_creation_enabled AS is_store_creation_enabled, s.default_commission_rate AS default_commission_rate, s.tag_line AS tag_line, s.created_at AS created_at, s.updated_at AS updated_at").
+		Select("s.id AS id, s.name AS name, s.website AS website, s.status AS status, a.address AS address, a.city AS city, a.country AS country, a.postcode AS postcode, a.email AS email, a.phone AS phone, s.is_sign_up_enabled AS is_sign_up_enabled, s.enabled_auto_store_confirmation AS enabled_auto_store_confirmation, s.is_store_creation_enabled AS is_store_creation_enabled, s.default_commission_rate AS default_commission_rate, s.default_reserve_period_days AS default_reserve_period_days, s.tag_line AS tag_line, s.created_at AS created_at, s.updated_at AS updated_at").
 		Joins(fmt.Sprintf("LEFT JOIN %s AS a ON s.company_address_id = a.id", a.TableName())).
 		Find(&settingsDetails).Error; err != nil {
 		return nil, err
@@ -180,7 +180,7 @@ func (au *MarketplaceRepositoryImpl) GetSettings(db *gorm.DB) (*models.Settings,
 
 func (au *MarketplaceRepositoryImpl) UpdateSettings(db *gorm.DB, s *models.Settings) error {
 	if err := db.Table(s.TableName()).
-		Select("name, status, website, company_address_id, default_commission_rate, enabled_auto_store_confirmation, tag_line, is_sign_up_enabled, is_store_creation_enabled, updated_at").
+		Select("name, status, website, company_address_id, default_commission_rate, default_reserve_period_days, enabled_auto_store_confirmation, tag_line, is_sign_up_enabled, is_store_creation_enabled, updated_at").
 		Where("id = ?", "1").
 		Update(map[string]interface{}{
 			"name":                            s.Name,
@@ -188,6 +188,7 @@ func (au *MarketplaceRepositoryImpl) UpdateSettings(db *gorm.DB, s *models.Setti
 			"website":                         s.Website,
 			"company_address_id":              s.CompanyAddressID,
 			"default_commission_rate":         s.DefaultCommissionRate,
+			"default_reserve_period_days":     s.DefaultReservePeriodDays,
 			"enabled_auto_store_confirmation": s.EnabledAutoStoreConfirmation,
 			"tag_line":                        s.TagLine,
 			"is_sign_up_enabled":              s.IsSignUpEnabled,
@@ -101,6 +101,17 @@ func (au *MarketplaceRepositoryImpl) ListPayoutEntries(db *gorm.DB, storeID stri
 	return entries, nil
 }
 
+func (au *MarketplaceRepositoryImpl) ListAllPayoutEntries(db *gorm.DB) ([]models.PayoutSend, error) {
+	m := models.PayoutSend{}
+	var entries []models.PayoutSend
+	if err := db.Table(m.TableName()).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
 func (au *MarketplaceRepositoryImpl) GetPayoutEntry(db *gorm.DB, storeID, entryID string) (*models.PayoutSend, error) {
 	ps := models.PayoutSend{}
 	if err := db.Table(ps.TableName()).Find(&ps, "id = ? AND store_id = ?", entryID, storeID).Error; err != nil {
@@ -109,6 +120,17 @@ func (au *MarketplaceRepositoryImpl) GetPayoutEntry(db *gorm.DB, storeID, entryI
 	return &ps, nil
 }
 
+// GetPayoutEntryForUpdate locks the entry until the transaction ends, so its status is changed one at a time
+func (au *MarketplaceRepositoryImpl) GetPayoutEntryForUpdate(db *gorm.DB, storeID, entryID string) (*models.PayoutSend, error) {
+	ps := models.PayoutSend{}
+	if err := db.Table(ps.TableName()).
+		Set("gorm:query_option", "FOR UPDATE").
+		Find(&ps, "id = ? AND store_id = ?", entryID, storeID).Error; err != nil {
+		return nil, err
+	}
+	return &ps, nil
+}
+
 func (au *MarketplaceRepositoryImpl) GetPayoutEntryDetails(db *gorm.DB, storeID, entryID string) (*models.PayoutSendDetails, error) {
 	pom := models.PayoutMethod{}
 	ps := models.PayoutSendDetails{}
@@ -116,7 +138,7 @@ func (au *MarketplaceRepositoryImpl) GetPayoutEntryDetails(db *gorm.DB, storeID,
 		Select("ps.id AS id, ps.store_id AS store_id, ps.initiated_by_user_id AS initiated_by_user_id, ps.is_marketplace_initiated AS is_marketplace_initiated,"+
 			"ps.status AS status, ps.amount AS amount, ps.failure_reason AS failure_reason, ps.note AS note, ps.highlights AS highlights,"+
 			"pom.id AS payout_method_id, pom.name AS payout_method_name, pom.inputs AS payout_method_inputs, ps.payout_method_details AS payout_method_details,"+
-			"ps.created_at AS created_at, ps.updated_at AS updated_at").
+			"ps.batch_id AS batch_id, ps.created_at AS created_at, ps.updated_at AS updated_at").
 		Joins(fmt.Sprintf("LEFT JOIN %s AS pom ON ps.payout_method_id = pom.id", pom.TableName())).
 		Find(&ps, "ps.id = ? AND ps.store_id = ?", entryID, storeID).
 		Error; err != nil {
@@ -128,15 +150,127 @@ func (au *MarketplaceRepositoryImpl) GetPayoutEntryDetails(db *gorm.DB, storeID,
 func (au *MarketplaceRepositoryImpl) UpdatePayoutEntry(db *gorm.DB, ps *models.PayoutSend) error {
 	if err := db.Table(ps.TableName()).
 		Where("id = ? AND store_id = ?", ps.ID, ps.StoreID).
-		Select("status, amount, failure_reason, highlights").
+		Select("status, amount, failure_reason, highlights, batch_id, updated_at").
 		Updates(map[string]interface{}{
 			"status":         ps.Status,
 			"amount":         ps.Amount,
 			"failure_reason": ps.FailureReason,
 			"highlights":     ps.Highlights,
+			"batch_id":       ps.BatchID,
+			"updated_at":     ps.UpdatedAt,
 		}).
 		Error; err != nil {
 		return err
 	}
 	return nil
 }
+
+// ListPayoutEntriesByIDsForUpdate locks the entries until the transaction ends, so an entry is exported
+// in a single batch
+func (au *MarketplaceRepositoryImpl) ListPayoutEntriesByIDsForUpdate(db *gorm.DB, entryIDs []string) ([]models.PayoutSend, error) {
+	m := models.PayoutSend{}
+	var entries []models.PayoutSend
+	if err := db.Table(m.TableName()).
+		Set("gorm:query_option", "FOR UPDATE").
+		Where("id IN (?)", entryIDs).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (au *MarketplaceRepositoryImpl) ListPayoutEntriesByBatch(db *gorm.DB, batchID string) ([]models.PayoutSend, error) {
+	m := models.PayoutSend{}
+	var entries []models.PayoutSend
+	if err := db.Table(m.TableName()).
+		Where("batch_id = ?", batchID).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ListPayoutEntriesByBatchForUpdate locks the entries of the batch until the transaction ends, so a bank
+// report and a new batch don't change the same entries at once
+func (au *MarketplaceRepositoryImpl) ListPayoutEntriesByBatchForUpdate(db *gorm.DB, batchID string) ([]models.PayoutSend, error) {
+	m := models.PayoutSend{}
+	var entries []models.PayoutSend
+	if err := db.Table(m.TableName()).
+		Set("gorm:query_option", "FOR UPDATE").
+		Where("batch_id = ?", batchID).
+		Order("created_at ASC").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (au *MarketplaceRepositoryImpl) CreatePayoutBatch(db *gorm.DB, b *models.PayoutBatch) error {
+	if err := db.Table(b.TableName()).Create(b).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *MarketplaceRepositoryImpl) UpdatePayoutBatch(db *gorm.DB, b *models.PayoutBatch) error {
+	if err := db.Table(b.TableName()).
+		Where("id = ?", b.ID).
+		Select("status, updated_at").
+		Updates(map[string]interface{}{
+			"status":     b.Status,
+			"updated_at": b.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *MarketplaceRepositoryImpl) ListPayoutBatches(db *gorm.DB, from, limit int) ([]models.PayoutBatch, error) {
+	m := models.PayoutBatch{}
+	var batches []models.PayoutBatch
+	if err := db.Table(m.TableName()).
+		Order("created_at DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&batches).Error; err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+func (au *MarketplaceRepositoryImpl) GetPayoutBatch(db *gorm.DB, batchID string) (*models.PayoutBatch, error) {
+	m := models.PayoutBatch{}
+	if err := db.Table(m.TableName()).Find(&m, "id = ?", batchID).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (au *MarketplaceRepositoryImpl) CreatePayoutEvent(db *gorm.DB, e *models.PayoutEvent) error {
+	if err := db.Table(e.TableName()).Create(e).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *MarketplaceRepositoryImpl) ListPayoutEvents(db *gorm.DB, storeID, payoutID string) ([]models.PayoutEvent, error) {
+	m := models.PayoutEvent{}
+	var events []models.PayoutEvent
+	if err := db.Table(m.TableName()).
+		Where("store_id = ? AND payout_id = ?", storeID, payoutID).
+		Order("created_at ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (au *MarketplaceRepositoryImpl) GetPayoutEvent(db *gorm.DB, eventID string) (*models.PayoutEvent, error) {
+	m := models.PayoutEvent{}
+	if err := db.Table(m.TableName()).Find(&m, "id = ?", eventID).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
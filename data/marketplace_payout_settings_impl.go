@@ -60,3 +60,17 @@ func (au *MarketplaceRepositoryImpl) GetPayoutSettingsDetails(db *gorm.DB, store
 	}
 	return &psd, nil
 }
+
+func (au *MarketplaceRepositoryImpl) ListPayoutSettingsOfActiveStores(db *gorm.DB) ([]models.PayoutSettings, error) {
+	ps := models.PayoutSettings{}
+	var data []models.PayoutSettings
+	if err := db.Table(fmt.Sprintf("%s AS ps", ps.TableName())).
+		Select("ps.*").
+		Joins("JOIN stores AS s ON ps.store_id = s.id").
+		Where("s.status = ?", models.StoreActive).
+		Order("ps.created_at ASC").
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
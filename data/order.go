@@ -12,13 +12,24 @@ type OrderRepository interface {
 	AddOrderedItem(db *gorm.DB, item *models.OrderedItem) error
 	AddOrderedItemAttribute(db *gorm.DB, attr *models.OrderedItemAttribute) error
 	GetOrderedItem(db *gorm.DB, orderID, productID string) (*models.OrderedItem, error)
+	GetOrderedItemByID(db *gorm.DB, orderID, orderedItemID string) (*models.OrderedItem, error)
 	GetDetailsAsUser(db *gorm.DB, userID, orderID string) (*models.OrderDetailsViewExternal, error)
 	GetDetailsAsStoreStuff(db *gorm.DB, storeID, orderID string) (*models.OrderDetailsView, error)
 	GetAsStoreStuff(db *gorm.DB, storeID, orderID string) (*models.Order, error)
+	GetAsUser(db *gorm.DB, userID, orderID string) (*models.Order, error)
 	GetDetails(db *gorm.DB, orderID string) (*models.OrderDetailsView, error)
+	Get(db *gorm.DB, orderID string) (*models.Order, error)
+	GetForUpdate(db *gorm.DB, orderID string) (*models.Order, error)
+	GetByTransactionID(db *gorm.DB, paymentGateway, transactionID string) (*models.Order, error)
+	ListByTransactionID(db *gorm.DB, paymentGateway, transactionID string) ([]models.Order, error)
+	ListByPaymentGateway(db *gorm.DB, paymentGateway string, statuses []models.PaymentStatus, from, end time.Time) ([]models.Order, error)
+	ListPaymentGateways(db *gorm.DB, from, end time.Time) ([]string, error)
+	ListIDsByPaymentStatus(db *gorm.DB, statuses []models.PaymentStatus) ([]string, error)
+	GetStoreSalesVolume(db *gorm.DB, storeID string, since time.Time) (int64, error)
 	UpdatePaymentInfo(db *gorm.DB, o *models.OrderDetailsView) error
 	UpdateStatus(db *gorm.DB, o *models.Order) error
 	UpdatePaymentStatus(db *gorm.DB, o *models.Order) error
+	UpdatePaymentMethod(db *gorm.DB, o *models.Order) error
 	List(db *gorm.DB, userID string, offset, limit int) ([]models.OrderDetailsViewExternal, error)
 	ListAsStoreStuff(db *gorm.DB, storeID string, offset, limit int) ([]models.OrderDetailsViewExternal, error)
 	Search(db *gorm.DB, query, userID string, offset, limit int) ([]models.OrderDetailsView, error)
@@ -35,16 +35,25 @@ func (os *OrderRepositoryImpl) CreateLog(db *gorm.DB, ol *models.OrderLog) error
 	return nil
 }
 
+// paymentStatusUpdates sets the payment status, and the time of payment when it becomes completed
+func paymentStatusUpdates(updates map[string]interface{}, status models.PaymentStatus) map[string]interface{} {
+	updates["payment_status"] = status
+	if status == models.PaymentCompleted {
+		updates["paid_at"] = gorm.Expr("CASE WHEN payment_status = ? THEN paid_at ELSE ? END",
+			models.PaymentCompleted, time.Now().UTC())
+	}
+	return updates
+}
+
 func (os *OrderRepositoryImpl) UpdatePaymentInfo(db *gorm.DB, o *models.OrderDetailsView) error {
 	order := models.Order{}
 	if err := db.Table(order.TableName()).
 		Where("id = ?", o.ID).
-		Select("nonce, transaction_id, payment_status").
-		Updates(map[string]interface{}{
+		Select("nonce, transaction_id, payment_status, paid_at").
+		Updates(paymentStatusUpdates(map[string]interface{}{
 			"nonce":          o.Nonce,
 			"transaction_id": o.TransactionID,
-			"payment_status": o.PaymentStatus,
-		}).Error; err != nil {
+		}, o.PaymentStatus)).Error; err != nil {
 		return err
 	}
 	return nil
@@ -67,10 +76,28 @@ func (os *OrderRepositoryImpl) UpdatePaymentStatus(db *gorm.DB, o *models.Order)
 	order := models.Order{}
 	if err := db.Table(order.TableName()).
 		Where("id = ?", o.ID).
-		Select("payment_status").
-		Updates(map[string]interface{}{
-			"payment_status": o.PaymentStatus,
-		}).Error; err != nil {
+		Select("payment_status, paid_at").
+		Updates(paymentStatusUpdates(map[string]interface{}{}, o.PaymentStatus)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (os *OrderRepositoryImpl) UpdatePaymentMethod(db *gorm.DB, o *models.Order) error {
+	order := models.Order{}
+	if err := db.Table(order.TableName()).
+		Where("id = ?", o.ID).
+		Select("payment_method_id, payment_gateway, payment_processing_fee, original_grand_total, grand_total, nonce, transaction_id, payment_status, paid_at, updated_at").
+		Updates(paymentStatusUpdates(map[string]interface{}{
+			"payment_method_id":      o.PaymentMethodID,
+			"payment_gateway":        o.PaymentGateway,
+			"payment_processing_fee": o.PaymentProcessingFee,
+			"original_grand_total":   o.OriginalGrandTotal,
+			"grand_total":            o.GrandTotal,
+			"nonce":                  o.Nonce,
+			"transaction_id":         o.TransactionID,
+			"updated_at":             o.UpdatedAt,
+		}, o.PaymentStatus)).Error; err != nil {
 		return err
 	}
 	return nil
@@ -106,6 +133,16 @@ func (os *OrderRepositoryImpl) GetOrderedItem(db *gorm.DB, orderID, productID st
 	return &oi, nil
 }
 
+func (os *OrderRepositoryImpl) GetOrderedItemByID(db *gorm.DB, orderID, orderedItemID string) (*models.OrderedItem, error) {
+	oi := models.OrderedItem{}
+	if err := db.Table(oi.TableName()).
+		Where("order_id = ? AND id = ?", orderID, orderedItemID).
+		First(&oi).Error; err != nil {
+		return nil, err
+	}
+	return &oi, nil
+}
+
 func (os *OrderRepositoryImpl) List(db *gorm.DB, userID string, offset, limit int) ([]models.OrderDetailsViewExternal, error) {
 	order := models.OrderDetailsViewExternal{}
 	var orders []models.OrderDetailsViewExternal
@@ -281,6 +318,23 @@ func (os *OrderRepositoryImpl) SearchAsStoreStuff(db *gorm.DB, query, storeID st
 	return orders, nil
 }
 
+// orderLicenseKeys returns the keys allocated to the items of the order by item
+func orderLicenseKeys(db *gorm.DB, orderID string) (map[string][]string, error) {
+	lu := NewLicenseKeyRepository()
+	keys, err := lu.ListKeysByOrder(db, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string][]string{}
+	for _, k := range keys {
+		if k.OrderedItemID != nil {
+			res[*k.OrderedItemID] = append(res[*k.OrderedItemID], k.Key)
+		}
+	}
+	return res, nil
+}
+
 func (os *OrderRepositoryImpl) GetDetails(db *gorm.DB, orderID string) (*models.OrderDetailsView, error) {
 	order := models.OrderDetailsView{}
 	if err := db.Model(&order).First(&order, "id = ?", orderID).Error; err != nil {
@@ -296,6 +350,11 @@ func (os *OrderRepositoryImpl) GetDetails(db *gorm.DB, orderID string) (*models.
 		return nil, err
 	}
 
+	keys, err := orderLicenseKeys(db, orderID)
+	if err != nil {
+		return nil, err
+	}
+
 	for i := range items {
 		pu := NewProductRepository()
 		images, err := pu.GetImages(db, items[i].ProductID)
@@ -303,6 +362,7 @@ func (os *OrderRepositoryImpl) GetDetails(db *gorm.DB, orderID string) (*models.
 			return nil, err
 		}
 		items[i].AdditionalImages = images
+		items[i].LicenseKeys = keys[items[i].ID]
 	}
 
 	order.Items = items
@@ -342,6 +402,11 @@ func (os *OrderRepositoryImpl) GetDetailsAsStoreStuff(db *gorm.DB, storeID, orde
 		}
 	}
 
+	keys, err := orderLicenseKeys(db, orderID)
+	if err != nil {
+		return nil, err
+	}
+
 	for i := range items {
 		pu := NewProductRepository()
 		images, err := pu.GetImages(db, items[i].ProductID)
@@ -349,6 +414,7 @@ func (os *OrderRepositoryImpl) GetDetailsAsStoreStuff(db *gorm.DB, storeID, orde
 			return nil, err
 		}
 		items[i].AdditionalImages = images
+		items[i].LicenseKeys = keys[items[i].ID]
 	}
 
 	order.Items = items
@@ -364,6 +430,91 @@ func (os *OrderRepositoryImpl) GetAsStoreStuff(db *gorm.DB, storeID, orderID str
 	return &order, nil
 }
 
+func (os *OrderRepositoryImpl) Get(db *gorm.DB, orderID string) (*models.Order, error) {
+	order := models.Order{}
+	if err := db.Model(&order).First(&order, "id = ?", orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetForUpdate locks the order until the transaction ends, the payments of the order wait for each other
+func (os *OrderRepositoryImpl) GetForUpdate(db *gorm.DB, orderID string) (*models.Order, error) {
+	order := models.Order{}
+	if err := db.Model(&order).
+		Set("gorm:query_option", "FOR UPDATE").
+		First(&order, "id = ?", orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (os *OrderRepositoryImpl) GetByTransactionID(db *gorm.DB, paymentGateway, transactionID string) (*models.Order, error) {
+	order := models.Order{}
+	if err := db.Model(&order).
+		First(&order, "payment_gateway = ? AND transaction_id = ?", paymentGateway, transactionID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (os *OrderRepositoryImpl) ListByTransactionID(db *gorm.DB, paymentGateway, transactionID string) ([]models.Order, error) {
+	order := models.Order{}
+	var orders []models.Order
+	if err := db.Table(order.TableName()).
+		Where("payment_gateway = ? AND transaction_id = ?", paymentGateway, transactionID).
+		Order("created_at ASC").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (os *OrderRepositoryImpl) ListByPaymentGateway(db *gorm.DB, paymentGateway string, statuses []models.PaymentStatus, from, end time.Time) ([]models.Order, error) {
+	order := models.Order{}
+	var orders []models.Order
+	if err := db.Table(order.TableName()).
+		Where("payment_gateway = ? AND payment_status IN (?) AND transaction_id IS NOT NULL", paymentGateway, statuses).
+		Where("created_at >= ? AND created_at < ?", from, end).
+		Order("created_at ASC").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (os *OrderRepositoryImpl) ListIDsByPaymentStatus(db *gorm.DB, statuses []models.PaymentStatus) ([]string, error) {
+	order := models.Order{}
+	var ids []string
+	if err := db.Table(order.TableName()).
+		Where("payment_status IN (?)", statuses).
+		Order("created_at ASC").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (os *OrderRepositoryImpl) ListPaymentGateways(db *gorm.DB, from, end time.Time) ([]string, error) {
+	order := models.Order{}
+	var gateways []string
+	if err := db.Table(order.TableName()).
+		Where("payment_gateway IS NOT NULL AND created_at >= ? AND created_at < ?", from, end).
+		Pluck("DISTINCT payment_gateway", &gateways).Error; err != nil {
+		return nil, err
+	}
+	return gateways, nil
+}
+
+func (os *OrderRepositoryImpl) GetAsUser(db *gorm.DB, userID, orderID string) (*models.Order, error) {
+	order := models.Order{}
+	if err := db.Model(&order).First(&order, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
+		log.Log().Errorln(err)
+		return nil, err
+	}
+	return &order, nil
+}
+
 func (os *OrderRepositoryImpl) GetDetailsAsUser(db *gorm.DB, userID, orderID string) (*models.OrderDetailsViewExternal, error) {
 	order := models.OrderDetailsViewExternal{}
 	if err := db.Model(&order).First(&order, "id = ? AND user_id = ?", orderID, userID).Error; err != nil {
@@ -397,6 +548,11 @@ func (os *OrderRepositoryImpl) GetDetailsAsUser(db *gorm.DB, userID, orderID str
 		}
 	}
 
+	keys, err := orderLicenseKeys(db, orderID)
+	if err != nil {
+		return nil, err
+	}
+
 	for i := range items {
 		pu := NewProductRepository()
 		images, err := pu.GetImages(db, items[i].ProductID)
@@ -404,6 +560,7 @@ func (os *OrderRepositoryImpl) GetDetailsAsUser(db *gorm.DB, userID, orderID str
 			return nil, err
 		}
 		items[i].AdditionalImages = images
+		items[i].LicenseKeys = keys[items[i].ID]
 	}
 
 	order.Items = items
@@ -501,3 +658,15 @@ func (os *OrderRepositoryImpl) CreateReview(db *gorm.DB, r *models.Review) error
 	}
 	return nil
 }
+
+func (os *OrderRepositoryImpl) GetStoreSalesVolume(db *gorm.DB, storeID string, since time.Time) (int64, error) {
+	order := models.Order{}
+	var volume int64
+	if err := db.Table(order.TableName()).
+		Select("COALESCE(SUM(sub_total), 0)").
+		Where("store_id = ? AND payment_status = ? AND created_at >= ?", storeID, models.PaymentCompleted, since).
+		Row().Scan(&volume); err != nil {
+		return 0, err
+	}
+	return volume, nil
+}
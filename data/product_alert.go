@@ -0,0 +1,20 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductAlertRepository interface {
+	Subscribe(db *gorm.DB, s *models.ProductAlertSubscription) error
+	Unsubscribe(db *gorm.DB, userID, productID string) error
+	ListSubscriptions(db *gorm.DB, userID string) ([]models.SavedProductDetails, error)
+	ListWatchedChanges(db *gorm.DB) ([]models.WatchedProduct, error)
+	GetAvailableStock(db *gorm.DB, productID string) (int, error)
+	MarkSeen(db *gorm.DB, productID string, price int64, stock int) error
+	CreateAlert(db *gorm.DB, a *models.ProductAlert) error
+	ListUnsentAlerts(db *gorm.DB) ([]models.ProductAlertDetails, error)
+	MarkAlertsSent(db *gorm.DB, alertIDs []string, at time.Time) error
+}
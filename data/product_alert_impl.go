@@ -0,0 +1,145 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductAlertRepositoryImpl struct {
+}
+
+var productAlertRepository ProductAlertRepository
+
+func NewProductAlertRepository() ProductAlertRepository {
+	if productAlertRepository == nil {
+		productAlertRepository = &ProductAlertRepositoryImpl{}
+	}
+	return productAlertRepository
+}
+
+func (au *ProductAlertRepositoryImpl) Subscribe(db *gorm.DB, s *models.ProductAlertSubscription) error {
+	if err := db.Table(s.TableName()).Create(s).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (au *ProductAlertRepositoryImpl) Unsubscribe(db *gorm.DB, userID, productID string) error {
+	s := models.ProductAlertSubscription{}
+	q := db.Table(s.TableName()).
+		Where("user_id = ? AND product_id = ?", userID, productID).
+		Delete(&s)
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (au *ProductAlertRepositoryImpl) ListSubscriptions(db *gorm.DB, userID string) ([]models.SavedProductDetails, error) {
+	products := []models.SavedProductDetails{}
+	s := models.ProductAlertSubscription{}
+	if err := db.Table(fmt.Sprintf("%s AS pas", s.TableName())).
+		Select(savedProductSelection+", pas.created_at AS added_at").
+		Joins(savedProductJoins("pas")).
+		Where("pas.user_id = ?", userID).
+		Order("pas.created_at DESC").
+		Scan(&products).Error; err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// watchedChangesSelection selects the saved products of the table aliased as alias whose price or stock changed.
+// The available stock of the products is given as the last argument.
+const watchedChangesSelection = "SELECT %[2]s, %[1]s.product_id, %[1]s.seen_price, %[1]s.seen_stock, p.price, %[6]s AS stock, p.is_digital, " +
+	"p.is_published AND s.status = '" + string(models.StoreActive) + "' AS is_available FROM %[3]s AS %[1]s %[4]s %[5]s " +
+	"WHERE %[1]s.seen_price <> p.price OR %[1]s.seen_stock <> %[6]s"
+
+// ListWatchedChanges returns the wishlisted and subscribed products whose price or stock changed since last seen
+func (au *ProductAlertRepositoryImpl) ListWatchedChanges(db *gorm.DB) ([]models.WatchedProduct, error) {
+	var watched []models.WatchedProduct
+
+	w := models.Wishlist{}
+	wi := models.WishlistItem{}
+	pas := models.ProductAlertSubscription{}
+
+	wishlisted := fmt.Sprintf(watchedChangesSelection, "wi", "w.user_id", wi.TableName(),
+		fmt.Sprintf("JOIN %s AS w ON w.id = wi.wishlist_id", w.TableName()), savedProductJoins("wi"), productAvailableStock("p"))
+	subscribed := fmt.Sprintf(watchedChangesSelection, "pas", "pas.user_id", pas.TableName(), "", savedProductJoins("pas"),
+		productAvailableStock("p"))
+
+	if err := db.Raw(wishlisted + " UNION ALL " + subscribed).Scan(&watched).Error; err != nil {
+		return nil, err
+	}
+	return watched, nil
+}
+
+// GetAvailableStock returns the stock of the product the alerts compare against, the stock of its active
+// variants when it has any
+func (au *ProductAlertRepositoryImpl) GetAvailableStock(db *gorm.DB, productID string) (int, error) {
+	p := models.Product{}
+	var stock int
+	if err := db.Table(fmt.Sprintf("%s AS p", p.TableName())).
+		Select(productAvailableStock("p")).
+		Where("p.id = ?", productID).
+		Row().Scan(&stock); err != nil {
+		return 0, err
+	}
+	return stock, nil
+}
+
+// MarkSeen remembers the price and the stock of the product wherever it's wishlisted or subscribed to
+func (au *ProductAlertRepositoryImpl) MarkSeen(db *gorm.DB, productID string, price int64, stock int) error {
+	for _, table := range []string{(&models.WishlistItem{}).TableName(), (&models.ProductAlertSubscription{}).TableName()} {
+		if err := db.Table(table).
+			Where("product_id = ?", productID).
+			Updates(map[string]interface{}{
+				"seen_price": price,
+				"seen_stock": stock,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (au *ProductAlertRepositoryImpl) CreateAlert(db *gorm.DB, a *models.ProductAlert) error {
+	if err := db.Table(a.TableName()).Create(a).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// ListUnsentAlerts returns the alerts to email, grouped by user
+func (au *ProductAlertRepositoryImpl) ListUnsentAlerts(db *gorm.DB) ([]models.ProductAlertDetails, error) {
+	var alerts []models.ProductAlertDetails
+	a := models.ProductAlert{}
+	u := models.User{}
+	p := models.Product{}
+	if err := db.Table(fmt.Sprintf("%s AS pa", a.TableName())).
+		Select("pa.*, u.name AS user_name, u.email AS user_email, p.name AS product_name, p.slug AS product_slug").
+		Joins(fmt.Sprintf("JOIN %s AS u ON u.id = pa.user_id", u.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS p ON p.id = pa.product_id", p.TableName())).
+		Where("pa.sent_at IS NULL").
+		Order("pa.user_id, pa.created_at").
+		Scan(&alerts).Error; err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+func (au *ProductAlertRepositoryImpl) MarkAlertsSent(db *gorm.DB, alertIDs []string, at time.Time) error {
+	a := models.ProductAlert{}
+	if err := db.Table(a.TableName()).
+		Where("id IN (?)", alertIDs).
+		Update("sent_at", at).Error; err != nil {
+		return err
+	}
+	return nil
+}
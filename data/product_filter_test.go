@@ -0,0 +1,284 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+// recordingConnector stands in for Postgres, it records the queries run and answers them with no rows
+type recordingConnector struct {
+	queries []recordedQuery
+}
+
+type recordedQuery struct {
+	SQL  string
+	Args []driver.Value
+}
+
+func (rc *recordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return &recordingConn{rc: rc}, nil
+}
+
+func (rc *recordingConnector) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	rc *recordingConnector
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{rc: c.rc, query: query}, nil
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions aren't recorded")
+}
+
+type recordingStmt struct {
+	rc    *recordingConnector
+	query string
+}
+
+func (s *recordingStmt) Close() error {
+	return nil
+}
+
+func (s *recordingStmt) NumInput() int {
+	return -1
+}
+
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.rc.queries = append(s.rc.queries, recordedQuery{SQL: s.query, Args: args})
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.rc.queries = append(s.rc.queries, recordedQuery{SQL: s.query, Args: args})
+	return recordingRows{}, nil
+}
+
+type recordingRows struct{}
+
+func (recordingRows) Columns() []string {
+	return nil
+}
+
+func (recordingRows) Close() error {
+	return nil
+}
+
+func (recordingRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func newRecordingDB(t *testing.T) (*gorm.DB, *recordingConnector) {
+	rc := &recordingConnector{}
+	db, err := gorm.Open("postgres", sql.OpenDB(rc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, rc
+}
+
+func TestFilterProducts(t *testing.T) {
+	minPrice, maxPrice := int64(100), int64(500)
+	isDigital := false
+	rating := 4
+
+	cases := []struct {
+		name     string
+		filter   models.ProductFilter
+		contains []string
+		excludes []string
+		args     []driver.Value
+	}{
+		{
+			name:     "no filter",
+			filter:   models.ProductFilter{},
+			contains: []string{"products.is_published = $1", "ORDER BY products.created_at DESC"},
+			excludes: []string{"to_tsquery", "search_rank DESC", "products.price >=", "products.price <="},
+			args:     []driver.Value{true},
+		},
+		{
+			name:   "text search",
+			filter: models.ProductFilter{Query: "red shirt"},
+			contains: []string{"(SELECT (to_tsquery('english', $1) || to_tsquery('simple', $2)) && (to_tsquery('english', $3) || to_tsquery('simple', $4)) AS tsq)",
+				"products.search_vector @@ tsq", "ORDER BY search_rank DESC,products.created_at DESC"},
+			args: []driver.Value{"red", "red", "shirt", "shirt", true},
+		},
+		{
+			// Red Shirts is indexed as shirt, the stemmed word must be excluded too
+			name:     "text search excluding a stemmed word",
+			filter:   models.ProductFilter{Query: "red -shirts"},
+			contains: []string{"(SELECT (to_tsquery('english', $1) || to_tsquery('simple', $2)) && !!(to_tsquery('english', $3) || to_tsquery('simple', $4)) AS tsq)"},
+			args:     []driver.Value{"red", "red", "shirts", "shirts", true},
+		},
+		{
+			name:     "relevance without text search",
+			filter:   models.ProductFilter{Sort: models.ProductSortRelevance},
+			contains: []string{"ORDER BY products.created_at DESC"},
+			excludes: []string{"search_rank"},
+			args:     []driver.Value{true},
+		},
+		{
+			name:     "newest text search",
+			filter:   models.ProductFilter{Query: "shirt", Sort: models.ProductSortNewest},
+			contains: []string{"@@ tsq", "ORDER BY products.created_at DESC"},
+			excludes: []string{"search_rank DESC"},
+			args:     []driver.Value{"shirt", "shirt", true},
+		},
+		{
+			name:     "price range",
+			filter:   models.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice, Sort: models.ProductSortPriceAsc},
+			contains: []string{"products.price >= $2", "products.price <= $3", "ORDER BY products.price ASC,products.created_at DESC"},
+			args:     []driver.Value{true, int64(100), int64(500)},
+		},
+		{
+			name:     "categories, taxonomy nodes and stores",
+			filter:   models.ProductFilter{CategoryIDs: []string{"c1", "c2"}, TaxonomyIDs: []string{"t1"}, StoreIDs: []string{"s1"}},
+			contains: []string{"products.category_id IN (SELECT d.id FROM categories", "taxonomy_id IN (SELECT d.id FROM taxonomy_nodes", "products.store_id IN ($5)"},
+			args:     []driver.Value{true, "c1", "c2", "t1", "s1"},
+		},
+		{
+			name: "attributes in key order",
+			filter: models.ProductFilter{Attributes: map[string][]string{
+				"size":  {"XL"},
+				"color": {"red", "blue"},
+			}, Sort: models.ProductSortPriceDesc},
+			contains: []string{"WHERE key = $2 AND value IN ($3,$4)", "WHERE key = $5 AND value IN ($6)", "ORDER BY products.price DESC"},
+			args:     []driver.Value{true, "color", "red", "blue", "size", "XL"},
+		},
+		{
+			name:     "digital, in stock and rated",
+			filter:   models.ProductFilter{IsDigital: &isDigital, InStock: true, MinRating: &rating, Sort: models.ProductSortPopularity},
+			contains: []string{"products.is_digital = $2", "stock > 0", "HAVING AVG(rating) >= $4", "ORDER BY products.views DESC"},
+			args:     []driver.Value{true, false, string(models.ReviewApproved), int64(4)},
+		},
+		{
+			name:     "best selling",
+			filter:   models.ProductFilter{Sort: models.ProductSortBestSelling},
+			contains: []string{"o.payment_status = $1", "ORDER BY COALESCE(sales.sold, 0) DESC"},
+			args:     []driver.Value{string(models.PaymentCompleted), true},
+		},
+	}
+
+	for _, c := range cases {
+		db, rc := newRecordingDB(t)
+
+		pu := NewProductRepository()
+		if _, err := pu.Filter(db, &c.filter, 0, 10); err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if len(rc.queries) != 1 {
+			t.Errorf("%s: expected 1 query, got %d", c.name, len(rc.queries))
+			continue
+		}
+
+		q := rc.queries[0]
+		for _, s := range c.contains {
+			if !strings.Contains(q.SQL, s) {
+				t.Errorf("%s: expected the query to contain %q, got %s", c.name, s, q.SQL)
+			}
+		}
+		for _, s := range c.excludes {
+			if strings.Contains(q.SQL, s) {
+				t.Errorf("%s: expected the query not to contain %q, got %s", c.name, s, q.SQL)
+			}
+		}
+		if !reflect.DeepEqual(q.Args, c.args) {
+			t.Errorf("%s: expected args %v, got %v", c.name, c.args, q.Args)
+		}
+	}
+}
+
+func TestProductFacets(t *testing.T) {
+	minPrice := int64(1000)
+
+	db, rc := newRecordingDB(t)
+
+	pu := NewProductRepository()
+	facets, err := pu.Facets(db, &models.ProductFilter{
+		CategoryIDs: []string{"c1"},
+		MinPrice:    &minPrice,
+		Attributes:  map[string][]string{"color": {"red"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rc.queries) != 4 {
+		t.Fatalf("expected 4 queries, got %d", len(rc.queries))
+	}
+
+	cases := []struct {
+		name     string
+		query    recordedQuery
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "attributes",
+			query:    rc.queries[0],
+			contains: []string{"products.category_id IN", "products.price >=", "WHERE key = ", "GROUP BY pa.key, pa.value"},
+			excludes: []string{"pa.key = "},
+		},
+		{
+			name:     "values of a filtered attribute",
+			query:    rc.queries[1],
+			contains: []string{"products.category_id IN", "products.price >=", "pa.key = "},
+			excludes: []string{"WHERE key = "},
+		},
+		{
+			name:     "categories",
+			query:    rc.queries[2],
+			contains: []string{"products.price >=", "WHERE key = ", "GROUP BY cat.id, cat.name"},
+			excludes: []string{"products.category_id IN"},
+		},
+		{
+			name:     "price buckets",
+			query:    rc.queries[3],
+			contains: []string{"products.category_id IN", "WHERE key = ", "WHEN products.price < 1000 THEN 0", "ELSE 6 END AS bucket"},
+			excludes: []string{"products.price >="},
+		},
+	}
+
+	for _, c := range cases {
+		for _, s := range c.contains {
+			if !strings.Contains(c.query.SQL, s) {
+				t.Errorf("%s: expected the query to contain %q, got %s", c.name, s, c.query.SQL)
+			}
+		}
+		for _, s := range c.excludes {
+			if strings.Contains(c.query.SQL, s) {
+				t.Errorf("%s: expected the query not to contain %q, got %s", c.name, s, c.query.SQL)
+			}
+		}
+	}
+
+	if len(facets.Attributes) != 0 || len(facets.Categories) != 0 {
+		t.Errorf("expected no attribute or category facets, got %+v", facets)
+	}
+	if len(facets.PriceBuckets) != len(models.ProductPriceBuckets)+1 {
+		t.Errorf("expected every price bucket, got %+v", facets.PriceBuckets)
+	}
+	for _, b := range facets.PriceBuckets {
+		if b.Count != 0 {
+			t.Errorf("expected empty price buckets, got %+v", facets.PriceBuckets)
+		}
+	}
+}
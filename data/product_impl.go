@@ -2,11 +2,14 @@ package data
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/shopicano/shopicano-backend/helpers"
 	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
 )
 
 type ProductRepositoryImpl struct {
@@ -29,18 +32,19 @@ func (pu *ProductRepositoryImpl) Create(db *gorm.DB, p *models.Product) error {
 	return nil
 }
 
+// Update leaves the stock out, it only changes through the stock adjustments
 func (pu *ProductRepositoryImpl) Update(db *gorm.DB, p *models.Product) error {
 	if err := db.Table(p.TableName()).
-		Select("name, description, is_published, category_id, sku, slug, stock, unit, price, product_cost, max_quantity_count, image, is_shippable, is_digital, digital_download_link, updated_at").
+		Select("name, description, is_published, unpublish_at, category_id, sku, slug, unit, price, product_cost, max_quantity_count, image, is_shippable, is_digital, digital_download_link, updated_at").
 		Where("id = ? AND store_id = ?", p.ID, p.StoreID).
 		Updates(map[string]interface{}{
 			"name":                  p.Name,
 			"description":           p.Description,
 			"is_published":          p.IsPublished,
+			"unpublish_at":          p.UnpublishAt,
 			"category_id":           p.CategoryID,
 			"sku":                   p.SKU,
 			"slug":                  p.Slug,
-			"stock":                 p.Stock,
 			"unit":                  p.Unit,
 			"price":                 p.Price,
 			"image":                 p.Image,
@@ -76,21 +80,6 @@ func (pu *ProductRepositoryImpl) IncreaseViewCounter(db *gorm.DB, pID, sID strin
 	return nil
 }
 
-func (pu *ProductRepositoryImpl) List(db *gorm.DB, from, limit int) ([]models.ProductDetails, error) {
-	var ps []models.ProductDetails
-	p := models.Product{}
-	if err := db.Table(p.TableName()).
-		Select("products.id, products.stock, products.sku, products.slug, products.unit, products.store_id, s.name AS store_name, products.name, products.price, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at").
-		Joins("LEFT JOIN categories AS c ON products.category_id = c.id").
-		Joins("LEFT JOIN stores AS s ON products.store_id = s.id").
-		Where("products.is_published = ?", true).
-		Offset(from).Limit(limit).
-		Order("products.created_at DESC").Find(&ps).Error; err != nil {
-		return nil, err
-	}
-	return ps, nil
-}
-
 func (pu *ProductRepositoryImpl) ListAsStoreStuff(db *gorm.DB, storeID string, from, limit int) ([]models.ProductDetailsInternal, error) {
 	var ps []models.ProductDetailsInternal
 	p := models.Product{}
@@ -106,39 +95,318 @@ func (pu *ProductRepositoryImpl) ListAsStoreStuff(db *gorm.DB, storeID string, f
 	return ps, nil
 }
 
-func (pu *ProductRepositoryImpl) Search(db *gorm.DB, query string, from, limit int) ([]models.ProductDetails, error) {
-	var ps []models.ProductDetails
+const productListingSelection = "products.id, products.name, products.sku, products.slug, products.unit, products.store_id, s.name AS store_name, products.stock, products.price, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at"
+
+// productRatingJoin makes the rating of the approved reviews available as pr
+const productRatingJoin = "LEFT JOIN (SELECT product_id, AVG(rating) AS rating_average, COUNT(*) AS rating_count FROM product_reviews " +
+	"WHERE status = '" + string(models.ReviewApproved) + "' GROUP BY product_id) AS pr ON pr.product_id = products.id"
+
+const productRatingSelection = "COALESCE(pr.rating_average, 0) AS rating_average, COALESCE(pr.rating_count, 0) AS rating_count"
+
+// productSearchJoin makes the text search query of productSearchQuery available as tsq
+const productSearchJoin = "CROSS JOIN (SELECT %s AS tsq) AS search"
+
+// productSearchTerm matches a term either stemmed or as it is, like the SKUs are indexed, so exact SKUs
+// match even when stemmed or made of stop words
+const productSearchTerm = "(to_tsquery('" + models.ProductSearchConfig + "', ?) || to_tsquery('" + models.ProductSKUSearchConfig + "', ?))"
+
+// productSearchQuery builds the text search query of a search box query with its arguments. Every term
+// matches in either config and negated terms match in neither. It's empty when nothing is searchable.
+func productSearchQuery(query string) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for _, t := range utils.ParseTSQuery(query) {
+		if t.Negate {
+			terms = append(terms, "!!"+productSearchTerm)
+		} else {
+			terms = append(terms, productSearchTerm)
+		}
+		args = append(args, t.Query, t.Query)
+	}
+	return strings.Join(terms, " && "), args
+}
+
+// productSearchSelection adds the rank and the highlights of the matches to the listing columns
+const productSearchSelection = "ts_rank(products.search_vector, tsq) AS search_rank, " +
+	"ts_headline('" + models.ProductSearchConfig + "', products.name, tsq, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS name_highlight, " +
+	"ts_headline('" + models.ProductSearchConfig + "', products.description, tsq, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5') AS description_highlight"
+
+func (pu *ProductRepositoryImpl) SearchAsStoreStuff(db *gorm.DB, storeID, query string, from, limit int) ([]models.ProductDetailsInternal, error) {
+	ps := []models.ProductDetailsInternal{}
+
+	tsQuery, tsArgs := productSearchQuery(query)
+	if tsQuery == "" {
+		return ps, nil
+	}
+
 	p := models.Product{}
 	if err := db.Table(p.TableName()).
-		Select("products.id, products.name, products.sku, products.slug, products.unit, products.store_id, s.name AS store_name, products.stock, products.price, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at").
+		Select(productListingSelection+", "+productSearchSelection).
+		Joins(fmt.Sprintf(productSearchJoin, tsQuery), tsArgs...).
 		Joins("LEFT JOIN categories AS c ON products.category_id = c.id").
-		Joins("LEFT JOIN collection_of_products AS cop ON products.id = cop.product_id").
-		Joins("LEFT JOIN collections AS col ON cop.collection_id = col.id").
 		Joins("LEFT JOIN stores AS s ON products.store_id = s.id").
-		Where("products.is_published = ? AND (LOWER(products.name) LIKE ? OR LOWER(c.name) LIKE ? OR LOWER(col.name) LIKE ?)", true, "%"+strings.ToLower(query)+"%", "%"+strings.ToLower(query)+"%", "%"+strings.ToLower(query)+"%").
-		Group("products.id, products.name, products.sku, products.unit, products.store_id, s.name, products.stock, products.price, products.description, products.is_published, products.is_shippable, products.is_digital, c.id, c.name, products.image, products.created_at, products.updated_at").
+		Where("products.search_vector @@ tsq AND products.store_id = ?", storeID).
 		Offset(from).Limit(limit).
-		Order("created_at DESC").Find(&ps).Error; err != nil {
+		Order("search_rank DESC, products.created_at DESC").
+		Find(&ps).Error; err != nil {
 		return nil, err
 	}
 	return ps, nil
 }
 
-func (pu *ProductRepositoryImpl) SearchAsStoreStuff(db *gorm.DB, storeID, query string, from, limit int) ([]models.ProductDetailsInternal, error) {
-	var ps []models.ProductDetailsInternal
+// Product facets whose filter is skipped when counting them
+const (
+	productFacetCategory = "category"
+	productFacetPrice    = "price"
+)
+
+// productAvailableStock is the stock of the product of the table aliased as alias that can be sold. Products
+// with variants have the stock of their active variants, so they are in stock when any of them is.
+func productAvailableStock(alias string) string {
+	pv := models.ProductVariant{}
+	return fmt.Sprintf("CASE WHEN EXISTS (SELECT 1 FROM %[1]s WHERE product_id = %[2]s.id AND is_active) "+
+		"THEN (SELECT COALESCE(SUM(stock), 0) FROM %[1]s WHERE product_id = %[2]s.id AND is_active AND stock > 0) "+
+		"ELSE %[2]s.stock END", pv.TableName(), alias)
+}
+
+// filterProducts narrows the published products down to the filter. The filter of skipFacet and of the
+// attribute key skipAttribute are left out, so the facet counts ignore their own selection.
+func filterProducts(db *gorm.DB, f *models.ProductFilter, skipFacet, skipAttribute string) *gorm.DB {
 	p := models.Product{}
-	if err := db.Table(p.TableName()).
-		Select("products.id, products.name, products.sku, products.slug, products.unit, products.store_id, s.name AS store_name, products.stock, products.price, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at").
+	pa := models.ProductAttribute{}
+	r := models.ProductReview{}
+	c := models.Category{}
+	tn := models.TaxonomyNode{}
+
+	q := db.Table(p.TableName()).Where("products.is_published = ?", true)
+
+	if tsQuery, tsArgs := productSearchQuery(f.Query); tsQuery != "" {
+		q = q.Joins(fmt.Sprintf(productSearchJoin, tsQuery), tsArgs...).Where("products.search_vector @@ tsq")
+	}
+	// Browsing a category or a taxonomy node includes the products of their descendants
+	if len(f.CategoryIDs) > 0 && skipFacet != productFacetCategory {
+		q = q.Where(fmt.Sprintf("products.category_id IN (%s)", descendantsOf(c.TableName())), f.CategoryIDs)
+	}
+	if len(f.TaxonomyIDs) > 0 {
+		q = q.Where(fmt.Sprintf("products.category_id IN (SELECT id FROM %s WHERE taxonomy_id IN (%s))",
+			c.TableName(), descendantsOf(tn.TableName())), f.TaxonomyIDs)
+	}
+	if len(f.StoreIDs) > 0 {
+		q = q.Where("products.store_id IN (?)", f.StoreIDs)
+	}
+	if skipFacet != productFacetPrice {
+		if f.MinPrice != nil {
+			q = q.Where("products.price >= ?", *f.MinPrice)
+		}
+		if f.MaxPrice != nil {
+			q = q.Where("products.price <= ?", *f.MaxPrice)
+		}
+	}
+
+	keys := make([]string, 0, len(f.Attributes))
+	for k := range f.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if k == skipAttribute {
+			continue
+		}
+		q = q.Where(fmt.Sprintf("products.id IN (SELECT product_id FROM %s WHERE key = ? AND value IN (?))", pa.TableName()),
+			k, f.Attributes[k])
+	}
+
+	if f.IsDigital != nil {
+		q = q.Where("products.is_digital = ?", *f.IsDigital)
+	}
+	if f.InStock {
+		q = q.Where(fmt.Sprintf("(products.is_digital OR %s > 0)", productAvailableStock("products")))
+	}
+	if f.MinRating != nil {
+		q = q.Where(fmt.Sprintf("products.id IN (SELECT product_id FROM %s WHERE status = ? "+
+			"GROUP BY product_id HAVING AVG(rating) >= ?)", r.TableName()), models.ReviewApproved, *f.MinRating)
+	}
+
+	return q
+}
+
+// Filter lists the published products matching the filter. Text searches are sorted by relevance and
+// everything else by newest unless another sort is given.
+func (pu *ProductRepositoryImpl) Filter(db *gorm.DB, f *models.ProductFilter, from, limit int) ([]models.ProductDetails, error) {
+	oi := models.OrderedItem{}
+	o := models.Order{}
+
+	isSearch := len(utils.ParseTSQuery(f.Query)) > 0
+
+	selection := productListingSelection + ", " + productRatingSelection
+	if isSearch {
+		selection += ", " + productSearchSelection
+	}
+
+	q := filterProducts(db, f, "", "").
+		Select(selection).
 		Joins("LEFT JOIN categories AS c ON products.category_id = c.id").
 		Joins("LEFT JOIN stores AS s ON products.store_id = s.id").
-		Where("products.store_id = ? AND (LOWER(products.name) LIKE ? OR LOWER(c.name) LIKE ?)", storeID, "%"+strings.ToLower(query)+"%", "%"+strings.ToLower(query)+"%").
+		Joins(productRatingJoin)
+
+	sortBy := f.Sort
+	if sortBy == "" || (sortBy == models.ProductSortRelevance && !isSearch) {
+		sortBy = models.ProductSortNewest
+		if isSearch {
+			sortBy = models.ProductSortRelevance
+		}
+	}
+
+	switch sortBy {
+	case models.ProductSortRelevance:
+		q = q.Order("search_rank DESC")
+	case models.ProductSortPriceAsc:
+		q = q.Order("products.price ASC")
+	case models.ProductSortPriceDesc:
+		q = q.Order("products.price DESC")
+	case models.ProductSortPopularity:
+		q = q.Order("products.views DESC")
+	case models.ProductSortBestSelling:
+		q = q.Joins(fmt.Sprintf("LEFT JOIN (SELECT oi.product_id, SUM(oi.quantity) AS sold FROM %s AS oi "+
+			"JOIN %s AS o ON oi.order_id = o.id WHERE o.payment_status = ? GROUP BY oi.product_id) AS sales "+
+			"ON sales.product_id = products.id", oi.TableName(), o.TableName()), models.PaymentCompleted).
+			Order("COALESCE(sales.sold, 0) DESC")
+	}
+
+	ps := []models.ProductDetails{}
+	if err := q.Order("products.created_at DESC").
 		Offset(from).Limit(limit).
-		Order("created_at DESC").Find(&ps).Error; err != nil {
+		Find(&ps).Error; err != nil {
 		return nil, err
 	}
 	return ps, nil
 }
 
+func (pu *ProductRepositoryImpl) CountFiltered(db *gorm.DB, f *models.ProductFilter) (int, error) {
+	count := 0
+	if err := filterProducts(db, f, "", "").Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+type productFacetRow struct {
+	Key   string
+	Value string
+	Name  string
+	Count int
+}
+
+// Facets counts the products matching the filter per attribute key and value, per category and per price bucket
+func (pu *ProductRepositoryImpl) Facets(db *gorm.DB, f *models.ProductFilter) (*models.ProductFacets, error) {
+	pa := models.ProductAttribute{}
+	c := models.Category{}
+
+	facets := models.ProductFacets{
+		Attributes:   []models.AttributeFacet{},
+		Categories:   []models.CategoryFacet{},
+		PriceBuckets: []models.PriceBucketFacet{},
+	}
+
+	attributeCounts := func(skipAttribute string) ([]productFacetRow, error) {
+		q := filterProducts(db, f, "", skipAttribute).
+			Select("pa.key AS key, pa.value AS value, COUNT(DISTINCT products.id) AS count").
+			Joins(fmt.Sprintf("JOIN %s AS pa ON pa.product_id = products.id", pa.TableName()))
+		if skipAttribute != "" {
+			q = q.Where("pa.key = ?", skipAttribute)
+		}
+
+		var rows []productFacetRow
+		if err := q.Group("pa.key, pa.value").
+			Order("pa.key ASC, pa.value ASC").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	rows, err := attributeCounts("")
+	if err != nil {
+		return nil, err
+	}
+
+	// The values of the filtered keys are counted again without their own selection
+	var attributeRows []productFacetRow
+	for _, r := range rows {
+		if _, ok := f.Attributes[r.Key]; !ok {
+			attributeRows = append(attributeRows, r)
+		}
+	}
+	for k := range f.Attributes {
+		rows, err := attributeCounts(k)
+		if err != nil {
+			return nil, err
+		}
+		attributeRows = append(attributeRows, rows...)
+	}
+	sort.SliceStable(attributeRows, func(i, j int) bool {
+		if attributeRows[i].Key != attributeRows[j].Key {
+			return attributeRows[i].Key < attributeRows[j].Key
+		}
+		return attributeRows[i].Value < attributeRows[j].Value
+	})
+
+	for _, r := range attributeRows {
+		n := len(facets.Attributes)
+		if n == 0 || facets.Attributes[n-1].Key != r.Key {
+			facets.Attributes = append(facets.Attributes, models.AttributeFacet{Key: r.Key})
+			n++
+		}
+		facets.Attributes[n-1].Values = append(facets.Attributes[n-1].Values, models.FacetValue{
+			Value: r.Value,
+			Count: r.Count,
+		})
+	}
+
+	var categoryRows []productFacetRow
+	if err := filterProducts(db, f, productFacetCategory, "").
+		Select("cat.id AS key, cat.name AS name, COUNT(products.id) AS count").
+		Joins(fmt.Sprintf("JOIN %s AS cat ON products.category_id = cat.id", c.TableName())).
+		Group("cat.id, cat.name").
+		Order("count DESC, cat.name ASC").
+		Scan(&categoryRows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range categoryRows {
+		facets.Categories = append(facets.Categories, models.CategoryFacet{
+			ID:    r.Key,
+			Name:  r.Name,
+			Count: r.Count,
+		})
+	}
+
+	bucket := "CASE"
+	for i, max := range models.ProductPriceBuckets {
+		bucket += fmt.Sprintf(" WHEN products.price < %d THEN %d", max, i)
+	}
+	bucket += fmt.Sprintf(" ELSE %d END", len(models.ProductPriceBuckets))
+
+	var bucketRows []struct {
+		Bucket int
+		Count  int
+	}
+	if err := filterProducts(db, f, productFacetPrice, "").
+		Select(bucket + " AS bucket, COUNT(products.id) AS count").
+		Group("bucket").
+		Scan(&bucketRows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[int]int{}
+	for _, r := range bucketRows {
+		counts[r.Bucket] = r.Count
+	}
+	facets.PriceBuckets = models.NewPriceBucketFacets(counts)
+
+	return &facets, nil
+}
+
 func (pu *ProductRepositoryImpl) Delete(db *gorm.DB, storeID, productID string) error {
 	p := models.Product{}
 	if err := db.Table(p.TableName()).
@@ -159,6 +427,31 @@ func (pu *ProductRepositoryImpl) Get(db *gorm.DB, productID string) (*models.Pro
 	return &p, nil
 }
 
+func (pu *ProductRepositoryImpl) GetBySKU(db *gorm.DB, sku string) (*models.Product, error) {
+	p := models.Product{}
+	if err := db.Table(p.TableName()).
+		Where("sku = ?", sku).
+		First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListForExport returns every product of the store with all the columns of the product CSV, by SKU
+func (pu *ProductRepositoryImpl) ListForExport(db *gorm.DB, storeID string) ([]models.ProductDetailsInternal, error) {
+	var ps []models.ProductDetailsInternal
+	p := models.Product{}
+	if err := db.Table(p.TableName()).
+		Select("products.id, products.store_id, products.name, products.slug, products.description, products.sku, products.stock, products.unit, products.price, products.product_cost, products.max_quantity_count, products.is_published, products.is_shippable, products.is_digital, products.image, c.id AS category_id, c.name AS category_name, products.created_at, products.updated_at").
+		Joins("LEFT JOIN categories AS c ON products.category_id = c.id").
+		Where("products.store_id = ?", storeID).
+		Order("products.sku ASC").
+		Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
 func (pu *ProductRepositoryImpl) GetAsStoreStuff(db *gorm.DB, storeID, productID string) (*models.Product, error) {
 	p := models.Product{}
 	if err := db.Table(fmt.Sprintf("%s", p.TableName())).
@@ -176,9 +469,10 @@ func (pu *ProductRepositoryImpl) GetDetails(db *gorm.DB, productID string) (*mod
 	store := models.Store{}
 
 	if err := db.Table(fmt.Sprintf("%s", p.TableName())).
-		Select("products.id, s.id AS store_id, s.name AS store_name, products.max_quantity_count AS max_quantity_count, products.digital_download_link, products.price, products.product_cost, products.unit, products.stock, products.sku, products.name, products.slug, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at").
+		Select("products.id, s.id AS store_id, s.name AS store_name, products.max_quantity_count AS max_quantity_count, products.digital_download_link, products.price, products.product_cost, products.unit, products.stock, products.sku, products.name, products.slug, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at, "+productRatingSelection).
 		Joins(fmt.Sprintf("LEFT JOIN %s AS c ON products.category_id = c.id", cat.TableName())).
 		Joins(fmt.Sprintf("LEFT JOIN %s AS s ON products.store_id = s.id", store.TableName())).
+		Joins(productRatingJoin).
 		Where("(products.id = ? OR products.slug = ?) AND products.is_published = ?", productID, productID, true).
 		First(&ps).Error; err != nil {
 		return nil, err
@@ -202,6 +496,19 @@ func (pu *ProductRepositoryImpl) GetDetails(db *gorm.DB, productID string) (*mod
 	}
 	ps.Attributes = attributes
 
+	vu := NewProductVariantRepository()
+	options, err := vu.ListOptions(db, ps.ID)
+	if err != nil {
+		return nil, err
+	}
+	ps.Options = options
+
+	variants, err := vu.ListVariants(db, ps.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	ps.Variants = variants
+
 	additionalImages, err := pu.GetImages(db, ps.ID)
 	if err != nil {
 		return nil, err
@@ -218,9 +525,10 @@ func (pu *ProductRepositoryImpl) GetDetailsAsStoreStuff(db *gorm.DB, storeID, pr
 	store := models.Store{}
 
 	if err := db.Table(fmt.Sprintf("%s", p.TableName())).
-		Select("products.id, s.id AS store_id, s.name AS store_name, products.max_quantity_count AS max_quantity_count, products.digital_download_link, products.price, products.product_cost, products.unit, products.stock, products.sku, products.name, products.slug, products.description, products.is_published, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at").
+		Select("products.id, s.id AS store_id, s.name AS store_name, products.max_quantity_count AS max_quantity_count, products.digital_download_link, products.price, products.product_cost, products.unit, products.stock, products.sku, products.name, products.slug, products.description, products.is_published, products.unpublish_at, products.is_shippable, products.is_digital, c.id AS category_id, c.name AS category_name, products.image, products.created_at, products.updated_at, "+productRatingSelection).
 		Joins(fmt.Sprintf("LEFT JOIN %s AS c ON products.category_id = c.id", cat.TableName())).
 		Joins(fmt.Sprintf("LEFT JOIN %s AS s ON products.store_id = s.id", store.TableName())).
+		Joins(productRatingJoin).
 		Where("(products.id = ? OR products.slug = ?) AND products.store_id = ?", productID, productID, storeID).
 		First(&ps).Error; err != nil {
 
@@ -245,6 +553,19 @@ func (pu *ProductRepositoryImpl) GetDetailsAsStoreStuff(db *gorm.DB, storeID, pr
 	}
 	ps.Attributes = attributes
 
+	vu := NewProductVariantRepository()
+	options, err := vu.ListOptions(db, ps.ID)
+	if err != nil {
+		return nil, err
+	}
+	ps.Options = options
+
+	variants, err := vu.ListVariants(db, ps.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	ps.Variants = variants
+
 	additionalImages, err := pu.GetImages(db, productID)
 	if err != nil {
 		return nil, err
@@ -254,26 +575,44 @@ func (pu *ProductRepositoryImpl) GetDetailsAsStoreStuff(db *gorm.DB, storeID, pr
 	return &ps, nil
 }
 
-func (pu *ProductRepositoryImpl) GetForOrder(db *gorm.DB, productID string, quantity int) (*models.Product, error) {
+// GetForOrder returns the product, or its variant when one is given, when it has the quantity in stock.
+// Digital products and their variants have unlimited stock. The quantity is taken when the order allocates it.
+func (pu *ProductRepositoryImpl) GetForOrder(db *gorm.DB, productID string, variantID *string, quantity int) (*models.Product, *models.ProductVariant, error) {
 	p := models.Product{}
 
+	if variantID != nil {
+		return pu.getVariantForOrder(db, productID, *variantID, quantity)
+	}
+
 	if err := db.Table(p.TableName()).
 		Where("id = ? AND (stock - ? >= 0 OR is_digital)", productID, quantity).
 		Find(&p).Error; err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if p.IsDigital {
-		return &p, nil
-	}
+	return &p, nil, nil
+}
 
+func (pu *ProductRepositoryImpl) getVariantForOrder(db *gorm.DB, productID, variantID string, quantity int) (*models.Product, *models.ProductVariant, error) {
+	p := models.Product{}
 	if err := db.Table(p.TableName()).
-		Where("id = ? AND stock - ? >= 0", productID, quantity).
-		Update("stock", gorm.Expr("stock - ?", quantity)).Error; err != nil {
-		return nil, err
+		Where("id = ?", productID).
+		First(&p).Error; err != nil {
+		return nil, nil, err
 	}
 
-	return &p, nil
+	v := models.ProductVariant{}
+	if err := db.Table(v.TableName()).
+		Where("id = ? AND product_id = ? AND is_active = ?", variantID, p.ID, true).
+		First(&v).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if !p.IsDigital && v.Stock < quantity {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	return &p, &v, nil
 }
 
 func (pu *ProductRepositoryImpl) Stats(db *gorm.DB, from, limit int) ([]helpers.ProductStats, error) {
@@ -339,6 +678,14 @@ func (pu *ProductRepositoryImpl) RemoveAttribute(db *gorm.DB, productID, attribu
 	return nil
 }
 
+func (pu *ProductRepositoryImpl) RemoveAttributesByKey(db *gorm.DB, productID, key string) error {
+	v := models.ProductAttribute{}
+	if err := db.Table(v.TableName()).Delete(&v, "product_id = ? AND key = ?", productID, key).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 func (pu *ProductRepositoryImpl) ListAttributes(db *gorm.DB, productID string) (map[string][]models.ProductKV, error) {
 	v := models.ProductAttribute{}
 
@@ -446,3 +793,18 @@ func (pu *ProductRepositoryImpl) RemoveImage(db *gorm.DB, productID string) erro
 	}
 	return nil
 }
+
+// UnpublishDue unpublishes the products whose scheduled unpublish date has passed and clears the date
+func (pu *ProductRepositoryImpl) UnpublishDue(db *gorm.DB, now time.Time) error {
+	p := models.Product{}
+	if err := db.Table(p.TableName()).
+		Where("unpublish_at IS NOT NULL AND unpublish_at <= ?", now).
+		Updates(map[string]interface{}{
+			"is_published": false,
+			"unpublish_at": nil,
+			"updated_at":   now,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
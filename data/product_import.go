@@ -0,0 +1,14 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductImportRepository interface {
+	Create(db *gorm.DB, pi *models.ProductImport) error
+	Update(db *gorm.DB, pi *models.ProductImport) error
+	Get(db *gorm.DB, importID string) (*models.ProductImport, error)
+	GetAsStoreStuff(db *gorm.DB, storeID, importID string) (*models.ProductImport, error)
+	List(db *gorm.DB, storeID string, from, limit int) ([]models.ProductImport, error)
+}
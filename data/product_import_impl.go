@@ -0,0 +1,79 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductImportRepositoryImpl struct {
+}
+
+var productImportRepository ProductImportRepository
+
+func NewProductImportRepository() ProductImportRepository {
+	if productImportRepository == nil {
+		productImportRepository = &ProductImportRepositoryImpl{}
+	}
+	return productImportRepository
+}
+
+func (pir *ProductImportRepositoryImpl) Create(db *gorm.DB, pi *models.ProductImport) error {
+	if err := db.Table(pi.TableName()).Create(pi).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pir *ProductImportRepositoryImpl) Update(db *gorm.DB, pi *models.ProductImport) error {
+	if err := db.Table(pi.TableName()).
+		Where("id = ?", pi.ID).
+		Select("report_path, status, total_rows, created_rows, updated_rows, failed_rows, error, updated_at, completed_at").
+		Updates(map[string]interface{}{
+			"report_path":  pi.ReportPath,
+			"status":       pi.Status,
+			"total_rows":   pi.TotalRows,
+			"created_rows": pi.CreatedRows,
+			"updated_rows": pi.UpdatedRows,
+			"failed_rows":  pi.FailedRows,
+			"error":        pi.Error,
+			"updated_at":   pi.UpdatedAt,
+			"completed_at": pi.CompletedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pir *ProductImportRepositoryImpl) Get(db *gorm.DB, importID string) (*models.ProductImport, error) {
+	pi := models.ProductImport{}
+	if err := db.Table(pi.TableName()).
+		Where("id = ?", importID).
+		First(&pi).Error; err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+func (pir *ProductImportRepositoryImpl) GetAsStoreStuff(db *gorm.DB, storeID, importID string) (*models.ProductImport, error) {
+	pi := models.ProductImport{}
+	if err := db.Table(pi.TableName()).
+		Where("store_id = ? AND id = ?", storeID, importID).
+		First(&pi).Error; err != nil {
+		return nil, err
+	}
+	return &pi, nil
+}
+
+func (pir *ProductImportRepositoryImpl) List(db *gorm.DB, storeID string, from, limit int) ([]models.ProductImport, error) {
+	pi := models.ProductImport{}
+	var imports []models.ProductImport
+	if err := db.Table(pi.TableName()).
+		Where("store_id = ?", storeID).
+		Order("created_at DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&imports).Error; err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
@@ -0,0 +1,15 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductRecommendationRepository interface {
+	CountOrdersByProduct(db *gorm.DB) ([]models.ProductCount, error)
+	ListCoPurchases(db *gorm.DB, minOrders int) ([]models.ProductPairCount, error)
+	ListCategoryPeers(db *gorm.DB, limit int) ([]models.ProductPairCount, error)
+	ListCollectionPeers(db *gorm.DB, limit int) ([]models.ProductPairCount, error)
+	Replace(db *gorm.DB, kind models.RecommendationKind, recommendations []models.ProductRecommendation) error
+	ListRecommended(db *gorm.DB, productID string, kind models.RecommendationKind, limit int) ([]models.ProductDetails, error)
+}
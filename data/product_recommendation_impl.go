@@ -0,0 +1,150 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductRecommendationRepositoryImpl struct {
+}
+
+var productRecommendationRepository ProductRecommendationRepository
+
+func NewProductRecommendationRepository() ProductRecommendationRepository {
+	if productRecommendationRepository == nil {
+		productRecommendationRepository = &ProductRecommendationRepositoryImpl{}
+	}
+	return productRecommendationRepository
+}
+
+// purchasedOrdersJoin narrows the ordered items down to the paid orders that weren't cancelled
+const purchasedOrdersJoin = "JOIN orders AS o ON o.id = %s.order_id AND o.payment_status = '" + string(models.PaymentCompleted) +
+	"' AND o.status <> '" + string(models.OrderCancelled) + "'"
+
+// recommendableProductsJoin narrows the recommended products down to the ones published by active stores,
+// so the products that can't be shown don't take the place of the ones that can
+const recommendableProductsJoin = "JOIN products AS rp ON rp.id = %s AND rp.is_published = true " +
+	"JOIN stores AS rs ON rs.id = rp.store_id AND rs.status = '" + string(models.StoreActive) + "'"
+
+// CountOrdersByProduct counts the purchased orders every product is in
+func (ru *ProductRecommendationRepositoryImpl) CountOrdersByProduct(db *gorm.DB) ([]models.ProductCount, error) {
+	var counts []models.ProductCount
+	oi := models.OrderedItem{}
+	if err := db.Table(fmt.Sprintf("%s AS oi", oi.TableName())).
+		Select("oi.product_id, COUNT(DISTINCT oi.order_id) AS count").
+		Joins(fmt.Sprintf(purchasedOrdersJoin, "oi")).
+		Group("oi.product_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// ListCoPurchases counts the purchased orders every two products are in together, both ways, for the
+// products that can be recommended. The pairs bought together in less than minOrders orders are left out as noise.
+func (ru *ProductRecommendationRepositoryImpl) ListCoPurchases(db *gorm.DB, minOrders int) ([]models.ProductPairCount, error) {
+	var pairs []models.ProductPairCount
+	oi := models.OrderedItem{}
+	if err := db.Table(fmt.Sprintf("%s AS a", oi.TableName())).
+		Select("a.product_id, b.product_id AS other_product_id, COUNT(DISTINCT a.order_id) AS count").
+		Joins(fmt.Sprintf("JOIN %s AS b ON b.order_id = a.order_id AND b.product_id <> a.product_id", oi.TableName())).
+		Joins(fmt.Sprintf(purchasedOrdersJoin, "a")).
+		Joins(fmt.Sprintf(recommendableProductsJoin, "b.product_id")).
+		Group("a.product_id, b.product_id").
+		Having("COUNT(DISTINCT a.order_id) >= ?", minOrders).
+		Scan(&pairs).Error; err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// ListCategoryPeers pairs every product with the most viewed products of its category that can be recommended,
+// up to limit
+func (ru *ProductRecommendationRepositoryImpl) ListCategoryPeers(db *gorm.DB, limit int) ([]models.ProductPairCount, error) {
+	var pairs []models.ProductPairCount
+	p := models.Product{}
+	if err := db.Raw(fmt.Sprintf("SELECT product_id, other_product_id, 1 AS count FROM ("+
+		"SELECT a.id AS product_id, b.id AS other_product_id, ROW_NUMBER() OVER (PARTITION BY a.id ORDER BY b.views DESC, b.id) AS position "+
+		"FROM %[1]s AS a JOIN %[1]s AS b ON b.category_id = a.category_id AND b.id <> a.id %[2]s"+
+		") AS peers WHERE position <= ?", p.TableName(), fmt.Sprintf(recommendableProductsJoin, "b.id")), limit).
+		Scan(&pairs).Error; err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// ListCollectionPeers counts the collections every two products share, both ways, for the products that
+// can be recommended. Every product is paired with the ones sharing the most collections, up to limit.
+func (ru *ProductRecommendationRepositoryImpl) ListCollectionPeers(db *gorm.DB, limit int) ([]models.ProductPairCount, error) {
+	var pairs []models.ProductPairCount
+	cop := models.CollectionOfProduct{}
+	if err := db.Raw(fmt.Sprintf("SELECT product_id, other_product_id, count FROM ("+
+		"SELECT a.product_id, b.product_id AS other_product_id, COUNT(*) AS count, "+
+		"ROW_NUMBER() OVER (PARTITION BY a.product_id ORDER BY COUNT(*) DESC, b.product_id) AS position "+
+		"FROM %[1]s AS a JOIN %[1]s AS b ON b.collection_id = a.collection_id AND b.product_id <> a.product_id %[2]s "+
+		"GROUP BY a.product_id, b.product_id"+
+		") AS peers WHERE position <= ?", cop.TableName(), fmt.Sprintf(recommendableProductsJoin, "b.product_id")), limit).
+		Scan(&pairs).Error; err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// recommendationsPerInsert keeps the parameters of a single insert well below the limit of Postgres
+const recommendationsPerInsert = 500
+
+// Replace swaps the recommendations of the kind for the given ones
+func (ru *ProductRecommendationRepositoryImpl) Replace(db *gorm.DB, kind models.RecommendationKind, recommendations []models.ProductRecommendation) error {
+	pr := models.ProductRecommendation{}
+	if err := db.Table(pr.TableName()).Where("kind = ?", kind).Delete(&pr).Error; err != nil {
+		return err
+	}
+
+	for from := 0; from < len(recommendations); from += recommendationsPerInsert {
+		to := from + recommendationsPerInsert
+		if to > len(recommendations) {
+			to = len(recommendations)
+		}
+
+		var values []string
+		var args []interface{}
+		for _, r := range recommendations[from:to] {
+			values = append(values, "(?, ?, ?, ?, ?)")
+			args = append(args, r.ProductID, r.RecommendedProductID, kind, r.Score, r.ComputedAt)
+		}
+
+		q := fmt.Sprintf("INSERT INTO %s (product_id, recommended_product_id, kind, score, computed_at) VALUES %s",
+			pr.TableName(), strings.Join(values, ", "))
+		if err := db.Exec(q, args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRecommended returns the recommended products that are published by active stores, the strongest first
+func (ru *ProductRecommendationRepositoryImpl) ListRecommended(db *gorm.DB, productID string, kind models.RecommendationKind, limit int) ([]models.ProductDetails, error) {
+	ps := []models.ProductDetails{}
+	p := models.Product{}
+	pr := models.ProductRecommendation{}
+	c := models.Category{}
+	s := models.Store{}
+
+	if err := db.Table(p.TableName()).
+		Select(productListingSelection+", "+productRatingSelection).
+		Joins(fmt.Sprintf("JOIN %s AS rec ON rec.recommended_product_id = products.id", pr.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS s ON products.store_id = s.id", s.TableName())).
+		Joins(fmt.Sprintf("LEFT JOIN %s AS c ON products.category_id = c.id", c.TableName())).
+		Joins(productRatingJoin).
+		Where("rec.product_id = ? AND rec.kind = ? AND products.is_published = ? AND s.status = ?",
+			productID, kind, true, models.StoreActive).
+		Order("rec.score DESC, products.id").
+		Limit(limit).
+		Find(&ps).Error; err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
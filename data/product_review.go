@@ -0,0 +1,18 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductReviewRepository interface {
+	Create(db *gorm.DB, r *models.ProductReview) error
+	Update(db *gorm.DB, r *models.ProductReview) error
+	Get(db *gorm.DB, reviewID string) (*models.ProductReview, error)
+	GetAsStoreStuff(db *gorm.DB, storeID, reviewID string) (*models.ProductReview, error)
+	List(db *gorm.DB, productID string, status models.ReviewStatus, from, limit int) ([]models.ProductReviewDetails, error)
+	ListPublic(db *gorm.DB, productID string, from, limit int) ([]models.PublicProductReview, error)
+	ExistsForOrderedItem(db *gorm.DB, orderedItemID string) (bool, error)
+	Count(db *gorm.DB, productID string, status models.ReviewStatus) (int, error)
+	AddPhoto(db *gorm.DB, p *models.ProductReviewPhoto) error
+}
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductReviewRepositoryImpl struct {
+}
+
+var productReviewRepository ProductReviewRepository
+
+func NewProductReviewRepository() ProductReviewRepository {
+	if productReviewRepository == nil {
+		productReviewRepository = &ProductReviewRepositoryImpl{}
+	}
+	return productReviewRepository
+}
+
+func (rr *ProductReviewRepositoryImpl) Create(db *gorm.DB, r *models.ProductReview) error {
+	if err := db.Table(r.TableName()).Create(r).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update saves the moderation and the reply of the store, the review itself belongs to the buyer
+func (rr *ProductReviewRepositoryImpl) Update(db *gorm.DB, r *models.ProductReview) error {
+	if err := db.Table(r.TableName()).
+		Where("id = ?", r.ID).
+		Select("status, moderation_note, moderated_at, moderated_by_user_id, reply, replied_at, replied_by_user_id, updated_at").
+		Updates(map[string]interface{}{
+			"status":               r.Status,
+			"moderation_note":      r.ModerationNote,
+			"moderated_at":         r.ModeratedAt,
+			"moderated_by_user_id": r.ModeratedByUserID,
+			"reply":                r.Reply,
+			"replied_at":           r.RepliedAt,
+			"replied_by_user_id":   r.RepliedByUserID,
+			"updated_at":           r.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ProductReviewRepositoryImpl) Get(db *gorm.DB, reviewID string) (*models.ProductReview, error) {
+	r := models.ProductReview{}
+	if err := db.Table(r.TableName()).
+		Where("id = ?", reviewID).
+		First(&r).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (rr *ProductReviewRepositoryImpl) GetAsStoreStuff(db *gorm.DB, storeID, reviewID string) (*models.ProductReview, error) {
+	r := models.ProductReview{}
+	if err := db.Table(r.TableName()).
+		Where("store_id = ? AND id = ?", storeID, reviewID).
+		First(&r).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// filterReviews narrows the reviews down to the product and the status, empty values don't filter
+func filterReviews(db *gorm.DB, productID string, status models.ReviewStatus) *gorm.DB {
+	r := models.ProductReview{}
+
+	q := db.Table(fmt.Sprintf("%s AS pr", r.TableName()))
+	if productID != "" {
+		q = q.Where("pr.product_id = ?", productID)
+	}
+	if status != "" {
+		q = q.Where("pr.status = ?", status)
+	}
+	return q
+}
+
+// joinReviewAuthors adds the buyer and the variant bought to the reviews
+func joinReviewAuthors(q *gorm.DB) *gorm.DB {
+	u := models.User{}
+	oi := models.OrderedItem{}
+	pv := models.ProductVariant{}
+
+	return q.Joins(fmt.Sprintf("JOIN %s AS u ON pr.user_id = u.id", u.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS oi ON pr.ordered_item_id = oi.id", oi.TableName())).
+		Joins(fmt.Sprintf("LEFT JOIN %s AS pv ON oi.variant_id = pv.id", pv.TableName()))
+}
+
+// List returns the newest reviews first, with their photos
+func (rr *ProductReviewRepositoryImpl) List(db *gorm.DB, productID string, status models.ReviewStatus, from, limit int) ([]models.ProductReviewDetails, error) {
+	reviews := []models.ProductReviewDetails{}
+	if err := joinReviewAuthors(filterReviews(db, productID, status)).
+		Select("pr.*, u.name AS user_name, pv.title AS variant_title").
+		Order("pr.created_at DESC").
+		Offset(from).Limit(limit).
+		Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	var reviewIDs []string
+	for _, r := range reviews {
+		reviewIDs = append(reviewIDs, r.ID)
+	}
+
+	photos, err := listReviewPhotos(db, reviewIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		reviews[i].Photos = photos[reviews[i].ID]
+		if reviews[i].Photos == nil {
+			reviews[i].Photos = []string{}
+		}
+	}
+	return reviews, nil
+}
+
+// ListPublic returns the newest approved reviews of the product first, with only the fields shown to the public
+func (rr *ProductReviewRepositoryImpl) ListPublic(db *gorm.DB, productID string, from, limit int) ([]models.PublicProductReview, error) {
+	reviews := []models.PublicProductReview{}
+	if err := joinReviewAuthors(filterReviews(db, productID, models.ReviewApproved)).
+		Select("pr.id, pr.product_id, pr.rating, pr.title, pr.description, pr.reply, pr.replied_at, pr.created_at, " +
+			"u.name AS user_name, pv.title AS variant_title").
+		Order("pr.created_at DESC").
+		Offset(from).Limit(limit).
+		Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	var reviewIDs []string
+	for _, r := range reviews {
+		reviewIDs = append(reviewIDs, r.ID)
+	}
+
+	photos, err := listReviewPhotos(db, reviewIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		reviews[i].Photos = photos[reviews[i].ID]
+		if reviews[i].Photos == nil {
+			reviews[i].Photos = []string{}
+		}
+	}
+	return reviews, nil
+}
+
+// listReviewPhotos returns the photo paths of the reviews in order, by review
+func listReviewPhotos(db *gorm.DB, reviewIDs []string) (map[string][]string, error) {
+	photosByReview := map[string][]string{}
+	if len(reviewIDs) == 0 {
+		return photosByReview, nil
+	}
+
+	p := models.ProductReviewPhoto{}
+	var photos []models.ProductReviewPhoto
+	if err := db.Table(p.TableName()).
+		Where("review_id IN (?)", reviewIDs).
+		Order("position ASC").
+		Find(&photos).Error; err != nil {
+		return nil, err
+	}
+
+	for _, p := range photos {
+		photosByReview[p.ReviewID] = append(photosByReview[p.ReviewID], p.Path)
+	}
+	return photosByReview, nil
+}
+
+func (rr *ProductReviewRepositoryImpl) ExistsForOrderedItem(db *gorm.DB, orderedItemID string) (bool, error) {
+	r := models.ProductReview{}
+	count := 0
+	if err := db.Table(r.TableName()).
+		Where("ordered_item_id = ?", orderedItemID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (rr *ProductReviewRepositoryImpl) Count(db *gorm.DB, productID string, status models.ReviewStatus) (int, error) {
+	count := 0
+	if err := filterReviews(db, productID, status).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (rr *ProductReviewRepositoryImpl) AddPhoto(db *gorm.DB, p *models.ProductReviewPhoto) error {
+	if err := db.Table(p.TableName()).Create(p).Error; err != nil {
+		return err
+	}
+	return nil
+}
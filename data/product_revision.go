@@ -0,0 +1,22 @@
+package data
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductRevisionRepository interface {
+	Create(db *gorm.DB, r *models.ProductRevision) error
+	Update(db *gorm.DB, r *models.ProductRevision) error
+	Delete(db *gorm.DB, r *models.ProductRevision) error
+	Get(db *gorm.DB, productID, revisionID string) (*models.ProductRevision, error)
+	GetForUpdate(db *gorm.DB, productID, revisionID string) (*models.ProductRevision, error)
+	List(db *gorm.DB, productID string, from, limit int) ([]models.ProductRevision, error)
+	ListDue(db *gorm.DB, now time.Time) ([]models.ProductRevision, error)
+	NextNumber(db *gorm.DB, productID string) (int, error)
+	ArchivePublished(db *gorm.DB, productID string) error
+	SetImages(db *gorm.DB, revisionID string, images []string) error
+	GetImages(db *gorm.DB, revisionID string) ([]string, error)
+}
@@ -0,0 +1,193 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductRevisionRepositoryImpl struct {
+}
+
+var productRevisionRepository ProductRevisionRepository
+
+func NewProductRevisionRepository() ProductRevisionRepository {
+	if productRevisionRepository == nil {
+		productRevisionRepository = &ProductRevisionRepositoryImpl{}
+	}
+	return productRevisionRepository
+}
+
+func (rr *ProductRevisionRepositoryImpl) Create(db *gorm.DB, r *models.ProductRevision) error {
+	if err := db.Table(r.TableName()).Create(r).Error; err != nil {
+		return err
+	}
+	return rr.SetImages(db, r.ID, r.AdditionalImages)
+}
+
+func (rr *ProductRevisionRepositoryImpl) Update(db *gorm.DB, r *models.ProductRevision) error {
+	if err := db.Table(r.TableName()).
+		Where("id = ?", r.ID).
+		Select("status, note, name, slug, description, category_id, image, unit, price, product_cost, max_quantity_count, is_shippable, is_digital, publish_at, published_at, published_by_user_id, updated_at").
+		Updates(map[string]interface{}{
+			"status":               r.Status,
+			"note":                 r.Note,
+			"name":                 r.Name,
+			"slug":                 r.Slug,
+			"description":          r.Description,
+			"category_id":          r.CategoryID,
+			"image":                r.Image,
+			"unit":                 r.Unit,
+			"price":                r.Price,
+			"product_cost":         r.ProductCost,
+			"max_quantity_count":   r.MaxQuantityCount,
+			"is_shippable":         r.IsShippable,
+			"is_digital":           r.IsDigital,
+			"publish_at":           r.PublishAt,
+			"published_at":         r.PublishedAt,
+			"published_by_user_id": r.PublishedByUserID,
+			"updated_at":           r.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ProductRevisionRepositoryImpl) Delete(db *gorm.DB, r *models.ProductRevision) error {
+	if err := rr.SetImages(db, r.ID, nil); err != nil {
+		return err
+	}
+	if err := db.Table(r.TableName()).Where("id = ?", r.ID).Delete(r).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ProductRevisionRepositoryImpl) Get(db *gorm.DB, productID, revisionID string) (*models.ProductRevision, error) {
+	r := models.ProductRevision{}
+	if err := db.Table(r.TableName()).
+		Where("product_id = ? AND id = ?", productID, revisionID).
+		First(&r).Error; err != nil {
+		return nil, err
+	}
+
+	images, err := rr.GetImages(db, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	r.AdditionalImages = images
+	return &r, nil
+}
+
+// GetForUpdate locks the revision until the transaction ends, so it's published at most once and not
+// while the staff change it
+func (rr *ProductRevisionRepositoryImpl) GetForUpdate(db *gorm.DB, productID, revisionID string) (*models.ProductRevision, error) {
+	r := models.ProductRevision{}
+	if err := db.Table(r.TableName()).
+		Set("gorm:query_option", "FOR UPDATE").
+		Where("product_id = ? AND id = ?", productID, revisionID).
+		First(&r).Error; err != nil {
+		return nil, err
+	}
+
+	images, err := rr.GetImages(db, r.ID)
+	if err != nil {
+		return nil, err
+	}
+	r.AdditionalImages = images
+	return &r, nil
+}
+
+// List returns the revisions of the product, the latest first, without their additional images
+func (rr *ProductRevisionRepositoryImpl) List(db *gorm.DB, productID string, from, limit int) ([]models.ProductRevision, error) {
+	var revisions []models.ProductRevision
+	r := models.ProductRevision{}
+	if err := db.Table(r.TableName()).
+		Where("product_id = ?", productID).
+		Order("number DESC").
+		Offset(from).Limit(limit).
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// ListDue returns the scheduled revisions to publish by now, the earliest first
+func (rr *ProductRevisionRepositoryImpl) ListDue(db *gorm.DB, now time.Time) ([]models.ProductRevision, error) {
+	var revisions []models.ProductRevision
+	r := models.ProductRevision{}
+	if err := db.Table(r.TableName()).
+		Where("status = ? AND publish_at <= ?", models.RevisionScheduled, now).
+		Order("publish_at ASC, number ASC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (rr *ProductRevisionRepositoryImpl) NextNumber(db *gorm.DB, productID string) (int, error) {
+	var res struct {
+		Number int
+	}
+	r := models.ProductRevision{}
+	if err := db.Table(r.TableName()).
+		Select("COALESCE(MAX(number), 0) + 1 AS number").
+		Where("product_id = ?", productID).
+		Scan(&res).Error; err != nil {
+		return 0, err
+	}
+	return res.Number, nil
+}
+
+// ArchivePublished archives the revision currently published for the product
+func (rr *ProductRevisionRepositoryImpl) ArchivePublished(db *gorm.DB, productID string) error {
+	r := models.ProductRevision{}
+	if err := db.Table(r.TableName()).
+		Where("product_id = ? AND status = ?", productID, models.RevisionPublished).
+		Updates(map[string]interface{}{
+			"status":     models.RevisionArchived,
+			"updated_at": time.Now().UTC(),
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetImages replaces the additional images of the revision
+func (rr *ProductRevisionRepositoryImpl) SetImages(db *gorm.DB, revisionID string, images []string) error {
+	pri := models.ProductRevisionImage{}
+	if err := db.Table(pri.TableName()).Where("revision_id = ?", revisionID).Delete(&pri).Error; err != nil {
+		return err
+	}
+
+	for _, i := range images {
+		if strings.TrimSpace(i) == "" {
+			continue
+		}
+
+		pri := models.ProductRevisionImage{
+			RevisionID: revisionID,
+			ImagePath:  strings.TrimSpace(i),
+		}
+		if err := db.Table(pri.TableName()).Create(&pri).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rr *ProductRevisionRepositoryImpl) GetImages(db *gorm.DB, revisionID string) ([]string, error) {
+	var images []models.ProductRevisionImage
+	pri := models.ProductRevisionImage{}
+	if err := db.Table(pri.TableName()).Where("revision_id = ?", revisionID).Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, i := range images {
+		paths = append(paths, i.ImagePath)
+	}
+	return paths, nil
+}
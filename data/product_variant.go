@@ -0,0 +1,26 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductVariantRepository interface {
+	CreateOption(db *gorm.DB, o *models.ProductOption) error
+	GetOption(db *gorm.DB, productID, optionID string) (*models.ProductOption, error)
+	DeleteOption(db *gorm.DB, productID, optionID string) error
+	CountOptions(db *gorm.DB, productID string) (int, error)
+	ListOptions(db *gorm.DB, productID string) ([]models.ProductOptionDetails, error)
+	CreateOptionValue(db *gorm.DB, v *models.ProductOptionValue) error
+	GetOptionValue(db *gorm.DB, optionID, valueID string) (*models.ProductOptionValue, error)
+	DeleteOptionValue(db *gorm.DB, optionID, valueID string) error
+	DetachOptionValues(db *gorm.DB, optionValueIDs []string) error
+	CreateVariant(db *gorm.DB, v *models.ProductVariant, optionValueIDs []string) error
+	UpdateVariant(db *gorm.DB, v *models.ProductVariant) error
+	SetVariantActive(db *gorm.DB, variantID string, isActive bool) error
+	GetVariant(db *gorm.DB, productID, variantID string) (*models.ProductVariant, error)
+	ListVariants(db *gorm.DB, productID string, activeOnly bool) ([]models.ProductVariantDetails, error)
+	ListVariantOptions(db *gorm.DB, variantID string) ([]models.ProductVariantOption, error)
+	HasActiveVariants(db *gorm.DB, productID string) (bool, error)
+	ListSKUsByPrefix(db *gorm.DB, prefix string) ([]string, error)
+}
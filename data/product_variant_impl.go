@@ -0,0 +1,328 @@
+package data
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ProductVariantRepositoryImpl struct {
+}
+
+var productVariantRepository ProductVariantRepository
+
+func NewProductVariantRepository() ProductVariantRepository {
+	if productVariantRepository == nil {
+		productVariantRepository = &ProductVariantRepositoryImpl{}
+	}
+	return productVariantRepository
+}
+
+func (pvr *ProductVariantRepositoryImpl) CreateOption(db *gorm.DB, o *models.ProductOption) error {
+	if err := db.Table(o.TableName()).Create(o).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) GetOption(db *gorm.DB, productID, optionID string) (*models.ProductOption, error) {
+	o := models.ProductOption{}
+	if err := db.Table(o.TableName()).
+		Where("product_id = ? AND id = ?", productID, optionID).
+		First(&o).Error; err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// DeleteOption deletes the option with its values, the values must be detached from the variants first
+func (pvr *ProductVariantRepositoryImpl) DeleteOption(db *gorm.DB, productID, optionID string) error {
+	v := models.ProductOptionValue{}
+	if err := db.Table(v.TableName()).
+		Where("option_id = ?", optionID).
+		Delete(&v).Error; err != nil {
+		return err
+	}
+
+	o := models.ProductOption{}
+	if err := db.Table(o.TableName()).
+		Where("product_id = ? AND id = ?", productID, optionID).
+		Delete(&o).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) CountOptions(db *gorm.DB, productID string) (int, error) {
+	o := models.ProductOption{}
+	count := 0
+	if err := db.Table(o.TableName()).
+		Where("product_id = ?", productID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListOptions returns the options of the product with their values, both in position order
+func (pvr *ProductVariantRepositoryImpl) ListOptions(db *gorm.DB, productID string) ([]models.ProductOptionDetails, error) {
+	o := models.ProductOption{}
+	var options []models.ProductOption
+	if err := db.Table(o.TableName()).
+		Where("product_id = ?", productID).
+		Order("position ASC, name ASC").
+		Find(&options).Error; err != nil {
+		return nil, err
+	}
+
+	v := models.ProductOptionValue{}
+	var values []models.ProductOptionValue
+	if err := db.Table(fmt.Sprintf("%s AS pov", v.TableName())).
+		Select("pov.*").
+		Joins(fmt.Sprintf("JOIN %s AS po ON pov.option_id = po.id", o.TableName())).
+		Where("po.product_id = ?", productID).
+		Order("pov.position ASC, pov.value ASC").
+		Find(&values).Error; err != nil {
+		return nil, err
+	}
+
+	details := []models.ProductOptionDetails{}
+	for _, op := range options {
+		d := models.ProductOptionDetails{
+			ProductOption: op,
+			Values:        []models.ProductOptionValue{},
+		}
+		for _, val := range values {
+			if val.OptionID == op.ID {
+				d.Values = append(d.Values, val)
+			}
+		}
+		details = append(details, d)
+	}
+	return details, nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) CreateOptionValue(db *gorm.DB, v *models.ProductOptionValue) error {
+	if err := db.Table(v.TableName()).Create(v).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) GetOptionValue(db *gorm.DB, optionID, valueID string) (*models.ProductOptionValue, error) {
+	v := models.ProductOptionValue{}
+	if err := db.Table(v.TableName()).
+		Where("option_id = ? AND id = ?", optionID, valueID).
+		First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// DeleteOptionValue deletes the value, it must be detached from the variants first
+func (pvr *ProductVariantRepositoryImpl) DeleteOptionValue(db *gorm.DB, optionID, valueID string) error {
+	v := models.ProductOptionValue{}
+	if err := db.Table(v.TableName()).
+		Where("option_id = ? AND id = ?", optionID, valueID).
+		Delete(&v).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// DetachOptionValues deactivates the variants made of any of the option values and unlinks the values from them.
+// The variants keep their title, SKU and stock as ordered items reference them.
+func (pvr *ProductVariantRepositoryImpl) DetachOptionValues(db *gorm.DB, optionValueIDs []string) error {
+	if len(optionValueIDs) == 0 {
+		return nil
+	}
+
+	pv := models.ProductVariant{}
+	pvov := models.ProductVariantOptionValue{}
+
+	if err := db.Table(pv.TableName()).
+		Where(fmt.Sprintf("id IN (SELECT variant_id FROM %s WHERE option_value_id IN (?))", pvov.TableName()), optionValueIDs).
+		Updates(map[string]interface{}{
+			"is_active":  false,
+			"updated_at": time.Now().UTC(),
+		}).Error; err != nil {
+		return err
+	}
+
+	if err := db.Table(pvov.TableName()).
+		Where("option_value_id IN (?)", optionValueIDs).
+		Delete(&pvov).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) CreateVariant(db *gorm.DB, v *models.ProductVariant, optionValueIDs []string) error {
+	if err := db.Table(v.TableName()).Create(v).Error; err != nil {
+		return err
+	}
+
+	for _, id := range optionValueIDs {
+		pvov := models.ProductVariantOptionValue{
+			VariantID:     v.ID,
+			OptionValueID: id,
+		}
+		if err := db.Table(pvov.TableName()).Create(&pvov).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateVariant leaves the stock out, it only changes through the stock adjustments
+func (pvr *ProductVariantRepositoryImpl) UpdateVariant(db *gorm.DB, v *models.ProductVariant) error {
+	if err := db.Table(v.TableName()).
+		Where("product_id = ? AND id = ?", v.ProductID, v.ID).
+		Select("sku, price, weight, image, updated_at").
+		Updates(map[string]interface{}{
+			"sku":        v.SKU,
+			"price":      v.Price,
+			"weight":     v.Weight,
+			"image":      v.Image,
+			"updated_at": v.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) SetVariantActive(db *gorm.DB, variantID string, isActive bool) error {
+	v := models.ProductVariant{}
+	if err := db.Table(v.TableName()).
+		Where("id = ?", variantID).
+		Updates(map[string]interface{}{
+			"is_active":  isActive,
+			"updated_at": time.Now().UTC(),
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) GetVariant(db *gorm.DB, productID, variantID string) (*models.ProductVariant, error) {
+	v := models.ProductVariant{}
+	if err := db.Table(v.TableName()).
+		Where("product_id = ? AND id = ?", productID, variantID).
+		First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+type productVariantOptionRow struct {
+	VariantID     string
+	OptionID      string
+	Option        string
+	OptionValueID string
+	Value         string
+}
+
+func (pvr *ProductVariantRepositoryImpl) listVariantOptionRows(db *gorm.DB, where string, args ...interface{}) ([]productVariantOptionRow, error) {
+	pv := models.ProductVariant{}
+	pvov := models.ProductVariantOptionValue{}
+	pov := models.ProductOptionValue{}
+	po := models.ProductOption{}
+
+	var rows []productVariantOptionRow
+	if err := db.Table(fmt.Sprintf("%s AS pvov", pvov.TableName())).
+		Select("pvov.variant_id AS variant_id, po.id AS option_id, po.name AS option, pov.id AS option_value_id, pov.value AS value").
+		Joins(fmt.Sprintf("JOIN %s AS pv ON pvov.variant_id = pv.id", pv.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS pov ON pvov.option_value_id = pov.id", pov.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS po ON pov.option_id = po.id", po.TableName())).
+		Where(where, args...).
+		Order("po.position ASC, po.name ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ListVariants returns the variants of the product with their option values, in creation order
+func (pvr *ProductVariantRepositoryImpl) ListVariants(db *gorm.DB, productID string, activeOnly bool) ([]models.ProductVariantDetails, error) {
+	v := models.ProductVariant{}
+
+	q := db.Table(v.TableName()).Where("product_id = ?", productID)
+	if activeOnly {
+		q = q.Where("is_active = ?", true)
+	}
+
+	var variants []models.ProductVariant
+	if err := q.Order("created_at ASC, title ASC").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+
+	rows, err := pvr.listVariantOptionRows(db, "pv.product_id = ?", productID)
+	if err != nil {
+		return nil, err
+	}
+
+	details := []models.ProductVariantDetails{}
+	for _, pv := range variants {
+		d := models.ProductVariantDetails{
+			ProductVariant: pv,
+			Options:        []models.ProductVariantOption{},
+		}
+		for _, r := range rows {
+			if r.VariantID == pv.ID {
+				d.Options = append(d.Options, models.ProductVariantOption{
+					OptionID:      r.OptionID,
+					Option:        r.Option,
+					OptionValueID: r.OptionValueID,
+					Value:         r.Value,
+				})
+			}
+		}
+		details = append(details, d)
+	}
+	return details, nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) ListVariantOptions(db *gorm.DB, variantID string) ([]models.ProductVariantOption, error) {
+	rows, err := pvr.listVariantOptionRows(db, "pv.id = ?", variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []models.ProductVariantOption
+	for _, r := range rows {
+		options = append(options, models.ProductVariantOption{
+			OptionID:      r.OptionID,
+			Option:        r.Option,
+			OptionValueID: r.OptionValueID,
+			Value:         r.Value,
+		})
+	}
+	return options, nil
+}
+
+func (pvr *ProductVariantRepositoryImpl) HasActiveVariants(db *gorm.DB, productID string) (bool, error) {
+	v := models.ProductVariant{}
+	count := 0
+	if err := db.Table(v.TableName()).
+		Where("product_id = ? AND is_active = ?", productID, true).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ListSKUsByPrefix returns the SKUs of the variants of every product starting with the prefix, SKUs are unique
+// across the products
+func (pvr *ProductVariantRepositoryImpl) ListSKUsByPrefix(db *gorm.DB, prefix string) ([]string, error) {
+	v := models.ProductVariant{}
+	var skus []string
+	if err := db.Table(v.TableName()).
+		Where("SUBSTR(sku, 1, ?) = ?", utf8.RuneCountInString(prefix), prefix).
+		Pluck("sku", &skus).Error; err != nil {
+		return nil, err
+	}
+	return skus, nil
+}
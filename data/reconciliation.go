@@ -0,0 +1,19 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ReconciliationRepository interface {
+	CreateReport(db *gorm.DB, r *models.ReconciliationReport) error
+	UpdateReport(db *gorm.DB, r *models.ReconciliationReport) error
+	ListReports(db *gorm.DB, from, limit int) ([]models.ReconciliationReport, error)
+	GetReport(db *gorm.DB, reportID string) (*models.ReconciliationReport, error)
+
+	CreateDiscrepancy(db *gorm.DB, d *models.PaymentDiscrepancy) error
+	HasDiscrepancy(db *gorm.DB, d *models.PaymentDiscrepancy) (bool, error)
+	ListDiscrepancies(db *gorm.DB, reportID string) ([]models.PaymentDiscrepancy, error)
+	GetDiscrepancy(db *gorm.DB, discrepancyID string) (*models.PaymentDiscrepancy, error)
+	ResolveDiscrepancy(db *gorm.DB, d *models.PaymentDiscrepancy) error
+}
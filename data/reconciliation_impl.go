@@ -0,0 +1,121 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type ReconciliationRepositoryImpl struct {
+}
+
+var reconciliationRepository ReconciliationRepository
+
+func NewReconciliationRepository() ReconciliationRepository {
+	if reconciliationRepository == nil {
+		reconciliationRepository = &ReconciliationRepositoryImpl{}
+	}
+	return reconciliationRepository
+}
+
+func (rr *ReconciliationRepositoryImpl) CreateReport(db *gorm.DB, r *models.ReconciliationReport) error {
+	if err := db.Table(r.TableName()).Create(r).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ReconciliationRepositoryImpl) UpdateReport(db *gorm.DB, r *models.ReconciliationReport) error {
+	if err := db.Table(r.TableName()).
+		Where("id = ?", r.ID).
+		Select("status, total_transactions, total_discrepancies, failure_reason, finished_at").
+		Updates(map[string]interface{}{
+			"status":              r.Status,
+			"total_transactions":  r.TotalTransactions,
+			"total_discrepancies": r.TotalDiscrepancies,
+			"failure_reason":      r.FailureReason,
+			"finished_at":         r.FinishedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *ReconciliationRepositoryImpl) ListReports(db *gorm.DB, from, limit int) ([]models.ReconciliationReport, error) {
+	r := models.ReconciliationReport{}
+	var reports []models.ReconciliationReport
+	if err := db.Table(r.TableName()).
+		Order("started_at DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (rr *ReconciliationRepositoryImpl) GetReport(db *gorm.DB, reportID string) (*models.ReconciliationReport, error) {
+	r := models.ReconciliationReport{}
+	if err := db.Table(r.TableName()).
+		First(&r, "id = ?", reportID).Error; err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (rr *ReconciliationRepositoryImpl) CreateDiscrepancy(db *gorm.DB, d *models.PaymentDiscrepancy) error {
+	if err := db.Table(d.TableName()).Create(d).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// HasDiscrepancy tells whether the same discrepancy of the transaction and the order was reported before
+func (rr *ReconciliationRepositoryImpl) HasDiscrepancy(db *gorm.DB, d *models.PaymentDiscrepancy) (bool, error) {
+	q := db.Table(d.TableName()).
+		Where("payment_gateway = ? AND transaction_id = ? AND type = ?", d.PaymentGateway, d.TransactionID, d.Type)
+	if d.OrderID != nil {
+		q = q.Where("order_id = ?", *d.OrderID)
+	} else {
+		q = q.Where("order_id IS NULL")
+	}
+
+	count := 0
+	if err := q.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (rr *ReconciliationRepositoryImpl) ListDiscrepancies(db *gorm.DB, reportID string) ([]models.PaymentDiscrepancy, error) {
+	d := models.PaymentDiscrepancy{}
+	var discrepancies []models.PaymentDiscrepancy
+	if err := db.Table(d.TableName()).
+		Order("created_at ASC").
+		Find(&discrepancies, "report_id = ?", reportID).Error; err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
+
+func (rr *ReconciliationRepositoryImpl) GetDiscrepancy(db *gorm.DB, discrepancyID string) (*models.PaymentDiscrepancy, error) {
+	d := models.PaymentDiscrepancy{}
+	if err := db.Table(d.TableName()).
+		First(&d, "id = ?", discrepancyID).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (rr *ReconciliationRepositoryImpl) ResolveDiscrepancy(db *gorm.DB, d *models.PaymentDiscrepancy) error {
+	if err := db.Table(d.TableName()).
+		Where("id = ?", d.ID).
+		Select("is_resolved, resolved_by_user_id, resolved_at").
+		Updates(map[string]interface{}{
+			"is_resolved":         d.IsResolved,
+			"resolved_by_user_id": d.ResolvedByUserID,
+			"resolved_at":         d.ResolvedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type SellerStatementRepository interface {
+	Create(db *gorm.DB, s *models.SellerStatement) error
+	List(db *gorm.DB, storeID string, from, limit int) ([]models.SellerStatement, error)
+	Get(db *gorm.DB, storeID, statementID string) (*models.SellerStatement, error)
+	Exists(db *gorm.DB, storeID string, periodStart time.Time) (bool, error)
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type SellerStatementRepositoryImpl struct {
+}
+
+var sellerStatementRepository SellerStatementRepository
+
+func NewSellerStatementRepository() SellerStatementRepository {
+	if sellerStatementRepository == nil {
+		sellerStatementRepository = &SellerStatementRepositoryImpl{}
+	}
+	return sellerStatementRepository
+}
+
+func (sr *SellerStatementRepositoryImpl) Create(db *gorm.DB, s *models.SellerStatement) error {
+	if err := db.Table(s.TableName()).Create(s).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (sr *SellerStatementRepositoryImpl) List(db *gorm.DB, storeID string, from, limit int) ([]models.SellerStatement, error) {
+	s := models.SellerStatement{}
+	var statements []models.SellerStatement
+	if err := db.Table(s.TableName()).
+		Where("store_id = ?", storeID).
+		Order("period_start DESC").
+		Offset(from).
+		Limit(limit).
+		Find(&statements).Error; err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
+func (sr *SellerStatementRepositoryImpl) Get(db *gorm.DB, storeID, statementID string) (*models.SellerStatement, error) {
+	s := models.SellerStatement{}
+	if err := db.Table(s.TableName()).
+		Where("store_id = ? AND id = ?", storeID, statementID).
+		First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (sr *SellerStatementRepositoryImpl) Exists(db *gorm.DB, storeID string, periodStart time.Time) (bool, error) {
+	s := models.SellerStatement{}
+	count := 0
+	if err := db.Table(s.TableName()).
+		Where("store_id = ? AND period_start = ?", storeID, periodStart).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
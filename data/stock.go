@@ -0,0 +1,29 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type StockRepository interface {
+	CreateLocation(db *gorm.DB, l *models.StockLocation) error
+	CreateLocationIfMissing(db *gorm.DB, l *models.StockLocation) (bool, error)
+	UpdateLocation(db *gorm.DB, l *models.StockLocation) error
+	SetDefaultLocation(db *gorm.DB, storeID, locationID string) error
+	GetLocation(db *gorm.DB, storeID, locationID string) (*models.StockLocation, error)
+	GetLocationByCode(db *gorm.DB, storeID, code string) (*models.StockLocation, error)
+	GetDefaultLocation(db *gorm.DB, storeID string) (*models.StockLocation, error)
+	ListLocations(db *gorm.DB, storeID string) ([]models.StockLocation, error)
+	CountLocationStock(db *gorm.DB, locationID string) (int, error)
+	SeedLevels(db *gorm.DB, storeID, locationID string) error
+	AdjustLevel(db *gorm.DB, locationID, productID string, variantID *string, quantity int) (int, error)
+	SetLevel(db *gorm.DB, locationID, productID string, variantID *string, quantity int) (int, error)
+	GetLevel(db *gorm.DB, locationID, productID string, variantID *string) (*models.StockLevel, error)
+	ListLevelsByProduct(db *gorm.DB, productID string) ([]models.StockLevelDetails, error)
+	ListLevelsByLocation(db *gorm.DB, locationID string, from, limit int) ([]models.StockLevelDetails, error)
+	ListAllocationLocations(db *gorm.DB, storeID, productID string, variantID *string, quantity int) ([]models.StockLocation, error)
+	AddToTotal(db *gorm.DB, productID string, variantID *string, quantity int) error
+	FindSKU(db *gorm.DB, storeID, sku string) (*models.StockItem, error)
+	CreateAdjustment(db *gorm.DB, a *models.StockAdjustment) error
+	ListAdjustments(db *gorm.DB, storeID, productID string, from, limit int) ([]models.StockAdjustment, error)
+}
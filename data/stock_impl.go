@@ -0,0 +1,368 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type StockRepositoryImpl struct {
+}
+
+var stockRepository StockRepository
+
+func NewStockRepository() StockRepository {
+	if stockRepository == nil {
+		stockRepository = &StockRepositoryImpl{}
+	}
+	return stockRepository
+}
+
+// stockVariantKey matches the levels and adjustments of the variant, or of the product itself when nil
+func stockVariantKey(variantID *string) string {
+	if variantID == nil {
+		return ""
+	}
+	return *variantID
+}
+
+func (su *StockRepositoryImpl) CreateLocation(db *gorm.DB, l *models.StockLocation) error {
+	if err := db.Table(l.TableName()).Create(l).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateLocationIfMissing saves the location unless the store has one with the same code, and tells whether it did.
+// Concurrent creations of the same location wait for each other.
+func (su *StockRepositoryImpl) CreateLocationIfMissing(db *gorm.DB, l *models.StockLocation) (bool, error) {
+	q := db.Exec(fmt.Sprintf("INSERT INTO %s (id, store_id, name, code, address, is_default, is_active, created_at, updated_at) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (store_id, code) DO NOTHING", l.TableName()),
+		l.ID, l.StoreID, l.Name, l.Code, l.Address, l.IsDefault, l.IsActive, l.CreatedAt, l.UpdatedAt)
+	if q.Error != nil {
+		return false, q.Error
+	}
+	return q.RowsAffected > 0, nil
+}
+
+func (su *StockRepositoryImpl) UpdateLocation(db *gorm.DB, l *models.StockLocation) error {
+	if err := db.Table(l.TableName()).
+		Where("store_id = ? AND id = ?", l.StoreID, l.ID).
+		Select("name, code, address, is_active, updated_at").
+		Updates(map[string]interface{}{
+			"name":       l.Name,
+			"code":       l.Code,
+			"address":    l.Address,
+			"is_active":  l.IsActive,
+			"updated_at": l.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetDefaultLocation makes the location the default one of the store, the previous default is unset
+func (su *StockRepositoryImpl) SetDefaultLocation(db *gorm.DB, storeID, locationID string) error {
+	l := models.StockLocation{}
+	if err := db.Table(l.TableName()).
+		Where("store_id = ?", storeID).
+		UpdateColumn("is_default", gorm.Expr("id = ?", locationID)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (su *StockRepositoryImpl) GetLocation(db *gorm.DB, storeID, locationID string) (*models.StockLocation, error) {
+	l := models.StockLocation{}
+	if err := db.Table(l.TableName()).
+		Where("store_id = ? AND id = ?", storeID, locationID).
+		First(&l).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (su *StockRepositoryImpl) GetLocationByCode(db *gorm.DB, storeID, code string) (*models.StockLocation, error) {
+	l := models.StockLocation{}
+	if err := db.Table(l.TableName()).
+		Where("store_id = ? AND code = ?", storeID, code).
+		First(&l).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (su *StockRepositoryImpl) GetDefaultLocation(db *gorm.DB, storeID string) (*models.StockLocation, error) {
+	l := models.StockLocation{}
+	if err := db.Table(l.TableName()).
+		Where("store_id = ? AND is_default = ?", storeID, true).
+		First(&l).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (su *StockRepositoryImpl) ListLocations(db *gorm.DB, storeID string) ([]models.StockLocation, error) {
+	var locations []models.StockLocation
+	l := models.StockLocation{}
+	if err := db.Table(l.TableName()).
+		Where("store_id = ?", storeID).
+		Order("is_default DESC, name ASC").
+		Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// CountLocationStock returns the quantity of all the products kept at the location
+func (su *StockRepositoryImpl) CountLocationStock(db *gorm.DB, locationID string) (int, error) {
+	sl := models.StockLevel{}
+
+	var res struct {
+		Total int
+	}
+	if err := db.Table(sl.TableName()).
+		Select("COALESCE(SUM(quantity), 0) AS total").
+		Where("location_id = ?", locationID).
+		Scan(&res).Error; err != nil {
+		return 0, err
+	}
+	return res.Total, nil
+}
+
+// SeedLevels puts the stock the products and their variants have at the location, for the first location of a store
+func (su *StockRepositoryImpl) SeedLevels(db *gorm.DB, storeID, locationID string) error {
+	sl := models.StockLevel{}
+	p := models.Product{}
+	pv := models.ProductVariant{}
+
+	now := time.Now().UTC()
+
+	if err := db.Exec(fmt.Sprintf("INSERT INTO %s (location_id, product_id, variant_id, quantity, updated_at) "+
+		"SELECT ?, id, NULL, stock, ? FROM %s WHERE store_id = ? AND stock > 0 AND NOT is_digital "+
+		"UNION ALL "+
+		"SELECT ?, pv.product_id, pv.id, pv.stock, ? FROM %s AS pv JOIN %s AS p ON p.id = pv.product_id "+
+		"WHERE p.store_id = ? AND pv.stock > 0 AND NOT p.is_digital",
+		sl.TableName(), p.TableName(), pv.TableName(), p.TableName()),
+		locationID, now, storeID, locationID, now, storeID).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// AdjustLevel adds the quantity to the level and returns the quantity left. Taking more than the level holds
+// is reported as not found, like taking from a missing level.
+func (su *StockRepositoryImpl) AdjustLevel(db *gorm.DB, locationID, productID string, variantID *string, quantity int) (int, error) {
+	sl := models.StockLevel{}
+
+	now := time.Now().UTC()
+
+	q := db.Table(sl.TableName()).
+		Where("location_id = ? AND product_id = ? AND COALESCE(variant_id, '') = ? AND quantity + ? >= 0",
+			locationID, productID, stockVariantKey(variantID), quantity).
+		UpdateColumns(map[string]interface{}{
+			"quantity":   gorm.Expr("quantity + ?", quantity),
+			"updated_at": now,
+		})
+	if q.Error != nil {
+		return 0, q.Error
+	}
+
+	if q.RowsAffected == 0 {
+		if quantity < 0 {
+			return 0, gorm.ErrRecordNotFound
+		}
+
+		sl = models.StockLevel{
+			LocationID: locationID,
+			ProductID:  productID,
+			VariantID:  variantID,
+			Quantity:   quantity,
+			UpdatedAt:  now,
+		}
+		if err := db.Table(sl.TableName()).Create(&sl).Error; err != nil {
+			return 0, err
+		}
+		return sl.Quantity, nil
+	}
+
+	l, err := su.GetLevel(db, locationID, productID, variantID)
+	if err != nil {
+		return 0, err
+	}
+	return l.Quantity, nil
+}
+
+// SetLevel sets the quantity of the level and returns the difference with the quantity it had, missing levels
+// had none. The level is locked while set, so the allocations of orders in between aren't overwritten unseen.
+func (su *StockRepositoryImpl) SetLevel(db *gorm.DB, locationID, productID string, variantID *string, quantity int) (int, error) {
+	sl := models.StockLevel{}
+
+	now := time.Now().UTC()
+
+	for {
+		var diff int
+		err := db.Raw(fmt.Sprintf("UPDATE %[1]s AS l SET quantity = ?, updated_at = ? FROM ("+
+			"SELECT location_id, product_id, variant_id, quantity FROM %[1]s "+
+			"WHERE location_id = ? AND product_id = ? AND COALESCE(variant_id, '') = ? FOR UPDATE) AS old "+
+			"WHERE l.location_id = old.location_id AND l.product_id = old.product_id "+
+			"AND COALESCE(l.variant_id, '') = COALESCE(old.variant_id, '') "+
+			"RETURNING l.quantity - old.quantity", sl.TableName()),
+			quantity, now, locationID, productID, stockVariantKey(variantID)).
+			Row().Scan(&diff)
+		if err == nil {
+			return diff, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, err
+		}
+
+		if quantity == 0 {
+			return 0, nil
+		}
+
+		// A level created at the same time is set on the next round
+		q := db.Exec(fmt.Sprintf("INSERT INTO %s (location_id, product_id, variant_id, quantity, updated_at) "+
+			"VALUES (?, ?, ?, ?, ?) ON CONFLICT (location_id, product_id, COALESCE(variant_id, '')) DO NOTHING", sl.TableName()),
+			locationID, productID, variantID, quantity, now)
+		if q.Error != nil {
+			return 0, q.Error
+		}
+		if q.RowsAffected > 0 {
+			return quantity, nil
+		}
+	}
+}
+
+func (su *StockRepositoryImpl) GetLevel(db *gorm.DB, locationID, productID string, variantID *string) (*models.StockLevel, error) {
+	sl := models.StockLevel{}
+	if err := db.Table(sl.TableName()).
+		Where("location_id = ? AND product_id = ? AND COALESCE(variant_id, '') = ?", locationID, productID, stockVariantKey(variantID)).
+		First(&sl).Error; err != nil {
+		return nil, err
+	}
+	return &sl, nil
+}
+
+const stockLevelSelection = "l.location_id, loc.name AS location_name, loc.code AS location_code, l.product_id, " +
+	"p.name AS product_name, l.variant_id, pv.title AS variant_title, COALESCE(pv.sku, p.sku) AS sku, l.quantity, l.updated_at"
+
+func stockLevelJoins(db *gorm.DB) *gorm.DB {
+	sl := models.StockLevel{}
+	loc := models.StockLocation{}
+	p := models.Product{}
+	pv := models.ProductVariant{}
+
+	return db.Table(fmt.Sprintf("%s AS l", sl.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS loc ON loc.id = l.location_id", loc.TableName())).
+		Joins(fmt.Sprintf("JOIN %s AS p ON p.id = l.product_id", p.TableName())).
+		Joins(fmt.Sprintf("LEFT JOIN %s AS pv ON pv.id = l.variant_id", pv.TableName()))
+}
+
+func (su *StockRepositoryImpl) ListLevelsByProduct(db *gorm.DB, productID string) ([]models.StockLevelDetails, error) {
+	var levels []models.StockLevelDetails
+	if err := stockLevelJoins(db).
+		Select(stockLevelSelection).
+		Where("l.product_id = ?", productID).
+		Order("loc.is_default DESC, loc.name ASC, pv.title ASC").
+		Scan(&levels).Error; err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+func (su *StockRepositoryImpl) ListLevelsByLocation(db *gorm.DB, locationID string, from, limit int) ([]models.StockLevelDetails, error) {
+	var levels []models.StockLevelDetails
+	if err := stockLevelJoins(db).
+		Select(stockLevelSelection).
+		Where("l.location_id = ?", locationID).
+		Order("p.name ASC, pv.title ASC").
+		Offset(from).Limit(limit).
+		Scan(&levels).Error; err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// ListAllocationLocations returns the active locations of the store holding the quantity of the product
+// or the variant, the default location first then the ones holding the most
+func (su *StockRepositoryImpl) ListAllocationLocations(db *gorm.DB, storeID, productID string, variantID *string, quantity int) ([]models.StockLocation, error) {
+	var locations []models.StockLocation
+	loc := models.StockLocation{}
+	sl := models.StockLevel{}
+	if err := db.Table(fmt.Sprintf("%s AS loc", loc.TableName())).
+		Select("loc.*").
+		Joins(fmt.Sprintf("JOIN %s AS l ON l.location_id = loc.id", sl.TableName())).
+		Where("loc.store_id = ? AND loc.is_active = ? AND l.product_id = ? AND COALESCE(l.variant_id, '') = ? AND l.quantity >= ?",
+			storeID, true, productID, stockVariantKey(variantID), quantity).
+		Order("loc.is_default DESC, l.quantity DESC").
+		Find(&locations).Error; err != nil {
+		return nil, err
+	}
+	return locations, nil
+}
+
+// AddToTotal adds the quantity to the stock of the variant, or of the product when the variant is nil
+func (su *StockRepositoryImpl) AddToTotal(db *gorm.DB, productID string, variantID *string, quantity int) error {
+	if variantID != nil {
+		pv := models.ProductVariant{}
+		if err := db.Table(pv.TableName()).
+			Where("product_id = ? AND id = ?", productID, *variantID).
+			UpdateColumn("stock", gorm.Expr("stock + ?", quantity)).Error; err != nil {
+			return err
+		}
+		return nil
+	}
+
+	p := models.Product{}
+	if err := db.Table(p.TableName()).
+		Where("id = ?", productID).
+		UpdateColumn("stock", gorm.Expr("stock + ?", quantity)).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindSKU returns the product or the variant of the store having the SKU
+func (su *StockRepositoryImpl) FindSKU(db *gorm.DB, storeID, sku string) (*models.StockItem, error) {
+	p := models.Product{}
+	pv := models.ProductVariant{}
+
+	var items []models.StockItem
+	if err := db.Raw(fmt.Sprintf("SELECT id AS product_id, CAST(NULL AS text) AS variant_id, sku, is_digital FROM %s "+
+		"WHERE store_id = ? AND sku = ? "+
+		"UNION ALL "+
+		"SELECT pv.product_id, pv.id, pv.sku, p.is_digital FROM %s AS pv JOIN %s AS p ON p.id = pv.product_id "+
+		"WHERE p.store_id = ? AND pv.sku = ?", p.TableName(), pv.TableName(), p.TableName()),
+		storeID, sku, storeID, sku).
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &items[0], nil
+}
+
+func (su *StockRepositoryImpl) CreateAdjustment(db *gorm.DB, a *models.StockAdjustment) error {
+	if err := db.Table(a.TableName()).Create(a).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (su *StockRepositoryImpl) ListAdjustments(db *gorm.DB, storeID, productID string, from, limit int) ([]models.StockAdjustment, error) {
+	var adjustments []models.StockAdjustment
+	a := models.StockAdjustment{}
+	if err := db.Table(a.TableName()).
+		Where("store_id = ? AND product_id = ?", storeID, productID).
+		Order("created_at DESC").
+		Offset(from).Limit(limit).
+		Find(&adjustments).Error; err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
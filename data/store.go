@@ -12,11 +12,13 @@ type StoreRepository interface {
 	FindByID(db *gorm.DB, ID string) (*models.StoreView, error)
 	AddStoreStuff(db *gorm.DB, staff *models.Staff) error
 	ListStaffs(db *gorm.DB, storeID string, from, limit int) ([]models.StaffProfile, error)
+	GetStoreCreator(db *gorm.DB, storeID string) (*models.StaffProfile, error)
 	SearchStaffs(db *gorm.DB, storeID, query string, from, limit int) ([]models.StaffProfile, error)
 	UpdateStoreStuffPermission(db *gorm.DB, staff *models.Staff) error
 	DeleteStoreStuffPermission(db *gorm.DB, storeID, userID string) error
 	IsAlreadyStaff(db *gorm.DB, userID string) (bool, error)
 	List(db *gorm.DB, from, limit int) ([]models.Store, error)
+	ListIDs(db *gorm.DB) ([]string, error)
 	Search(db *gorm.DB, query string, from, limit int) ([]models.Store, error)
 	UpdateStoreStatus(db *gorm.DB, s *models.Store) error
 	UpdateStore(db *gorm.DB, s *models.Store) error
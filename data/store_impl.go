@@ -118,6 +118,23 @@ func (su *StoreRepositoryImpl) ListStaffs(db *gorm.DB, storeID string, from, lim
 	return sup, nil
 }
 
+func (su *StoreRepositoryImpl) GetStoreCreator(db *gorm.DB, storeID string) (*models.StaffProfile, error) {
+	sup := models.StaffProfile{}
+	st := models.Staff{}
+	if err := db.Table(fmt.Sprintf("%s AS st", st.TableName())).
+		Select("st.user_id AS staff_id, st.store_id AS store_id, s.name AS store_name, s.status AS store_status, st.is_creator AS is_creator,"+
+			" sp.permission AS staff_permission, u.name AS staff_name, u.email AS staff_email, u.phone AS staff_phone,"+
+			" u.profile_picture AS staff_picture, u.status AS staff_status").
+		Joins("LEFT JOIN store_permissions AS sp ON st.permission_id = sp.id").
+		Joins("LEFT JOIN stores AS s ON st.store_id = s.id").
+		Joins("LEFT JOIN users AS u ON st.user_id = u.id").
+		Where("st.store_id = ? AND st.is_creator = ?", storeID, true).
+		Find(&sup).Error; err != nil {
+		return nil, err
+	}
+	return &sup, nil
+}
+
 func (su *StoreRepositoryImpl) SearchStaffs(db *gorm.DB, storeID, query string, from, limit int) ([]models.StaffProfile, error) {
 	var sup []models.StaffProfile
 	st := models.Staff{}
@@ -163,11 +180,13 @@ func (su *StoreRepositoryImpl) Search(db *gorm.DB, query string, from, limit int
 
 func (su *StoreRepositoryImpl) UpdateStoreStatus(db *gorm.DB, s *models.Store) error {
 	if err := db.Table(s.TableName()).
-		Select("status, commission_rate").
+		Select("status, commission_rate, tier, reserve_period_days").
 		Where("id = ?", s.ID).
 		Update(map[string]interface{}{
-			"status":          s.Status,
-			"commission_rate": s.CommissionRate,
+			"status":              s.Status,
+			"commission_rate":     s.CommissionRate,
+			"tier":                s.Tier,
+			"reserve_period_days": s.ReservePeriodDays,
 		}).
 		Error; err != nil {
 		return err
@@ -209,3 +228,14 @@ func (su *StoreRepositoryImpl) GetStorePayoutSummary(db *gorm.DB, storeID string
 	}
 	return &m, nil
 }
+
+func (su *StoreRepositoryImpl) ListIDs(db *gorm.DB) ([]string, error) {
+	s := models.Store{}
+	var ids []string
+	if err := db.Table(s.TableName()).
+		Order("created_at ASC").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,20 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type TaxonomyRepository interface {
+	Create(db *gorm.DB, n *models.TaxonomyNode) error
+	Update(db *gorm.DB, n *models.TaxonomyNode) error
+	Delete(db *gorm.DB, nodeID string) error
+	Get(db *gorm.DB, nodeID string) (*models.TaxonomyNode, error)
+	List(db *gorm.DB) ([]models.TaxonomyNode, error)
+	ListByIDs(db *gorm.DB, nodeIDs []string) ([]models.TaxonomyNode, error)
+	ListChildren(db *gorm.DB, nodeID string) ([]models.TaxonomyNode, error)
+	CountChildren(db *gorm.DB, nodeID string) (int, error)
+	CountCategories(db *gorm.DB, nodeID string) (int, error)
+	MoveSubtree(db *gorm.DB, oldPath, newPath string, depthDelta int) error
+	SubtreeHeight(db *gorm.DB, n *models.TaxonomyNode) (int, error)
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type TaxonomyRepositoryImpl struct {
+}
+
+var taxonomyRepository TaxonomyRepository
+
+func NewTaxonomyRepository() TaxonomyRepository {
+	if taxonomyRepository == nil {
+		taxonomyRepository = &TaxonomyRepositoryImpl{}
+	}
+	return taxonomyRepository
+}
+
+func (tr *TaxonomyRepositoryImpl) Create(db *gorm.DB, n *models.TaxonomyNode) error {
+	if err := db.Table(n.TableName()).Create(n).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *TaxonomyRepositoryImpl) Update(db *gorm.DB, n *models.TaxonomyNode) error {
+	if err := db.Table(n.TableName()).
+		Where("id = ?", n.ID).
+		Select("parent_id, name, slug, description, image, path, depth, position, updated_at").
+		Updates(map[string]interface{}{
+			"parent_id":   n.ParentID,
+			"name":        n.Name,
+			"slug":        n.Slug,
+			"description": n.Description,
+			"image":       n.Image,
+			"path":        n.Path,
+			"depth":       n.Depth,
+			"position":    n.Position,
+			"updated_at":  n.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *TaxonomyRepositoryImpl) Delete(db *gorm.DB, nodeID string) error {
+	n := models.TaxonomyNode{}
+	if err := db.Table(n.TableName()).
+		Where("id = ?", nodeID).
+		Delete(&n).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (tr *TaxonomyRepositoryImpl) Get(db *gorm.DB, nodeID string) (*models.TaxonomyNode, error) {
+	n := models.TaxonomyNode{}
+	if err := db.Table(n.TableName()).
+		Where("id = ? OR slug = ?", nodeID, nodeID).
+		First(&n).Error; err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// List returns every node, parents before their children and siblings by position
+func (tr *TaxonomyRepositoryImpl) List(db *gorm.DB) ([]models.TaxonomyNode, error) {
+	n := models.TaxonomyNode{}
+	nodes := []models.TaxonomyNode{}
+	if err := db.Table(n.TableName()).
+		Order("depth ASC, position ASC, name ASC").
+		Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// ListByIDs returns the nodes from the root down, as needed for the ancestors of a path
+func (tr *TaxonomyRepositoryImpl) ListByIDs(db *gorm.DB, nodeIDs []string) ([]models.TaxonomyNode, error) {
+	n := models.TaxonomyNode{}
+	nodes := []models.TaxonomyNode{}
+	if len(nodeIDs) == 0 {
+		return nodes, nil
+	}
+	if err := db.Table(n.TableName()).
+		Where("id IN (?)", nodeIDs).
+		Order("depth ASC").
+		Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (tr *TaxonomyRepositoryImpl) ListChildren(db *gorm.DB, nodeID string) ([]models.TaxonomyNode, error) {
+	n := models.TaxonomyNode{}
+	nodes := []models.TaxonomyNode{}
+	if err := db.Table(n.TableName()).
+		Where("parent_id = ?", nodeID).
+		Order("position ASC, name ASC").
+		Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+func (tr *TaxonomyRepositoryImpl) CountChildren(db *gorm.DB, nodeID string) (int, error) {
+	n := models.TaxonomyNode{}
+	return countChildren(db, n.TableName(), nodeID)
+}
+
+// CountCategories counts the store categories mapped into the node
+func (tr *TaxonomyRepositoryImpl) CountCategories(db *gorm.DB, nodeID string) (int, error) {
+	c := models.Category{}
+	count := 0
+	if err := db.Table(c.TableName()).
+		Where("taxonomy_id = ?", nodeID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (tr *TaxonomyRepositoryImpl) MoveSubtree(db *gorm.DB, oldPath, newPath string, depthDelta int) error {
+	n := models.TaxonomyNode{}
+	return moveSubtree(db, n.TableName(), oldPath, newPath, depthDelta)
+}
+
+func (tr *TaxonomyRepositoryImpl) SubtreeHeight(db *gorm.DB, n *models.TaxonomyNode) (int, error) {
+	return subtreeHeight(db, n.TableName(), n.Path, n.Depth)
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// moveSubtree rewrites the paths of the node at oldPath and of its descendants below newPath
+func moveSubtree(db *gorm.DB, table, oldPath, newPath string, depthDelta int) error {
+	if err := db.Table(table).
+		Where("path LIKE ?", oldPath+"%").
+		Updates(map[string]interface{}{
+			"path":  gorm.Expr("? || SUBSTRING(path FROM ?)", newPath, len(oldPath)+1),
+			"depth": gorm.Expr("depth + ?", depthDelta),
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// subtreeHeight is how many levels the descendants of the node at path go below it
+func subtreeHeight(db *gorm.DB, table, path string, depth int) (int, error) {
+	res := struct {
+		MaxDepth int
+	}{}
+	if err := db.Table(table).
+		Select("COALESCE(MAX(depth), ?) AS max_depth", depth).
+		Where("path LIKE ?", path+"%").
+		Scan(&res).Error; err != nil {
+		return 0, err
+	}
+	return res.MaxDepth - depth, nil
+}
+
+func countChildren(db *gorm.DB, table, parentID string) (int, error) {
+	count := 0
+	if err := db.Table(table).
+		Where("parent_id = ?", parentID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// descendantsOf selects the IDs of the nodes with the given IDs and of their descendants
+func descendantsOf(table string) string {
+	return fmt.Sprintf("SELECT d.id FROM %[1]s AS d JOIN %[1]s AS a ON d.path LIKE a.path || '%%' WHERE a.id IN (?)", table)
+}
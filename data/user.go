@@ -20,4 +20,11 @@ type UserRepository interface {
 	GetByEmail(db *gorm.DB, email string) (*models.User, error)
 	List(db *gorm.DB, from, limit int) ([]models.User, error)
 	Search(db *gorm.DB, query string, from, limit int) ([]models.User, error)
+
+	CreatePaymentCustomer(db *gorm.DB, pc *models.PaymentCustomer) error
+	GetPaymentCustomer(db *gorm.DB, userID, paymentGateway string) (*models.PaymentCustomer, error)
+	CreateSavedPaymentMethod(db *gorm.DB, spm *models.SavedPaymentMethod) error
+	ListSavedPaymentMethods(db *gorm.DB, userID, paymentGateway string) ([]models.SavedPaymentMethod, error)
+	GetSavedPaymentMethod(db *gorm.DB, userID, savedPaymentMethodID string) (*models.SavedPaymentMethod, error)
+	DeleteSavedPaymentMethod(db *gorm.DB, userID, savedPaymentMethodID string) error
 }
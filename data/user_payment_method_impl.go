@@ -0,0 +1,58 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func (uu *UserRepositoryImpl) CreatePaymentCustomer(db *gorm.DB, pc *models.PaymentCustomer) error {
+	if err := db.Table(pc.TableName()).Create(pc).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (uu *UserRepositoryImpl) GetPaymentCustomer(db *gorm.DB, userID, paymentGateway string) (*models.PaymentCustomer, error) {
+	pc := models.PaymentCustomer{}
+	if err := db.Table(pc.TableName()).
+		First(&pc, "user_id = ? AND payment_gateway = ?", userID, paymentGateway).Error; err != nil {
+		return nil, err
+	}
+	return &pc, nil
+}
+
+func (uu *UserRepositoryImpl) CreateSavedPaymentMethod(db *gorm.DB, spm *models.SavedPaymentMethod) error {
+	if err := db.Table(spm.TableName()).Create(spm).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (uu *UserRepositoryImpl) ListSavedPaymentMethods(db *gorm.DB, userID, paymentGateway string) ([]models.SavedPaymentMethod, error) {
+	spm := models.SavedPaymentMethod{}
+	var methods []models.SavedPaymentMethod
+	if err := db.Table(spm.TableName()).
+		Order("created_at DESC").
+		Find(&methods, "user_id = ? AND payment_gateway = ?", userID, paymentGateway).Error; err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+func (uu *UserRepositoryImpl) GetSavedPaymentMethod(db *gorm.DB, userID, savedPaymentMethodID string) (*models.SavedPaymentMethod, error) {
+	spm := models.SavedPaymentMethod{}
+	if err := db.Table(spm.TableName()).
+		First(&spm, "id = ? AND user_id = ?", savedPaymentMethodID, userID).Error; err != nil {
+		return nil, err
+	}
+	return &spm, nil
+}
+
+func (uu *UserRepositoryImpl) DeleteSavedPaymentMethod(db *gorm.DB, userID, savedPaymentMethodID string) error {
+	spm := models.SavedPaymentMethod{}
+	if err := db.Table(spm.TableName()).
+		Delete(&spm, "id = ? AND user_id = ?", savedPaymentMethodID, userID).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package data
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type WishlistRepository interface {
+	Create(db *gorm.DB, w *models.Wishlist) error
+	Update(db *gorm.DB, w *models.Wishlist) error
+	Delete(db *gorm.DB, w *models.Wishlist) error
+	Get(db *gorm.DB, userID, wishlistID string) (*models.Wishlist, error)
+	GetByShareToken(db *gorm.DB, token string) (*models.Wishlist, error)
+	List(db *gorm.DB, userID string) ([]models.Wishlist, error)
+	AddItem(db *gorm.DB, wi *models.WishlistItem) error
+	RemoveItem(db *gorm.DB, wishlistID, productID string) error
+	ListItems(db *gorm.DB, wishlistID string) ([]models.SavedProductDetails, error)
+}
@@ -0,0 +1,130 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+type WishlistRepositoryImpl struct {
+}
+
+var wishlistRepository WishlistRepository
+
+func NewWishlistRepository() WishlistRepository {
+	if wishlistRepository == nil {
+		wishlistRepository = &WishlistRepositoryImpl{}
+	}
+	return wishlistRepository
+}
+
+func (wu *WishlistRepositoryImpl) Create(db *gorm.DB, w *models.Wishlist) error {
+	if err := db.Table(w.TableName()).Create(w).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wu *WishlistRepositoryImpl) Update(db *gorm.DB, w *models.Wishlist) error {
+	if err := db.Table(w.TableName()).
+		Where("id = ? AND user_id = ?", w.ID, w.UserID).
+		Select("name, share_token, updated_at").
+		Updates(map[string]interface{}{
+			"name":        w.Name,
+			"share_token": w.ShareToken,
+			"updated_at":  w.UpdatedAt,
+		}).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wu *WishlistRepositoryImpl) Delete(db *gorm.DB, w *models.Wishlist) error {
+	wi := models.WishlistItem{}
+	if err := db.Table(wi.TableName()).Where("wishlist_id = ?", w.ID).Delete(&wi).Error; err != nil {
+		return err
+	}
+	if err := db.Table(w.TableName()).Where("id = ? AND user_id = ?", w.ID, w.UserID).Delete(w).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wu *WishlistRepositoryImpl) Get(db *gorm.DB, userID, wishlistID string) (*models.Wishlist, error) {
+	w := models.Wishlist{}
+	if err := db.Table(w.TableName()).
+		Where("id = ? AND user_id = ?", wishlistID, userID).
+		First(&w).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (wu *WishlistRepositoryImpl) GetByShareToken(db *gorm.DB, token string) (*models.Wishlist, error) {
+	w := models.Wishlist{}
+	if err := db.Table(w.TableName()).
+		Where("share_token = ?", token).
+		First(&w).Error; err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (wu *WishlistRepositoryImpl) List(db *gorm.DB, userID string) ([]models.Wishlist, error) {
+	wishlists := []models.Wishlist{}
+	w := models.Wishlist{}
+	if err := db.Table(w.TableName()).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&wishlists).Error; err != nil {
+		return nil, err
+	}
+	return wishlists, nil
+}
+
+func (wu *WishlistRepositoryImpl) AddItem(db *gorm.DB, wi *models.WishlistItem) error {
+	if err := db.Table(wi.TableName()).Create(wi).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wu *WishlistRepositoryImpl) RemoveItem(db *gorm.DB, wishlistID, productID string) error {
+	wi := models.WishlistItem{}
+	q := db.Table(wi.TableName()).
+		Where("wishlist_id = ? AND product_id = ?", wishlistID, productID).
+		Delete(&wi)
+	if q.Error != nil {
+		return q.Error
+	}
+	if q.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (wu *WishlistRepositoryImpl) ListItems(db *gorm.DB, wishlistID string) ([]models.SavedProductDetails, error) {
+	items := []models.SavedProductDetails{}
+	wi := models.WishlistItem{}
+	if err := db.Table(fmt.Sprintf("%s AS wi", wi.TableName())).
+		Select(savedProductSelection+", wi.created_at AS added_at").
+		Joins(savedProductJoins("wi")).
+		Where("wi.wishlist_id = ?", wishlistID).
+		Order("wi.created_at DESC").
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// savedProductSelection selects the saved products joined by savedProductJoins
+const savedProductSelection = "p.id AS product_id, p.name, p.slug, p.image, p.price, p.stock, p.is_digital, " +
+	"p.is_published AND s.status = '" + string(models.StoreActive) + "' AS is_available, s.id AS store_id, s.name AS store_name"
+
+// savedProductJoins joins the products saved in the table aliased as alias, along with their store
+func savedProductJoins(alias string) string {
+	p := models.Product{}
+	s := models.Store{}
+	return fmt.Sprintf("JOIN %s AS p ON p.id = %s.product_id JOIN %s AS s ON s.id = p.store_id", p.TableName(), alias, s.TableName())
+}
@@ -16,6 +16,23 @@ const (
 	ExceedMaxProductQuantity                      ErrorCode = "400012"
 	PaymentMethodMustBeOnlineForDigitalProducts   ErrorCode = "400013"
 	PayoutAmountInvalid                           ErrorCode = "400014"
+	PaymentDiscrepancyNotFixable                  ErrorCode = "400015"
+	SavedPaymentMethodNotSupported                ErrorCode = "400016"
+	PayoutEntryNotExportable                      ErrorCode = "400017"
+	PayoutBankReportInvalid                       ErrorCode = "400018"
+	PayoutStatusTransitionNotAllowed              ErrorCode = "400019"
+	DisputeStatusTransitionNotAllowed             ErrorCode = "400020"
+	DisputeWebhookInvalid                         ErrorCode = "400021"
+	ProductVariantRequired                        ErrorCode = "400022"
+	ProductImportFileInvalid                      ErrorCode = "400023"
+	ProductReviewNotAllowed                       ErrorCode = "400024"
+	CategoryMoveNotAllowed                        ErrorCode = "400025"
+	ProductRevisionNotEditable                    ErrorCode = "400026"
+	ProductRevisionNotRevertible                  ErrorCode = "400027"
+	StockNotAvailableAtLocation                   ErrorCode = "400028"
+	StockAdjustmentNotAllowed                     ErrorCode = "400029"
+	StockLocationNotDeactivatable                 ErrorCode = "400030"
+	LicenseKeysNotAllowed                         ErrorCode = "400031"
 	StoreCreationDataInvalid                      ErrorCode = "422001"
 	UserLoginDataInvalid                          ErrorCode = "422002"
 	UserSignUpDataInvalid                         ErrorCode = "422003"
@@ -39,6 +56,19 @@ const (
 	PayoutMethodDataInvalid                       ErrorCode = "422021"
 	PayoutSettingsDataInvalid                     ErrorCode = "422022"
 	PayoutEntryDataInvalid                        ErrorCode = "422023"
+	SavedPaymentMethodDataInvalid                 ErrorCode = "422024"
+	LedgerStatementQueryInvalid                   ErrorCode = "422025"
+	PayoutBatchDataInvalid                        ErrorCode = "422026"
+	CommissionRuleDataInvalid                     ErrorCode = "422027"
+	DisputeDataInvalid                            ErrorCode = "422028"
+	ProductVariantDataInvalid                     ErrorCode = "422029"
+	ProductFilterInvalid                          ErrorCode = "422030"
+	TaxonomyNodeDataInvalid                       ErrorCode = "422031"
+	ProductRevisionDataInvalid                    ErrorCode = "422032"
+	WishlistDataInvalid                           ErrorCode = "422033"
+	StockLocationDataInvalid                      ErrorCode = "422034"
+	StockAdjustmentDataInvalid                    ErrorCode = "422035"
+	LicenseKeyDataInvalid                         ErrorCode = "422036"
 	StoreCreationQueryFailed                      ErrorCode = "500001"
 	DatabaseQueryFailed                           ErrorCode = "500002"
 	PasswordEncryptionFailed                      ErrorCode = "500003"
@@ -67,6 +97,16 @@ const (
 	UserAlreadyStaff                              ErrorCode = "409015"
 	BusinessAccountTypeAlreadyExists              ErrorCode = "409016"
 	PayoutMethodAlreadyExists                     ErrorCode = "409017"
+	PaymentDiscrepancyAlreadyResolved             ErrorCode = "409018"
+	DisputeAlreadyOpen                            ErrorCode = "409019"
+	ProductOptionAlreadyExists                    ErrorCode = "409020"
+	ProductReviewAlreadyExists                    ErrorCode = "409021"
+	CategoryHasChildren                           ErrorCode = "409022"
+	TaxonomyNodeInUse                             ErrorCode = "409023"
+	TaxonomyNodeAlreadyExists                     ErrorCode = "409024"
+	WishlistItemAlreadyExists                     ErrorCode = "409025"
+	ProductAlertAlreadySubscribed                 ErrorCode = "409026"
+	StockLocationAlreadyExists                    ErrorCode = "409027"
 	UserHasAStore                                 ErrorCode = "403001"
 	UserSignUpDisabled                            ErrorCode = "403002"
 	StoreCreationDisabled                         ErrorCode = "403003"
@@ -102,6 +142,27 @@ const (
 	PayoutMethodNotFound                          ErrorCode = "404020"
 	PayoutSettingsNotFound                        ErrorCode = "404021"
 	PayoutEntryNotFound                           ErrorCode = "404022"
+	ReconciliationReportNotFound                  ErrorCode = "404023"
+	PaymentDiscrepancyNotFound                    ErrorCode = "404024"
+	SavedPaymentMethodNotFound                    ErrorCode = "404025"
+	LedgerAccountNotFound                         ErrorCode = "404026"
+	PayoutBatchNotFound                           ErrorCode = "404027"
+	CommissionRuleNotFound                        ErrorCode = "404028"
+	SellerStatementNotFound                       ErrorCode = "404029"
+	CommissionInvoiceNotFound                     ErrorCode = "404030"
+	DisputeNotFound                               ErrorCode = "404031"
+	DisputeEvidenceNotFound                       ErrorCode = "404032"
+	ProductOptionNotFound                         ErrorCode = "404033"
+	ProductImportNotFound                         ErrorCode = "404034"
+	ProductImportReportNotFound                   ErrorCode = "404035"
+	ProductReviewNotFound                         ErrorCode = "404036"
+	TaxonomyNodeNotFound                          ErrorCode = "404037"
+	ProductRevisionNotFound                       ErrorCode = "404038"
+	WishlistNotFound                              ErrorCode = "404039"
+	ProductAlertSubscriptionNotFound              ErrorCode = "404040"
+	StockLocationNotFound                         ErrorCode = "404041"
+	LicenseKeyNotFound                            ErrorCode = "404042"
+	LicenseKeyPoolNotFound                        ErrorCode = "404043"
 	LoginCredentialsInvalid                       ErrorCode = "401001"
 	VerificationTokenIsInvalid                    ErrorCode = "401002"
 	UnauthorizedRequest                           ErrorCode = "401004"
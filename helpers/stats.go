@@ -11,9 +11,11 @@ type ProductStats struct {
 }
 
 type CategoryStats struct {
-	ID          string `json:"id" sql:"id"`
-	Name        string `json:"name" sql:"name"`
-	Image       string `json:"image" sql:"image"`
-	Description string `json:"description" sql:"description"`
-	Count       int    `json:"count" json:"count"`
+	ID          string  `json:"id" sql:"id"`
+	Name        string  `json:"name" sql:"name"`
+	Image       string  `json:"image" sql:"image"`
+	Description string  `json:"description" sql:"description"`
+	ParentID    *string `json:"parent_id" sql:"parent_id"`
+	Depth       int     `json:"depth" sql:"depth"`
+	Count       int     `json:"count" json:"count"`
 }
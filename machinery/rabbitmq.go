@@ -54,6 +54,39 @@ func RegisterRabbitMQTasks() error {
 	if err := machineryServer.RegisterTask(tasks.SendResetPasswordConfirmationEmailTaskName, tasks.SendResetPasswordConfirmationEmailFn); err != nil {
 		return err
 	}
+	if err := machineryServer.RegisterTask(tasks.ReconcilePaymentsTaskName, tasks.ReconcilePaymentsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.CreateScheduledPayoutsTaskName, tasks.CreateScheduledPayoutsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.SyncLedgerTaskName, tasks.SyncLedgerFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.GenerateSellerStatementsTaskName, tasks.GenerateSellerStatementsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.SendPayoutStatusEmailTaskName, tasks.SendPayoutStatusEmailFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.GenerateCommissionInvoicesTaskName, tasks.GenerateCommissionInvoicesFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.ImportProductsTaskName, tasks.ImportProductsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.PublishScheduledProductsTaskName, tasks.PublishScheduledProductsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.ComputeProductRecommendationsTaskName, tasks.ComputeProductRecommendationsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.CheckProductAlertsTaskName, tasks.CheckProductAlertsFn); err != nil {
+		return err
+	}
+	if err := machineryServer.RegisterTask(tasks.CheckLicenseKeyPoolsTaskName, tasks.CheckLicenseKeyPoolsFn); err != nil {
+		return err
+	}
 	return nil
 }
 
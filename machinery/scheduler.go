@@ -0,0 +1,239 @@
+package machinery
+
+import (
+	"fmt"
+	"github.com/RichardKnop/machinery/v1/tasks"
+	cfg "github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/log"
+	tasks2 "github.com/shopicano/shopicano-backend/tasks"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule returns the next run time of a periodic task after now
+type Schedule func(now time.Time) time.Time
+
+type scheduledTask struct {
+	name     string
+	schedule Schedule
+	nextRun  time.Time
+}
+
+var scheduledTasks []*scheduledTask
+var schedulerMu sync.Mutex
+
+// Daily returns a schedule that runs every day at the given UTC time in HH:MM format
+func Daily(at string) (Schedule, error) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("invalid daily schedule %s : %v", at, err)
+	}
+
+	return func(now time.Time) time.Time {
+		now = now.UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}, nil
+}
+
+// Weekly returns a schedule that runs every week on the given weekday at the given UTC time in HH:MM format
+func Weekly(weekday, at string) (Schedule, error) {
+	return weekly(weekday, at, 1)
+}
+
+// Biweekly returns a schedule that runs on the given weekday every other week, counted from weeklyAnchor
+func Biweekly(weekday, at string) (Schedule, error) {
+	return weekly(weekday, at, 2)
+}
+
+// weeklyAnchor is the Monday the weeks of the schedules running every few weeks are counted from.
+// Counting from a fixed date keeps the interval across years with 53 ISO weeks.
+var weeklyAnchor = time.Date(2020, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+func weekly(weekday, at string, everyWeeks int) (Schedule, error) {
+	wd, err := parseWeekday(weekday)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := Daily(at)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(now time.Time) time.Time {
+		next := daily(now)
+		for next.Weekday() != wd {
+			next = next.AddDate(0, 0, 1)
+		}
+		if weeks := int(next.Sub(weeklyAnchor).Hours()/24) / 7; weeks%everyWeeks != 0 {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next
+	}, nil
+}
+
+// Monthly returns a schedule that runs every month on the given day at the given UTC time in HH:MM format.
+// Days are limited to 1-28 so that every month has a run.
+func Monthly(day int, at string) (Schedule, error) {
+	if day < 1 || day > 28 {
+		return nil, fmt.Errorf("invalid monthly schedule day %d", day)
+	}
+
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return nil, fmt.Errorf("invalid monthly schedule %s : %v", at, err)
+	}
+
+	return func(now time.Time) time.Time {
+		now = now.UTC()
+		next := time.Date(now.Year(), now.Month(), day, t.Hour(), t.Minute(), 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 1, 0)
+		}
+		return next
+	}, nil
+}
+
+// Every returns a schedule that runs at every interval, aligned on the interval in UTC
+func Every(interval time.Duration) (Schedule, error) {
+	if interval < time.Minute {
+		return nil, fmt.Errorf("invalid interval schedule %s", interval)
+	}
+
+	return func(now time.Time) time.Time {
+		return now.UTC().Truncate(interval).Add(interval)
+	}, nil
+}
+
+func parseWeekday(v string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), v) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %s", v)
+}
+
+func RegisterScheduledTask(name string, schedule Schedule) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+
+	scheduledTasks = append(scheduledTasks, &scheduledTask{
+		name:     name,
+		schedule: schedule,
+		nextRun:  schedule(time.Now()),
+	})
+}
+
+func RegisterScheduledTasks() error {
+	reconciliation, err := Daily(cfg.Scheduler().PaymentReconciliationAt)
+	if err != nil {
+		return err
+	}
+	RegisterScheduledTask(tasks2.ReconcilePaymentsTaskName, reconciliation)
+
+	payouts, err := payoutSchedule(cfg.Scheduler())
+	if err != nil {
+		return err
+	}
+	if payouts != nil {
+		RegisterScheduledTask(tasks2.CreateScheduledPayoutsTaskName, payouts)
+	}
+
+	if at := cfg.Scheduler().SellerStatementsAt; at != "" {
+		statements, err := Monthly(1, at)
+		if err != nil {
+			return err
+		}
+		RegisterScheduledTask(tasks2.GenerateSellerStatementsTaskName, statements)
+	}
+
+	if at := cfg.Scheduler().CommissionInvoicesAt; at != "" {
+		invoices, err := Monthly(1, at)
+		if err != nil {
+			return err
+		}
+		RegisterScheduledTask(tasks2.GenerateCommissionInvoicesTaskName, invoices)
+	}
+
+	if mins := cfg.Scheduler().ProductPublishingIntervalMins; mins > 0 {
+		publishing, err := Every(time.Duration(mins) * time.Minute)
+		if err != nil {
+			return err
+		}
+		RegisterScheduledTask(tasks2.PublishScheduledProductsTaskName, publishing)
+	}
+
+	if at := cfg.Scheduler().ProductRecommendationsAt; at != "" {
+		recommendations, err := Daily(at)
+		if err != nil {
+			return err
+		}
+		RegisterScheduledTask(tasks2.ComputeProductRecommendationsTaskName, recommendations)
+	}
+
+	if mins := cfg.Scheduler().ProductAlertsIntervalMins; mins > 0 {
+		alerts, err := Every(time.Duration(mins) * time.Minute)
+		if err != nil {
+			return err
+		}
+		RegisterScheduledTask(tasks2.CheckProductAlertsTaskName, alerts)
+	}
+
+	if mins := cfg.Scheduler().LicenseKeyPoolsIntervalMins; mins > 0 {
+		pools, err := Every(time.Duration(mins) * time.Minute)
+		if err != nil {
+			return err
+		}
+		RegisterScheduledTask(tasks2.CheckLicenseKeyPoolsTaskName, pools)
+	}
+	return nil
+}
+
+// payoutSchedule returns nil when automatic payouts are disabled
+func payoutSchedule(c cfg.SchedulerCfg) (Schedule, error) {
+	switch c.PayoutSchedule {
+	case "":
+		return nil, nil
+	case "weekly":
+		return Weekly(c.PayoutWeekday, c.PayoutAt)
+	case "biweekly":
+		return Biweekly(c.PayoutWeekday, c.PayoutAt)
+	case "monthly":
+		return Monthly(c.PayoutDayOfMonth, c.PayoutAt)
+	}
+	return nil, fmt.Errorf("invalid payout schedule %s", c.PayoutSchedule)
+}
+
+// RunScheduler enqueues the registered periodic tasks when they are due
+func RunScheduler() {
+	for _, t := range scheduledTasks {
+		log.Log().Infoln("Scheduled task ", t.name, " next run at ", t.nextRun)
+	}
+
+	ticker := time.NewTicker(time.Second * 30)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		schedulerMu.Lock()
+		for _, t := range scheduledTasks {
+			if now.Before(t.nextRun) {
+				continue
+			}
+
+			if _, err := RabbitMQConnection().SendTask(&tasks.Signature{Name: t.name}); err != nil {
+				log.Log().Errorln("Failed to enqueue scheduled task ", t.name, " : ", err)
+				continue
+			}
+
+			t.nextRun = t.schedule(now)
+			log.Log().Infoln("Scheduled task ", t.name, " next run at ", t.nextRun)
+		}
+		schedulerMu.Unlock()
+	}
+}
@@ -0,0 +1,76 @@
+package machinery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedules(t *testing.T) {
+	// Wednesday, ISO week 41
+	now := time.Date(2020, time.October, 7, 10, 0, 0, 0, time.UTC)
+
+	daily, err := Daily("09:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekly, err := Weekly("monday", "04:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameDay, err := Weekly("Wednesday", "12:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	biweekly, err := Biweekly("monday", "04:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	monthly, err := Monthly(1, "04:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	every, err := Every(time.Minute * 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range map[string]struct {
+		schedule Schedule
+		expected time.Time
+	}{
+		"daily":    {daily, time.Date(2020, time.October, 8, 9, 30, 0, 0, time.UTC)},
+		"weekly":   {weekly, time.Date(2020, time.October, 12, 4, 0, 0, 0, time.UTC)},
+		"same day": {sameDay, time.Date(2020, time.October, 7, 12, 0, 0, 0, time.UTC)},
+		"biweekly": {biweekly, time.Date(2020, time.October, 12, 4, 0, 0, 0, time.UTC)},
+		"monthly":  {monthly, time.Date(2020, time.November, 1, 4, 0, 0, 0, time.UTC)},
+		"every":    {every, time.Date(2020, time.October, 7, 10, 5, 0, 0, time.UTC)},
+	} {
+		if next := c.schedule(now); !next.Equal(c.expected) {
+			t.Errorf("%s : expected %s, got %s", name, c.expected, next)
+		}
+	}
+
+	if next := biweekly(time.Date(2020, time.October, 12, 4, 0, 0, 0, time.UTC)); !next.Equal(time.Date(2020, time.October, 26, 4, 0, 0, 0, time.UTC)) {
+		t.Errorf("biweekly : expected two weeks between runs, got %s", next)
+	}
+
+	// 2026 has 53 ISO weeks, week 53 is followed by week 1
+	run := time.Date(2026, time.December, 14, 4, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		next := biweekly(run)
+		if !next.Equal(run.AddDate(0, 0, 14)) {
+			t.Fatalf("biweekly : expected two weeks after %s, got %s", run, next)
+		}
+		run = next
+	}
+
+	if _, err := Weekly("someday", "04:00"); err == nil {
+		t.Error("expected invalid weekday to be rejected")
+	}
+	if _, err := Monthly(31, "04:00"); err == nil {
+		t.Error("expected invalid day of month to be rejected")
+	}
+	if _, err := Every(time.Second); err == nil {
+		t.Error("expected interval under a minute to be rejected")
+	}
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"fmt"
 	"time"
+
+	"github.com/jinzhu/gorm"
 )
 
 type Category struct {
@@ -12,6 +14,10 @@ type Category struct {
 	Description string    `json:"description" gorm:"column:description;not null"`
 	Image       string    `json:"image" gorm:"column:image;not null"`
 	IsPublished bool      `json:"is_published" gorm:"column:is_published;index;not null"`
+	ParentID    *string   `json:"parent_id" gorm:"column:parent_id;index"`
+	Path        string    `json:"path" gorm:"column:path;not null;default:''"`
+	Depth       int       `json:"depth" gorm:"column:depth;not null;default:0"`
+	TaxonomyID  *string   `json:"taxonomy_id" gorm:"column:taxonomy_id;index"`
 	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at"`
 }
@@ -22,25 +28,45 @@ func (c *Category) TableName() string {
 
 func (c *Category) ForeignKeys() []string {
 	s := Store{}
+	t := TaxonomyNode{}
 
 	return []string{
 		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("parent_id;%s(id);RESTRICT;RESTRICT", c.TableName()),
+		fmt.Sprintf("taxonomy_id;%s(id);RESTRICT;RESTRICT", t.TableName()),
+	}
+}
+
+// MigratePaths gives the categories created before the hierarchy a root path and indexes the paths
+// for the prefix matches of the descendants
+func (c *Category) MigratePaths(tx *gorm.DB) error {
+	if err := tx.Exec(fmt.Sprintf("UPDATE %s SET path = '/' || id || '/', depth = 0 WHERE path = '' AND parent_id IS NULL",
+		c.TableName())).Error; err != nil {
+		return err
 	}
+	return createPathIndex(tx, c.TableName())
 }
 
 type ResCategorySearch struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	StoreID     string `json:"store_id"`
-	Description string `json:"description"`
-	Image       string `json:"image"`
-	Count       int64  `json:"count"`
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	StoreID     string  `json:"store_id"`
+	ParentID    *string `json:"parent_id"`
+	Depth       int     `json:"depth"`
+	TaxonomyID  *string `json:"taxonomy_id"`
+	Description string  `json:"description"`
+	Image       string  `json:"image"`
+	Count       int64   `json:"count"`
 }
 
 type ResCategorySearchInternal struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
 	StoreID     string    `json:"store_id"`
+	ParentID    *string   `json:"parent_id"`
+	Path        string    `json:"path"`
+	Depth       int       `json:"depth"`
+	TaxonomyID  *string   `json:"taxonomy_id"`
 	Description string    `json:"description"`
 	Image       string    `json:"image"`
 	Count       int64     `json:"count"`
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CommissionInvoice is the monthly invoice issued by the platform to a store for the commission
+// earned on its orders. The issuer and seller details are copied at issue time so later changes
+// of the settings don't alter issued invoices. The PDF is kept in the reserved bucket.
+type CommissionInvoice struct {
+	ID              string    `json:"id" gorm:"column:id;primary_key"`
+	Number          string    `json:"number" gorm:"column:number;unique_index;not null"`
+	StoreID         string    `json:"store_id" gorm:"column:store_id;unique_index:uix_commission_invoices_store_id_period_start;not null"`
+	PeriodStart     time.Time `json:"period_start" gorm:"column:period_start;unique_index:uix_commission_invoices_store_id_period_start;not null"`
+	PeriodEnd       time.Time `json:"period_end" gorm:"column:period_end;not null"`
+	IssuerName      string    `json:"issuer_name" gorm:"column:issuer_name;not null"`
+	IssuerAddress   string    `json:"issuer_address" gorm:"column:issuer_address;not null"`
+	SellerName      string    `json:"seller_name" gorm:"column:seller_name;not null"`
+	SellerAddress   string    `json:"seller_address" gorm:"column:seller_address"`
+	SellerVatNumber string    `json:"seller_vat_number" gorm:"column:seller_vat_number"`
+	Currency        string    `json:"currency" gorm:"column:currency;not null"`
+	TotalOrders     int       `json:"total_orders" gorm:"column:total_orders;not null"`
+	TotalCommission int64     `json:"total_commission" gorm:"column:total_commission;not null"`
+	TotalCredits    int64     `json:"total_credits" gorm:"column:total_credits;not null"`
+	TotalAmount     int64     `json:"total_amount" gorm:"column:total_amount;not null"`
+	IsCreditNote    bool      `json:"is_credit_note" gorm:"column:is_credit_note;not null;default:false"`
+	PDFPath         string    `json:"-" gorm:"column:pdf_path;not null"`
+	IssuedAt        time.Time `json:"issued_at" gorm:"column:issued_at;index;not null"`
+}
+
+func (ci *CommissionInvoice) TableName() string {
+	return "commission_invoices"
+}
+
+func (ci *CommissionInvoice) ForeignKeys() []string {
+	s := Store{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+	}
+}
+
+// InvoiceSequence holds the last number issued in a numbering sequence
+type InvoiceSequence struct {
+	Name       string    `json:"name" gorm:"column:name;primary_key"`
+	LastNumber int64     `json:"last_number" gorm:"column:last_number;not null"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (is *InvoiceSequence) TableName() string {
+	return "invoice_sequences"
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CommissionRule overrides the commission rate of the store for the order items it matches.
+// Empty criteria match everything and the monthly sales band is the store's paid sales
+// of the current month, with an exclusive upper bound.
+type CommissionRule struct {
+	ID              string     `json:"id" gorm:"column:id;primary_key"`
+	Name            string     `json:"name" gorm:"column:name;not null"`
+	ProductID       *string    `json:"product_id" gorm:"column:product_id;index"`
+	CategoryID      *string    `json:"category_id" gorm:"column:category_id;index"`
+	StoreTier       *string    `json:"store_tier" gorm:"column:store_tier;index"`
+	MinMonthlySales *int64     `json:"min_monthly_sales" gorm:"column:min_monthly_sales"`
+	MaxMonthlySales *int64     `json:"max_monthly_sales" gorm:"column:max_monthly_sales"`
+	Rate            int64      `json:"rate" gorm:"column:rate;not null;default:0"`
+	FixedFee        int64      `json:"fixed_fee" gorm:"column:fixed_fee;not null;default:0"`
+	Priority        int        `json:"priority" gorm:"column:priority;not null;default:0"`
+	IsActive        bool       `json:"is_active" gorm:"column:is_active;index;not null;default:false"`
+	EffectiveFrom   time.Time  `json:"effective_from" gorm:"column:effective_from;index;not null"`
+	EffectiveTo     *time.Time `json:"effective_to" gorm:"column:effective_to;index"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (cr *CommissionRule) TableName() string {
+	return "commission_rules"
+}
+
+func (cr *CommissionRule) ForeignKeys() []string {
+	p := Product{}
+	c := Category{}
+
+	return []string{
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("category_id;%s(id);RESTRICT;RESTRICT", c.TableName()),
+	}
+}
+
+// IsEffective tells whether the rule is in force at the given time
+func (cr *CommissionRule) IsEffective(at time.Time) bool {
+	if !cr.IsActive || at.Before(cr.EffectiveFrom) {
+		return false
+	}
+	return cr.EffectiveTo == nil || at.Before(*cr.EffectiveTo)
+}
+
+// Specificity ranks the rule by its criteria, a product rule beats a category rule
+// which beats a store tier rule which beats a sales band rule
+func (cr *CommissionRule) Specificity() int {
+	s := 0
+	if cr.ProductID != nil {
+		s += 8
+	}
+	if cr.CategoryID != nil {
+		s += 4
+	}
+	if cr.StoreTier != nil {
+		s += 2
+	}
+	if cr.MinMonthlySales != nil || cr.MaxMonthlySales != nil {
+		s += 1
+	}
+	return s
+}
+
+func (cr *CommissionRule) CalculateCommission(value int64) int64 {
+	if value == 0 || cr.Rate == 0 {
+		return 0
+	}
+	return (value * cr.Rate) / 100
+}
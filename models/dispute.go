@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DisputeStatusOpen        DisputeStatus = "dispute_open"
+	DisputeStatusUnderReview DisputeStatus = "dispute_under_review"
+	DisputeStatusWon         DisputeStatus = "dispute_won"
+	DisputeStatusLost        DisputeStatus = "dispute_lost"
+	DisputeStatusClosed      DisputeStatus = "dispute_closed"
+)
+
+type DisputeStatus string
+
+func (ds DisputeStatus) IsValid() bool {
+	for _, v := range []DisputeStatus{DisputeStatusOpen, DisputeStatusUnderReview, DisputeStatusWon, DisputeStatusLost, DisputeStatusClosed} {
+		if v == ds {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen tells whether the dispute is still to be decided, the earnings of the order are frozen meanwhile
+func (ds DisputeStatus) IsOpen() bool {
+	return ds == DisputeStatusOpen || ds == DisputeStatusUnderReview
+}
+
+// CanTransitionTo allows open disputes to move back and forth until they are decided, decided disputes are final
+func (ds DisputeStatus) CanTransitionTo(next DisputeStatus) bool {
+	return ds.IsOpen() && next.IsValid() && next != ds
+}
+
+// OpenDisputeStatuses lists the statuses of the disputes that are still to be decided
+func OpenDisputeStatuses() []DisputeStatus {
+	return []DisputeStatus{DisputeStatusOpen, DisputeStatusUnderReview}
+}
+
+// Dispute is a chargeback of the payment of an order raised by the buyer with the card issuer.
+// Disputes come from the webhooks of the payment gateway or are entered by the platform admins.
+type Dispute struct {
+	ID               string        `json:"id" gorm:"column:id;primary_key"`
+	OrderID          string        `json:"order_id" gorm:"column:order_id;index;not null"`
+	StoreID          string        `json:"store_id" gorm:"column:store_id;index;not null"`
+	PaymentGateway   string        `json:"payment_gateway" gorm:"column:payment_gateway;unique_index:uix_disputes_payment_gateway_gateway_dispute_id;not null"`
+	GatewayDisputeID *string       `json:"gateway_dispute_id" gorm:"column:gateway_dispute_id;unique_index:uix_disputes_payment_gateway_gateway_dispute_id"`
+	Amount           int64         `json:"amount" gorm:"column:amount;not null"`
+	Fee              int64         `json:"fee" gorm:"column:fee;not null;default:0"`
+	Reason           string        `json:"reason" gorm:"column:reason;not null"`
+	Status           DisputeStatus `json:"status" gorm:"column:status;index;not null"`
+	EvidenceDueBy    *time.Time    `json:"evidence_due_by" gorm:"column:evidence_due_by"`
+	Note             string        `json:"note" gorm:"column:note"`
+	CreatedByUserID  *string       `json:"created_by_user_id" gorm:"column:created_by_user_id"`
+	ResolvedAt       *time.Time    `json:"resolved_at" gorm:"column:resolved_at"`
+	CreatedAt        time.Time     `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt        time.Time     `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (d *Dispute) TableName() string {
+	return "disputes"
+}
+
+func (d *Dispute) ForeignKeys() []string {
+	o := Order{}
+	s := Store{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("order_id;%s(id);RESTRICT;RESTRICT", o.TableName()),
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("created_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+// DisputeEvidence is a file submitted to contest a dispute, kept in the reserved bucket
+type DisputeEvidence struct {
+	ID               string    `json:"id" gorm:"column:id;primary_key"`
+	DisputeID        string    `json:"dispute_id" gorm:"column:dispute_id;index;not null"`
+	FileName         string    `json:"file_name" gorm:"column:file_name;not null"`
+	ContentType      string    `json:"content_type" gorm:"column:content_type;not null"`
+	Size             int64     `json:"size" gorm:"column:size;not null"`
+	Path             string    `json:"-" gorm:"column:path;not null"`
+	Description      string    `json:"description" gorm:"column:description"`
+	UploadedByUserID string    `json:"uploaded_by_user_id" gorm:"column:uploaded_by_user_id;not null"`
+	CreatedAt        time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (de *DisputeEvidence) TableName() string {
+	return "dispute_evidences"
+}
+
+func (de *DisputeEvidence) ForeignKeys() []string {
+	d := Dispute{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("dispute_id;%s(id);RESTRICT;RESTRICT", d.TableName()),
+		fmt.Sprintf("uploaded_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+type DisputeDetails struct {
+	Dispute
+	Evidences []DisputeEvidence `json:"evidences"`
+}
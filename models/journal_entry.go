@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	JournalReferenceOrder   JournalReferenceType = "order"
+	JournalReferencePayout  JournalReferenceType = "payout"
+	JournalReferenceDispute JournalReferenceType = "dispute"
+)
+
+type JournalReferenceType string
+
+// JournalEntry is an append-only ledger posting. Its lines always balance.
+type JournalEntry struct {
+	ID            string               `json:"id" gorm:"column:id;primary_key"`
+	ReferenceType JournalReferenceType `json:"reference_type" gorm:"column:reference_type;index;not null"`
+	ReferenceID   string               `json:"reference_id" gorm:"column:reference_id;index;not null"`
+	StoreID       string               `json:"store_id" gorm:"column:store_id;index;not null"`
+	Event         string               `json:"event" gorm:"column:event;index;not null"`
+	Description   string               `json:"description" gorm:"column:description"`
+	CreatedAt     time.Time            `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (je *JournalEntry) TableName() string {
+	return "journal_entries"
+}
+
+func (je *JournalEntry) ForeignKeys() []string {
+	s := Store{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+	}
+}
+
+type JournalLine struct {
+	ID             string `json:"id" gorm:"column:id;primary_key"`
+	JournalEntryID string `json:"journal_entry_id" gorm:"column:journal_entry_id;index;not null"`
+	AccountID      string `json:"account_id" gorm:"column:account_id;index;not null"`
+	Debit          int64  `json:"debit" gorm:"column:debit;not null;default:0"`
+	Credit         int64  `json:"credit" gorm:"column:credit;not null;default:0"`
+}
+
+func (jl *JournalLine) TableName() string {
+	return "journal_lines"
+}
+
+func (jl *JournalLine) ForeignKeys() []string {
+	je := JournalEntry{}
+	la := LedgerAccount{}
+
+	return []string{
+		fmt.Sprintf("journal_entry_id;%s(id);RESTRICT;RESTRICT", je.TableName()),
+		fmt.Sprintf("account_id;%s(id);RESTRICT;RESTRICT", la.TableName()),
+	}
+}
+
+type JournalEntryDetails struct {
+	JournalEntry
+	Lines []JournalLine `json:"lines"`
+}
+
+// StatementLine is a journal line of an account with the running balance of the account
+type StatementLine struct {
+	JournalEntryID string               `json:"journal_entry_id"`
+	ReferenceType  JournalReferenceType `json:"reference_type"`
+	ReferenceID    string               `json:"reference_id"`
+	Event          string               `json:"event"`
+	Description    string               `json:"description"`
+	Debit          int64                `json:"debit"`
+	Credit         int64                `json:"credit"`
+	Balance        int64                `json:"balance"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+// StoreLedgerSummary is the payout summary of a store derived from the ledger
+type StoreLedgerSummary struct {
+	StoreID         string `json:"store_id"`
+	TotalIncome     int64  `json:"total_income"`
+	TotalEarnings   int64  `json:"total_earnings"`
+	TotalCommission int64  `json:"total_commission"`
+	TotalRequested  int64  `json:"total_requested"`
+	TotalPaid       int64  `json:"total_paid"`
+	TotalOnHold     int64  `json:"total_on_hold"`
+	TotalFrozen     int64  `json:"total_frozen"`
+	TotalAvailable  int64  `json:"total_available"`
+}
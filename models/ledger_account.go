@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	LedgerAccountAsset     LedgerAccountType = "asset"
+	LedgerAccountLiability LedgerAccountType = "liability"
+	LedgerAccountRevenue   LedgerAccountType = "revenue"
+)
+
+type LedgerAccountType string
+
+const (
+	LedgerAccountPlatformCommission = "platform_commission"
+	LedgerAccountGatewayFees        = "gateway_fees"
+	LedgerAccountShippingCharges    = "shipping_charges"
+	LedgerAccountPayoutsPending     = "payouts_pending"
+	LedgerAccountPayoutsSent        = "payouts_sent"
+)
+
+func StoreLedgerAccountCode(storeID string) string {
+	return fmt.Sprintf("store:%s", storeID)
+}
+
+func GatewayLedgerAccountCode(gateway string) string {
+	return fmt.Sprintf("gateway:%s", gateway)
+}
+
+type LedgerAccount struct {
+	ID        string            `json:"id" gorm:"column:id;primary_key"`
+	Code      string            `json:"code" gorm:"column:code;unique_index;not null"`
+	Name      string            `json:"name" gorm:"column:name;not null"`
+	Type      LedgerAccountType `json:"type" gorm:"column:type;index;not null"`
+	StoreID   *string           `json:"store_id" gorm:"column:store_id;index"`
+	CreatedAt time.Time         `json:"created_at" gorm:"column:created_at;not null"`
+}
+
+func (la *LedgerAccount) TableName() string {
+	return "ledger_accounts"
+}
+
+func (la *LedgerAccount) ForeignKeys() []string {
+	s := Store{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+	}
+}
+
+// IsDebitNormal reports whether debits increase the balance of the account
+func (la *LedgerAccount) IsDebitNormal() bool {
+	return la.Type == LedgerAccountAsset
+}
+
+type LedgerAccountBalance struct {
+	ID          string            `json:"id"`
+	Code        string            `json:"code"`
+	Name        string            `json:"name"`
+	Type        LedgerAccountType `json:"type"`
+	StoreID     *string           `json:"store_id"`
+	TotalDebit  int64             `json:"total_debit"`
+	TotalCredit int64             `json:"total_credit"`
+	Balance     int64             `json:"balance"`
+}
+
+func (lab *LedgerAccountBalance) TableName() string {
+	la := LedgerAccount{}
+	return la.TableName()
+}
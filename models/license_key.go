@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	LicenseKeyAvailable LicenseKeyStatus = "license_key_available"
+	LicenseKeyAllocated LicenseKeyStatus = "license_key_allocated"
+	LicenseKeyRevoked   LicenseKeyStatus = "license_key_revoked"
+)
+
+type LicenseKeyStatus string
+
+func (lks LicenseKeyStatus) IsValid() bool {
+	for _, v := range []LicenseKeyStatus{LicenseKeyAvailable, LicenseKeyAllocated, LicenseKeyRevoked} {
+		if v == lks {
+			return true
+		}
+	}
+	return false
+}
+
+// LicenseKeyPool makes a digital product hand out license keys, every unit paid for gets one key of the pool.
+// The store is warned once when the available keys drop to the low threshold, and again after refilling it.
+type LicenseKeyPool struct {
+	ProductID    string     `json:"product_id" gorm:"column:product_id;primary_key"`
+	StoreID      string     `json:"store_id" gorm:"column:store_id;index;not null"`
+	LowThreshold int        `json:"low_threshold" gorm:"column:low_threshold;not null;default:10"`
+	WarnedAt     *time.Time `json:"warned_at" gorm:"column:warned_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"column:created_at;not null"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (lkp *LicenseKeyPool) TableName() string {
+	return "license_key_pools"
+}
+
+func (lkp *LicenseKeyPool) ForeignKeys() []string {
+	s := Store{}
+	p := Product{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+	}
+}
+
+// LicenseKey is a key of the pool of a product. Allocated keys belong to an ordered item, they are revoked
+// rather than given back when the payment of the order is reverted.
+type LicenseKey struct {
+	ID            string           `json:"id" gorm:"column:id;primary_key"`
+	StoreID       string           `json:"store_id" gorm:"column:store_id;index;not null"`
+	ProductID     string           `json:"product_id" gorm:"column:product_id;unique_index:uix_license_keys_product_id_key;not null"`
+	Key           string           `json:"key" gorm:"column:key;unique_index:uix_license_keys_product_id_key;not null"`
+	Status        LicenseKeyStatus `json:"status" gorm:"column:status;index;not null"`
+	OrderID       *string          `json:"order_id,omitempty" gorm:"column:order_id;index"`
+	OrderedItemID *string          `json:"ordered_item_id,omitempty" gorm:"column:ordered_item_id;index"`
+	AllocatedAt   *time.Time       `json:"allocated_at,omitempty" gorm:"column:allocated_at"`
+	RevokedAt     *time.Time       `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (lk *LicenseKey) TableName() string {
+	return "license_keys"
+}
+
+func (lk *LicenseKey) ForeignKeys() []string {
+	s := Store{}
+	p := Product{}
+	o := Order{}
+	oi := OrderedItem{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("order_id;%s(id);RESTRICT;RESTRICT", o.TableName()),
+		fmt.Sprintf("ordered_item_id;%s(id);RESTRICT;RESTRICT", oi.TableName()),
+	}
+}
+
+// LicenseKeyPoolDetails is a pool with the number of keys in every status. Pending is the number of units
+// paid for that are still waiting for a key.
+type LicenseKeyPoolDetails struct {
+	ProductID    string     `json:"product_id"`
+	StoreID      string     `json:"store_id"`
+	ProductName  string     `json:"product_name"`
+	LowThreshold int        `json:"low_threshold"`
+	WarnedAt     *time.Time `json:"warned_at"`
+	Available    int        `json:"available"`
+	Allocated    int        `json:"allocated"`
+	Revoked      int        `json:"revoked"`
+	Pending      int        `json:"pending"`
+}
+
+// PendingLicenseKeyItem is an ordered item of a paid order that has fewer keys than units. Missing is counted
+// when listed, the allocation counts it again with the item locked.
+type PendingLicenseKeyItem struct {
+	OrderedItemID string
+	OrderID       string
+	ProductID     string
+	Missing       int
+}
+
+// LicenseKeyUploadResult counts the keys of an upload, the keys already in the pool are skipped
+type LicenseKeyUploadResult struct {
+	Added             int      `json:"added"`
+	Duplicates        []string `json:"duplicates"`
+	Fulfilled         int      `json:"fulfilled"`
+	FulfilledOrderIDs []string `json:"-"`
+}
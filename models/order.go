@@ -59,10 +59,12 @@ type Order struct {
 	SellerEarnings       int64         `json:"seller_earnings" gorm:"seller_earnings;index;not nul;default:0"`
 	PlatformEarnings     int64         `json:"platform_earnings" gorm:"platform_earnings;index;not null;default:0"`
 	ActualEarnings       int64         `json:"actual_earnings" gorm:"actual_earnings;index;not null;default:0"`
+	CommissionFixedFee   int64         `json:"commission_fixed_fee" gorm:"column:commission_fixed_fee;not null;default:0"`
 	GrandTotal           int64         `json:"grand_total" gorm:"column:grand_total;not nul;default:0"`
 	DiscountedAmount     int64         `json:"discounted_amount" gorm:"column:discounted_amount"`
 	Status               OrderStatus   `json:"status" gorm:"column:status"`
 	PaymentStatus        PaymentStatus `json:"payment_status" gorm:"column:payment_status"`
+	PaidAt               *time.Time    `json:"paid_at" gorm:"column:paid_at;index"`
 	CreatedAt            time.Time     `json:"created_at" gorm:"column:created_at;index;not null"`
 	UpdatedAt            time.Time     `json:"updated_at" gorm:"column:updated_at"`
 }
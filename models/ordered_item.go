@@ -3,13 +3,21 @@ package models
 import "fmt"
 
 type OrderedItem struct {
-	ID          string `json:"id" gorm:"column:id;primary_key;not null"`
-	OrderID     string `json:"order_id" gorm:"column:order_id"`
-	ProductID   string `json:"product_id" gorm:"column:product_id"`
-	Quantity    int    `json:"quantity" gorm:"column:quantity"`
-	Price       int64  `json:"price" gorm:"column:price"`
-	ProductCost int64  `json:"product_cost" gorm:"column:product_cost"`
-	SubTotal    int64  `json:"sub_total" gorm:"column:sub_total"`
+	ID          string  `json:"id" gorm:"column:id;primary_key;not null"`
+	OrderID     string  `json:"order_id" gorm:"column:order_id"`
+	ProductID   string  `json:"product_id" gorm:"column:product_id"`
+	VariantID   *string `json:"variant_id" gorm:"column:variant_id;index"`
+	Quantity    int     `json:"quantity" gorm:"column:quantity"`
+	Price       int64   `json:"price" gorm:"column:price"`
+	ProductCost int64   `json:"product_cost" gorm:"column:product_cost"`
+	SubTotal    int64   `json:"sub_total" gorm:"column:sub_total"`
+	// StockLocationID is the location the quantity was allocated from, digital products have none
+	StockLocationID *string `json:"stock_location_id" gorm:"column:stock_location_id;index"`
+	// Commission resolved when the order was placed, kept so later rule changes don't alter past earnings
+	CommissionRuleID   *string `json:"commission_rule_id" gorm:"column:commission_rule_id;index"`
+	CommissionRate     int64   `json:"commission_rate" gorm:"column:commission_rate;not null;default:0"`
+	CommissionFixedFee int64   `json:"commission_fixed_fee" gorm:"column:commission_fixed_fee;not null;default:0"`
+	Commission         int64   `json:"commission" gorm:"column:commission;not null;default:0"`
 }
 
 func (op *OrderedItem) TableName() string {
@@ -19,9 +27,15 @@ func (op *OrderedItem) TableName() string {
 func (op *OrderedItem) ForeignKeys() []string {
 	o := Order{}
 	p := Product{}
+	pv := ProductVariant{}
+	cr := CommissionRule{}
+	sl := StockLocation{}
 
 	return []string{
 		fmt.Sprintf("order_id;%s(id);RESTRICT;RESTRICT", o.TableName()),
 		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("variant_id;%s(id);RESTRICT;RESTRICT", pv.TableName()),
+		fmt.Sprintf("commission_rule_id;%s(id);RESTRICT;RESTRICT", cr.TableName()),
+		fmt.Sprintf("stock_location_id;%s(id);RESTRICT;RESTRICT", sl.TableName()),
 	}
 }
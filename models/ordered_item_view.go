@@ -9,6 +9,8 @@ type OrderedItemView struct {
 	ID               string                 `json:"id"`
 	OrderID          string                 `json:"order_id"`
 	ProductID        string                 `json:"product_id"`
+	VariantID        *string                `json:"variant_id"`
+	VariantTitle     string                 `json:"variant_title,omitempty"`
 	Name             string                 `json:"name"`
 	Quantity         int                    `json:"quantity"`
 	Price            int64                  `json:"price"`
@@ -21,6 +23,7 @@ type OrderedItemView struct {
 	IsShippable      bool                   `json:"is_shippable"`
 	IsDigital        bool                   `json:"is_digital"`
 	Attributes       []OrderItemAttributeKV `json:"attributes"`
+	LicenseKeys      []string               `json:"license_keys,omitempty"`
 }
 
 func (oiv *OrderedItemView) TableName() string {
@@ -30,10 +33,12 @@ func (oiv *OrderedItemView) TableName() string {
 func (oiv *OrderedItemView) CreateView(tx *gorm.DB) error {
 	sql := fmt.Sprintf("CREATE OR REPLACE VIEW %s AS SELECT oi.id AS id, oi.order_id AS order_id, oi.product_id AS product_id, p.name AS name,"+
 		" oi.quantity AS quantity, oi.price AS price, oi.product_cost AS product_cost, oi.sub_total AS sub_total,"+
-		" p.description AS description, p.sku AS sku, p.image AS image,"+
-		" p.is_shippable AS is_shippable, p.is_digital AS is_digital, p.digital_download_link AS digital_download_link"+
+		" p.description AS description, COALESCE(pv.sku, p.sku) AS sku, COALESCE(NULLIF(pv.image, ''), p.image) AS image,"+
+		" p.is_shippable AS is_shippable, p.is_digital AS is_digital, p.digital_download_link AS digital_download_link,"+
+		" oi.variant_id AS variant_id, COALESCE(pv.title, '') AS variant_title"+
 		" FROM ordered_items AS oi"+
-		" LEFT JOIN products AS p ON oi.product_id = p.id;", oiv.TableName())
+		" LEFT JOIN products AS p ON oi.product_id = p.id"+
+		" LEFT JOIN product_variants AS pv ON oi.variant_id = pv.id;", oiv.TableName())
 	if err := tx.Exec(sql).Error; err != nil {
 		return err
 	}
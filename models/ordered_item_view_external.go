@@ -4,6 +4,8 @@ type OrderedItemViewExternal struct {
 	ID               string                 `json:"id"`
 	OrderID          string                 `json:"order_id"`
 	ProductID        string                 `json:"product_id"`
+	VariantID        *string                `json:"variant_id"`
+	VariantTitle     string                 `json:"variant_title,omitempty"`
 	Name             string                 `json:"name"`
 	Quantity         int                    `json:"quantity"`
 	Price            int64                  `json:"price"`
@@ -15,6 +17,7 @@ type OrderedItemViewExternal struct {
 	IsShippable      bool                   `json:"is_shippable"`
 	IsDigital        bool                   `json:"is_digital"`
 	Attributes       []OrderItemAttributeKV `json:"attributes"`
+	LicenseKeys      []string               `json:"license_keys,omitempty"`
 }
 
 func (oive *OrderedItemViewExternal) TableName() string {
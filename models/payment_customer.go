@@ -0,0 +1,27 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// PaymentCustomer links a user to the customer created for them in a payment gateway vault
+type PaymentCustomer struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	UserID         string    `json:"user_id" gorm:"column:user_id;unique_index:uix_payment_customers_user_id_payment_gateway;not null"`
+	PaymentGateway string    `json:"payment_gateway" gorm:"column:payment_gateway;unique_index:uix_payment_customers_user_id_payment_gateway;not null"`
+	CustomerID     string    `json:"customer_id" gorm:"column:customer_id;not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;not null"`
+}
+
+func (pc *PaymentCustomer) TableName() string {
+	return "payment_customers"
+}
+
+func (pc *PaymentCustomer) ForeignKeys() []string {
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DiscrepancyPaidButPending       DiscrepancyType = "paid_but_pending"
+	DiscrepancyRefundedInGateway    DiscrepancyType = "refunded_in_gateway"
+	DiscrepancyAmountMismatch       DiscrepancyType = "amount_mismatch"
+	DiscrepancyUnknownTransaction   DiscrepancyType = "unknown_transaction"
+	DiscrepancyDuplicateTransaction DiscrepancyType = "duplicate_transaction"
+	DiscrepancyMissingInGateway     DiscrepancyType = "missing_in_gateway"
+)
+
+type DiscrepancyType string
+
+type PaymentDiscrepancy struct {
+	ID                     string          `json:"id" gorm:"column:id;primary_key"`
+	ReportID               string          `json:"report_id" gorm:"column:report_id;index;not null"`
+	OrderID                *string         `json:"order_id" gorm:"column:order_id;index"`
+	PaymentGateway         string          `json:"payment_gateway" gorm:"column:payment_gateway;not null"`
+	TransactionID          string          `json:"transaction_id" gorm:"column:transaction_id;index"`
+	Type                   DiscrepancyType `json:"type" gorm:"column:type;index;not null"`
+	GatewayAmount          int64           `json:"gateway_amount" gorm:"column:gateway_amount;not null;default:0"`
+	GatewayRefundedAmount  int64           `json:"gateway_refunded_amount" gorm:"column:gateway_refunded_amount;not null;default:0"`
+	OrderAmount            int64           `json:"order_amount" gorm:"column:order_amount;not null;default:0"`
+	OrderPaymentStatus     *PaymentStatus  `json:"order_payment_status" gorm:"column:order_payment_status"`
+	SuggestedPaymentStatus *PaymentStatus  `json:"suggested_payment_status" gorm:"column:suggested_payment_status"`
+	IsResolved             bool            `json:"is_resolved" gorm:"column:is_resolved;index;not null;default:false"`
+	ResolvedByUserID       *string         `json:"resolved_by_user_id" gorm:"column:resolved_by_user_id"`
+	ResolvedAt             *time.Time      `json:"resolved_at" gorm:"column:resolved_at"`
+	CreatedAt              time.Time       `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (pd *PaymentDiscrepancy) TableName() string {
+	return "payment_discrepancies"
+}
+
+func (pd *PaymentDiscrepancy) ForeignKeys() []string {
+	rr := ReconciliationReport{}
+	o := Order{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("report_id;%s(id);RESTRICT;RESTRICT", rr.TableName()),
+		fmt.Sprintf("order_id;%s(id);RESTRICT;RESTRICT", o.TableName()),
+		fmt.Sprintf("resolved_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
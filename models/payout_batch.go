@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PayoutBatchFormatCSV     PayoutBatchFormat = "csv"
+	PayoutBatchFormatPain001 PayoutBatchFormat = "pain.001"
+)
+
+type PayoutBatchFormat string
+
+func (pbf PayoutBatchFormat) IsValid() bool {
+	for _, v := range []PayoutBatchFormat{PayoutBatchFormatCSV, PayoutBatchFormatPain001} {
+		if pbf == v {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	PayoutBatchExported   PayoutBatchStatus = "payout_batch_exported"
+	PayoutBatchReconciled PayoutBatchStatus = "payout_batch_reconciled"
+)
+
+type PayoutBatchStatus string
+
+type PayoutBatch struct {
+	ID              string            `json:"id" gorm:"column:id;primary_key"`
+	Format          PayoutBatchFormat `json:"format" gorm:"column:format;not null"`
+	Status          PayoutBatchStatus `json:"status" gorm:"column:status;index;not null"`
+	FilePath        string            `json:"-" gorm:"column:file_path;not null"`
+	Currency        string            `json:"currency" gorm:"column:currency;not null"`
+	TotalEntries    int               `json:"total_entries" gorm:"column:total_entries;not null"`
+	TotalAmount     int64             `json:"total_amount" gorm:"column:total_amount;not null"`
+	CreatedByUserID string            `json:"created_by_user_id" gorm:"column:created_by_user_id;index;not null"`
+	CreatedAt       time.Time         `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt       time.Time         `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (pb *PayoutBatch) TableName() string {
+	return "payout_batches"
+}
+
+func (pb *PayoutBatch) ForeignKeys() []string {
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("created_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+type PayoutBatchDetails struct {
+	PayoutBatch
+	Entries []PayoutSend `json:"entries"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"time"
+)
+
+// PayoutEvent records a status change of a payout entry. Events are only ever inserted, never updated
+// or deleted, so they form the audit trail of the entry. The old status is empty for the creation event
+// and the actor is empty for changes made by the system.
+type PayoutEvent struct {
+	ID          string           `json:"id" gorm:"column:id;primary_key"`
+	PayoutID    string           `json:"payout_id" gorm:"column:payout_id;index;not null"`
+	StoreID     string           `json:"store_id" gorm:"column:store_id;index;not null"`
+	ActorUserID *string          `json:"actor_user_id" gorm:"column:actor_user_id;index"`
+	OldStatus   PayoutSendStatus `json:"old_status" gorm:"column:old_status"`
+	NewStatus   PayoutSendStatus `json:"new_status" gorm:"column:new_status;not null"`
+	Note        string           `json:"note" gorm:"column:note"`
+	CreatedAt   time.Time        `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (pe *PayoutEvent) TableName() string {
+	return "payout_events"
+}
+
+func (pe *PayoutEvent) ForeignKeys() []string {
+	ps := PayoutSend{}
+	s := Store{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("payout_id;%s(id);RESTRICT;RESTRICT", ps.TableName()),
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("actor_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+// CreateImmutabilityTrigger makes the database reject any update or delete of the events
+func (pe *PayoutEvent) CreateImmutabilityTrigger(tx *gorm.DB) error {
+	fn := "CREATE OR REPLACE FUNCTION payout_events_immutable() RETURNS trigger AS $$ " +
+		"BEGIN RAISE EXCEPTION 'payout events are immutable'; END; $$ LANGUAGE plpgsql"
+	if err := tx.Exec(fn).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS payout_events_immutable ON %s", pe.TableName())).Error; err != nil {
+		return err
+	}
+
+	trigger := fmt.Sprintf("CREATE TRIGGER payout_events_immutable BEFORE UPDATE OR DELETE ON %s "+
+		"FOR EACH ROW EXECUTE PROCEDURE payout_events_immutable()", pe.TableName())
+	if err := tx.Exec(trigger).Error; err != nil {
+		return err
+	}
+	return nil
+}
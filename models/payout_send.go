@@ -17,6 +17,23 @@ func (pss PayoutSendStatus) IsValid() bool {
 	return false
 }
 
+// payoutSendTransitions lists the statuses a payout entry can move to from each status.
+// Completed and failed payouts are final.
+var payoutSendTransitions = map[PayoutSendStatus][]PayoutSendStatus{
+	PayoutSendStatusPending:    {PayoutSendStatusConfirmed, PayoutSendStatusFailed},
+	PayoutSendStatusConfirmed:  {PayoutSendStatusProcessing, PayoutSendStatusCompleted, PayoutSendStatusFailed},
+	PayoutSendStatusProcessing: {PayoutSendStatusCompleted, PayoutSendStatusFailed},
+}
+
+func (pss PayoutSendStatus) CanTransitionTo(next PayoutSendStatus) bool {
+	for _, v := range payoutSendTransitions[pss] {
+		if v == next {
+			return true
+		}
+	}
+	return false
+}
+
 const (
 	PayoutSendStatusPending    PayoutSendStatus = "payout_pending"
 	PayoutSendStatusConfirmed  PayoutSendStatus = "payout_confirmed"
@@ -37,6 +54,7 @@ type PayoutSend struct {
 	Highlights             string           `json:"highlights" gorm:"column:highlights"`
 	PayoutMethodID         string           `json:"payout_method_id" gorm:"column:payout_method_id"`
 	PayoutMethodDetails    string           `json:"payout_method_details" gorm:"column:payout_method_details"`
+	BatchID                *string          `json:"batch_id" gorm:"column:batch_id;index"`
 	CreatedAt              time.Time        `json:"created_at" gorm:"column:created_at;index"`
 	UpdatedAt              time.Time        `json:"updated_at" gorm:"column:updated_at"`
 }
@@ -49,11 +67,13 @@ func (pst *PayoutSend) ForeignKeys() []string {
 	s := Store{}
 	u := User{}
 	pom := PayoutMethod{}
+	pb := PayoutBatch{}
 
 	return []string{
 		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
 		fmt.Sprintf("initiated_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
 		fmt.Sprintf("payout_method_id;%s(id);RESTRICT;RESTRICT", pom.TableName()),
+		fmt.Sprintf("batch_id;%s(id);RESTRICT;RESTRICT", pb.TableName()),
 	}
 }
 
@@ -71,6 +91,7 @@ type PayoutSendDetails struct {
 	PayoutMethodName       string           `json:"payout_method_name"`
 	PayoutMethodInputs     string           `json:"payout_method_inputs"`
 	PayoutMethodDetails    string           `json:"payout_method_details"`
+	BatchID                *string          `json:"batch_id"`
 	CreatedAt              time.Time        `json:"created_at"`
 	UpdatedAt              time.Time        `json:"updated_at"`
 }
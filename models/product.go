@@ -6,27 +6,28 @@ import (
 )
 
 type Product struct {
-	ID                  string    `json:"id" gorm:"column:id;unique"`
-	Name                string    `json:"name" gorm:"column:name;primary_key"`
-	Slug                string    `json:"slug" gorm:"column:slug;index"`
-	Description         string    `json:"description" gorm:"column:description"`
-	IsPublished         bool      `json:"is_published" gorm:"column:is_published;index"`
-	StoreID             string    `json:"store_id" gorm:"column:store_id;primary_key"`
-	CategoryID          *string   `json:"category_id,omitempty" gorm:"column:category_id;index"`
-	SKU                 string    `json:"sku" gorm:"column:sku;unique"`
-	Stock               int       `json:"stock" gorm:"column:stock;index"`
-	MaxQuantityCount    int       `json:"max_quantity_count" gorm:"column:max_quantity_count;not null;default:10"`
-	Unit                string    `json:"unit" gorm:"column:unit"`
-	Price               int64     `json:"price" gorm:"column:price;index"`
-	ProductCost         int64     `json:"product_cost" gorm:"column:product_cost;index"`
-	Image               string    `json:"image,omitempty" gorm:"column:image"`
-	IsShippable         bool      `json:"is_shippable" gorm:"column:is_shippable;index"`
-	IsDigital           bool      `json:"is_digital" gorm:"column:is_digital;index"`
-	DigitalDownloadLink string    `json:"-" gorm:"column:digital_download_link"`
-	DownloadCounter     int       `json:"download_counter" gorm:"column:download_counter;default:0;index"`
-	Views               int       `json:"views" gorm:"column:views;default:0;index"`
-	CreatedAt           time.Time `json:"created_at" gorm:"column:created_at;index"`
-	UpdatedAt           time.Time `json:"updated_at" gorm:"column:updated_at;index"`
+	ID                  string     `json:"id" gorm:"column:id;unique"`
+	Name                string     `json:"name" gorm:"column:name;primary_key"`
+	Slug                string     `json:"slug" gorm:"column:slug;index"`
+	Description         string     `json:"description" gorm:"column:description"`
+	IsPublished         bool       `json:"is_published" gorm:"column:is_published;index"`
+	UnpublishAt         *time.Time `json:"unpublish_at,omitempty" gorm:"column:unpublish_at;index"`
+	StoreID             string     `json:"store_id" gorm:"column:store_id;primary_key"`
+	CategoryID          *string    `json:"category_id,omitempty" gorm:"column:category_id;index"`
+	SKU                 string     `json:"sku" gorm:"column:sku;unique"`
+	Stock               int        `json:"stock" gorm:"column:stock;index"`
+	MaxQuantityCount    int        `json:"max_quantity_count" gorm:"column:max_quantity_count;not null;default:10"`
+	Unit                string     `json:"unit" gorm:"column:unit"`
+	Price               int64      `json:"price" gorm:"column:price;index"`
+	ProductCost         int64      `json:"product_cost" gorm:"column:product_cost;index"`
+	Image               string     `json:"image,omitempty" gorm:"column:image"`
+	IsShippable         bool       `json:"is_shippable" gorm:"column:is_shippable;index"`
+	IsDigital           bool       `json:"is_digital" gorm:"column:is_digital;index"`
+	DigitalDownloadLink string     `json:"-" gorm:"column:digital_download_link"`
+	DownloadCounter     int        `json:"download_counter" gorm:"column:download_counter;default:0;index"`
+	Views               int        `json:"views" gorm:"column:views;default:0;index"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"column:created_at;index"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"column:updated_at;index"`
 }
 
 func (p *Product) TableName() string {
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	AlertPriceDrop   ProductAlertKind = "price_drop"
+	AlertBackInStock ProductAlertKind = "back_in_stock"
+)
+
+type ProductAlertKind string
+
+func (ak ProductAlertKind) IsValid() bool {
+	for _, v := range []ProductAlertKind{AlertPriceDrop, AlertBackInStock} {
+		if v == ak {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductAlertSubscription is the "notify me" of a user on a product, alerted like the wishlisted products
+type ProductAlertSubscription struct {
+	UserID    string    `json:"-" gorm:"column:user_id;primary_key"`
+	ProductID string    `json:"product_id" gorm:"column:product_id;primary_key"`
+	SeenPrice int64     `json:"-" gorm:"column:seen_price;not null"`
+	SeenStock int       `json:"-" gorm:"column:seen_stock;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (pas *ProductAlertSubscription) TableName() string {
+	return "product_alert_subscriptions"
+}
+
+func (pas *ProductAlertSubscription) ForeignKeys() []string {
+	u := User{}
+	p := Product{}
+
+	return []string{
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+	}
+}
+
+// ProductAlert is a price drop or a product back in stock to email to the user. Alerts failing to be
+// sent stay unsent and are sent again by the next run of the worker.
+type ProductAlert struct {
+	ID        string           `json:"id" gorm:"column:id;primary_key"`
+	UserID    string           `json:"-" gorm:"column:user_id;index;not null"`
+	ProductID string           `json:"product_id" gorm:"column:product_id;index;not null"`
+	Kind      ProductAlertKind `json:"kind" gorm:"column:kind;not null"`
+	OldPrice  int64            `json:"old_price" gorm:"column:old_price;not null"`
+	NewPrice  int64            `json:"new_price" gorm:"column:new_price;not null"`
+	SentAt    *time.Time       `json:"sent_at,omitempty" gorm:"column:sent_at;index"`
+	CreatedAt time.Time        `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (pa *ProductAlert) TableName() string {
+	return "product_alerts"
+}
+
+func (pa *ProductAlert) ForeignKeys() []string {
+	u := User{}
+	p := Product{}
+
+	return []string{
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+	}
+}
+
+// WatchedProduct is a wishlisted or subscribed product of a user whose price or stock changed since last seen
+type WatchedProduct struct {
+	UserID      string
+	ProductID   string
+	SeenPrice   int64
+	SeenStock   int
+	Price       int64
+	Stock       int
+	IsDigital   bool
+	IsAvailable bool
+}
+
+// ProductAlertDetails is an unsent alert with what's needed to email it
+type ProductAlertDetails struct {
+	ProductAlert
+	UserName    string
+	UserEmail   string
+	ProductName string
+	ProductSlug string
+}
@@ -0,0 +1,93 @@
+package models
+
+type ProductSort string
+
+const (
+	ProductSortRelevance   ProductSort = "relevance"
+	ProductSortNewest      ProductSort = "newest"
+	ProductSortPriceAsc    ProductSort = "price_asc"
+	ProductSortPriceDesc   ProductSort = "price_desc"
+	ProductSortPopularity  ProductSort = "popularity"
+	ProductSortBestSelling ProductSort = "best_selling"
+)
+
+func (ps ProductSort) IsValid() bool {
+	for _, s := range []ProductSort{ProductSortRelevance, ProductSortNewest, ProductSortPriceAsc, ProductSortPriceDesc,
+		ProductSortPopularity, ProductSortBestSelling} {
+		if ps == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductPriceBuckets are the upper bounds of the price facet buckets, the last bucket has no upper bound
+var ProductPriceBuckets = []int64{1000, 2500, 5000, 10000, 25000, 50000}
+
+// ProductFilter narrows the public product listing. Values of the same attribute key or of the categories,
+// taxonomy nodes and stores match any of them, while different filters must all match. Categories and
+// taxonomy nodes match the products of their descendants too.
+type ProductFilter struct {
+	Query       string
+	CategoryIDs []string
+	TaxonomyIDs []string
+	StoreIDs    []string
+	MinPrice    *int64
+	MaxPrice    *int64
+	Attributes  map[string][]string
+	IsDigital   *bool
+	InStock     bool
+	MinRating   *int
+	Sort        ProductSort
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type AttributeFacet struct {
+	Key    string       `json:"key"`
+	Values []FacetValue `json:"values"`
+}
+
+type CategoryFacet struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PriceBucketFacet counts the products priced from Min, inclusive, up to Max, exclusive
+type PriceBucketFacet struct {
+	Min   int64  `json:"min"`
+	Max   *int64 `json:"max"`
+	Count int    `json:"count"`
+}
+
+// ProductFacets counts the products matching the filter per facet. The counts of a facet ignore the
+// filter on the facet itself, so they tell how many products selecting another value would add.
+type ProductFacets struct {
+	Attributes   []AttributeFacet   `json:"attributes"`
+	Categories   []CategoryFacet    `json:"categories"`
+	PriceBuckets []PriceBucketFacet `json:"price_buckets"`
+}
+
+// NewPriceBucketFacets lays out every price bucket with its count, counts being keyed by the bucket index
+func NewPriceBucketFacets(counts map[int]int) []PriceBucketFacet {
+	var facets []PriceBucketFacet
+	min := int64(0)
+
+	for i := 0; i <= len(ProductPriceBuckets); i++ {
+		f := PriceBucketFacet{
+			Min:   min,
+			Count: counts[i],
+		}
+		if i < len(ProductPriceBuckets) {
+			max := ProductPriceBuckets[i]
+			f.Max = &max
+			min = max
+		}
+		facets = append(facets, f)
+	}
+	return facets
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type ProductImportStatus string
+
+const (
+	ProductImportPending    ProductImportStatus = "import_pending"
+	ProductImportProcessing ProductImportStatus = "import_processing"
+	ProductImportCompleted  ProductImportStatus = "import_completed"
+	ProductImportFailed     ProductImportStatus = "import_failed"
+)
+
+func (pis ProductImportStatus) IsValid() bool {
+	for _, s := range []ProductImportStatus{ProductImportPending, ProductImportProcessing, ProductImportCompleted, ProductImportFailed} {
+		if pis == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinished tells whether the worker is done with the import
+func (pis ProductImportStatus) IsFinished() bool {
+	return pis == ProductImportCompleted || pis == ProductImportFailed
+}
+
+// ProductImport is a CSV file of products uploaded by a store and processed by the worker.
+// The report lists the outcome of every row, Error is only set when the whole file was rejected.
+type ProductImport struct {
+	ID          string              `json:"id" gorm:"column:id;primary_key"`
+	StoreID     string              `json:"store_id" gorm:"column:store_id;index;not null"`
+	UserID      string              `json:"user_id" gorm:"column:user_id;not null"`
+	FileName    string              `json:"file_name" gorm:"column:file_name"`
+	FilePath    string              `json:"-" gorm:"column:file_path;not null"`
+	ReportPath  string              `json:"-" gorm:"column:report_path"`
+	Status      ProductImportStatus `json:"status" gorm:"column:status;index;not null"`
+	TotalRows   int                 `json:"total_rows" gorm:"column:total_rows;not null;default:0"`
+	CreatedRows int                 `json:"created_rows" gorm:"column:created_rows;not null;default:0"`
+	UpdatedRows int                 `json:"updated_rows" gorm:"column:updated_rows;not null;default:0"`
+	FailedRows  int                 `json:"failed_rows" gorm:"column:failed_rows;not null;default:0"`
+	Error       string              `json:"error,omitempty" gorm:"column:error"`
+	CreatedAt   time.Time           `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt   time.Time           `json:"updated_at" gorm:"column:updated_at"`
+	CompletedAt *time.Time          `json:"completed_at" gorm:"column:completed_at"`
+}
+
+func (pi *ProductImport) TableName() string {
+	return "product_imports"
+}
+
+func (pi *ProductImport) ForeignKeys() []string {
+	s := Store{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
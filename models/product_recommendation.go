@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	RecommendationBoughtTogether RecommendationKind = "bought_together"
+	RecommendationRelated        RecommendationKind = "related"
+)
+
+type RecommendationKind string
+
+func (rk RecommendationKind) IsValid() bool {
+	for _, v := range []RecommendationKind{RecommendationBoughtTogether, RecommendationRelated} {
+		if v == rk {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductRecommendation is a product recommended along with another one, computed by the worker.
+// The higher the score the stronger the recommendation, scores only compare within a kind.
+type ProductRecommendation struct {
+	ProductID            string             `json:"product_id" gorm:"column:product_id;primary_key"`
+	RecommendedProductID string             `json:"recommended_product_id" gorm:"column:recommended_product_id;primary_key"`
+	Kind                 RecommendationKind `json:"kind" gorm:"column:kind;primary_key"`
+	Score                float64            `json:"score" gorm:"column:score;not null"`
+	ComputedAt           time.Time          `json:"computed_at" gorm:"column:computed_at;not null"`
+}
+
+func (pr *ProductRecommendation) TableName() string {
+	return "product_recommendations"
+}
+
+func (pr *ProductRecommendation) ForeignKeys() []string {
+	p := Product{}
+
+	// Recommendations are computed again anyway, they go away along with their products
+	return []string{
+		fmt.Sprintf("product_id;%s(id);CASCADE;RESTRICT", p.TableName()),
+		fmt.Sprintf("recommended_product_id;%s(id);CASCADE;RESTRICT", p.TableName()),
+	}
+}
+
+// ProductPairCount counts what two products have in common, like the orders both are in
+type ProductPairCount struct {
+	ProductID      string
+	OtherProductID string
+	Count          int
+}
+
+// ProductCount counts something about a product, like the orders it's in
+type ProductCount struct {
+	ProductID string
+	Count     int
+}
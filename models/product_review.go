@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ReviewPending  ReviewStatus = "review_pending"
+	ReviewApproved ReviewStatus = "review_approved"
+	ReviewRejected ReviewStatus = "review_rejected"
+)
+
+type ReviewStatus string
+
+func (rs ReviewStatus) IsValid() bool {
+	for _, v := range []ReviewStatus{ReviewPending, ReviewApproved, ReviewRejected} {
+		if v == rs {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductReviewMaxPhotos limits the photos attached to a review
+const ProductReviewMaxPhotos = 5
+
+// ProductReview is the review of a purchased item by the buyer. A review waits for moderation before it's
+// public and only approved reviews count in the rating of the product.
+type ProductReview struct {
+	ID                string       `json:"id" gorm:"column:id;primary_key"`
+	OrderedItemID     string       `json:"ordered_item_id" gorm:"column:ordered_item_id;unique;not null"`
+	OrderID           string       `json:"order_id" gorm:"column:order_id;index;not null"`
+	ProductID         string       `json:"product_id" gorm:"column:product_id;index;not null"`
+	StoreID           string       `json:"store_id" gorm:"column:store_id;index;not null"`
+	UserID            string       `json:"user_id" gorm:"column:user_id;index;not null"`
+	Rating            int          `json:"rating" gorm:"column:rating;index;not null"`
+	Title             string       `json:"title" gorm:"column:title"`
+	Description       string       `json:"description" gorm:"column:description"`
+	Status            ReviewStatus `json:"status" gorm:"column:status;index;not null"`
+	ModerationNote    string       `json:"moderation_note,omitempty" gorm:"column:moderation_note"`
+	ModeratedAt       *time.Time   `json:"moderated_at,omitempty" gorm:"column:moderated_at"`
+	ModeratedByUserID *string      `json:"-" gorm:"column:moderated_by_user_id"`
+	Reply             string       `json:"reply,omitempty" gorm:"column:reply"`
+	RepliedAt         *time.Time   `json:"replied_at,omitempty" gorm:"column:replied_at"`
+	RepliedByUserID   *string      `json:"-" gorm:"column:replied_by_user_id"`
+	CreatedAt         time.Time    `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt         time.Time    `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (pr *ProductReview) TableName() string {
+	return "product_reviews"
+}
+
+func (pr *ProductReview) ForeignKeys() []string {
+	oi := OrderedItem{}
+	o := Order{}
+	p := Product{}
+	s := Store{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("ordered_item_id;%s(id);RESTRICT;RESTRICT", oi.TableName()),
+		fmt.Sprintf("order_id;%s(id);RESTRICT;RESTRICT", o.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+		fmt.Sprintf("moderated_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+		fmt.Sprintf("replied_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+type ProductReviewPhoto struct {
+	ID        string    `json:"id" gorm:"column:id;primary_key"`
+	ReviewID  string    `json:"review_id" gorm:"column:review_id;index;not null"`
+	Path      string    `json:"path" gorm:"column:path;not null"`
+	Position  int       `json:"position" gorm:"column:position;not null;default:0"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;not null"`
+}
+
+func (prp *ProductReviewPhoto) TableName() string {
+	return "product_review_photos"
+}
+
+func (prp *ProductReviewPhoto) ForeignKeys() []string {
+	pr := ProductReview{}
+
+	return []string{
+		fmt.Sprintf("review_id;%s(id);RESTRICT;RESTRICT", pr.TableName()),
+	}
+}
+
+// ProductReviewDetails is the review with the name of the buyer and the variant bought
+type ProductReviewDetails struct {
+	ProductReview
+	UserName     string   `json:"user_name"`
+	VariantTitle *string  `json:"variant_title,omitempty"`
+	Photos       []string `json:"photos" gorm:"-"`
+}
+
+// PublicProductReview is an approved review as shown on the product, it leaves out the order of the buyer
+// and the moderation
+type PublicProductReview struct {
+	ID           string     `json:"id"`
+	ProductID    string     `json:"product_id"`
+	Rating       int        `json:"rating"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Reply        string     `json:"reply,omitempty"`
+	RepliedAt    *time.Time `json:"replied_at,omitempty"`
+	UserName     string     `json:"user_name"`
+	VariantTitle *string    `json:"variant_title,omitempty"`
+	Photos       []string   `json:"photos" gorm:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
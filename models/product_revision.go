@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	RevisionDraft     RevisionStatus = "revision_draft"
+	RevisionScheduled RevisionStatus = "revision_scheduled"
+	RevisionPublished RevisionStatus = "revision_published"
+	RevisionArchived  RevisionStatus = "revision_archived"
+)
+
+type RevisionStatus string
+
+func (rs RevisionStatus) IsValid() bool {
+	for _, v := range []RevisionStatus{RevisionDraft, RevisionScheduled, RevisionPublished, RevisionArchived} {
+		if v == rs {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEditable tells whether the revision hasn't gone live yet
+func (rs RevisionStatus) IsEditable() bool {
+	return rs == RevisionDraft || rs == RevisionScheduled
+}
+
+// ProductRevision is a snapshot of the content of a product. Drafts are prepared and previewed by the
+// store staff and go live when published, right away or by the worker at PublishAt. The published
+// revision holds the content currently on the product, the ones published before it are archived
+// and can be reverted to.
+type ProductRevision struct {
+	ID                string         `json:"id" gorm:"column:id;primary_key"`
+	ProductID         string         `json:"product_id" gorm:"column:product_id;unique_index:uix_product_revisions_number;not null"`
+	StoreID           string         `json:"store_id" gorm:"column:store_id;index;not null"`
+	Number            int            `json:"number" gorm:"column:number;unique_index:uix_product_revisions_number;not null"`
+	Status            RevisionStatus `json:"status" gorm:"column:status;index;not null"`
+	Note              string         `json:"note" gorm:"column:note"`
+	Name              string         `json:"name" gorm:"column:name;not null"`
+	Slug              string         `json:"slug" gorm:"column:slug;not null"`
+	Description       string         `json:"description" gorm:"column:description"`
+	CategoryID        *string        `json:"category_id,omitempty" gorm:"column:category_id"`
+	Image             string         `json:"image,omitempty" gorm:"column:image"`
+	Unit              string         `json:"unit" gorm:"column:unit"`
+	Price             int64          `json:"price" gorm:"column:price"`
+	ProductCost       int64          `json:"product_cost" gorm:"column:product_cost"`
+	MaxQuantityCount  int            `json:"max_quantity_count" gorm:"column:max_quantity_count"`
+	IsShippable       bool           `json:"is_shippable" gorm:"column:is_shippable"`
+	IsDigital         bool           `json:"is_digital" gorm:"column:is_digital"`
+	AdditionalImages  []string       `json:"additional_images" gorm:"-"`
+	PublishAt         *time.Time     `json:"publish_at,omitempty" gorm:"column:publish_at;index"`
+	PublishedAt       *time.Time     `json:"published_at,omitempty" gorm:"column:published_at"`
+	RevertedFromID    *string        `json:"reverted_from_id,omitempty" gorm:"column:reverted_from_id"`
+	CreatedByUserID   *string        `json:"-" gorm:"column:created_by_user_id"`
+	PublishedByUserID *string        `json:"-" gorm:"column:published_by_user_id"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt         time.Time      `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (pr *ProductRevision) TableName() string {
+	return "product_revisions"
+}
+
+func (pr *ProductRevision) ForeignKeys() []string {
+	p := Product{}
+	s := Store{}
+	c := Category{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("category_id;%s(id);RESTRICT;RESTRICT", c.TableName()),
+		fmt.Sprintf("reverted_from_id;%s(id);RESTRICT;RESTRICT", pr.TableName()),
+		fmt.Sprintf("created_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+		fmt.Sprintf("published_by_user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+type ProductRevisionImage struct {
+	RevisionID string `json:"revision_id" gorm:"column:revision_id;primary_key"`
+	ImagePath  string `json:"image_path" gorm:"column:image_path;primary_key"`
+}
+
+func (pri *ProductRevisionImage) TableName() string {
+	return "product_revision_images"
+}
+
+func (pri *ProductRevisionImage) ForeignKeys() []string {
+	pr := ProductRevision{}
+
+	return []string{
+		fmt.Sprintf("revision_id;%s(id);RESTRICT;RESTRICT", pr.TableName()),
+	}
+}
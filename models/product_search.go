@@ -0,0 +1,67 @@
+package models
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+)
+
+// ProductSearchConfig is the text search configuration used for indexing and querying products
+const ProductSearchConfig = "english"
+
+// ProductSKUSearchConfig indexes and queries the SKUs as they are, without stemming or stop words
+const ProductSKUSearchConfig = "simple"
+
+// CreateSearchIndex adds the search_vector column of the products with its GIN index and the triggers
+// keeping it up to date. The vector weights the name and SKU first, then the category, the attributes
+// and the description. It's kept out of the Product struct so the ORM never writes it.
+func (p *Product) CreateSearchIndex(tx *gorm.DB) error {
+	c := Category{}
+	pa := ProductAttribute{}
+
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector", p.TableName()),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_products_search_vector ON %s USING GIN (search_vector)", p.TableName()),
+
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION products_search_vector(p_id text, p_name text, p_description text, p_sku text, p_category_id text) "+
+			"RETURNS tsvector AS $$ SELECT "+
+			"setweight(to_tsvector('%[1]s', COALESCE(p_name, '')), 'A') || "+
+			"setweight(to_tsvector('%[4]s', COALESCE(p_sku, '')), 'A') || "+
+			"setweight(to_tsvector('%[1]s', COALESCE((SELECT name FROM %[2]s WHERE id = p_category_id), '')), 'B') || "+
+			"setweight(to_tsvector('%[1]s', COALESCE((SELECT string_agg(key || ' ' || value, ' ') FROM %[3]s WHERE product_id = p_id), '')), 'C') || "+
+			"setweight(to_tsvector('%[1]s', COALESCE(p_description, '')), 'D') "+
+			"$$ LANGUAGE sql STABLE", ProductSearchConfig, c.TableName(), pa.TableName(), ProductSKUSearchConfig),
+
+		"CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS trigger AS $$ BEGIN " +
+			"NEW.search_vector := products_search_vector(NEW.id, NEW.name, NEW.description, NEW.sku, NEW.category_id); " +
+			"RETURN NEW; END; $$ LANGUAGE plpgsql",
+		fmt.Sprintf("DROP TRIGGER IF EXISTS products_search_vector_update ON %s", p.TableName()),
+		fmt.Sprintf("CREATE TRIGGER products_search_vector_update BEFORE INSERT OR UPDATE OF name, description, sku, category_id ON %s "+
+			"FOR EACH ROW EXECUTE PROCEDURE products_search_vector_update()", p.TableName()),
+
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION product_attributes_search_vector_update() RETURNS trigger AS $$ BEGIN "+
+			"IF TG_OP <> 'INSERT' THEN UPDATE %[1]s SET search_vector = products_search_vector(id, name, description, sku, category_id) WHERE id = OLD.product_id; END IF; "+
+			"IF TG_OP <> 'DELETE' THEN UPDATE %[1]s SET search_vector = products_search_vector(id, name, description, sku, category_id) WHERE id = NEW.product_id; END IF; "+
+			"RETURN NULL; END; $$ LANGUAGE plpgsql", p.TableName()),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS product_attributes_search_vector_update ON %s", pa.TableName()),
+		fmt.Sprintf("CREATE TRIGGER product_attributes_search_vector_update AFTER INSERT OR UPDATE OR DELETE ON %s "+
+			"FOR EACH ROW EXECUTE PROCEDURE product_attributes_search_vector_update()", pa.TableName()),
+
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION categories_search_vector_update() RETURNS trigger AS $$ BEGIN "+
+			"UPDATE %s SET search_vector = products_search_vector(id, name, description, sku, category_id) WHERE category_id = NEW.id; "+
+			"RETURN NULL; END; $$ LANGUAGE plpgsql", p.TableName()),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS categories_search_vector_update ON %s", c.TableName()),
+		fmt.Sprintf("CREATE TRIGGER categories_search_vector_update AFTER UPDATE OF name ON %s "+
+			"FOR EACH ROW EXECUTE PROCEDURE categories_search_vector_update()", c.TableName()),
+
+		// Products created before the column existed
+		fmt.Sprintf("UPDATE %s SET search_vector = products_search_vector(id, name, description, sku, category_id) "+
+			"WHERE search_vector IS NULL", p.TableName()),
+	}
+
+	for _, s := range statements {
+		if err := tx.Exec(s).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
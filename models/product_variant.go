@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProductOption is an option the customer chooses from when buying the product, e.g. Size or Color
+type ProductOption struct {
+	ID        string `json:"id" gorm:"column:id;primary_key"`
+	ProductID string `json:"product_id" gorm:"column:product_id;not null;unique_index:uix_product_options_product_id_name"`
+	Name      string `json:"name" gorm:"column:name;not null;unique_index:uix_product_options_product_id_name"`
+	Position  int    `json:"position" gorm:"column:position;not null;default:0"`
+}
+
+func (po *ProductOption) TableName() string {
+	return "product_options"
+}
+
+func (po *ProductOption) ForeignKeys() []string {
+	p := Product{}
+
+	return []string{
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+	}
+}
+
+type ProductOptionValue struct {
+	ID       string `json:"id" gorm:"column:id;primary_key"`
+	OptionID string `json:"option_id" gorm:"column:option_id;not null;unique_index:uix_product_option_values_option_id_value"`
+	Value    string `json:"value" gorm:"column:value;not null;unique_index:uix_product_option_values_option_id_value"`
+	Position int    `json:"position" gorm:"column:position;not null;default:0"`
+}
+
+func (pov *ProductOptionValue) TableName() string {
+	return "product_option_values"
+}
+
+func (pov *ProductOptionValue) ForeignKeys() []string {
+	po := ProductOption{}
+
+	return []string{
+		fmt.Sprintf("option_id;%s(id);RESTRICT;RESTRICT", po.TableName()),
+	}
+}
+
+type ProductOptionDetails struct {
+	ProductOption
+	Values []ProductOptionValue `json:"values"`
+}
+
+// ProductVariant is a combination of one value of every option of the product. Variants are never
+// deleted as orders reference them, those left out of the combinations after an option change are deactivated.
+type ProductVariant struct {
+	ID        string `json:"id" gorm:"column:id;primary_key"`
+	ProductID string `json:"product_id" gorm:"column:product_id;not null;unique_index:uix_product_variants_product_id_combination"`
+	// Combination is the option value IDs in option order, it identifies the variant among the product variants
+	Combination string `json:"-" gorm:"column:combination;not null;unique_index:uix_product_variants_product_id_combination"`
+	Title       string `json:"title" gorm:"column:title;not null"`
+	SKU         string `json:"sku" gorm:"column:sku;unique;not null"`
+	// Price overrides the product price when set
+	Price     *int64    `json:"price" gorm:"column:price"`
+	Stock     int       `json:"stock" gorm:"column:stock;not null;default:0;index"`
+	Weight    int       `json:"weight" gorm:"column:weight;not null;default:0"`
+	Image     string    `json:"image,omitempty" gorm:"column:image"`
+	IsActive  bool      `json:"is_active" gorm:"column:is_active;not null;default:true;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at"`
+}
+
+func (pv *ProductVariant) TableName() string {
+	return "product_variants"
+}
+
+func (pv *ProductVariant) ForeignKeys() []string {
+	p := Product{}
+
+	return []string{
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+	}
+}
+
+// EffectivePrice is the price of the variant, falling back to the product price
+func (pv *ProductVariant) EffectivePrice(p *Product) int64 {
+	if pv.Price != nil {
+		return *pv.Price
+	}
+	return p.Price
+}
+
+type ProductVariantOptionValue struct {
+	VariantID     string `json:"variant_id" gorm:"column:variant_id;primary_key"`
+	OptionValueID string `json:"option_value_id" gorm:"column:option_value_id;primary_key"`
+}
+
+func (pvov *ProductVariantOptionValue) TableName() string {
+	return "product_variant_option_values"
+}
+
+func (pvov *ProductVariantOptionValue) ForeignKeys() []string {
+	pv := ProductVariant{}
+	pov := ProductOptionValue{}
+
+	return []string{
+		fmt.Sprintf("variant_id;%s(id);RESTRICT;RESTRICT", pv.TableName()),
+		fmt.Sprintf("option_value_id;%s(id);RESTRICT;RESTRICT", pov.TableName()),
+	}
+}
+
+// ProductVariantOption is the chosen value of an option of a variant
+type ProductVariantOption struct {
+	OptionID      string `json:"option_id"`
+	Option        string `json:"option"`
+	OptionValueID string `json:"option_value_id"`
+	Value         string `json:"value"`
+}
+
+type ProductVariantDetails struct {
+	ProductVariant
+	Options []ProductVariantOption `json:"options"`
+}
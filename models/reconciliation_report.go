@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ReconciliationRunning   ReconciliationStatus = "reconciliation_running"
+	ReconciliationCompleted ReconciliationStatus = "reconciliation_completed"
+	ReconciliationFailed    ReconciliationStatus = "reconciliation_failed"
+)
+
+type ReconciliationStatus string
+
+type ReconciliationReport struct {
+	ID                 string               `json:"id" gorm:"column:id;primary_key"`
+	From               time.Time            `json:"from" gorm:"column:from_time;not null"`
+	To                 time.Time            `json:"to" gorm:"column:to_time;not null"`
+	Status             ReconciliationStatus `json:"status" gorm:"column:status;index;not null"`
+	TotalTransactions  int                  `json:"total_transactions" gorm:"column:total_transactions;not null;default:0"`
+	TotalDiscrepancies int                  `json:"total_discrepancies" gorm:"column:total_discrepancies;not null;default:0"`
+	FailureReason      string               `json:"failure_reason" gorm:"column:failure_reason"`
+	StartedAt          time.Time            `json:"started_at" gorm:"column:started_at;index;not null"`
+	FinishedAt         *time.Time           `json:"finished_at" gorm:"column:finished_at"`
+}
+
+func (rr *ReconciliationReport) TableName() string {
+	return "reconciliation_reports"
+}
+
+type ReconciliationReportDetails struct {
+	ReconciliationReport
+	Discrepancies []PaymentDiscrepancy `json:"discrepancies"`
+}
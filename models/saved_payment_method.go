@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type SavedPaymentMethod struct {
+	ID             string    `json:"id" gorm:"column:id;primary_key"`
+	UserID         string    `json:"user_id" gorm:"column:user_id;index;not null"`
+	PaymentGateway string    `json:"payment_gateway" gorm:"column:payment_gateway;index;not null"`
+	CustomerID     string    `json:"-" gorm:"column:customer_id;not null"`
+	Token          string    `json:"-" gorm:"column:token;unique_index;not null"`
+	Brand          string    `json:"brand" gorm:"column:brand"`
+	Last4          string    `json:"last4" gorm:"column:last4"`
+	ExpMonth       int       `json:"exp_month" gorm:"column:exp_month"`
+	ExpYear        int       `json:"exp_year" gorm:"column:exp_year"`
+	CreatedAt      time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (spm *SavedPaymentMethod) TableName() string {
+	return "saved_payment_methods"
+}
+
+func (spm *SavedPaymentMethod) ForeignKeys() []string {
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
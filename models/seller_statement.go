@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SellerStatement is the monthly account statement of a store, generated from its ledger account.
+// The PDF and CSV files are kept in the reserved bucket.
+type SellerStatement struct {
+	ID              string    `json:"id" gorm:"column:id;primary_key"`
+	StoreID         string    `json:"store_id" gorm:"column:store_id;unique_index:uix_seller_statements_store_id_period_start;not null"`
+	PeriodStart     time.Time `json:"period_start" gorm:"column:period_start;unique_index:uix_seller_statements_store_id_period_start;not null"`
+	PeriodEnd       time.Time `json:"period_end" gorm:"column:period_end;not null"`
+	OpeningBalance  int64     `json:"opening_balance" gorm:"column:opening_balance;not null"`
+	TotalOrders     int       `json:"total_orders" gorm:"column:total_orders;not null"`
+	TotalSales      int64     `json:"total_sales" gorm:"column:total_sales;not null"`
+	TotalCommission int64     `json:"total_commission" gorm:"column:total_commission;not null"`
+	TotalEarnings   int64     `json:"total_earnings" gorm:"column:total_earnings;not null"`
+	TotalRefunds    int64     `json:"total_refunds" gorm:"column:total_refunds;not null"`
+	TotalPayouts    int64     `json:"total_payouts" gorm:"column:total_payouts;not null"`
+	ClosingBalance  int64     `json:"closing_balance" gorm:"column:closing_balance;not null"`
+	PDFPath         string    `json:"-" gorm:"column:pdf_path;not null"`
+	CSVPath         string    `json:"-" gorm:"column:csv_path;not null"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (ss *SellerStatement) TableName() string {
+	return "seller_statements"
+}
+
+func (ss *SellerStatement) ForeignKeys() []string {
+	s := Store{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+	}
+}
+
+// StoreLedgerMovement is the effect of a journal entry on the store account and on the platform commission
+type StoreLedgerMovement struct {
+	JournalEntryID   string               `json:"journal_entry_id"`
+	ReferenceType    JournalReferenceType `json:"reference_type"`
+	ReferenceID      string               `json:"reference_id"`
+	Event            string               `json:"event"`
+	Description      string               `json:"description"`
+	StoreAmount      int64                `json:"store_amount"`
+	CommissionAmount int64                `json:"commission_amount"`
+	CreatedAt        time.Time            `json:"created_at"`
+}
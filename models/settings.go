@@ -21,6 +21,7 @@ type Settings struct {
 	IsSignUpEnabled              bool           `json:"is_sign_up_enabled" gorm:"column:is_sign_up_enabled;not null"`
 	IsStoreCreationEnabled       bool           `json:"is_store_creation_enabled" gorm:"column:is_store_creation_enabled;not null"`
 	DefaultCommissionRate        int64          `json:"default_commission_rate" gorm:"column:default_commission_rate;not null;default:0"`
+	DefaultReservePeriodDays     int            `json:"default_reserve_period_days" gorm:"column:default_reserve_period_days;not null;default:0"`
 	EnabledAutoStoreConfirmation bool           `json:"enabled_auto_store_confirmation" gorm:"column:enabled_auto_store_confirmation"`
 	TagLine                      string         `json:"tag_line" gorm:"column:tag_line;not null"`
 	CreatedAt                    time.Time      `json:"created_at" gorm:"column:created_at;not null"`
@@ -17,6 +17,7 @@ type SettingsDetails struct {
 	IsStoreCreationEnabled       bool           `json:"is_store_creation_enabled"`
 	EnabledAutoStoreConfirmation bool           `json:"enabled_auto_store_confirmation"`
 	DefaultCommissionRate        int64          `json:"default_commission_rate"`
+	DefaultReservePeriodDays     int            `json:"default_reserve_period_days"`
 	TagLine                      string         `json:"tag_line"`
 	CreatedAt                    time.Time      `json:"created_at"`
 	UpdatedAt                    time.Time      `json:"updated_at"`
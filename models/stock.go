@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	StockReceived   StockAdjustmentReason = "received"
+	StockDamaged    StockAdjustmentReason = "damaged"
+	StockCorrection StockAdjustmentReason = "correction"
+	StockSale       StockAdjustmentReason = "sale"
+	StockReturn     StockAdjustmentReason = "return"
+)
+
+type StockAdjustmentReason string
+
+func (sar StockAdjustmentReason) IsValid() bool {
+	for _, v := range []StockAdjustmentReason{StockReceived, StockDamaged, StockCorrection, StockSale, StockReturn} {
+		if v == sar {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign is 1 for the reasons adding stock, -1 for the ones removing it and 0 for corrections going either way
+func (sar StockAdjustmentReason) Sign() int {
+	switch sar {
+	case StockReceived, StockReturn:
+		return 1
+	case StockDamaged, StockSale:
+		return -1
+	}
+	return 0
+}
+
+// StockLocation is a warehouse or a shop the store keeps stock at. The first location of a store is
+// its default one, it takes over the stock the products had before and receives the stock set without a location.
+type StockLocation struct {
+	ID        string    `json:"id" gorm:"column:id;primary_key"`
+	StoreID   string    `json:"store_id" gorm:"column:store_id;unique_index:uix_stock_locations_store_id_code;not null"`
+	Name      string    `json:"name" gorm:"column:name;not null"`
+	Code      string    `json:"code" gorm:"column:code;unique_index:uix_stock_locations_store_id_code;not null"`
+	Address   string    `json:"address" gorm:"column:address"`
+	IsDefault bool      `json:"is_default" gorm:"column:is_default;not null;default:false"`
+	IsActive  bool      `json:"is_active" gorm:"column:is_active;not null;default:true;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (sl *StockLocation) TableName() string {
+	return "stock_locations"
+}
+
+func (sl *StockLocation) ForeignKeys() []string {
+	s := Store{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+	}
+}
+
+// StockLevel is the quantity of a product, or of one of its variants, at a location. The stock of
+// the product or the variant is the total of its levels.
+type StockLevel struct {
+	LocationID string    `json:"location_id" gorm:"column:location_id;index;not null"`
+	ProductID  string    `json:"product_id" gorm:"column:product_id;index;not null"`
+	VariantID  *string   `json:"variant_id" gorm:"column:variant_id;index"`
+	Quantity   int       `json:"quantity" gorm:"column:quantity;not null;default:0"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (sl *StockLevel) TableName() string {
+	return "stock_levels"
+}
+
+func (sl *StockLevel) ForeignKeys() []string {
+	loc := StockLocation{}
+	p := Product{}
+	pv := ProductVariant{}
+
+	return []string{
+		fmt.Sprintf("location_id;%s(id);RESTRICT;RESTRICT", loc.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("variant_id;%s(id);RESTRICT;RESTRICT", pv.TableName()),
+	}
+}
+
+// CreateUniqueIndex keeps a single level per product or variant and location, the variant is null for products
+func (sl *StockLevel) CreateUniqueIndex(tx *gorm.DB) error {
+	return tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS uix_stock_levels_item ON %s "+
+		"(location_id, product_id, COALESCE(variant_id, ''))", sl.TableName())).Error
+}
+
+// StockAdjustment is a change of the quantity at a location. UserID is the staff member who made it,
+// the sales reference their order instead.
+type StockAdjustment struct {
+	ID         string                `json:"id" gorm:"column:id;primary_key"`
+	StoreID    string                `json:"store_id" gorm:"column:store_id;index;not null"`
+	LocationID string                `json:"location_id" gorm:"column:location_id;index;not null"`
+	ProductID  string                `json:"product_id" gorm:"column:product_id;index;not null"`
+	VariantID  *string               `json:"variant_id" gorm:"column:variant_id;index"`
+	Reason     StockAdjustmentReason `json:"reason" gorm:"column:reason;index;not null"`
+	Quantity   int                   `json:"quantity" gorm:"column:quantity;not null"`
+	// QuantityAfter is the quantity left at the location once adjusted
+	QuantityAfter int       `json:"quantity_after" gorm:"column:quantity_after;not null"`
+	Note          string    `json:"note" gorm:"column:note"`
+	OrderID       *string   `json:"order_id,omitempty" gorm:"column:order_id;index"`
+	UserID        *string   `json:"user_id,omitempty" gorm:"column:user_id"`
+	CreatedAt     time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (sa *StockAdjustment) TableName() string {
+	return "stock_adjustments"
+}
+
+func (sa *StockAdjustment) ForeignKeys() []string {
+	s := Store{}
+	loc := StockLocation{}
+	p := Product{}
+	pv := ProductVariant{}
+	o := Order{}
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("store_id;%s(id);RESTRICT;RESTRICT", s.TableName()),
+		fmt.Sprintf("location_id;%s(id);RESTRICT;RESTRICT", loc.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+		fmt.Sprintf("variant_id;%s(id);RESTRICT;RESTRICT", pv.TableName()),
+		fmt.Sprintf("order_id;%s(id);RESTRICT;RESTRICT", o.TableName()),
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+// StockLevelDetails is a level with the location and the product or variant it's for
+type StockLevelDetails struct {
+	LocationID   string    `json:"location_id"`
+	LocationName string    `json:"location_name"`
+	LocationCode string    `json:"location_code"`
+	ProductID    string    `json:"product_id"`
+	ProductName  string    `json:"product_name"`
+	VariantID    *string   `json:"variant_id"`
+	VariantTitle *string   `json:"variant_title"`
+	SKU          string    `json:"sku"`
+	Quantity     int       `json:"quantity"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// StockItem is the product or the variant a SKU belongs to
+type StockItem struct {
+	ProductID string
+	VariantID *string
+	SKU       string
+	IsDigital bool
+}
+
+const (
+	StockSyncUpdated    = "updated"
+	StockSyncUnchanged  = "unchanged"
+	StockSyncNotFound   = "not_found"
+	StockSyncNotTracked = "not_tracked"
+)
+
+// StockSyncResult is the outcome of setting the quantity of a SKU in a bulk stock update
+type StockSyncResult struct {
+	SKU      string `json:"sku"`
+	Status   string `json:"status"`
+	Quantity int    `json:"quantity"`
+}
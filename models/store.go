@@ -31,6 +31,8 @@ type Store struct {
 	LogoImage                string      `json:"logo_image" gorm:"column:logo_image"`
 	CoverImage               string      `json:"cover_image" gorm:"column:cover_image"`
 	CommissionRate           int64       `json:"commission_rate" gorm:"column:commission_rate;not null;default:0"`
+	Tier                     string      `json:"tier" gorm:"column:tier;index"`
+	ReservePeriodDays        *int        `json:"reserve_period_days" gorm:"column:reserve_period_days"`
 	IsProductCreationEnabled bool        `json:"is_product_creation_enabled" gorm:"column:is_product_creation_enabled;not null;index"`
 	IsOrderCreationEnabled   bool        `json:"is_order_creation_enabled" gorm:"column:is_order_creation_enabled;not null;index"`
 	IsAutoConfirmEnabled     bool        `json:"is_auto_confirm_enabled" json:"column:is_auto_confirm_enabled;not null;index"`
@@ -13,6 +13,8 @@ type StoreView struct {
 	LogoImage                string      `json:"logo_image"`
 	CoverImage               string      `json:"cover_image"`
 	CommissionRate           int64       `json:"commission_rate"`
+	Tier                     string      `json:"tier"`
+	ReservePeriodDays        *int        `json:"reserve_period_days"`
 	IsProductCreationEnabled bool        `json:"is_product_creation_enabled"`
 	IsOrderCreationEnabled   bool        `json:"is_order_creation_enabled"`
 	IsAutoConfirmEnabled     bool        `json:"is_auto_confirm_enabled"`
@@ -33,7 +35,7 @@ func (sv *StoreView) TableName() string {
 
 func (sv *StoreView) CreateView(tx *gorm.DB) error {
 	sql := fmt.Sprintf("CREATE OR REPLACE VIEW %s AS SELECT s.id AS id, s.name AS name, s.status AS status, s.logo_image AS logo_image,"+
-		" s.cover_image AS cover_image, s.commission_rate AS commission_rate, s.is_product_creation_enabled AS is_product_creation_enabled,"+
+		" s.cover_image AS cover_image, s.commission_rate AS commission_rate, s.tier AS tier, s.reserve_period_days AS reserve_period_days, s.is_product_creation_enabled AS is_product_creation_enabled,"+
 		" s.is_order_creation_enabled AS is_order_creation_enabled, s.is_auto_confirm_enabled AS is_auto_confirm_enabled,"+
 		" s.description AS description, av.address AS address, av.city AS city, av.country AS country, av.postcode AS postcode,"+
 		" av.email AS email, av.phone AS phone, s.created_at AS created_at, s.updated_at AS updated_at"+
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CategoryMaxDepth is the depth of the deepest categories and taxonomy nodes, roots being at depth 0
+const CategoryMaxDepth = 4
+
+// TaxonomyNode is a category of the marketplace-wide taxonomy owned by the platform.
+// Store categories map into it so products of every store can be browsed together.
+type TaxonomyNode struct {
+	ID          string    `json:"id" gorm:"column:id;primary_key"`
+	ParentID    *string   `json:"parent_id" gorm:"column:parent_id;index"`
+	Name        string    `json:"name" gorm:"column:name;not null"`
+	Slug        string    `json:"slug" gorm:"column:slug;unique;not null"`
+	Description string    `json:"description" gorm:"column:description"`
+	Image       string    `json:"image" gorm:"column:image"`
+	Path        string    `json:"path" gorm:"column:path;not null"`
+	Depth       int       `json:"depth" gorm:"column:depth;not null;default:0"`
+	Position    int       `json:"position" gorm:"column:position;not null;default:0"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (tn *TaxonomyNode) TableName() string {
+	return "taxonomy_nodes"
+}
+
+func (tn *TaxonomyNode) ForeignKeys() []string {
+	return []string{
+		fmt.Sprintf("parent_id;%s(id);RESTRICT;RESTRICT", tn.TableName()),
+	}
+}
+
+// CreatePathIndex indexes the paths for the prefix matches of the descendants
+func (tn *TaxonomyNode) CreatePathIndex(tx *gorm.DB) error {
+	return createPathIndex(tx, tn.TableName())
+}
+
+// TaxonomyTree is a node with its descendants
+type TaxonomyTree struct {
+	TaxonomyNode
+	Children []TaxonomyTree `json:"children"`
+}
+
+// TaxonomyNodeDetails is a node with the breadcrumb leading to it and its direct children
+type TaxonomyNodeDetails struct {
+	TaxonomyNode
+	Ancestors []TaxonomyNode `json:"ancestors"`
+	Children  []TaxonomyNode `json:"children"`
+}
+
+// MaterializedPath is the path of the node below the parent, made of the IDs from the root
+// down to the node, e.g. /electronics-id/phones-id/. Roots have an empty parent path.
+func MaterializedPath(parentPath, id string) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + id + "/"
+}
+
+// PathIDs returns the IDs of the path from the root down to the node itself
+func PathIDs(path string) []string {
+	var ids []string
+	for _, id := range strings.Split(path, "/") {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// IsPathWithin tells whether the path is the ancestor path itself or one of its descendants
+func IsPathWithin(path, ancestorPath string) bool {
+	return ancestorPath != "" && strings.HasPrefix(path, ancestorPath)
+}
+
+func createPathIndex(tx *gorm.DB, table string) error {
+	return tx.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_path ON %[1]s (path text_pattern_ops)", table)).Error
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Wishlist is a named list of products saved by a user. It's private unless shared, sharing gives it
+// a token anyone with the link can view it by.
+type Wishlist struct {
+	ID         string    `json:"id" gorm:"column:id;primary_key"`
+	UserID     string    `json:"-" gorm:"column:user_id;index;not null"`
+	Name       string    `json:"name" gorm:"column:name;not null"`
+	ShareToken *string   `json:"share_token,omitempty" gorm:"column:share_token;unique"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at;not null"`
+}
+
+func (w *Wishlist) TableName() string {
+	return "wishlists"
+}
+
+func (w *Wishlist) ForeignKeys() []string {
+	u := User{}
+
+	return []string{
+		fmt.Sprintf("user_id;%s(id);RESTRICT;RESTRICT", u.TableName()),
+	}
+}
+
+// WishlistItem is a product saved in a wishlist. The price and the stock last seen by the alerts worker
+// are kept to detect the price drops and the products coming back in stock.
+type WishlistItem struct {
+	WishlistID string    `json:"wishlist_id" gorm:"column:wishlist_id;primary_key"`
+	ProductID  string    `json:"product_id" gorm:"column:product_id;primary_key"`
+	SeenPrice  int64     `json:"-" gorm:"column:seen_price;not null"`
+	SeenStock  int       `json:"-" gorm:"column:seen_stock;not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at;index;not null"`
+}
+
+func (wi *WishlistItem) TableName() string {
+	return "wishlist_items"
+}
+
+func (wi *WishlistItem) ForeignKeys() []string {
+	w := Wishlist{}
+	p := Product{}
+
+	return []string{
+		fmt.Sprintf("wishlist_id;%s(id);RESTRICT;RESTRICT", w.TableName()),
+		fmt.Sprintf("product_id;%s(id);RESTRICT;RESTRICT", p.TableName()),
+	}
+}
+
+// WishlistDetails is the wishlist with its products
+type WishlistDetails struct {
+	Wishlist
+	ShareURL string                `json:"share_url,omitempty"`
+	Items    []SavedProductDetails `json:"items"`
+}
+
+// SavedProductDetails is a wishlisted or subscribed product as it's now. Products unpublished or of
+// inactive stores stay in the lists as unavailable.
+type SavedProductDetails struct {
+	ProductID   string    `json:"product_id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Image       string    `json:"image,omitempty"`
+	Price       int64     `json:"price"`
+	Stock       int       `json:"stock"`
+	IsDigital   bool      `json:"is_digital"`
+	IsAvailable bool      `json:"is_available"`
+	StoreID     string    `json:"store_id"`
+	StoreName   string    `json:"store_name"`
+	AddedAt     time.Time `json:"added_at"`
+}
@@ -11,6 +11,7 @@ import (
 	url2 "net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -229,6 +230,10 @@ func (tco *twoCheckoutPaymentGateway) VoidTransaction(orderDetails *models.Order
 	return nil
 }
 
+func (tco *twoCheckoutPaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	return nil, ErrSettlementListingNotSupported
+}
+
 func (tco *twoCheckoutPaymentGateway) DisplayName() string {
 	return "2Checkout"
 }
@@ -0,0 +1,301 @@
+package payment_gateways
+
+import (
+	"fmt"
+	"github.com/nahid/gohttp"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	BKashPaymentGatewayName = "bkash"
+)
+
+const (
+	bKashStatusCodeSuccess         = "0000"
+	bKashTransactionInitiated      = "Initiated"
+	bKashTransactionCompleted      = "Completed"
+	bKashTokenizedCheckoutMode     = "0011"
+	bKashTokenExpiryBufferDuration = time.Minute
+)
+
+type bKashPaymentGateway struct {
+	Host      string
+	AppKey    string
+	AppSecret string
+	Username  string
+	Password  string
+	Callback  string
+}
+
+// bKashToken is a granted id token, kept until it's about to expire
+type bKashToken struct {
+	IDToken   string
+	ExpiresAt time.Time
+}
+
+// The gateway is built on every use, so the tokens are kept for the process by merchant account
+var bKashTokens = map[string]bKashToken{}
+var bKashTokensMu sync.Mutex
+
+func NewBKashPaymentGateway(cfg map[string]interface{}) (*bKashPaymentGateway, error) {
+	return &bKashPaymentGateway{
+		Host:      cfg["host"].(string),
+		AppKey:    cfg["app_key"].(string),
+		AppSecret: cfg["app_secret"].(string),
+		Username:  cfg["username"].(string),
+		Password:  cfg["password"].(string),
+		Callback:  cfg["callback"].(string),
+	}, nil
+}
+
+func (bk *bKashPaymentGateway) GetName() string {
+	return BKashPaymentGatewayName
+}
+
+type resBKash struct {
+	StatusCode            string `json:"statusCode"`
+	StatusMessage         string `json:"statusMessage"`
+	PaymentID             string `json:"paymentID"`
+	BKashURL              string `json:"bkashURL"`
+	TrxID                 string `json:"trxID"`
+	TransactionStatus     string `json:"transactionStatus"`
+	Amount                string `json:"amount"`
+	MerchantInvoiceNumber string `json:"merchantInvoiceNumber"`
+	RefundTrxID           string `json:"refundTrxID"`
+}
+
+type resBKashGrantToken struct {
+	StatusCode    string `json:"statusCode"`
+	StatusMessage string `json:"statusMessage"`
+	IDToken       string `json:"id_token"`
+	ExpiresIn     int64  `json:"expires_in"`
+}
+
+// token returns the cached id token, granting a new one when it's about to expire
+func (bk *bKashPaymentGateway) token() (string, error) {
+	bKashTokensMu.Lock()
+	defer bKashTokensMu.Unlock()
+
+	key := fmt.Sprintf("%s|%s|%s", bk.Host, bk.AppKey, bk.Username)
+	if t, ok := bKashTokens[key]; ok && time.Now().Before(t.ExpiresAt) {
+		return t.IDToken, nil
+	}
+
+	resp, err := gohttp.NewRequest().
+		JSON(map[string]interface{}{
+			"app_key":    bk.AppKey,
+			"app_secret": bk.AppSecret,
+		}).
+		Headers(map[string]string{
+			"Accept":   "application/json",
+			"username": bk.Username,
+			"password": bk.Password,
+		}).
+		Post(fmt.Sprintf("%s/tokenized/checkout/token/grant", bk.Host))
+	if err != nil {
+		log.Log().Errorln(err)
+		return "", err
+	}
+
+	if resp.GetStatusCode() != http.StatusOK {
+		return "", errors.NewError(fmt.Sprintf("Request failed with status code : %d", resp.GetStatusCode()))
+	}
+
+	body := resBKashGrantToken{}
+	if err := resp.UnmarshalBody(&body); err != nil {
+		return "", err
+	}
+
+	if body.StatusCode != bKashStatusCodeSuccess || body.IDToken == "" {
+		return "", errors.NewError(fmt.Sprintf("Failed to grant token : %s", body.StatusMessage))
+	}
+
+	bKashTokens[key] = bKashToken{
+		IDToken:   body.IDToken,
+		ExpiresAt: time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - bKashTokenExpiryBufferDuration),
+	}
+	return body.IDToken, nil
+}
+
+func (bk *bKashPaymentGateway) post(path string, payload map[string]interface{}) (*resBKash, error) {
+	token, err := bk.token()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := gohttp.NewRequest().
+		JSON(payload).
+		Headers(map[string]string{
+			"Accept":        "application/json",
+			"Authorization": token,
+			"X-APP-Key":     bk.AppKey,
+		}).
+		Post(fmt.Sprintf("%s%s", bk.Host, path))
+	if err != nil {
+		log.Log().Errorln(err)
+		return nil, err
+	}
+
+	if resp.GetStatusCode() != http.StatusOK {
+		return nil, errors.NewError(fmt.Sprintf("Request failed with status code : %d", resp.GetStatusCode()))
+	}
+
+	body := resBKash{}
+	if err := resp.UnmarshalBody(&body); err != nil {
+		return nil, err
+	}
+
+	if body.StatusCode != bKashStatusCodeSuccess {
+		return nil, errors.NewError(fmt.Sprintf("Request failed with status : %s %s", body.StatusCode, body.StatusMessage))
+	}
+	return &body, nil
+}
+
+func (bk *bKashPaymentGateway) createPayment(orderDetails *models.OrderDetailsView) (*resBKash, error) {
+	return bk.post("/tokenized/checkout/create", map[string]interface{}{
+		"mode":                  bKashTokenizedCheckoutMode,
+		"payerReference":        orderDetails.BillingPhone,
+		"callbackURL":           fmt.Sprintf(bk.Callback, orderDetails.ID),
+		"amount":                formatBKashAmount(orderDetails.GrandTotal),
+		"currency":              "BDT",
+		"intent":                "sale",
+		"merchantInvoiceNumber": orderDetails.ID,
+	})
+}
+
+func (bk *bKashPaymentGateway) executePayment(paymentID string) (*resBKash, error) {
+	return bk.post("/tokenized/checkout/execute", map[string]interface{}{
+		"paymentID": paymentID,
+	})
+}
+
+func (bk *bKashPaymentGateway) queryPayment(paymentID string) (*resBKash, error) {
+	return bk.post("/tokenized/checkout/payment/status", map[string]interface{}{
+		"paymentID": paymentID,
+	})
+}
+
+func (bk *bKashPaymentGateway) refundPayment(paymentID, trxID string, amount int64, reason string) (*resBKash, error) {
+	return bk.post("/tokenized/checkout/payment/refund", map[string]interface{}{
+		"paymentID": paymentID,
+		"trxID":     trxID,
+		"amount":    formatBKashAmount(amount),
+		"sku":       "order",
+		"reason":    reason,
+	})
+}
+
+func (bk *bKashPaymentGateway) Pay(orderDetails *models.OrderDetailsView) (*PaymentGatewayResponse, error) {
+	result, err := bk.createPayment(orderDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentGatewayResponse{
+		Result: result.PaymentID,
+		Nonce:  result.BKashURL,
+	}, nil
+}
+
+func (bk *bKashPaymentGateway) GetConfig() (map[string]interface{}, error) {
+	cfg := map[string]interface{}{
+		"callback_url": bk.Callback,
+	}
+	return cfg, nil
+}
+
+// ValidateTransaction executes the payment authorized by the buyer if it isn't executed yet
+// and verifies the completed payment against the order
+func (bk *bKashPaymentGateway) ValidateTransaction(orderDetails *models.OrderDetailsView) error {
+	if orderDetails.TransactionID == nil {
+		return errors.NewError("invalid transactionID")
+	}
+
+	result, err := bk.queryPayment(*orderDetails.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	if result.TransactionStatus == bKashTransactionInitiated {
+		result, err = bk.executePayment(*orderDetails.TransactionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if result.TransactionStatus != bKashTransactionCompleted {
+		return errors.NewError("Transaction isn't completed")
+	}
+
+	if result.MerchantInvoiceNumber != orderDetails.ID {
+		return errors.NewError("transaction isn't valid for the order")
+	}
+
+	capturedAmount, err := parseBKashAmount(result.Amount)
+	if err != nil {
+		return err
+	}
+
+	log.Log().Infoln("Amount : ", orderDetails.GrandTotal)
+	log.Log().Infoln("Target : ", capturedAmount)
+
+	if capturedAmount != orderDetails.GrandTotal {
+		return errors.NewError("invalid transaction amount")
+	}
+	return nil
+}
+
+func (bk *bKashPaymentGateway) VoidTransaction(orderDetails *models.OrderDetailsView, params map[string]interface{}) error {
+	if orderDetails.TransactionID == nil {
+		return errors.NewError("invalid transactionID")
+	}
+
+	result, err := bk.queryPayment(*orderDetails.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	if result.TransactionStatus != bKashTransactionCompleted {
+		return errors.NewError("payment isn't paid yet")
+	}
+
+	reason, _ := params["reason"].(string)
+	refundAmount := orderDetails.GrandTotal - orderDetails.PaymentProcessingFee
+
+	refund, err := bk.refundPayment(result.PaymentID, result.TrxID, refundAmount, reason)
+	if err != nil {
+		return err
+	}
+
+	if refund.TransactionStatus != bKashTransactionCompleted {
+		return errors.NewError("Refund request failed")
+	}
+	return nil
+}
+
+func (bk *bKashPaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	return nil, ErrSettlementListingNotSupported
+}
+
+func (bk *bKashPaymentGateway) DisplayName() string {
+	return "bKash"
+}
+
+func formatBKashAmount(amount int64) string {
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
+
+func parseBKashAmount(amount string) (int64, error) {
+	v, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(v * 100)), nil
+}
@@ -0,0 +1,220 @@
+package payment_gateways
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// bKashStub is a local stand-in for the bKash tokenized checkout API
+type bKashStub struct {
+	mu       sync.Mutex
+	grants   int
+	payments map[string]map[string]interface{}
+	refunds  []map[string]interface{}
+}
+
+func newBKashStub(t *testing.T) (*bKashStub, *httptest.Server) {
+	stub := &bKashStub{payments: map[string]map[string]interface{}{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tokenized/checkout/token/grant", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+
+		if r.Header.Get("username") != "user" || r.Header.Get("password") != "pass" {
+			writeBKashStub(w, map[string]interface{}{"statusCode": "2001", "statusMessage": "Invalid credentials"})
+			return
+		}
+		stub.grants++
+		writeBKashStub(w, map[string]interface{}{"statusCode": "0000", "id_token": "token", "expires_in": 3600})
+	})
+
+	authorized := func(next func(w http.ResponseWriter, body map[string]interface{})) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "token" || r.Header.Get("X-APP-Key") != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			body := map[string]interface{}{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Error(err)
+			}
+
+			stub.mu.Lock()
+			defer stub.mu.Unlock()
+			next(w, body)
+		}
+	}
+
+	mux.HandleFunc("/tokenized/checkout/create", authorized(func(w http.ResponseWriter, body map[string]interface{}) {
+		id := "PAY" + body["merchantInvoiceNumber"].(string)
+		stub.payments[id] = map[string]interface{}{
+			"statusCode":            "0000",
+			"paymentID":             id,
+			"transactionStatus":     "Initiated",
+			"amount":                body["amount"],
+			"merchantInvoiceNumber": body["merchantInvoiceNumber"],
+			"bkashURL":              "https://checkout.bka.sh/" + id,
+		}
+		writeBKashStub(w, stub.payments[id])
+	}))
+	mux.HandleFunc("/tokenized/checkout/execute", authorized(func(w http.ResponseWriter, body map[string]interface{}) {
+		p := stub.payments[body["paymentID"].(string)]
+		p["transactionStatus"] = "Completed"
+		p["trxID"] = "TRX1"
+		writeBKashStub(w, p)
+	}))
+	mux.HandleFunc("/tokenized/checkout/payment/status", authorized(func(w http.ResponseWriter, body map[string]interface{}) {
+		p, ok := stub.payments[body["paymentID"].(string)]
+		if !ok {
+			writeBKashStub(w, map[string]interface{}{"statusCode": "2056", "statusMessage": "Invalid payment ID"})
+			return
+		}
+		writeBKashStub(w, p)
+	}))
+	mux.HandleFunc("/tokenized/checkout/payment/refund", authorized(func(w http.ResponseWriter, body map[string]interface{}) {
+		stub.refunds = append(stub.refunds, body)
+		writeBKashStub(w, map[string]interface{}{"statusCode": "0000", "refundTrxID": "RTRX1", "transactionStatus": "Completed"})
+	}))
+
+	return stub, httptest.NewServer(mux)
+}
+
+func writeBKashStub(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestBKashPaymentGateway(t *testing.T, host string) *bKashPaymentGateway {
+	pg, err := NewBKashPaymentGateway(map[string]interface{}{
+		"host":       host,
+		"app_key":    "key",
+		"app_secret": "secret",
+		"username":   "user",
+		"password":   "pass",
+		"callback":   "http://localhost/v1/orders/%s/pay",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pg
+}
+
+func TestBKashPaymentGateway(t *testing.T) {
+	stub, srv := newBKashStub(t)
+	defer srv.Close()
+
+	pg := newTestBKashPaymentGateway(t, srv.URL)
+	o := newTestOrderDetails()
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Result != "PAYorder-1" || res.Nonce != "https://checkout.bka.sh/PAYorder-1" {
+		t.Fatalf("unexpected pay response : %+v", res)
+	}
+	if amount := stub.payments[res.Result]["amount"]; amount != "105.00" {
+		t.Fatalf("expected amount 105.00, got %v", amount)
+	}
+
+	if err := pg.VoidTransaction(o, map[string]interface{}{"reason": "test"}); err == nil {
+		t.Fatal("expected refund without a transaction to fail")
+	}
+
+	o.TransactionID = &res.Result
+	if err := pg.VoidTransaction(o, map[string]interface{}{"reason": "test"}); err == nil {
+		t.Fatal("expected refund of an unexecuted payment to fail")
+	}
+
+	// The gateway is built again for every request, the token granted before is reused
+	pg = newTestBKashPaymentGateway(t, srv.URL)
+
+	if err := pg.ValidateTransaction(o); err != nil {
+		t.Fatal(err)
+	}
+	if status := stub.payments[res.Result]["transactionStatus"]; status != "Completed" {
+		t.Fatalf("expected payment to be executed, got %v", status)
+	}
+
+	// An executed payment is only queried again
+	if err := pg.ValidateTransaction(o); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pg.VoidTransaction(o, map[string]interface{}{"reason": "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.refunds) != 1 || stub.refunds[0]["amount"] != "100.00" || stub.refunds[0]["trxID"] != "TRX1" {
+		t.Fatalf("unexpected refunds : %v", stub.refunds)
+	}
+
+	if stub.grants != 1 {
+		t.Fatalf("expected the token to be granted once, got %d", stub.grants)
+	}
+}
+
+func TestBKashPaymentGatewayValidationFailures(t *testing.T) {
+	stub, srv := newBKashStub(t)
+	defer srv.Close()
+
+	pg := newTestBKashPaymentGateway(t, srv.URL)
+	o := newTestOrderDetails()
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.TransactionID = &res.Result
+
+	o.GrandTotal = 10000
+	if err := pg.ValidateTransaction(o); err == nil {
+		t.Fatal("expected amount mismatch to be rejected")
+	}
+
+	o.GrandTotal = 10500
+	o.ID = "order-2"
+	if err := pg.ValidateTransaction(o); err == nil {
+		t.Fatal("expected transaction of another order to be rejected")
+	}
+
+	unknown := "PAYunknown"
+	o.TransactionID = &unknown
+	if err := pg.ValidateTransaction(o); err == nil {
+		t.Fatal("expected unknown payment to be rejected")
+	}
+
+	stub.payments[res.Result]["transactionStatus"] = "Failed"
+	o.ID = "order-1"
+	o.TransactionID = &res.Result
+	if err := pg.ValidateTransaction(o); err == nil {
+		t.Fatal("expected failed payment to be rejected")
+	}
+}
+
+func TestBKashPaymentGatewayInvalidCredentials(t *testing.T) {
+	_, srv := newBKashStub(t)
+	defer srv.Close()
+
+	pg := newTestBKashPaymentGateway(t, srv.URL)
+	pg.Password = "wrong"
+
+	if _, err := pg.Pay(newTestOrderDetails()); err == nil {
+		t.Fatal("expected token grant to fail")
+	}
+}
+
+func TestBKashAmount(t *testing.T) {
+	for amount, formatted := range map[int64]string{0: "0.00", 5: "0.05", 10500: "105.00", 123456: "1234.56"} {
+		if v := formatBKashAmount(amount); v != formatted {
+			t.Fatalf("expected %s, got %s", formatted, v)
+		}
+		if v, err := parseBKashAmount(formatted); err != nil || v != amount {
+			t.Fatalf("expected %d, got %d (%v)", amount, v, err)
+		}
+	}
+}
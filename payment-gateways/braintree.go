@@ -7,6 +7,8 @@ import (
 	"github.com/braintree-go/braintree-go"
 	"github.com/shopicano/shopicano-backend/log"
 	"github.com/shopicano/shopicano-backend/models"
+	"strconv"
+	"time"
 )
 
 const (
@@ -177,6 +179,123 @@ func (bt *brainTreePaymentGateway) VoidTransaction(orderDetails *models.OrderDet
 	return nil
 }
 
+func (bt *brainTreePaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	query := new(braintree.SearchQuery)
+	settledAt := query.AddTimeField("settled-at")
+	settledAt.Min = from
+	settledAt.Max = to
+
+	ids, err := bt.client.Transaction().SearchIDs(context.Background(), query)
+	if err != nil {
+		log.Log().Errorln(err)
+		return nil, err
+	}
+
+	var result []SettledTransaction
+
+	for page := 1; page <= ids.PageCount; page++ {
+		res, err := bt.client.Transaction().SearchPage(context.Background(), query, ids, page)
+		if err != nil {
+			log.Log().Errorln(err)
+			return nil, err
+		}
+
+		for _, t := range res.Transactions {
+			st := SettledTransaction{
+				TransactionID: t.Id,
+				OrderID:       t.OrderId,
+			}
+			if t.UpdatedAt != nil {
+				st.SettledAt = t.UpdatedAt.UTC()
+			}
+
+			if t.Type == "credit" {
+				if t.RefundedTransactionId == nil {
+					continue
+				}
+				st.TransactionID = *t.RefundedTransactionId
+				st.RefundedAmount = t.Amount.Unscaled
+			} else {
+				st.Amount = t.Amount.Unscaled
+			}
+
+			result = append(result, st)
+		}
+	}
+	return result, nil
+}
+
+func (bt *brainTreePaymentGateway) CreateCustomer(u *models.User) (string, error) {
+	c, err := bt.client.Customer().Create(context.Background(), &braintree.CustomerRequest{
+		FirstName: u.Name,
+		Email:     u.Email,
+	})
+	if err != nil {
+		log.Log().Errorln(err)
+		return "", err
+	}
+	return c.Id, nil
+}
+
+func (bt *brainTreePaymentGateway) SavePaymentMethod(customerID, token string) (*VaultedPaymentMethod, error) {
+	verifyCard := true
+
+	pm, err := bt.client.PaymentMethod().Create(context.Background(), &braintree.PaymentMethodRequest{
+		CustomerId:         customerID,
+		PaymentMethodNonce: token,
+		Options: &braintree.PaymentMethodRequestOptions{
+			VerifyCard: &verifyCard,
+		},
+	})
+	if err != nil {
+		log.Log().Errorln(err)
+		return nil, err
+	}
+
+	vpm := &VaultedPaymentMethod{
+		Token: pm.GetToken(),
+	}
+	if cc, ok := pm.(*braintree.CreditCard); ok {
+		vpm.Brand = cc.CardType
+		vpm.Last4 = cc.Last4
+		vpm.ExpMonth, _ = strconv.Atoi(cc.ExpirationMonth)
+		vpm.ExpYear, _ = strconv.Atoi(cc.ExpirationYear)
+	}
+	return vpm, nil
+}
+
+func (bt *brainTreePaymentGateway) DeletePaymentMethod(token string) error {
+	if err := bt.client.PaymentMethod().Delete(context.Background(), token); err != nil {
+		log.Log().Errorln(err)
+		return err
+	}
+	return nil
+}
+
+func (bt *brainTreePaymentGateway) PayWithSavedPaymentMethod(orderDetails *models.OrderDetailsView, customerID, token string) (*PaymentGatewayResponse, error) {
+	d := braintree.NewDecimal(orderDetails.GrandTotal, 2)
+
+	resp, err := bt.client.Transaction().Create(context.Background(), &braintree.TransactionRequest{
+		CustomerID:         customerID,
+		PaymentMethodToken: token,
+		Amount:             d,
+		OrderId:            orderDetails.ID,
+		Options: &braintree.TransactionOptions{
+			SubmitForSettlement: true,
+		},
+		Type: string(Sale),
+	})
+	if err != nil {
+		log.Log().Errorln(err)
+		return nil, err
+	}
+
+	return &PaymentGatewayResponse{
+		Result:                     resp.Id,
+		BrainTreeTransactionStatus: resp.Status,
+	}, nil
+}
+
 func (bt *brainTreePaymentGateway) DisplayName() string {
 	return "BrainTree"
 }
@@ -0,0 +1,33 @@
+package payment_gateways
+
+import (
+	"errors"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+// CustomerVault is implemented by the payment gateways that can store customers
+// and their payment methods, so repeat buyers can pay without re-entering card details.
+type CustomerVault interface {
+	CreateCustomer(u *models.User) (string, error)
+	SavePaymentMethod(customerID, token string) (*VaultedPaymentMethod, error)
+	DeletePaymentMethod(token string) error
+	PayWithSavedPaymentMethod(orderDetails *models.OrderDetailsView, customerID, token string) (*PaymentGatewayResponse, error)
+}
+
+type VaultedPaymentMethod struct {
+	Token    string
+	Brand    string
+	Last4    string
+	ExpMonth int
+	ExpYear  int
+}
+
+var ErrCustomerVaultNotSupported = errors.New("payment gateway doesn't support saved payment methods")
+
+func GetCustomerVault(pg PaymentGateway) (CustomerVault, error) {
+	cv, ok := pg.(CustomerVault)
+	if !ok {
+		return nil, ErrCustomerVaultNotSupported
+	}
+	return cv, nil
+}
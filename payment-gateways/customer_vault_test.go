@@ -0,0 +1,24 @@
+package payment_gateways
+
+import (
+	"testing"
+)
+
+func TestGetCustomerVault(t *testing.T) {
+	stripe, err := NewStripePaymentGateway(map[string]interface{}{
+		"secret_key":       "sk_test",
+		"public_key":       "pk_test",
+		"success_callback": "http://localhost/v1/orders/%s/pay",
+		"failure_callback": "http://localhost/v1/orders/%s/pay",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetCustomerVault(stripe); err != nil {
+		t.Fatalf("expected stripe to support saved payment methods, got %v", err)
+	}
+
+	if _, err := GetCustomerVault(newTestMockPaymentGateway(t, MockOutcomeSuccess)); err != ErrCustomerVaultNotSupported {
+		t.Fatalf("expected %v, got %v", ErrCustomerVaultNotSupported, err)
+	}
+}
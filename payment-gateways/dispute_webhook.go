@@ -0,0 +1,39 @@
+package payment_gateways
+
+import (
+	"errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"net/http"
+	"time"
+)
+
+// DisputeWebhook is implemented by the payment gateways notifying the disputes of their transactions
+// through webhooks
+type DisputeWebhook interface {
+	ParseDisputeWebhook(header http.Header, body []byte) (*DisputeNotification, error)
+}
+
+// DisputeNotification is the state of a dispute as reported by the payment gateway
+type DisputeNotification struct {
+	DisputeID     string
+	TransactionID string
+	Amount        int64
+	Fee           int64
+	Reason        string
+	Status        models.DisputeStatus
+	EvidenceDueBy *time.Time
+}
+
+var (
+	ErrDisputeWebhookNotSupported = errors.New("payment gateway doesn't support dispute webhooks")
+	// ErrDisputeWebhookIgnored is returned for valid webhook events which aren't about a dispute
+	ErrDisputeWebhookIgnored = errors.New("webhook event isn't about a dispute")
+)
+
+func GetDisputeWebhook(pg PaymentGateway) (DisputeWebhook, error) {
+	dw, ok := pg.(DisputeWebhook)
+	if !ok {
+		return nil, ErrDisputeWebhookNotSupported
+	}
+	return dw, nil
+}
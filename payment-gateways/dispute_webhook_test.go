@@ -0,0 +1,74 @@
+package payment_gateways
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/stripe/stripe-go/webhook"
+)
+
+func TestStripeParseDisputeWebhook(t *testing.T) {
+	pg := &stripePaymentGateway{WebhookSecret: "whsec_test"}
+
+	body := []byte(`{"id":"evt_1","type":"charge.dispute.closed","data":{"object":{"id":"dp_1","object":"dispute",` +
+		`"amount":10500,"payment_intent":"pi_1","reason":"fraudulent","status":"lost",` +
+		`"balance_transactions":[{"id":"txn_1","fee":1500}],"evidence_details":{"due_by":1603065600}}}}`)
+
+	sign := func(payload []byte, secret string) http.Header {
+		now := time.Now()
+		h := http.Header{}
+		h.Set("Stripe-Signature", fmt.Sprintf("t=%d,v1=%x", now.Unix(), webhook.ComputeSignature(now, payload, secret)))
+		return h
+	}
+
+	n, err := pg.ParseDisputeWebhook(sign(body, "whsec_test"), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.DisputeID != "dp_1" || n.TransactionID != "pi_1" || n.Amount != 10500 || n.Fee != 1500 {
+		t.Errorf("unexpected notification %+v", n)
+	}
+	if n.Status != models.DisputeStatusLost || n.Reason != "fraudulent" {
+		t.Errorf("unexpected status %s or reason %s", n.Status, n.Reason)
+	}
+	if n.EvidenceDueBy == nil || n.EvidenceDueBy.Unix() != 1603065600 {
+		t.Errorf("unexpected evidence due by %v", n.EvidenceDueBy)
+	}
+
+	if _, err := pg.ParseDisputeWebhook(sign(body, "whsec_other"), body); err == nil {
+		t.Error("expected an invalid signature to be rejected")
+	}
+
+	other := []byte(`{"id":"evt_2","type":"charge.succeeded","data":{"object":{"id":"ch_1"}}}`)
+	if _, err := pg.ParseDisputeWebhook(sign(other, "whsec_test"), other); err != ErrDisputeWebhookIgnored {
+		t.Errorf("expected the event to be ignored, got %v", err)
+	}
+}
+
+func TestMockParseDisputeWebhook(t *testing.T) {
+	pg := newTestMockPaymentGateway(t, MockOutcomeSuccess)
+
+	res, err := pg.Pay(newTestOrderDetails())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(fmt.Sprintf(`{"dispute_id":"md_1","transaction_id":"%s","amount":10500,"fee":1500,`+
+		`"reason":"product_not_received","status":"dispute_open"}`, res.Result))
+
+	n, err := pg.ParseDisputeWebhook(http.Header{}, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.TransactionID != res.Result || n.Status != models.DisputeStatusOpen || n.Fee != 1500 {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	unknown := []byte(`{"dispute_id":"md_2","transaction_id":"mock_unknown","amount":100,"status":"dispute_open"}`)
+	if _, err := pg.ParseDisputeWebhook(http.Header{}, unknown); err != ErrMockTransactionNotFound {
+		t.Errorf("expected unknown transaction error, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package payment_gateways
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	MockPaymentGatewayName = "mock"
+)
+
+const (
+	MockOutcomeSuccess MockOutcome = "success"
+	MockOutcomeDecline MockOutcome = "decline"
+	MockOutcomeTimeout MockOutcome = "timeout"
+	MockOutcomePending MockOutcome = "pending"
+)
+
+type MockOutcome string
+
+func (mo MockOutcome) IsValid() bool {
+	for _, v := range []MockOutcome{MockOutcomeSuccess, MockOutcomeDecline, MockOutcomeTimeout, MockOutcomePending} {
+		if v == mo {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrMockTransactionNotFound = errors.New("mock transaction not found")
+	ErrMockGatewayTimeout      = errors.New("mock payment gateway timed out")
+)
+
+// MockTransaction is a transaction recorded by the mock gateway.
+// Transactions live in memory only, so they are lost when the process restarts.
+type MockTransaction struct {
+	ID             string      `json:"id"`
+	OrderID        string      `json:"order_id"`
+	OrderHash      string      `json:"order_hash"`
+	Amount         int64       `json:"amount"`
+	RefundedAmount int64       `json:"refunded_amount"`
+	Outcome        MockOutcome `json:"outcome"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+var mockTransactions = map[string]*MockTransaction{}
+var mockMu sync.Mutex
+
+type mockPaymentGateway struct {
+	Outcome         MockOutcome
+	PayPage         string
+	SuccessCallback string
+	FailureCallback string
+}
+
+func NewMockPaymentGateway(cfg map[string]interface{}) (*mockPaymentGateway, error) {
+	outcome := MockOutcomeSuccess
+	if v, ok := cfg["outcome"].(string); ok && v != "" {
+		outcome = MockOutcome(v)
+	}
+	if !outcome.IsValid() {
+		return nil, fmt.Errorf("invalid mock outcome : %s", outcome)
+	}
+
+	return &mockPaymentGateway{
+		Outcome:         outcome,
+		PayPage:         cfg["pay_page"].(string),
+		SuccessCallback: cfg["success_callback"].(string),
+		FailureCallback: cfg["failure_callback"].(string),
+	}, nil
+}
+
+func (mpg *mockPaymentGateway) GetName() string {
+	return MockPaymentGatewayName
+}
+
+func (mpg *mockPaymentGateway) Pay(orderDetails *models.OrderDetailsView) (*PaymentGatewayResponse, error) {
+	if mpg.Outcome == MockOutcomeTimeout {
+		return nil, ErrMockGatewayTimeout
+	}
+
+	trx := &MockTransaction{
+		ID:        fmt.Sprintf("mock_%s", utils.NewUUID()),
+		OrderID:   orderDetails.ID,
+		OrderHash: orderDetails.Hash,
+		Amount:    orderDetails.GrandTotal,
+		Outcome:   mpg.Outcome,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	mockMu.Lock()
+	mockTransactions[trx.ID] = trx
+	mockMu.Unlock()
+
+	log.Log().Infoln("Mock transaction created : ", trx.ID)
+
+	return &PaymentGatewayResponse{
+		Result: trx.ID,
+		Nonce:  fmt.Sprintf(mpg.PayPage, trx.ID),
+	}, nil
+}
+
+func (mpg *mockPaymentGateway) GetConfig() (map[string]interface{}, error) {
+	cfg := map[string]interface{}{
+		"outcome":              mpg.Outcome,
+		"success_callback_url": mpg.SuccessCallback,
+		"failure_callback_url": mpg.FailureCallback,
+	}
+	return cfg, nil
+}
+
+func (mpg *mockPaymentGateway) ValidateTransaction(orderDetails *models.OrderDetailsView) error {
+	if orderDetails.TransactionID == nil {
+		return errors.New("invalid transactionID")
+	}
+
+	trx, err := GetMockTransaction(*orderDetails.TransactionID)
+	if err != nil {
+		return err
+	}
+
+	switch trx.Outcome {
+	case MockOutcomeDecline:
+		return errors.New("transaction declined")
+	case MockOutcomeTimeout:
+		return ErrMockGatewayTimeout
+	case MockOutcomePending:
+		return errors.New("transaction is pending")
+	}
+
+	if trx.OrderID != orderDetails.ID {
+		return errors.New("transaction isn't valid for the order")
+	}
+
+	if trx.Amount != orderDetails.GrandTotal {
+		return errors.New("invalid transaction amount")
+	}
+	return nil
+}
+
+func (mpg *mockPaymentGateway) VoidTransaction(orderDetails *models.OrderDetailsView, params map[string]interface{}) error {
+	if orderDetails.TransactionID == nil {
+		return errors.New("invalid transactionID")
+	}
+
+	mockMu.Lock()
+	defer mockMu.Unlock()
+
+	trx, ok := mockTransactions[*orderDetails.TransactionID]
+	if !ok {
+		return ErrMockTransactionNotFound
+	}
+
+	if trx.Outcome != MockOutcomeSuccess {
+		return errors.New("payment isn't paid yet")
+	}
+
+	if trx.RefundedAmount != 0 {
+		return errors.New("transaction already refunded")
+	}
+
+	trx.RefundedAmount = orderDetails.GrandTotal - orderDetails.PaymentProcessingFee
+	return nil
+}
+
+func (mpg *mockPaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	mockMu.Lock()
+	defer mockMu.Unlock()
+
+	var result []SettledTransaction
+	for _, trx := range mockTransactions {
+		if trx.Outcome != MockOutcomeSuccess || trx.CreatedAt.Before(from) || !trx.CreatedAt.Before(to) {
+			continue
+		}
+
+		result = append(result, SettledTransaction{
+			TransactionID:  trx.ID,
+			OrderID:        trx.OrderID,
+			Amount:         trx.Amount,
+			RefundedAmount: trx.RefundedAmount,
+			SettledAt:      trx.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+func (mpg *mockPaymentGateway) DisplayName() string {
+	return "Mock"
+}
+
+// mockDisputeEvent is the webhook payload of the mock gateway, it's posted by hand to simulate disputes
+type mockDisputeEvent struct {
+	DisputeID     string               `json:"dispute_id"`
+	TransactionID string               `json:"transaction_id"`
+	Amount        int64                `json:"amount"`
+	Fee           int64                `json:"fee"`
+	Reason        string               `json:"reason"`
+	Status        models.DisputeStatus `json:"status"`
+	EvidenceDueBy *time.Time           `json:"evidence_due_by"`
+}
+
+func (mpg *mockPaymentGateway) ParseDisputeWebhook(header http.Header, body []byte) (*DisputeNotification, error) {
+	e := mockDisputeEvent{}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	if e.DisputeID == "" {
+		return nil, ErrDisputeWebhookIgnored
+	}
+	if !e.Status.IsValid() {
+		return nil, fmt.Errorf("invalid dispute status : %s", e.Status)
+	}
+	if _, err := GetMockTransaction(e.TransactionID); err != nil {
+		return nil, err
+	}
+
+	return &DisputeNotification{
+		DisputeID:     e.DisputeID,
+		TransactionID: e.TransactionID,
+		Amount:        e.Amount,
+		Fee:           e.Fee,
+		Reason:        e.Reason,
+		Status:        e.Status,
+		EvidenceDueBy: e.EvidenceDueBy,
+	}, nil
+}
+
+// GetMockTransaction returns a copy of the mock transaction
+func GetMockTransaction(transactionID string) (*MockTransaction, error) {
+	mockMu.Lock()
+	defer mockMu.Unlock()
+
+	trx, ok := mockTransactions[transactionID]
+	if !ok {
+		return nil, ErrMockTransactionNotFound
+	}
+
+	v := *trx
+	return &v, nil
+}
+
+// SetMockTransactionOutcome overrides the configured outcome of a mock transaction,
+// it is used by the hosted pay page to simulate the buyer's choice
+func SetMockTransactionOutcome(transactionID string, outcome MockOutcome) (*MockTransaction, error) {
+	if !outcome.IsValid() {
+		return nil, fmt.Errorf("invalid mock outcome : %s", outcome)
+	}
+
+	mockMu.Lock()
+	defer mockMu.Unlock()
+
+	trx, ok := mockTransactions[transactionID]
+	if !ok {
+		return nil, ErrMockTransactionNotFound
+	}
+
+	trx.Outcome = outcome
+
+	v := *trx
+	return &v, nil
+}
@@ -0,0 +1,180 @@
+package payment_gateways
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func TestMain(m *testing.M) {
+	log.SetupLog()
+	os.Exit(m.Run())
+}
+
+func newTestMockPaymentGateway(t *testing.T, outcome MockOutcome) *mockPaymentGateway {
+	pg, err := NewMockPaymentGateway(map[string]interface{}{
+		"outcome":          string(outcome),
+		"pay_page":         "http://localhost/v1/payments/mock/%s/",
+		"success_callback": "http://localhost/v1/orders/%s/pay",
+		"failure_callback": "http://localhost/v1/orders/%s/pay",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pg
+}
+
+func newTestOrderDetails() *models.OrderDetailsView {
+	return &models.OrderDetailsView{
+		ID:                   "order-1",
+		Hash:                 "HASH1",
+		GrandTotal:           10500,
+		PaymentProcessingFee: 500,
+	}
+}
+
+func TestMockPaymentGatewaySuccess(t *testing.T) {
+	pg := newTestMockPaymentGateway(t, MockOutcomeSuccess)
+	o := newTestOrderDetails()
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Nonce != "http://localhost/v1/payments/mock/"+res.Result+"/" {
+		t.Fatalf("unexpected pay page url : %s", res.Nonce)
+	}
+
+	o.TransactionID = &res.Result
+	if err := pg.ValidateTransaction(o); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pg.VoidTransaction(o, map[string]interface{}{"reason": "test", "type": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	trx, err := GetMockTransaction(res.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trx.RefundedAmount != 10000 {
+		t.Fatalf("expected refunded amount 10000, got %d", trx.RefundedAmount)
+	}
+
+	if err := pg.VoidTransaction(o, map[string]interface{}{"reason": "test", "type": 0}); err == nil {
+		t.Fatal("expected second refund to fail")
+	}
+}
+
+func TestMockPaymentGatewayOutcomes(t *testing.T) {
+	for _, outcome := range []MockOutcome{MockOutcomeDecline, MockOutcomePending} {
+		pg := newTestMockPaymentGateway(t, outcome)
+		o := newTestOrderDetails()
+
+		res, err := pg.Pay(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		o.TransactionID = &res.Result
+		if err := pg.ValidateTransaction(o); err == nil {
+			t.Fatalf("expected %s transaction to be invalid", outcome)
+		}
+		if err := pg.VoidTransaction(o, nil); err == nil {
+			t.Fatalf("expected %s transaction refund to fail", outcome)
+		}
+	}
+}
+
+func TestMockPaymentGatewayTimeout(t *testing.T) {
+	pg := newTestMockPaymentGateway(t, MockOutcomeTimeout)
+
+	if _, err := pg.Pay(newTestOrderDetails()); err != ErrMockGatewayTimeout {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
+
+func TestMockPaymentGatewayPayPageOutcome(t *testing.T) {
+	pg := newTestMockPaymentGateway(t, MockOutcomePending)
+	o := newTestOrderDetails()
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.TransactionID = &res.Result
+
+	if _, err := SetMockTransactionOutcome(res.Result, MockOutcome("unknown")); err == nil {
+		t.Fatal("expected invalid outcome to be rejected")
+	}
+
+	if _, err := SetMockTransactionOutcome(res.Result, MockOutcomeSuccess); err != nil {
+		t.Fatal(err)
+	}
+	if err := pg.ValidateTransaction(o); err != nil {
+		t.Fatal(err)
+	}
+
+	o.GrandTotal = 1
+	if err := pg.ValidateTransaction(o); err == nil {
+		t.Fatal("expected amount mismatch to be rejected")
+	}
+}
+
+func TestMockPaymentGatewayListSettledTransactions(t *testing.T) {
+	pg := newTestMockPaymentGateway(t, MockOutcomeSuccess)
+	o := newTestOrderDetails()
+	o.ID = "order-settled"
+
+	from := time.Now().UTC().Add(-time.Minute)
+
+	res, err := pg.Pay(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.TransactionID = &res.Result
+	if err := pg.VoidTransaction(o, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	declined := newTestMockPaymentGateway(t, MockOutcomeDecline)
+	declinedRes, err := declined.Pay(newTestOrderDetails())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settled, err := pg.ListSettledTransactions(from, time.Now().UTC().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found *SettledTransaction
+	for i := range settled {
+		if settled[i].TransactionID == res.Result {
+			found = &settled[i]
+		}
+		if settled[i].TransactionID == declinedRes.Result {
+			t.Fatal("expected declined transaction to be excluded")
+		}
+	}
+	if found == nil {
+		t.Fatal("expected settled transaction to be listed")
+	}
+	if found.OrderID != "order-settled" || found.Amount != 10500 || found.RefundedAmount != 10000 {
+		t.Fatalf("unexpected settled transaction : %+v", *found)
+	}
+
+	settled, err = pg.ListSettledTransactions(from.Add(-time.Hour), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range settled {
+		if st.TransactionID == res.Result {
+			t.Fatal("expected transactions outside of the range to be excluded")
+		}
+	}
+}
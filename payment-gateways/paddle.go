@@ -9,6 +9,7 @@ import (
 	"github.com/shopicano/shopicano-backend/models"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 const (
@@ -230,6 +231,10 @@ func (pd *paddlePaymentGateway) VoidTransaction(orderDetails *models.OrderDetail
 	return nil
 }
 
+func (pd *paddlePaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	return nil, ErrSettlementListingNotSupported
+}
+
 func (pd *paddlePaymentGateway) DisplayName() string {
 	return "Paddle"
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/braintree-go/braintree-go"
 	"github.com/shopicano/shopicano-backend/config"
 	"github.com/shopicano/shopicano-backend/models"
+	"time"
 )
 
 type PaymentGateway interface {
@@ -13,6 +14,7 @@ type PaymentGateway interface {
 	Pay(orderDetails *models.OrderDetailsView) (*PaymentGatewayResponse, error)
 	ValidateTransaction(orderDetails *models.OrderDetailsView) error
 	VoidTransaction(orderDetails *models.OrderDetailsView, params map[string]interface{}) error
+	ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error)
 	DisplayName() string
 }
 
@@ -22,6 +24,18 @@ type PaymentGatewayResponse struct {
 	BrainTreeTransactionStatus braintree.TransactionStatus
 }
 
+// SettledTransaction is a transaction settled by the payment gateway.
+// Refunds are reported against the ID of the refunded transaction.
+type SettledTransaction struct {
+	TransactionID  string
+	OrderID        string
+	Amount         int64
+	RefundedAmount int64
+	SettledAt      time.Time
+}
+
+var ErrSettlementListingNotSupported = errors.New("payment gateway doesn't support listing settled transactions")
+
 var activePaymentGateway PaymentGateway
 
 func SetActivePaymentGateway(cfg config.PaymentGatewayCfg) error {
@@ -69,6 +83,18 @@ func GetPaymentGatewayByName(name string) (PaymentGateway, error) {
 			return nil, err
 		}
 		return pd, nil
+	} else if name == BKashPaymentGatewayName {
+		bk, err := NewBKashPaymentGateway(cfg.Configs[BKashPaymentGatewayName].(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		return bk, nil
+	} else if name == MockPaymentGatewayName {
+		mock, err := NewMockPaymentGateway(cfg.Configs[MockPaymentGatewayName].(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+		return mock, nil
 	}
 	return nil, errors.New("payment gateway not found")
 }
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	url2 "net/url"
 	"strconv"
+	"time"
 )
 
 const (
@@ -233,6 +234,10 @@ func (ssl *sslCommerzPaymentGateway) VoidTransaction(orderDetails *models.OrderD
 	return nil
 }
 
+func (ssl *sslCommerzPaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	return nil, ErrSettlementListingNotSupported
+}
+
 func (ssl *sslCommerzPaymentGateway) DisplayName() string {
 	return "SSLCommerz"
 }
@@ -1,6 +1,7 @@
 package payment_gateways
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/shopicano/shopicano-backend/log"
@@ -8,6 +9,9 @@ import (
 	"github.com/stripe/stripe-go"
 	"github.com/stripe/stripe-go/checkout/session"
 	"github.com/stripe/stripe-go/client"
+	"github.com/stripe/stripe-go/webhook"
+	"net/http"
+	"time"
 )
 
 const (
@@ -19,15 +23,19 @@ type stripePaymentGateway struct {
 	SuccessCallback string
 	FailureCallback string
 	PublicKey       string
+	WebhookSecret   string
 	client          *client.API
 }
 
 func NewStripePaymentGateway(cfg map[string]interface{}) (*stripePaymentGateway, error) {
+	webhookSecret, _ := cfg["webhook_secret"].(string)
+
 	return &stripePaymentGateway{
 		SecretKey:       cfg["secret_key"].(string),
 		SuccessCallback: cfg["success_callback"].(string),
 		FailureCallback: cfg["failure_callback"].(string),
 		PublicKey:       cfg["public_key"].(string),
+		WebhookSecret:   webhookSecret,
 		client:          client.New(cfg["secret_key"].(string), nil),
 	}, nil
 }
@@ -161,6 +169,192 @@ func (spg *stripePaymentGateway) VoidTransaction(orderDetails *models.OrderDetai
 	return nil
 }
 
+func (spg *stripePaymentGateway) ListSettledTransactions(from, to time.Time) ([]SettledTransaction, error) {
+	params := &stripe.ChargeListParams{
+		CreatedRange: &stripe.RangeQueryParams{
+			GreaterThanOrEqual: from.Unix(),
+			LesserThan:         to.Unix(),
+		},
+	}
+
+	var result []SettledTransaction
+
+	it := spg.client.Charges.List(params)
+	for it.Next() {
+		c := it.Charge()
+		if !c.Paid || !c.Captured || c.PaymentIntent == "" {
+			continue
+		}
+
+		result = append(result, SettledTransaction{
+			TransactionID:  c.PaymentIntent,
+			Amount:         c.Amount,
+			RefundedAmount: c.AmountRefunded,
+			SettledAt:      time.Unix(c.Created, 0).UTC(),
+		})
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	// Refunds of the charges listed above are in their refunded amount, the refunds of older charges
+	// are reported on their own
+	refundParams := &stripe.RefundListParams{
+		CreatedRange: &stripe.RangeQueryParams{
+			GreaterThanOrEqual: from.Unix(),
+			LesserThan:         to.Unix(),
+		},
+	}
+	refundParams.AddExpand("data.charge")
+
+	rit := spg.client.Refunds.List(refundParams)
+	for rit.Next() {
+		r := rit.Refund()
+		if r.Status == stripe.RefundStatusFailed || r.Status == stripe.RefundStatusCanceled {
+			continue
+		}
+		if r.Charge == nil || r.Charge.PaymentIntent == "" || r.Charge.Created >= from.Unix() {
+			continue
+		}
+
+		result = append(result, SettledTransaction{
+			TransactionID:  r.Charge.PaymentIntent,
+			RefundedAmount: r.Amount,
+			SettledAt:      time.Unix(r.Created, 0).UTC(),
+		})
+	}
+	if err := rit.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (spg *stripePaymentGateway) CreateCustomer(u *models.User) (string, error) {
+	c, err := spg.client.Customers.New(&stripe.CustomerParams{
+		Name:  stripe.String(u.Name),
+		Email: stripe.String(u.Email),
+		Params: stripe.Params{
+			Metadata: map[string]string{
+				"user_id": u.ID,
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return c.ID, nil
+}
+
+func (spg *stripePaymentGateway) SavePaymentMethod(customerID, token string) (*VaultedPaymentMethod, error) {
+	pm, err := spg.client.PaymentMethods.Attach(token, &stripe.PaymentMethodAttachParams{
+		Customer: stripe.String(customerID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vpm := &VaultedPaymentMethod{
+		Token: pm.ID,
+	}
+	if pm.Card != nil {
+		vpm.Brand = string(pm.Card.Brand)
+		vpm.Last4 = pm.Card.Last4
+		vpm.ExpMonth = int(pm.Card.ExpMonth)
+		vpm.ExpYear = int(pm.Card.ExpYear)
+	}
+	return vpm, nil
+}
+
+func (spg *stripePaymentGateway) DeletePaymentMethod(token string) error {
+	if _, err := spg.client.PaymentMethods.Detach(token, &stripe.PaymentMethodDetachParams{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (spg *stripePaymentGateway) PayWithSavedPaymentMethod(orderDetails *models.OrderDetailsView, customerID, token string) (*PaymentGatewayResponse, error) {
+	pi, err := spg.client.PaymentIntents.New(&stripe.PaymentIntentParams{
+		Amount:        stripe.Int64(orderDetails.GrandTotal),
+		Currency:      stripe.String("usd"),
+		Customer:      stripe.String(customerID),
+		PaymentMethod: stripe.String(token),
+		Description:   stripe.String(fmt.Sprintf("Payment for Order #%s", orderDetails.Hash)),
+		Confirm:       stripe.Bool(true),
+		Params: stripe.Params{
+			IdempotencyKey: stripe.String(stripe.NewIdempotencyKey()),
+			Metadata: map[string]string{
+				"order_id": orderDetails.ID,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &PaymentGatewayResponse{
+		Result: pi.ID,
+		Nonce:  pi.ClientSecret,
+	}, nil
+}
+
 func (spg *stripePaymentGateway) DisplayName() string {
 	return "Stripe"
 }
+
+// ParseDisputeWebhook verifies the signature of the event with the webhook secret and reads the dispute
+// of the charge.dispute.* events. Disputes are matched to the orders by their payment intent.
+func (spg *stripePaymentGateway) ParseDisputeWebhook(header http.Header, body []byte) (*DisputeNotification, error) {
+	if spg.WebhookSecret == "" {
+		return nil, errors.New("stripe webhook secret isn't configured")
+	}
+
+	event, err := webhook.ConstructEvent(body, header.Get("Stripe-Signature"), spg.WebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed",
+		"charge.dispute.funds_withdrawn", "charge.dispute.funds_reinstated":
+	default:
+		return nil, ErrDisputeWebhookIgnored
+	}
+
+	d := stripe.Dispute{}
+	if err := json.Unmarshal(event.Data.Raw, &d); err != nil {
+		return nil, err
+	}
+	if d.PaymentIntent == nil || d.PaymentIntent.ID == "" {
+		return nil, errors.New("dispute has no payment intent")
+	}
+
+	n := &DisputeNotification{
+		DisputeID:     d.ID,
+		TransactionID: d.PaymentIntent.ID,
+		Amount:        d.Amount,
+		Reason:        string(d.Reason),
+		Status:        stripeDisputeStatus(d.Status),
+	}
+	for _, bt := range d.BalanceTransactions {
+		n.Fee += bt.Fee
+	}
+	if d.EvidenceDetails != nil && d.EvidenceDetails.DueBy > 0 {
+		dueBy := time.Unix(d.EvidenceDetails.DueBy, 0).UTC()
+		n.EvidenceDueBy = &dueBy
+	}
+	return n, nil
+}
+
+func stripeDisputeStatus(s stripe.DisputeStatus) models.DisputeStatus {
+	switch s {
+	case stripe.DisputeStatusUnderReview, stripe.DisputeStatusWarningUnderReview:
+		return models.DisputeStatusUnderReview
+	case stripe.DisputeStatusWon, stripe.DisputeStatusWarningClosed:
+		return models.DisputeStatusWon
+	case stripe.DisputeStatusLost:
+		return models.DisputeStatusLost
+	case stripe.DisputeStatusChargeRefunded:
+		return models.DisputeStatusClosed
+	}
+	return models.DisputeStatusOpen
+}
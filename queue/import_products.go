@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/machinery"
+	tasks2 "github.com/shopicano/shopicano-backend/tasks"
+	"time"
+)
+
+func ImportProducts(importID string) error {
+	now := time.Now().Add(time.Second * 10)
+
+	sig := &tasks.Signature{
+		Name: tasks2.ImportProductsTaskName,
+		Args: []tasks.Arg{
+			{
+				Type:  "string",
+				Value: importID,
+				Name:  "importID",
+			},
+		},
+		ETA: &now,
+	}
+	_, err := machinery.RabbitMQConnection().SendTask(sig)
+	if err != nil {
+		return err
+	}
+	return nil
+}
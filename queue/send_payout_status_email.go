@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/machinery"
+	tasks2 "github.com/shopicano/shopicano-backend/tasks"
+	"time"
+)
+
+func SendPayoutStatusEmail(eventID string) error {
+	now := time.Now().Add(time.Second * 10)
+
+	sig := &tasks.Signature{
+		Name: tasks2.SendPayoutStatusEmailTaskName,
+		Args: []tasks.Arg{
+			{
+				Type:  "string",
+				Value: eventID,
+				Name:  "eventID",
+			},
+		},
+		ETA: &now,
+	}
+	_, err := machinery.RabbitMQConnection().SendTask(sig)
+	if err != nil {
+		return err
+	}
+	return nil
+}
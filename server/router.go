@@ -51,7 +51,9 @@ func registerV1Routes() {
 	api.RegisterUserRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterStoreRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterProductRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterStockRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterCategoryRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterTaxonomyRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterCollectionRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterFSRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterAddressRoutes(publicEndpoints, platformEndpoints)
@@ -61,4 +63,10 @@ func registerV1Routes() {
 	api.RegisterStatsRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterCouponRoutes(publicEndpoints, platformEndpoints)
 	api.RegisterLocationRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterReconciliationRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterLedgerRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterPayoutBatchRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterDisputeRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterReviewRoutes(publicEndpoints, platformEndpoints)
+	api.RegisterWishlistRoutes(publicEndpoints, platformEndpoints)
 }
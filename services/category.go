@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+// CheckTreeMove returns an error when the node at path, whose descendants go height levels below it,
+// can't be placed below the parent. New nodes have no path and roots have no parent path.
+func CheckTreeMove(path string, height int, parentPath string, parentDepth int) error {
+	if parentPath == "" {
+		parentDepth = -1
+	} else if models.IsPathWithin(parentPath, path) {
+		return errors.NewError("can't be moved below itself or its descendants")
+	}
+
+	if parentDepth+1+height > models.CategoryMaxDepth {
+		return errors.NewError(fmt.Sprintf("can't be nested more than %d levels deep", models.CategoryMaxDepth+1))
+	}
+	return nil
+}
+
+// PlaceCategory puts the category below the parent, or at the root without parent, within the given
+// transaction. Moving an existing category moves its descendants along.
+func PlaceCategory(db *gorm.DB, c *models.Category, parent *models.Category) error {
+	cu := data.NewCategoryRepository()
+
+	height := 0
+	if c.Path != "" {
+		h, err := cu.SubtreeHeight(db, c)
+		if err != nil {
+			return err
+		}
+		height = h
+	}
+
+	var parentID *string
+	parentPath, parentDepth := "", 0
+	if parent != nil {
+		parentID, parentPath, parentDepth = &parent.ID, parent.Path, parent.Depth
+	}
+
+	if err := CheckTreeMove(c.Path, height, parentPath, parentDepth); err != nil {
+		return err
+	}
+
+	oldPath, oldDepth := c.Path, c.Depth
+
+	c.ParentID = parentID
+	c.Path = models.MaterializedPath(parentPath, c.ID)
+	c.Depth = 0
+	if parent != nil {
+		c.Depth = parent.Depth + 1
+	}
+
+	if oldPath != "" && oldPath != c.Path {
+		return cu.MoveSubtree(db, oldPath, c.Path, c.Depth-oldDepth)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestCheckTreeMove(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		height      int
+		parentPath  string
+		parentDepth int
+		ok          bool
+	}{
+		{"new root", "", 0, "", 0, true},
+		{"new child", "", 0, "/a/", 0, true},
+		{"new node at the deepest level", "", 0, "/a/b/c/d/", 3, true},
+		{"new node too deep", "", 0, "/a/b/c/d/e/", 4, false},
+		{"move to the root", "/a/b/", 1, "", 0, true},
+		{"move below a sibling", "/a/b/", 0, "/a/c/", 1, true},
+		{"move below itself", "/a/b/", 0, "/a/b/", 1, false},
+		{"move below a descendant", "/a/b/", 1, "/a/b/c/", 2, false},
+		{"move with descendants too deep", "/x/", 3, "/a/b/", 1, false},
+		{"move with descendants at the deepest level", "/x/", 2, "/a/", 0, true},
+	}
+
+	for _, tt := range tests {
+		err := CheckTreeMove(tt.path, tt.height, tt.parentPath, tt.parentDepth)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: expected ok %v, got %v", tt.name, tt.ok, err)
+		}
+	}
+}
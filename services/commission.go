@@ -0,0 +1,154 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+// CommissionCriteria describes an order item for matching it against the commission rules
+type CommissionCriteria struct {
+	ProductID    string
+	CategoryID   *string
+	StoreTier    string
+	MonthlySales int64
+	At           time.Time
+}
+
+// ResolveCommissionRule picks the rule for the order item among the given rules.
+// The most specific matching rule wins, then the one with the higher priority and then the most recent one.
+// Nil is returned when no rule matches and the commission rate of the store applies.
+func ResolveCommissionRule(rules []models.CommissionRule, c CommissionCriteria) *models.CommissionRule {
+	var resolved *models.CommissionRule
+
+	for i := range rules {
+		r := &rules[i]
+
+		if !r.IsEffective(c.At) {
+			continue
+		}
+		if r.ProductID != nil && *r.ProductID != c.ProductID {
+			continue
+		}
+		if r.CategoryID != nil && (c.CategoryID == nil || *r.CategoryID != *c.CategoryID) {
+			continue
+		}
+		if r.StoreTier != nil && *r.StoreTier != c.StoreTier {
+			continue
+		}
+		if r.MinMonthlySales != nil && c.MonthlySales < *r.MinMonthlySales {
+			continue
+		}
+		if r.MaxMonthlySales != nil && c.MonthlySales >= *r.MaxMonthlySales {
+			continue
+		}
+
+		if resolved == nil || isPreferredCommissionRule(r, resolved) {
+			resolved = r
+		}
+	}
+
+	return resolved
+}
+
+func isPreferredCommissionRule(r, than *models.CommissionRule) bool {
+	if r.Specificity() != than.Specificity() {
+		return r.Specificity() > than.Specificity()
+	}
+	if r.Priority != than.Priority {
+		return r.Priority > than.Priority
+	}
+	return r.EffectiveFrom.After(than.EffectiveFrom)
+}
+
+// SplitOrderCommission snapshots the commission of each ordered item and sets the platform and seller
+// earnings of the order. The actual earnings are shared among the items by their sub total, so order
+// level discounts lower the commission proportionally. The highest fixed fee of the resolved rules is
+// charged once per order and the commission never exceeds the actual earnings.
+func SplitOrderCommission(s *models.Store, o *models.Order, items []*models.OrderedItem, itemRules map[string]*models.CommissionRule) {
+	remaining := o.ActualEarnings
+	commission := int64(0)
+	fixedFee := int64(0)
+
+	for i, oi := range items {
+		base := remaining
+		if i < len(items)-1 {
+			base = 0
+			if o.SubTotal != 0 {
+				base = o.ActualEarnings * oi.SubTotal / o.SubTotal
+			}
+		}
+		remaining -= base
+
+		if r, ok := itemRules[oi.ID]; ok && r != nil {
+			oi.CommissionRuleID = &r.ID
+			oi.CommissionRate = r.Rate
+			oi.CommissionFixedFee = r.FixedFee
+			oi.Commission = r.CalculateCommission(base)
+		} else {
+			oi.CommissionRuleID = nil
+			oi.CommissionRate = s.CommissionRate
+			oi.CommissionFixedFee = 0
+			oi.Commission = s.CalculateCommission(base)
+		}
+
+		commission += oi.Commission
+		if oi.CommissionFixedFee > fixedFee {
+			fixedFee = oi.CommissionFixedFee
+		}
+	}
+
+	if commission+fixedFee > o.ActualEarnings {
+		fixedFee = o.ActualEarnings - commission
+		if fixedFee < 0 {
+			fixedFee = 0
+			commission = o.ActualEarnings
+		}
+	}
+
+	o.CommissionFixedFee = fixedFee
+	o.PlatformEarnings = commission + fixedFee
+	o.SellerEarnings = o.ActualEarnings - o.PlatformEarnings
+}
+
+// ApplyOrderCommission resolves the commission rule of every ordered item of the new order and
+// splits the earnings between the platform and the store. itemCategories maps the ordered item ID
+// to the category of its product.
+func ApplyOrderCommission(db *gorm.DB, s *models.Store, o *models.Order, items []*models.OrderedItem, itemCategories map[string]*string) error {
+	mu := data.NewMarketplaceRepository()
+	ou := data.NewOrderRepository()
+
+	at := o.CreatedAt
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	rules, err := mu.ListEffectiveCommissionRules(db, at)
+	if err != nil {
+		return err
+	}
+
+	itemRules := map[string]*models.CommissionRule{}
+
+	if len(rules) > 0 {
+		monthStart := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+		monthlySales, err := ou.GetStoreSalesVolume(db, s.ID, monthStart)
+		if err != nil {
+			return err
+		}
+
+		for _, oi := range items {
+			itemRules[oi.ID] = ResolveCommissionRule(rules, CommissionCriteria{
+				ProductID:    oi.ProductID,
+				CategoryID:   itemCategories[oi.ID],
+				StoreTier:    s.Tier,
+				MonthlySales: monthlySales,
+				At:           at,
+			})
+		}
+	}
+
+	SplitOrderCommission(s, o, items, itemRules)
+	return nil
+}
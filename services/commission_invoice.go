@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/templates"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/values"
+	"strings"
+	"time"
+)
+
+// CommissionInvoiceLine is the commission charged or credited back for an order within the invoice period
+type CommissionInvoiceLine struct {
+	Date        time.Time
+	Reference   string
+	Description string
+	Amount      int64
+}
+
+// GenerateMonthlyCommissionInvoices issues the commission invoice of the month of the given time to every
+// store the platform earned a commission from. Stores that already have the invoice are skipped,
+// so the task can be retried safely.
+func GenerateMonthlyCommissionInvoices(month time.Time) ([]models.CommissionInvoice, error) {
+	month = month.UTC()
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	su := data.NewStoreRepository()
+	storeIDs, err := su.ListIDs(app.DB())
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices []models.CommissionInvoice
+	for _, storeID := range storeIDs {
+		ci, err := generateCommissionInvoice(app.DB(), storeID, start, end)
+		if err != nil {
+			log.Log().Errorln("Failed to generate commission invoice of store ", storeID, " : ", err)
+			continue
+		}
+		if ci == nil {
+			continue
+		}
+
+		invoices = append(invoices, *ci)
+
+		if err := sendCommissionInvoiceEmail(app.DB(), ci); err != nil {
+			log.Log().Errorln("Failed to send commission invoice email of store ", storeID, " : ", err)
+		}
+	}
+	return invoices, nil
+}
+
+// generateCommissionInvoice returns nil when the invoice already exists or the commission of the period nets to zero.
+// Periods where more commission was credited back than charged get a credit note instead.
+func generateCommissionInvoice(db *gorm.DB, storeID string, start, end time.Time) (*models.CommissionInvoice, error) {
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	cr := data.NewCommissionInvoiceRepository()
+	exists, err := cr.Exists(tx, storeID, start)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, nil
+	}
+
+	lu := data.NewLedgerRepository()
+	movements, err := lu.ListStoreMovements(tx, storeID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	ci, lines := BuildCommissionInvoice(storeID, start, end, movements)
+	if ci.TotalAmount == 0 {
+		return nil, nil
+	}
+
+	if err := fillCommissionInvoiceParties(tx, ci); err != nil {
+		return nil, err
+	}
+
+	prefix := config.Invoice().NumberPrefix
+	if ci.IsCreditNote {
+		prefix = config.Invoice().CreditNoteNumberPrefix
+	}
+	sequence := fmt.Sprintf("%s-%d", prefix, ci.IssuedAt.Year())
+	number, err := cr.NextNumber(tx, sequence)
+	if err != nil {
+		return nil, err
+	}
+	ci.Number = FormatInvoiceNumber(sequence, number)
+
+	body := GenerateCommissionInvoicePDF(ci, lines)
+
+	ci.PDFPath = fmt.Sprintf("%s/invoices/%s/%s-%s.pdf", values.ReservedBucketName, storeID, start.Format("2006-01"), ci.ID)
+	if err := UploadToMinio(ci.PDFPath, "application/pdf", bytes.NewReader(body), int64(len(body))); err != nil {
+		return nil, err
+	}
+
+	if err := cr.Create(tx, ci); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return ci, nil
+}
+
+// fillCommissionInvoiceParties copies the company details of the platform and the business details of the store.
+// Stores without payout settings are billed by their name only.
+func fillCommissionInvoiceParties(db *gorm.DB, ci *models.CommissionInvoice) error {
+	mu := data.NewMarketplaceRepository()
+	settings, err := mu.GetSettings(db)
+	if err != nil {
+		return err
+	}
+
+	au := data.NewAddressRepository()
+	company, err := au.GetAddressByID(db, settings.CompanyAddressID)
+	if err != nil {
+		return err
+	}
+
+	ci.IssuerName = settings.Name
+	ci.IssuerAddress = FormatInvoiceAddress(company.Address, company.City, company.State, company.Postcode, company.Country)
+
+	psd, err := mu.GetPayoutSettingsDetails(db, ci.StoreID)
+	if err == nil {
+		ci.SellerName = psd.BusinessName
+		ci.SellerAddress = FormatInvoiceAddress(psd.BusinessAddressAddress, psd.BusinessAddressCity,
+			psd.BusinessAddressState, psd.BusinessAddressPostcode, psd.CountryName)
+		ci.SellerVatNumber = psd.VatNumber
+		return nil
+	}
+	if !errors.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	su := data.NewStoreRepository()
+	store, err := su.FindStoreByID(db, ci.StoreID)
+	if err != nil {
+		return err
+	}
+	ci.SellerName = store.Name
+	return nil
+}
+
+// BuildCommissionInvoice sums up the commission the platform earned from the store in the period.
+// Commission taken back on reverted orders is credited on the same invoice, a negative total makes it a credit note.
+func BuildCommissionInvoice(storeID string, start, end time.Time, movements []models.StoreLedgerMovement) (*models.CommissionInvoice, []CommissionInvoiceLine) {
+	ci := &models.CommissionInvoice{
+		ID:          utils.NewUUID(),
+		StoreID:     storeID,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Currency:    config.Payout().Currency,
+		IssuedAt:    time.Now().UTC(),
+	}
+
+	var lines []CommissionInvoiceLine
+
+	for _, m := range movements {
+		if m.CommissionAmount == 0 {
+			continue
+		}
+
+		if m.CommissionAmount > 0 {
+			ci.TotalCommission += m.CommissionAmount
+			if m.Event == string(models.PaymentCompleted) {
+				ci.TotalOrders++
+			}
+		} else {
+			ci.TotalCredits -= m.CommissionAmount
+		}
+
+		lines = append(lines, CommissionInvoiceLine{
+			Date:        m.CreatedAt,
+			Reference:   m.ReferenceID,
+			Description: m.Description,
+			Amount:      m.CommissionAmount,
+		})
+	}
+
+	ci.TotalAmount = ci.TotalCommission - ci.TotalCredits
+	ci.IsCreditNote = ci.TotalAmount < 0
+	return ci, lines
+}
+
+// FormatInvoiceNumber numbers the invoice within its sequence, e.g. INV-2020-000042
+func FormatInvoiceNumber(sequence string, number int64) string {
+	return fmt.Sprintf("%s-%06d", sequence, number)
+}
+
+// FormatInvoiceAddress writes the non empty parts of the address on separate lines
+func FormatInvoiceAddress(address, city, state, postcode, country string) string {
+	return strings.Join(nonEmpty([]string{address, strings.Join(nonEmpty([]string{city, state, postcode}), " "), country}), "\n")
+}
+
+func nonEmpty(values []string) []string {
+	var res []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// commissionInvoiceKind names the document, an invoice or a credit note
+func commissionInvoiceKind(ci *models.CommissionInvoice) string {
+	if ci.IsCreditNote {
+		return "Credit Note"
+	}
+	return "Invoice"
+}
+
+// GenerateCommissionInvoicePDF writes the invoice with the commission of every order of the period
+func GenerateCommissionInvoicePDF(ci *models.CommissionInvoice, lines []CommissionInvoiceLine) []byte {
+	kind := commissionInvoiceKind(ci)
+	d := templates.NewPDFDocument(fmt.Sprintf("%s | %s %s", ci.IssuerName, kind, ci.Number))
+
+	d.Title("Commission " + kind)
+	d.KeyValues([][2]string{
+		{kind + " number", ci.Number},
+		{"Issue date", ci.IssuedAt.Format(utils.DateFormat)},
+		{"Period", fmt.Sprintf("%s to %s", ci.PeriodStart.Format(utils.DateFormat),
+			ci.PeriodEnd.AddDate(0, 0, -1).Format(utils.DateFormat))},
+	})
+
+	d.Heading("Issued by")
+	d.Text(strings.TrimSpace(ci.IssuerName + "\n" + ci.IssuerAddress))
+
+	d.Heading("Billed to")
+	seller := ci.SellerName + "\n" + ci.SellerAddress
+	if ci.SellerVatNumber != "" {
+		seller += "\nVAT number: " + ci.SellerVatNumber
+	}
+	d.Text(strings.TrimSpace(seller))
+
+	d.Heading("Details")
+	var rows [][]string
+	for _, l := range lines {
+		rows = append(rows, []string{l.Date.Format(utils.DateFormat), l.Reference, l.Description, formatDecimalAmount(l.Amount)})
+	}
+	d.Table([]templates.PDFColumn{
+		{Title: "Date", Width: 65},
+		{Title: "Order", Width: 185},
+		{Title: "Description", Width: 165},
+		{Title: "Amount", Width: d.ContentWidth() - 415, AlignRight: true},
+	}, rows)
+
+	d.KeyValues([][2]string{
+		{"Commission", formatAmount(ci.TotalCommission)},
+		{"Credits", formatAmount(-ci.TotalCredits)},
+		{fmt.Sprintf("Total (%s)", ci.Currency), formatAmount(ci.TotalAmount)},
+	})
+	if ci.IsCreditNote {
+		d.Text("\nThe total has been credited back to the earnings of the store.")
+	} else {
+		d.Text("\nThe total has been deducted from the earnings of the store, no payment is due.")
+	}
+
+	return d.Bytes()
+}
+
+func sendCommissionInvoiceEmail(db *gorm.DB, ci *models.CommissionInvoice) error {
+	su := data.NewStoreRepository()
+	creator, err := su.GetStoreCreator(db, ci.StoreID)
+	if err != nil {
+		return err
+	}
+
+	kind := commissionInvoiceKind(ci)
+	period := ci.PeriodStart.Format("January 2006")
+	return SendNotificationEmail(creator.StaffEmail, fmt.Sprintf("Commission %s %s for %s", strings.ToLower(kind), ci.Number, period), &Notification{
+		Title:     "Commission " + kind,
+		Greetings: fmt.Sprintf("Hi %s,", creator.StaffName),
+		Intros: fmt.Sprintf("The commission %s of %s for %s is ready to download from your dashboard.",
+			strings.ToLower(kind), creator.StoreName, period),
+		Details: []NotificationDetail{
+			{Label: kind + " number", Value: ci.Number},
+			{Label: "Commission", Value: formatAmount(ci.TotalCommission)},
+			{Label: "Credits", Value: formatAmount(ci.TotalCredits)},
+			{Label: "Total", Value: formatAmount(ci.TotalAmount)},
+		},
+	})
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"bytes"
+	"github.com/shopicano/shopicano-backend/models"
+	"testing"
+	"time"
+)
+
+func TestBuildCommissionInvoice(t *testing.T) {
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	movements := []models.StoreLedgerMovement{
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o1", Event: string(models.PaymentCompleted),
+			StoreAmount: 9000, CommissionAmount: 1000, CreatedAt: start.Add(time.Hour)},
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o2", Event: string(models.PaymentCompleted),
+			StoreAmount: 4500, CommissionAmount: 500, CreatedAt: start.Add(2 * time.Hour)},
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o2", Event: string(models.PaymentReverted),
+			StoreAmount: -4500, CommissionAmount: -500, CreatedAt: start.Add(3 * time.Hour)},
+		{ReferenceType: models.JournalReferencePayout, ReferenceID: "p1", Event: string(models.PayoutSendStatusPending),
+			StoreAmount: -6000, CreatedAt: start.Add(4 * time.Hour)},
+	}
+
+	ci, lines := BuildCommissionInvoice("s1", start, end, movements)
+
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	if ci.TotalOrders != 2 || ci.TotalCommission != 1500 || ci.TotalCredits != 500 || ci.TotalAmount != 1000 {
+		t.Errorf("unexpected totals %+v", ci)
+	}
+	if lines[2].Amount != -500 {
+		t.Errorf("expected the reverted order to be credited, got %d", lines[2].Amount)
+	}
+
+	ci.Number = FormatInvoiceNumber("INV-2020", 42)
+	if ci.Number != "INV-2020-000042" {
+		t.Errorf("unexpected invoice number %s", ci.Number)
+	}
+
+	body := GenerateCommissionInvoicePDF(ci, lines)
+	if !bytes.HasPrefix(body, []byte("%PDF-")) || !bytes.Contains(body, []byte("INV-2020-000042")) {
+		t.Error("expected a PDF with the invoice number")
+	}
+	if ci.IsCreditNote {
+		t.Error("expected an invoice for a positive total")
+	}
+}
+
+func TestBuildCommissionCreditNote(t *testing.T) {
+	start := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	// The order paid in the previous period is reverted in this one
+	movements := []models.StoreLedgerMovement{
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o1", Event: string(models.PaymentReverted),
+			StoreAmount: -9000, CommissionAmount: -1000, CreatedAt: start.Add(time.Hour)},
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o3", Event: string(models.PaymentCompleted),
+			StoreAmount: 2700, CommissionAmount: 300, CreatedAt: start.Add(2 * time.Hour)},
+	}
+
+	ci, lines := BuildCommissionInvoice("s1", start, end, movements)
+
+	if len(lines) != 2 || ci.TotalCommission != 300 || ci.TotalCredits != 1000 || ci.TotalAmount != -700 {
+		t.Errorf("unexpected totals %+v", ci)
+	}
+	if !ci.IsCreditNote {
+		t.Fatal("expected a credit note for a negative total")
+	}
+
+	ci.Number = FormatInvoiceNumber("CN-2020", 7)
+	body := GenerateCommissionInvoicePDF(ci, lines)
+	if !bytes.Contains(body, []byte("Commission Credit Note")) || !bytes.Contains(body, []byte("CN-2020-000007")) {
+		t.Error("expected a credit note PDF with its number")
+	}
+}
+
+func TestFormatInvoiceAddress(t *testing.T) {
+	got := FormatInvoiceAddress("1 Main Street", "London", "", "E1 6AN", "United Kingdom")
+	if got != "1 Main Street\nLondon E1 6AN\nUnited Kingdom" {
+		t.Errorf("unexpected address %q", got)
+	}
+	if got := FormatInvoiceAddress("", "", "", "", ""); got != "" {
+		t.Errorf("expected empty address, got %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"testing"
+	"time"
+)
+
+func TestResolveCommissionRule(t *testing.T) {
+	at := time.Date(2020, 10, 12, 0, 0, 0, 0, time.UTC)
+	product := "p1"
+	category := "c1"
+	tier := "gold"
+	min := int64(100000)
+	max := int64(500000)
+	expired := at.Add(-time.Hour)
+
+	rules := []models.CommissionRule{
+		{ID: "band", MinMonthlySales: &min, MaxMonthlySales: &max, Rate: 8, IsActive: true, EffectiveFrom: at.AddDate(0, -1, 0)},
+		{ID: "tier", StoreTier: &tier, Rate: 7, IsActive: true, EffectiveFrom: at.AddDate(0, -1, 0)},
+		{ID: "category", CategoryID: &category, Rate: 5, IsActive: true, EffectiveFrom: at.AddDate(0, -1, 0)},
+		{ID: "category-priority", CategoryID: &category, Rate: 4, Priority: 1, IsActive: true, EffectiveFrom: at.AddDate(0, -2, 0)},
+		{ID: "product-expired", ProductID: &product, Rate: 1, IsActive: true, EffectiveFrom: at.AddDate(0, -1, 0), EffectiveTo: &expired},
+		{ID: "product-inactive", ProductID: &product, Rate: 2, EffectiveFrom: at.AddDate(0, -1, 0)},
+		{ID: "product-future", ProductID: &product, Rate: 3, IsActive: true, EffectiveFrom: at.Add(time.Hour)},
+	}
+
+	cases := []struct {
+		name     string
+		criteria CommissionCriteria
+		expected string
+	}{
+		{"no match", CommissionCriteria{ProductID: "p2", At: at}, ""},
+		{"sales band lower bound", CommissionCriteria{ProductID: "p2", MonthlySales: min, At: at}, "band"},
+		{"sales band upper bound is exclusive", CommissionCriteria{ProductID: "p2", MonthlySales: max, At: at}, ""},
+		{"tier beats sales band", CommissionCriteria{ProductID: "p2", StoreTier: tier, MonthlySales: min, At: at}, "tier"},
+		{"category beats tier, then priority", CommissionCriteria{ProductID: "p2", CategoryID: &category, StoreTier: tier, At: at}, "category-priority"},
+		{"ineffective product rules are skipped", CommissionCriteria{ProductID: product, CategoryID: &category, At: at}, "category-priority"},
+		{"product rule once effective", CommissionCriteria{ProductID: product, At: at.Add(2 * time.Hour)}, "product-future"},
+	}
+
+	for _, c := range cases {
+		r := ResolveCommissionRule(rules, c.criteria)
+		got := ""
+		if r != nil {
+			got = r.ID
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected rule %q, got %q", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestSplitOrderCommission(t *testing.T) {
+	s := &models.Store{CommissionRate: 10}
+	o := &models.Order{SubTotal: 10000, ActualEarnings: 9000}
+	items := []*models.OrderedItem{
+		{ID: "i1", SubTotal: 6000},
+		{ID: "i2", SubTotal: 4000},
+	}
+	rules := map[string]*models.CommissionRule{
+		"i2": {ID: "r1", Rate: 20, FixedFee: 50},
+	}
+
+	SplitOrderCommission(s, o, items, rules)
+
+	// 9000 is shared as 5400 and 3600, then 10% and 20% with a 50 fixed fee
+	if items[0].Commission != 540 || items[0].CommissionRuleID != nil || items[0].CommissionRate != 10 {
+		t.Errorf("unexpected first item %+v", items[0])
+	}
+	if items[1].Commission != 720 || items[1].CommissionRuleID == nil || *items[1].CommissionRuleID != "r1" {
+		t.Errorf("unexpected second item %+v", items[1])
+	}
+	if o.CommissionFixedFee != 50 || o.PlatformEarnings != 1310 || o.SellerEarnings != 7690 {
+		t.Errorf("unexpected earnings fee=%d platform=%d seller=%d", o.CommissionFixedFee, o.PlatformEarnings, o.SellerEarnings)
+	}
+
+	o = &models.Order{SubTotal: 100, ActualEarnings: 100}
+	items = []*models.OrderedItem{{ID: "i1", SubTotal: 100}}
+	SplitOrderCommission(s, o, items, map[string]*models.CommissionRule{"i1": {ID: "r1", Rate: 50, FixedFee: 500}})
+	if o.PlatformEarnings != 100 || o.SellerEarnings != 0 || o.CommissionFixedFee != 50 {
+		t.Errorf("fixed fee must be capped, got fee=%d platform=%d seller=%d", o.CommissionFixedFee, o.PlatformEarnings, o.SellerEarnings)
+	}
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	payment_gateways "github.com/shopicano/shopicano-backend/payment-gateways"
+	"github.com/shopicano/shopicano-backend/utils"
+	"time"
+)
+
+// CheckDisputeTransition returns an error when the dispute can't move from one status to the other
+func CheckDisputeTransition(from, to models.DisputeStatus) error {
+	if !from.CanTransitionTo(to) {
+		return errors.NewError(fmt.Sprintf("dispute can't move from %s to %s", from, to))
+	}
+	return nil
+}
+
+// CreateDispute records the dispute of the order within the given transaction. The earnings of the order
+// are frozen until the dispute is decided.
+func CreateDispute(db *gorm.DB, o *models.Order, d *models.Dispute) error {
+	d.OrderID = o.ID
+	d.StoreID = o.StoreID
+	if d.PaymentGateway == "" {
+		d.PaymentGateway = "offline"
+		if o.PaymentGateway != nil && *o.PaymentGateway != "" {
+			d.PaymentGateway = *o.PaymentGateway
+		}
+	}
+	if d.Status == "" {
+		d.Status = models.DisputeStatusOpen
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	d.UpdatedAt = d.CreatedAt
+	if !d.Status.IsOpen() {
+		d.ResolvedAt = &d.UpdatedAt
+	}
+
+	dr := data.NewDisputeRepository()
+	if err := dr.Create(db, d); err != nil {
+		return err
+	}
+	return applyDisputeOutcome(db, d)
+}
+
+// UpdateDisputeStatus moves the dispute to the given status within the given transaction.
+// A lost dispute reverts the payment of the order and charges the dispute fee to the store.
+func UpdateDisputeStatus(db *gorm.DB, d *models.Dispute, status models.DisputeStatus) error {
+	if err := CheckDisputeTransition(d.Status, status); err != nil {
+		return err
+	}
+
+	d.Status = status
+	d.UpdatedAt = time.Now().UTC()
+	if !status.IsOpen() {
+		d.ResolvedAt = &d.UpdatedAt
+	}
+
+	dr := data.NewDisputeRepository()
+	if err := dr.Update(db, d); err != nil {
+		return err
+	}
+	return applyDisputeOutcome(db, d)
+}
+
+func applyDisputeOutcome(db *gorm.DB, d *models.Dispute) error {
+	if d.Status != models.DisputeStatusLost {
+		return nil
+	}
+
+	ou := data.NewOrderRepository()
+	o, err := ou.Get(db, d.OrderID)
+	if err != nil {
+		return err
+	}
+
+	if o.PaymentStatus == models.PaymentCompleted {
+		o.PaymentStatus = models.PaymentReverted
+		if err := ou.UpdatePaymentStatus(db, o); err != nil {
+			return err
+		}
+		if err := PostOrderLedgerEntries(db, o.ID); err != nil {
+			return err
+		}
+		if err := SyncOrderLicenseKeys(db, o.ID); err != nil {
+			return err
+		}
+
+		ol := models.OrderLog{
+			ID:        utils.NewUUID(),
+			OrderID:   o.ID,
+			Action:    string(o.PaymentStatus),
+			Details:   fmt.Sprintf("Payment reverted for lost dispute : %s", d.Reason),
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := ou.CreateLog(db, &ol); err != nil {
+			return err
+		}
+	}
+
+	return PostDisputeLedgerEntries(db, d)
+}
+
+// IngestDisputeNotification creates or updates the dispute reported by the payment gateway within the
+// given transaction. Gateways may report the same state more than once, which is safe. Status changes
+// the dispute can't make anymore are ignored, as decided disputes are final. A dispute an admin opened
+// for the order before the gateway reported it becomes the dispute of the gateway.
+func IngestDisputeNotification(db *gorm.DB, gatewayName string, n *payment_gateways.DisputeNotification) (*models.Dispute, error) {
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.GetByGatewayDisputeID(db, gatewayName, n.DisputeID)
+	if err != nil && !errors.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	var o *models.Order
+	if d == nil {
+		o, d, err = lockDisputedOrder(db, gatewayName, n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if d == nil {
+		d = &models.Dispute{
+			ID:               utils.NewUUID(),
+			PaymentGateway:   gatewayName,
+			GatewayDisputeID: &n.DisputeID,
+			Amount:           n.Amount,
+			Fee:              n.Fee,
+			Reason:           n.Reason,
+			Status:           n.Status,
+			EvidenceDueBy:    n.EvidenceDueBy,
+		}
+		if err := CreateDispute(db, o, d); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+
+	if !d.Status.IsOpen() {
+		return d, nil
+	}
+
+	d.Amount = n.Amount
+	d.Fee = n.Fee
+	d.Reason = n.Reason
+	d.EvidenceDueBy = n.EvidenceDueBy
+
+	if d.Status != n.Status {
+		if err := UpdateDisputeStatus(db, d, n.Status); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+
+	d.UpdatedAt = time.Now().UTC()
+	if err := dr.Update(db, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// lockDisputedOrder locks the order of the disputed transaction until the transaction ends, so the order
+// gets a single open dispute when admins or the gateway open one at the same time. Along with the order it
+// returns the dispute of the gateway when it was created meanwhile, or the open dispute an admin opened.
+func lockDisputedOrder(db *gorm.DB, gatewayName string, n *payment_gateways.DisputeNotification) (*models.Order, *models.Dispute, error) {
+	ou := data.NewOrderRepository()
+	o, err := ou.GetByTransactionID(db, gatewayName, n.TransactionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	o, err = ou.GetForUpdate(db, o.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dr := data.NewDisputeRepository()
+
+	d, err := dr.GetByGatewayDisputeID(db, gatewayName, n.DisputeID)
+	if err == nil {
+		return o, d, nil
+	}
+	if !errors.IsRecordNotFoundError(err) {
+		return nil, nil, err
+	}
+
+	d, err = dr.GetOpenByOrderID(db, o.ID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			return o, nil, nil
+		}
+		return nil, nil, err
+	}
+	if d.GatewayDisputeID != nil {
+		return o, nil, nil
+	}
+
+	d.GatewayDisputeID = &n.DisputeID
+	return o, d, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"testing"
+)
+
+func TestCheckDisputeTransition(t *testing.T) {
+	cases := []struct {
+		from, to models.DisputeStatus
+		allowed  bool
+	}{
+		{models.DisputeStatusOpen, models.DisputeStatusUnderReview, true},
+		{models.DisputeStatusUnderReview, models.DisputeStatusOpen, true},
+		{models.DisputeStatusOpen, models.DisputeStatusLost, true},
+		{models.DisputeStatusUnderReview, models.DisputeStatusWon, true},
+		{models.DisputeStatusOpen, models.DisputeStatusOpen, false},
+		{models.DisputeStatusOpen, "dispute_unknown", false},
+		{models.DisputeStatusWon, models.DisputeStatusLost, false},
+		{models.DisputeStatusLost, models.DisputeStatusOpen, false},
+		{models.DisputeStatusClosed, models.DisputeStatusUnderReview, false},
+	}
+
+	for _, c := range cases {
+		err := CheckDisputeTransition(c.from, c.to)
+		if c.allowed && err != nil {
+			t.Errorf("%s -> %s: expected to be allowed, got %v", c.from, c.to, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("%s -> %s: expected to be rejected", c.from, c.to)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"time"
+)
+
+// ledgerPosting is the net debit (positive) or credit (negative) of an account for a reference
+type ledgerPosting struct {
+	account models.LedgerAccount
+	amount  int64
+}
+
+func storeLedgerAccount(storeID string) models.LedgerAccount {
+	return models.LedgerAccount{
+		Code:    models.StoreLedgerAccountCode(storeID),
+		Name:    "Store earnings",
+		Type:    models.LedgerAccountLiability,
+		StoreID: &storeID,
+	}
+}
+
+func platformLedgerAccount(code, name string, t models.LedgerAccountType) models.LedgerAccount {
+	return models.LedgerAccount{
+		Code: code,
+		Name: name,
+		Type: t,
+	}
+}
+
+// PostOrderLedgerEntries brings the ledger in line with the payment status of the order.
+// A completed payment is split between the store, the platform commission, the gateway fee and the
+// shipping charge. A reverted payment keeps only the gateway fee, which is not refunded to the customer.
+func PostOrderLedgerEntries(db *gorm.DB, orderID string) error {
+	ou := data.NewOrderRepository()
+	o, err := ou.Get(db, orderID)
+	if err != nil {
+		return err
+	}
+
+	gateway := "offline"
+	if o.PaymentGateway != nil && *o.PaymentGateway != "" {
+		gateway = *o.PaymentGateway
+	}
+	gatewayAccount := platformLedgerAccount(models.GatewayLedgerAccountCode(gateway),
+		fmt.Sprintf("Payments received through %s", gateway), models.LedgerAccountAsset)
+	feesAccount := platformLedgerAccount(models.LedgerAccountGatewayFees, "Gateway fees", models.LedgerAccountRevenue)
+
+	var postings []ledgerPosting
+
+	switch o.PaymentStatus {
+	case models.PaymentCompleted:
+		shipping := o.GrandTotal - o.SellerEarnings - o.PlatformEarnings - o.PaymentProcessingFee
+
+		postings = []ledgerPosting{
+			{account: gatewayAccount, amount: o.GrandTotal},
+			{account: storeLedgerAccount(o.StoreID), amount: -o.SellerEarnings},
+			{account: platformLedgerAccount(models.LedgerAccountPlatformCommission, "Platform commission", models.LedgerAccountRevenue), amount: -o.PlatformEarnings},
+			{account: feesAccount, amount: -o.PaymentProcessingFee},
+			{account: platformLedgerAccount(models.LedgerAccountShippingCharges, "Shipping charges", models.LedgerAccountRevenue), amount: -shipping},
+		}
+	case models.PaymentReverted:
+		postings = []ledgerPosting{
+			{account: gatewayAccount, amount: o.PaymentProcessingFee},
+			{account: feesAccount, amount: -o.PaymentProcessingFee},
+		}
+	}
+
+	return postLedgerEntries(db, models.JournalReferenceOrder, o.ID, o.StoreID, string(o.PaymentStatus),
+		fmt.Sprintf("Order %s %s", o.Hash, o.PaymentStatus), postings)
+}
+
+// PostPayoutLedgerEntries brings the ledger in line with the status of the payout entry.
+// Requested payouts are held in the pending payouts account until they are completed or failed.
+func PostPayoutLedgerEntries(db *gorm.DB, p *models.PayoutSend) error {
+	var postings []ledgerPosting
+
+	switch p.Status {
+	case models.PayoutSendStatusPending, models.PayoutSendStatusConfirmed, models.PayoutSendStatusProcessing:
+		postings = []ledgerPosting{
+			{account: storeLedgerAccount(p.StoreID), amount: p.Amount},
+			{account: platformLedgerAccount(models.LedgerAccountPayoutsPending, "Pending payouts", models.LedgerAccountLiability), amount: -p.Amount},
+		}
+	case models.PayoutSendStatusCompleted:
+		postings = []ledgerPosting{
+			{account: storeLedgerAccount(p.StoreID), amount: p.Amount},
+			{account: platformLedgerAccount(models.LedgerAccountPayoutsSent, "Payouts sent", models.LedgerAccountAsset), amount: -p.Amount},
+		}
+	}
+
+	return postLedgerEntries(db, models.JournalReferencePayout, p.ID, p.StoreID, string(p.Status),
+		fmt.Sprintf("Payout %s", p.Status), postings)
+}
+
+// PostDisputeLedgerEntries charges the store the fee the payment gateway withheld for a lost dispute.
+// The disputed amount itself is taken back by reverting the payment of the order.
+func PostDisputeLedgerEntries(db *gorm.DB, d *models.Dispute) error {
+	var postings []ledgerPosting
+
+	if d.Status == models.DisputeStatusLost {
+		postings = []ledgerPosting{
+			{account: storeLedgerAccount(d.StoreID), amount: d.Fee},
+			{account: platformLedgerAccount(models.GatewayLedgerAccountCode(d.PaymentGateway),
+				fmt.Sprintf("Payments received through %s", d.PaymentGateway), models.LedgerAccountAsset), amount: -d.Fee},
+		}
+	}
+
+	return postLedgerEntries(db, models.JournalReferenceDispute, d.ID, d.StoreID, string(d.Status),
+		fmt.Sprintf("Dispute fee %s", d.Status), postings)
+}
+
+// postLedgerEntries posts a journal entry with the difference between the wanted postings of the
+// reference and what has already been posted for it. Nothing is posted when they are the same,
+// so calling it again for the same state is safe. Postings of the same reference wait for each other.
+func postLedgerEntries(db *gorm.DB, referenceType models.JournalReferenceType, referenceID, storeID, event, description string, postings []ledgerPosting) error {
+	lu := data.NewLedgerRepository()
+
+	if err := lu.LockReference(db, referenceType, referenceID); err != nil {
+		return err
+	}
+
+	posted, err := lu.ListReferenceBalances(db, referenceType, referenceID)
+	if err != nil {
+		return err
+	}
+
+	diff := map[string]int64{}
+	for _, b := range posted {
+		diff[b.ID] -= b.TotalDebit - b.TotalCredit
+	}
+
+	for _, p := range postings {
+		if p.amount == 0 {
+			continue
+		}
+
+		a := p.account
+		a.ID = utils.NewUUID()
+		a.CreatedAt = time.Now().UTC()
+
+		account, err := lu.GetOrCreateAccount(db, &a)
+		if err != nil {
+			return err
+		}
+		diff[account.ID] += p.amount
+	}
+
+	var lines []models.JournalLine
+	for accountID, amount := range diff {
+		if amount == 0 {
+			continue
+		}
+
+		l := models.JournalLine{
+			ID:        utils.NewUUID(),
+			AccountID: accountID,
+		}
+		if amount > 0 {
+			l.Debit = amount
+		} else {
+			l.Credit = -amount
+		}
+		lines = append(lines, l)
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	e := &models.JournalEntry{
+		ID:            utils.NewUUID(),
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		StoreID:       storeID,
+		Event:         event,
+		Description:   description,
+		CreatedAt:     time.Now().UTC(),
+	}
+	return lu.CreateEntry(db, e, lines)
+}
+
+// GetStoreLedgerSummary returns the payout summary of the store from the ledger.
+// Earnings still within the reserve period are on hold and earnings of disputed orders are frozen,
+// neither is available.
+func GetStoreLedgerSummary(db *gorm.DB, storeID string) (*models.StoreLedgerSummary, error) {
+	lu := data.NewLedgerRepository()
+
+	summary, err := lu.GetStoreSummary(db, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	held, err := getStoreHeldEarnings(db, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	frozen, err := lu.GetStoreFrozenEarnings(db, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.TotalOnHold = held
+	summary.TotalFrozen = frozen
+	summary.TotalAvailable -= held + frozen
+	return summary, nil
+}
+
+// GetStoreAvailableBalance returns the ledger balance of the store that can be paid out
+func GetStoreAvailableBalance(db *gorm.DB, storeID string) (int64, error) {
+	lu := data.NewLedgerRepository()
+
+	b, err := lu.GetAccountBalance(db, models.StoreLedgerAccountCode(storeID))
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	held, err := getStoreHeldEarnings(db, storeID)
+	if err != nil {
+		return 0, err
+	}
+
+	frozen, err := lu.GetStoreFrozenEarnings(db, storeID)
+	if err != nil {
+		return 0, err
+	}
+	return b.Balance - held - frozen, nil
+}
+
+// LockStoreAvailableBalance locks the balance of the store until the transaction ends and returns the part
+// of it that can be paid out. Payouts are checked against it so concurrent payouts can't overdraw the store.
+func LockStoreAvailableBalance(db *gorm.DB, storeID string) (int64, error) {
+	lu := data.NewLedgerRepository()
+	if err := lu.LockStore(db, storeID); err != nil {
+		return 0, err
+	}
+	return GetStoreAvailableBalance(db, storeID)
+}
+
+// getStoreHeldEarnings returns the earnings of undelivered orders paid within the reserve period of the store
+func getStoreHeldEarnings(db *gorm.DB, storeID string) (int64, error) {
+	reserve, err := GetStoreReservePeriod(db, storeID)
+	if err != nil {
+		return 0, err
+	}
+	if reserve == 0 {
+		return 0, nil
+	}
+
+	lu := data.NewLedgerRepository()
+	return lu.GetStoreHeldEarnings(db, storeID, time.Now().UTC().Add(-reserve))
+}
+
+// GetStoreReservePeriod returns the reserve period of the store, falling back to the platform default
+func GetStoreReservePeriod(db *gorm.DB, storeID string) (time.Duration, error) {
+	su := data.NewStoreRepository()
+	s, err := su.FindStoreByID(db, storeID)
+	if err != nil {
+		return 0, err
+	}
+
+	days := 0
+	if s.ReservePeriodDays != nil {
+		days = *s.ReservePeriodDays
+	} else {
+		au := data.NewMarketplaceRepository()
+		settings, err := au.GetSettings(db)
+		if err != nil {
+			return 0, err
+		}
+		days = settings.DefaultReservePeriodDays
+	}
+	return time.Duration(days) * time.Hour * 24, nil
+}
+
+// SyncLedger posts the missing ledger entries of every paid or reverted order and every payout entry.
+// It is used to build the ledger from the existing orders and payouts.
+func SyncLedger(db *gorm.DB) error {
+	ou := data.NewOrderRepository()
+	orderIDs, err := ou.ListIDsByPaymentStatus(db, []models.PaymentStatus{models.PaymentCompleted, models.PaymentReverted})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range orderIDs {
+		if err := syncLedgerEntries(db, func(tx *gorm.DB) error {
+			return PostOrderLedgerEntries(tx, id)
+		}); err != nil {
+			return err
+		}
+	}
+
+	au := data.NewMarketplaceRepository()
+	payouts, err := au.ListAllPayoutEntries(db)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range payouts {
+		p := p
+		if err := syncLedgerEntries(db, func(tx *gorm.DB) error {
+			return PostPayoutLedgerEntries(tx, &p)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncLedgerEntries(db *gorm.DB, post func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+	if err := post(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
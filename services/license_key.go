@@ -0,0 +1,230 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+)
+
+// DefaultLicenseKeyLowThreshold is the number of available keys the stores are warned at unless they set theirs
+const DefaultLicenseKeyLowThreshold = 10
+
+// ParseLicenseKeys trims the uploaded keys and drops the blank ones. Keys given more than once are kept
+// once and returned as repeated.
+func ParseLicenseKeys(raw []string) ([]string, []string) {
+	var keys, repeated []string
+
+	seen := map[string]bool{}
+	for _, k := range raw {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if seen[k] {
+			repeated = append(repeated, k)
+			continue
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys, repeated
+}
+
+// AddLicenseKeys adds the keys to the pool, then gives them to the paid orders still waiting for keys of the
+// product. The store is warned again once the pool drops back to its low threshold. The orders given keys
+// are returned so the buyers can be sent their keys.
+func AddLicenseKeys(db *gorm.DB, pool *models.LicenseKeyPool, raw []string) (*models.LicenseKeyUploadResult, error) {
+	lu := data.NewLicenseKeyRepository()
+
+	keys, repeated := ParseLicenseKeys(raw)
+
+	res := &models.LicenseKeyUploadResult{
+		Duplicates: repeated,
+	}
+
+	for _, key := range keys {
+		added, err := lu.AddKey(db, &models.LicenseKey{
+			ID:        utils.NewUUID(),
+			StoreID:   pool.StoreID,
+			ProductID: pool.ProductID,
+			Key:       key,
+			Status:    models.LicenseKeyAvailable,
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if added {
+			res.Added++
+		} else {
+			res.Duplicates = append(res.Duplicates, key)
+		}
+	}
+
+	if res.Duplicates == nil {
+		res.Duplicates = []string{}
+	}
+
+	fulfilled, orderIDs, err := fulfillLicenseKeys(db, nil, &pool.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	res.Fulfilled = fulfilled
+	res.FulfilledOrderIDs = orderIDs
+
+	if pool.WarnedAt != nil {
+		details, err := lu.GetPoolDetails(db, pool.StoreID, pool.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if details.Available > details.LowThreshold {
+			if err := lu.SetPoolWarnedAt(db, pool.ProductID, nil); err != nil {
+				return nil, err
+			}
+			pool.WarnedAt = nil
+		}
+	}
+	return res, nil
+}
+
+// SyncOrderLicenseKeys gives every unit of the order a license key once paid and revokes them once the payment
+// is reverted. Units of pools running out of keys get theirs when the store adds more.
+func SyncOrderLicenseKeys(db *gorm.DB, orderID string) error {
+	ou := data.NewOrderRepository()
+	o, err := ou.Get(db, orderID)
+	if err != nil {
+		return err
+	}
+
+	if !o.IsAllDigitalProducts {
+		return nil
+	}
+
+	switch o.PaymentStatus {
+	case models.PaymentCompleted:
+		_, _, err := fulfillLicenseKeys(db, &o.ID, nil)
+		return err
+	case models.PaymentReverted:
+		lu := data.NewLicenseKeyRepository()
+		return lu.RevokeKeys(db, o.ID, time.Now().UTC())
+	}
+	return nil
+}
+
+// fulfillLicenseKeys allocates keys to the items waiting for them, of the order or of the product,
+// and returns the number of keys allocated with the orders that got any
+func fulfillLicenseKeys(db *gorm.DB, orderID, productID *string) (int, []string, error) {
+	lu := data.NewLicenseKeyRepository()
+
+	items, err := lu.ListPendingItems(db, orderID, productID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	allocated := 0
+	var orderIDs []string
+	seen := map[string]bool{}
+	for i := range items {
+		n, err := lu.AllocateKeys(db, &items[i], time.Now().UTC())
+		if err != nil {
+			return 0, nil, err
+		}
+		allocated += n
+
+		if n > 0 && !seen[items[i].OrderID] {
+			seen[items[i].OrderID] = true
+			orderIDs = append(orderIDs, items[i].OrderID)
+		}
+	}
+	return allocated, orderIDs, nil
+}
+
+// GetLicenseKeyPoolDetails returns the pool of the product with the number of units still waiting for a key
+func GetLicenseKeyPoolDetails(db *gorm.DB, storeID, productID string) (*models.LicenseKeyPoolDetails, error) {
+	lu := data.NewLicenseKeyRepository()
+	p, err := lu.GetPoolDetails(db, storeID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Pending, err = countPendingLicenseKeys(db, productID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func countPendingLicenseKeys(db *gorm.DB, productID string) (int, error) {
+	lu := data.NewLicenseKeyRepository()
+	items, err := lu.ListPendingItems(db, nil, &productID)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, it := range items {
+		pending += it.Missing
+	}
+	return pending, nil
+}
+
+// CheckLicenseKeyPools warns the stores about the pools at or below their low threshold, once until refilled
+func CheckLicenseKeyPools() error {
+	db := app.DB()
+
+	lu := data.NewLicenseKeyRepository()
+	pools, err := lu.ListLowPools(db)
+	if err != nil {
+		return err
+	}
+
+	for i := range pools {
+		p := &pools[i]
+
+		if p.Pending, err = countPendingLicenseKeys(db, p.ProductID); err != nil {
+			return err
+		}
+
+		if err := sendLowLicenseKeyPoolEmail(db, p); err != nil {
+			log.Log().Errorln("Failed to send low license key pool email for product ", p.ProductID, " : ", err)
+			continue
+		}
+
+		now := time.Now().UTC()
+		if err := lu.SetPoolWarnedAt(db, p.ProductID, &now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendLowLicenseKeyPoolEmail(db *gorm.DB, p *models.LicenseKeyPoolDetails) error {
+	su := data.NewStoreRepository()
+	creator, err := su.GetStoreCreator(db, p.StoreID)
+	if err != nil {
+		return err
+	}
+
+	details := []NotificationDetail{
+		{Label: "Product", Value: p.ProductName},
+		{Label: "Available keys", Value: fmt.Sprintf("%d", p.Available)},
+		{Label: "Low threshold", Value: fmt.Sprintf("%d", p.LowThreshold)},
+	}
+	if p.Pending > 0 {
+		details = append(details, NotificationDetail{Label: "Units waiting for a key", Value: fmt.Sprintf("%d", p.Pending)})
+	}
+
+	return SendNotificationEmail(creator.StaffEmail, fmt.Sprintf("License keys of %s are running low", p.ProductName), &Notification{
+		Title:     "License Keys Running Low",
+		Greetings: fmt.Sprintf("Hi %s,", creator.StaffName),
+		Intros:    fmt.Sprintf("%s is running out of license keys, upload more so the buyers get theirs right away.", creator.StoreName),
+		Details:   details,
+	})
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLicenseKeys(t *testing.T) {
+	keys, repeated := ParseLicenseKeys([]string{" AAAA-1111 ", "", "BBBB-2222", "AAAA-1111", "\t", "CCCC-3333\r", "BBBB-2222"})
+
+	if want := []string{"AAAA-1111", "BBBB-2222", "CCCC-3333"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected keys %v, got %v", want, keys)
+	}
+	if want := []string{"AAAA-1111", "BBBB-2222"}; !reflect.DeepEqual(repeated, want) {
+		t.Errorf("expected repeated keys %v, got %v", want, repeated)
+	}
+
+	keys, repeated = ParseLicenseKeys([]string{" ", ""})
+	if len(keys) != 0 || len(repeated) != 0 {
+		t.Errorf("expected no keys, got %v and %v", keys, repeated)
+	}
+}
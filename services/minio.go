@@ -40,3 +40,22 @@ func ServeAsStreamFromMinio(fileName string) (*minio.Object, error) {
 	}
 	return o, nil
 }
+
+// StatMinioObject fails when the file doesn't exist
+func StatMinioObject(fileName string) error {
+	conn := app.Minio()
+	cfg := config.Minio()
+	if _, err := conn.StatObject(cfg.Bucket, fileName, minio.StatObjectOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func RemoveFromMinio(fileName string) error {
+	conn := app.Minio()
+	cfg := config.Minio()
+	if err := conn.RemoveObject(cfg.Bucket, fileName); err != nil {
+		return err
+	}
+	return nil
+}
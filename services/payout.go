@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"strings"
+	"time"
+)
+
+// CheckPayoutTransition returns an error when the payout entry can't move from one status to the other
+func CheckPayoutTransition(from, to models.PayoutSendStatus) error {
+	if !from.CanTransitionTo(to) {
+		return errors.NewError(fmt.Sprintf("payout can't move from %s to %s", from, to))
+	}
+	return nil
+}
+
+// RecordPayoutCreated adds the creation event to the history of a new payout entry
+func RecordPayoutCreated(db *gorm.DB, p *models.PayoutSend, actorUserID *string, note string) (*models.PayoutEvent, error) {
+	return createPayoutEvent(db, p, "", actorUserID, note)
+}
+
+// UpdatePayoutStatus moves the payout entry to the given status, posts it to the ledger and records the
+// change in the history of the entry, within the given transaction. The failure reason is kept for
+// failed payouts. A nil actor stands for the system.
+func UpdatePayoutStatus(db *gorm.DB, p *models.PayoutSend, status models.PayoutSendStatus, failureReason string,
+	actorUserID *string, note string) (*models.PayoutEvent, error) {
+	if err := CheckPayoutTransition(p.Status, status); err != nil {
+		return nil, err
+	}
+
+	old := p.Status
+
+	p.Status = status
+	if status == models.PayoutSendStatusFailed {
+		p.FailureReason = failureReason
+	}
+	p.UpdatedAt = time.Now().UTC()
+
+	mu := data.NewMarketplaceRepository()
+	if err := mu.UpdatePayoutEntry(db, p); err != nil {
+		return nil, err
+	}
+	if err := PostPayoutLedgerEntries(db, p); err != nil {
+		return nil, err
+	}
+
+	if note == "" && status == models.PayoutSendStatusFailed {
+		note = failureReason
+	}
+	return createPayoutEvent(db, p, old, actorUserID, note)
+}
+
+func createPayoutEvent(db *gorm.DB, p *models.PayoutSend, old models.PayoutSendStatus, actorUserID *string, note string) (*models.PayoutEvent, error) {
+	e := &models.PayoutEvent{
+		ID:          utils.NewUUID(),
+		PayoutID:    p.ID,
+		StoreID:     p.StoreID,
+		ActorUserID: actorUserID,
+		OldStatus:   old,
+		NewStatus:   p.Status,
+		Note:        note,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	mu := data.NewMarketplaceRepository()
+	if err := mu.CreatePayoutEvent(db, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// SendPayoutStatusEmail tells the store owner about the status change of the payout
+func SendPayoutStatusEmail(db *gorm.DB, eventID string) error {
+	mu := data.NewMarketplaceRepository()
+	e, err := mu.GetPayoutEvent(db, eventID)
+	if err != nil {
+		return err
+	}
+	p, err := mu.GetPayoutEntry(db, e.StoreID, e.PayoutID)
+	if err != nil {
+		return err
+	}
+
+	su := data.NewStoreRepository()
+	creator, err := su.GetStoreCreator(db, e.StoreID)
+	if err != nil {
+		return err
+	}
+
+	status := payoutStatusName(e.NewStatus)
+	details := []NotificationDetail{
+		{Label: "Payout ID", Value: p.ID},
+		{Label: "Amount", Value: formatAmount(p.Amount)},
+		{Label: "Previous status", Value: payoutStatusName(e.OldStatus)},
+		{Label: "Status", Value: status},
+		{Label: "Date", Value: e.CreatedAt.Format(utils.DateTimeFormatForDistribution)},
+	}
+	if e.Note != "" {
+		details = append(details, NotificationDetail{Label: "Note", Value: e.Note})
+	}
+
+	return SendNotificationEmail(creator.StaffEmail, fmt.Sprintf("Your payout is %s", strings.ToLower(status)), &Notification{
+		Title:     "Payout Updated",
+		Greetings: fmt.Sprintf("Hi %s,", creator.StaffName),
+		Intros:    fmt.Sprintf("The status of a payout of %s has changed.", creator.StoreName),
+		Details:   details,
+	})
+}
+
+func payoutStatusName(s models.PayoutSendStatus) string {
+	name := strings.TrimPrefix(string(s), "payout_")
+	if name == "" {
+		return "-"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
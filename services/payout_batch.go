@@ -0,0 +1,494 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/models"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// PayoutRecipient is the bank account a payout entry is sent to, as found in the payout method details
+type PayoutRecipient struct {
+	AccountName   string `json:"account_name"`
+	AccountNumber string `json:"account_number"`
+	BankName      string `json:"bank_name"`
+	BankCode      string `json:"bank_code"`
+}
+
+// recipientFieldAliases lists the keys accepted for each recipient field in the payout method details
+var recipientFieldAliases = map[string][]string{
+	"account_name":   {"account_name", "account_holder", "beneficiary_name", "name"},
+	"account_number": {"account_number", "iban", "account_no"},
+	"bank_name":      {"bank_name", "bank"},
+	"bank_code":      {"bank_code", "bic", "swift_code", "swift", "routing_number"},
+}
+
+// ParsePayoutRecipient reads the recipient bank account from the payout method details,
+// which is expected to be a JSON object
+func ParsePayoutRecipient(details string) (*PayoutRecipient, error) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(details), &fields); err != nil {
+		return nil, fmt.Errorf("payout method details is not a JSON object")
+	}
+
+	lookup := func(field string) string {
+		for _, k := range recipientFieldAliases[field] {
+			for fk, fv := range fields {
+				if fv == nil || !strings.EqualFold(fk, k) {
+					continue
+				}
+				if v := strings.TrimSpace(fmt.Sprint(fv)); v != "" {
+					return v
+				}
+			}
+		}
+		return ""
+	}
+
+	r := PayoutRecipient{
+		AccountName:   lookup("account_name"),
+		AccountNumber: strings.ReplaceAll(lookup("account_number"), " ", ""),
+		BankName:      lookup("bank_name"),
+		BankCode:      strings.ReplaceAll(lookup("bank_code"), " ", ""),
+	}
+
+	if r.AccountName == "" {
+		return nil, fmt.Errorf("account_name is missing in payout method details")
+	}
+	if r.AccountNumber == "" {
+		return nil, fmt.Errorf("account_number is missing in payout method details")
+	}
+
+	// Account numbers starting like an IBAN must be one, a typo would send the payout elsewhere
+	if ibanPrefixRegexp.MatchString(strings.ToUpper(r.AccountNumber)) {
+		if !IsValidIBAN(r.AccountNumber) {
+			return nil, fmt.Errorf("account_number %s is not a valid IBAN", r.AccountNumber)
+		}
+		r.AccountNumber = strings.ToUpper(r.AccountNumber)
+	}
+	return &r, nil
+}
+
+var (
+	ibanPrefixRegexp = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}`)
+	ibanRegexp       = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	bicRegexp        = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+)
+
+// IsValidIBAN checks the format and the mod 97 check digits of the IBAN
+func IsValidIBAN(iban string) bool {
+	iban = strings.ToUpper(iban)
+	if !ibanRegexp.MatchString(iban) {
+		return false
+	}
+
+	digits := strings.Builder{}
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(fmt.Sprintf("%d", c-'A'+10))
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// IsValidBIC checks the format of the BIC, 8 or 11 characters
+func IsValidBIC(bic string) bool {
+	return bicRegexp.MatchString(strings.ToUpper(bic))
+}
+
+// PayoutEndToEndID is the reference of the payout entry in batch files and bank reports.
+// ISO 20022 limits identifiers to 35 characters, so the hyphens of the entry ID are dropped.
+func PayoutEndToEndID(entryID string) string {
+	return strings.ReplaceAll(entryID, "-", "")
+}
+
+// formatDecimalAmount formats an amount in cents as a plain decimal number, e.g. 1234 as 12.34
+func formatDecimalAmount(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+}
+
+// GeneratePayoutBatchCSV writes the payout entries as a generic CSV file with one row per entry
+func GeneratePayoutBatchCSV(b *models.PayoutBatch, entries []models.PayoutSend, recipients map[string]*PayoutRecipient) ([]byte, error) {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write([]string{"entry_id", "reference", "store_id", "amount", "currency",
+		"account_name", "account_number", "bank_name", "bank_code"}); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		r := recipients[e.ID]
+		if err := w.Write([]string{e.ID, PayoutEndToEndID(e.ID), e.StoreID, formatDecimalAmount(e.Amount), b.Currency,
+			r.AccountName, r.AccountNumber, r.BankName, r.BankCode}); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type pain001Document struct {
+	XMLName xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.001.001.03 Document"`
+	Init    struct {
+		GrpHdr struct {
+			MsgId    string `xml:"MsgId"`
+			CreDtTm  string `xml:"CreDtTm"`
+			NbOfTxs  int    `xml:"NbOfTxs"`
+			CtrlSum  string `xml:"CtrlSum"`
+			InitgPty struct {
+				Nm string `xml:"Nm"`
+			} `xml:"InitgPty"`
+		} `xml:"GrpHdr"`
+		PmtInf struct {
+			PmtInfId    string `xml:"PmtInfId"`
+			PmtMtd      string `xml:"PmtMtd"`
+			NbOfTxs     int    `xml:"NbOfTxs"`
+			CtrlSum     string `xml:"CtrlSum"`
+			ReqdExctnDt string `xml:"ReqdExctnDt"`
+			Dbtr        struct {
+				Nm string `xml:"Nm"`
+			} `xml:"Dbtr"`
+			DbtrAcct struct {
+				Id struct {
+					IBAN string `xml:"IBAN"`
+				} `xml:"Id"`
+			} `xml:"DbtrAcct"`
+			DbtrAgt     pain001Agent         `xml:"DbtrAgt"`
+			CdtTrfTxInf []pain001Transaction `xml:"CdtTrfTxInf"`
+		} `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001Agent struct {
+	FinInstnId struct {
+		BIC         string `xml:"BIC,omitempty"`
+		ClrSysMmbId *struct {
+			MmbId string `xml:"MmbId"`
+		} `xml:"ClrSysMmbId,omitempty"`
+		Othr *struct {
+			Id string `xml:"Id"`
+		} `xml:"Othr,omitempty"`
+	} `xml:"FinInstnId"`
+}
+
+type pain001Account struct {
+	Id struct {
+		IBAN string `xml:"IBAN,omitempty"`
+		Othr *struct {
+			Id string `xml:"Id"`
+		} `xml:"Othr,omitempty"`
+	} `xml:"Id"`
+}
+
+// newPain001Agent identifies the bank by its BIC, other bank codes such as routing numbers or sort codes
+// are given as the clearing system member
+func newPain001Agent(bankCode string) *pain001Agent {
+	a := &pain001Agent{}
+	if IsValidBIC(bankCode) {
+		a.FinInstnId.BIC = strings.ToUpper(bankCode)
+	} else {
+		a.FinInstnId.ClrSysMmbId = &struct {
+			MmbId string `xml:"MmbId"`
+		}{MmbId: bankCode}
+	}
+	return a
+}
+
+// newPain001Account identifies the account by its IBAN, other account numbers are given as a generic identifier
+func newPain001Account(accountNumber string) pain001Account {
+	a := pain001Account{}
+	if IsValidIBAN(accountNumber) {
+		a.Id.IBAN = strings.ToUpper(accountNumber)
+	} else {
+		a.Id.Othr = &struct {
+			Id string `xml:"Id"`
+		}{Id: accountNumber}
+	}
+	return a
+}
+
+type pain001Transaction struct {
+	PmtId struct {
+		EndToEndId string `xml:"EndToEndId"`
+	} `xml:"PmtId"`
+	Amt struct {
+		InstdAmt struct {
+			Ccy   string `xml:"Ccy,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"InstdAmt"`
+	} `xml:"Amt"`
+	CdtrAgt *pain001Agent `xml:"CdtrAgt,omitempty"`
+	Cdtr    struct {
+		Nm string `xml:"Nm"`
+	} `xml:"Cdtr"`
+	CdtrAcct pain001Account `xml:"CdtrAcct"`
+	RmtInf   struct {
+		Ustrd string `xml:"Ustrd"`
+	} `xml:"RmtInf"`
+}
+
+// GeneratePayoutBatchPain001 writes the payout entries as an ISO 20022 pain.001.001.03 credit transfer
+// initiation, paid from the debtor account in the payout config
+func GeneratePayoutBatchPain001(b *models.PayoutBatch, entries []models.PayoutSend, recipients map[string]*PayoutRecipient) ([]byte, error) {
+	cfg := config.Payout()
+
+	doc := pain001Document{}
+	doc.Init.GrpHdr.MsgId = PayoutEndToEndID(b.ID)
+	doc.Init.GrpHdr.CreDtTm = b.CreatedAt.UTC().Format("2006-01-02T15:04:05")
+	doc.Init.GrpHdr.NbOfTxs = len(entries)
+	doc.Init.GrpHdr.CtrlSum = formatDecimalAmount(b.TotalAmount)
+	doc.Init.GrpHdr.InitgPty.Nm = cfg.DebtorName
+
+	pi := &doc.Init.PmtInf
+	pi.PmtInfId = PayoutEndToEndID(b.ID)
+	pi.PmtMtd = "TRF"
+	pi.NbOfTxs = len(entries)
+	pi.CtrlSum = formatDecimalAmount(b.TotalAmount)
+	pi.ReqdExctnDt = b.CreatedAt.UTC().Format("2006-01-02")
+	pi.Dbtr.Nm = cfg.DebtorName
+	pi.DbtrAcct.Id.IBAN = cfg.DebtorIBAN
+	// The debtor agent is mandatory, banks taking the debtor account alone are told its BIC isn't provided
+	if cfg.DebtorBIC != "" {
+		pi.DbtrAgt.FinInstnId.BIC = cfg.DebtorBIC
+	} else {
+		pi.DbtrAgt.FinInstnId.Othr = &struct {
+			Id string `xml:"Id"`
+		}{Id: "NOTPROVIDED"}
+	}
+
+	for _, e := range entries {
+		r := recipients[e.ID]
+
+		tx := pain001Transaction{}
+		tx.PmtId.EndToEndId = PayoutEndToEndID(e.ID)
+		tx.Amt.InstdAmt.Ccy = b.Currency
+		tx.Amt.InstdAmt.Value = formatDecimalAmount(e.Amount)
+		if r.BankCode != "" {
+			tx.CdtrAgt = newPain001Agent(r.BankCode)
+		}
+		tx.Cdtr.Nm = r.AccountName
+		tx.CdtrAcct = newPain001Account(r.AccountNumber)
+		tx.RmtInf.Ustrd = fmt.Sprintf("Payout %s", e.ID)
+
+		pi.CdtTrfTxInf = append(pi.CdtTrfTxInf, tx)
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// GeneratePayoutBatchFile writes the payout entries in the format of the batch and returns
+// the file content, its extension and its content type
+func GeneratePayoutBatchFile(b *models.PayoutBatch, entries []models.PayoutSend, recipients map[string]*PayoutRecipient) ([]byte, string, string, error) {
+	switch b.Format {
+	case models.PayoutBatchFormatCSV:
+		body, err := GeneratePayoutBatchCSV(b, entries, recipients)
+		return body, "csv", "text/csv", err
+	case models.PayoutBatchFormatPain001:
+		body, err := GeneratePayoutBatchPain001(b, entries, recipients)
+		return body, "xml", "application/xml", err
+	}
+	return nil, "", "", fmt.Errorf("unsupported payout batch format %s", b.Format)
+}
+
+// BankReportLine is the outcome of a single payout reported by the bank. Lines whose reference is the
+// batch itself apply to every payout of the batch.
+type BankReportLine struct {
+	Reference string                  `json:"reference"`
+	Status    models.PayoutSendStatus `json:"status"`
+	Reason    string                  `json:"reason"`
+}
+
+type pain002StatusReason struct {
+	Rsn struct {
+		Cd string `xml:"Cd"`
+	} `xml:"Rsn"`
+	AddtlInf []string `xml:"AddtlInf"`
+}
+
+type pain002Document struct {
+	XMLName xml.Name `xml:"Document"`
+	Report  struct {
+		OrgnlGrpInfAndSts struct {
+			OrgnlMsgId string                `xml:"OrgnlMsgId"`
+			GrpSts     string                `xml:"GrpSts"`
+			StsRsnInf  []pain002StatusReason `xml:"StsRsnInf"`
+		} `xml:"OrgnlGrpInfAndSts"`
+		OrgnlPmtInfAndSts []struct {
+			OrgnlPmtInfId string                `xml:"OrgnlPmtInfId"`
+			PmtInfSts     string                `xml:"PmtInfSts"`
+			StsRsnInf     []pain002StatusReason `xml:"StsRsnInf"`
+			TxInfAndSts   []struct {
+				OrgnlEndToEndId string                `xml:"OrgnlEndToEndId"`
+				TxSts           string                `xml:"TxSts"`
+				StsRsnInf       []pain002StatusReason `xml:"StsRsnInf"`
+			} `xml:"TxInfAndSts"`
+		} `xml:"OrgnlPmtInfAndSts"`
+	} `xml:"CstmrPmtStsRpt"`
+}
+
+// ParseBankReport reads the outcome of the payouts from a bank report. Both an ISO 20022 pain.002
+// payment status report and a CSV file with the entry_id, status and reason columns are accepted.
+// Settled transactions are completed, rejected ones are failed and the ones still in progress are skipped.
+// A rejected group or payment information fails all of its payouts.
+func ParseBankReport(content []byte) ([]BankReportLine, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return parsePain002Report(trimmed)
+	}
+	return parseCSVBankReport(trimmed)
+}
+
+func parsePain002Report(content []byte) ([]BankReportLine, error) {
+	doc := pain002Document{}
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid pain.002 report: %v", err)
+	}
+
+	grp := doc.Report.OrgnlGrpInfAndSts
+	if isPain002Rejected(grp.GrpSts) {
+		return []BankReportLine{newPain002RejectedLine(grp.OrgnlMsgId, grp.StsRsnInf)}, nil
+	}
+
+	var lines []BankReportLine
+	for _, pi := range doc.Report.OrgnlPmtInfAndSts {
+		if isPain002Rejected(pi.PmtInfSts) {
+			lines = append(lines, newPain002RejectedLine(pi.OrgnlPmtInfId, pi.StsRsnInf))
+			continue
+		}
+
+		for _, tx := range pi.TxInfAndSts {
+			// Accepted transactions may still be rejected later, only settled ones are completed
+			switch strings.ToUpper(strings.TrimSpace(tx.TxSts)) {
+			case "ACSC", "ACCC":
+				lines = append(lines, BankReportLine{
+					Reference: strings.TrimSpace(tx.OrgnlEndToEndId),
+					Status:    models.PayoutSendStatusCompleted,
+				})
+			case "RJCT":
+				lines = append(lines, newPain002RejectedLine(tx.OrgnlEndToEndId, tx.StsRsnInf))
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("pain.002 report has no settled or rejected transactions")
+	}
+	return lines, nil
+}
+
+func isPain002Rejected(status string) bool {
+	return strings.ToUpper(strings.TrimSpace(status)) == "RJCT"
+}
+
+func newPain002RejectedLine(reference string, reasons []pain002StatusReason) BankReportLine {
+	var details []string
+	for _, r := range reasons {
+		if r.Rsn.Cd != "" {
+			details = append(details, r.Rsn.Cd)
+		}
+		details = append(details, r.AddtlInf...)
+	}
+
+	l := BankReportLine{
+		Reference: strings.TrimSpace(reference),
+		Status:    models.PayoutSendStatusFailed,
+		Reason:    strings.Join(details, ": "),
+	}
+	if l.Reason == "" {
+		l.Reason = "Rejected by bank"
+	}
+	return l
+}
+
+func parseCSVBankReport(content []byte) ([]BankReportLine, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv report: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	refCol, ok := columns["entry_id"]
+	if !ok {
+		if refCol, ok = columns["reference"]; !ok {
+			return nil, fmt.Errorf("csv report must have an entry_id column")
+		}
+	}
+	statusCol, ok := columns["status"]
+	if !ok {
+		return nil, fmt.Errorf("csv report must have a status column")
+	}
+	reasonCol, hasReason := columns["reason"]
+
+	var lines []BankReportLine
+	for row := 2; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv report: %v", err)
+		}
+		if len(record) <= refCol || len(record) <= statusCol {
+			return nil, fmt.Errorf("row %d has missing columns", row)
+		}
+
+		l := BankReportLine{
+			Reference: strings.TrimSpace(record[refCol]),
+		}
+		if hasReason && len(record) > reasonCol {
+			l.Reason = strings.TrimSpace(record[reasonCol])
+		}
+
+		switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(record[statusCol])), "payout_") {
+		case "completed", "success", "paid":
+			l.Status = models.PayoutSendStatusCompleted
+		case "failed", "rejected":
+			l.Status = models.PayoutSendStatusFailed
+			if l.Reason == "" {
+				l.Reason = "Rejected by bank"
+			}
+		default:
+			return nil, fmt.Errorf("row %d has unknown status %s", row, record[statusCol])
+		}
+
+		lines = append(lines, l)
+	}
+
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("csv report has no rows")
+	}
+	return lines, nil
+}
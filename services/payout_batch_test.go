@@ -0,0 +1,248 @@
+package services
+
+import (
+	"encoding/xml"
+	"github.com/shopicano/shopicano-backend/models"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePayoutRecipient(t *testing.T) {
+	r, err := ParsePayoutRecipient(`{"Beneficiary_Name": "Jane Doe", "iban": "DE89 3704 0044 0532 0130 00", "swift_code": "COBADEFFXXX"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.AccountName != "Jane Doe" || r.AccountNumber != "DE89370400440532013000" || r.BankCode != "COBADEFFXXX" {
+		t.Errorf("unexpected recipient %+v", r)
+	}
+
+	if _, err := ParsePayoutRecipient(`{"account_name": "Jane Doe"}`); err == nil {
+		t.Error("expected an error for missing account number")
+	}
+	if _, err := ParsePayoutRecipient(`Bank: XYZ`); err == nil {
+		t.Error("expected an error for details that are not JSON")
+	}
+	if _, err := ParsePayoutRecipient(`{"account_name": "Jane Doe", "iban": "DE89 3704 0044 0532 0130 01"}`); err == nil {
+		t.Error("expected an error for an IBAN with wrong check digits")
+	}
+
+	r, err = ParsePayoutRecipient(`{"account_name": "John Doe", "account_number": "000123456789", "routing_number": "021000021"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.AccountNumber != "000123456789" || r.BankCode != "021000021" {
+		t.Errorf("unexpected recipient %+v", r)
+	}
+}
+
+func TestIsValidIBANAndBIC(t *testing.T) {
+	ibans := []struct {
+		iban  string
+		valid bool
+	}{
+		{"DE89370400440532013000", true},
+		{"GB29NWBK60161331926819", true},
+		{"gb29nwbk60161331926819", true},
+		{"DE89370400440532013001", false},
+		{"DE8937040044", false},
+		{"000123456789", false},
+		{"", false},
+	}
+	for _, c := range ibans {
+		if IsValidIBAN(c.iban) != c.valid {
+			t.Errorf("IBAN %q: expected valid to be %v", c.iban, c.valid)
+		}
+	}
+
+	bics := []struct {
+		bic   string
+		valid bool
+	}{
+		{"COBADEFFXXX", true},
+		{"NWBKGB2L", true},
+		{"COBADEFF1", false},
+		{"021000021", false},
+		{"", false},
+	}
+	for _, c := range bics {
+		if IsValidBIC(c.bic) != c.valid {
+			t.Errorf("BIC %q: expected valid to be %v", c.bic, c.valid)
+		}
+	}
+}
+
+func TestGeneratePayoutBatchFile(t *testing.T) {
+	b := &models.PayoutBatch{
+		ID:          "0b5a3c36-7a0c-4c8e-a1c5-8b0f6e6a1d11",
+		Currency:    "EUR",
+		TotalAmount: 16050,
+		CreatedAt:   time.Date(2020, 10, 12, 4, 0, 0, 0, time.UTC),
+	}
+	entries := []models.PayoutSend{
+		{ID: "e1a7c0a2-1111-4c8e-a1c5-8b0f6e6a1d11", StoreID: "s1", Amount: 10000},
+		{ID: "e1a7c0a2-2222-4c8e-a1c5-8b0f6e6a1d11", StoreID: "s2", Amount: 5050},
+		{ID: "e1a7c0a2-3333-4c8e-a1c5-8b0f6e6a1d11", StoreID: "s3", Amount: 1000},
+	}
+	recipients := map[string]*PayoutRecipient{
+		entries[0].ID: {AccountName: "Jane Doe", AccountNumber: "DE89370400440532013000", BankCode: "COBADEFFXXX"},
+		entries[1].ID: {AccountName: "John Doe", AccountNumber: "GB29NWBK60161331926819"},
+		entries[2].ID: {AccountName: "Jim Doe", AccountNumber: "000123456789", BankCode: "021000021"},
+	}
+
+	b.Format = models.PayoutBatchFormatCSV
+	body, ext, _, err := GeneratePayoutBatchFile(b, entries, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if ext != "csv" || len(lines) != 4 {
+		t.Fatalf("unexpected csv file %s", body)
+	}
+	if lines[2] != "e1a7c0a2-2222-4c8e-a1c5-8b0f6e6a1d11,e1a7c0a222224c8ea1c58b0f6e6a1d11,s2,50.50,EUR,John Doe,GB29NWBK60161331926819,," {
+		t.Errorf("unexpected csv row %s", lines[2])
+	}
+
+	b.Format = models.PayoutBatchFormatPain001
+	body, ext, _, err = GeneratePayoutBatchFile(b, entries, recipients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := pain001Document{}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if ext != "xml" || doc.Init.GrpHdr.NbOfTxs != 3 || doc.Init.GrpHdr.CtrlSum != "160.50" {
+		t.Errorf("unexpected group header %+v", doc.Init.GrpHdr)
+	}
+	// No debtor BIC is configured in the tests
+	if agt := doc.Init.PmtInf.DbtrAgt.FinInstnId; agt.BIC != "" || agt.Othr == nil || agt.Othr.Id != "NOTPROVIDED" {
+		t.Errorf("unexpected debtor agent %+v", agt)
+	}
+	if !strings.Contains(string(body), "<DbtrAgt>") {
+		t.Errorf("expected the debtor agent in %s", body)
+	}
+	txs := doc.Init.PmtInf.CdtTrfTxInf
+	if len(txs) != 3 || txs[0].CdtrAgt == nil || txs[1].CdtrAgt != nil || txs[2].CdtrAgt == nil {
+		t.Fatalf("unexpected transactions %+v", txs)
+	}
+	if txs[0].CdtrAgt.FinInstnId.BIC != "COBADEFFXXX" || txs[0].CdtrAcct.Id.IBAN != "DE89370400440532013000" ||
+		txs[0].CdtrAcct.Id.Othr != nil {
+		t.Errorf("unexpected IBAN transaction %+v", txs[0])
+	}
+	if txs[2].CdtrAgt.FinInstnId.BIC != "" || txs[2].CdtrAgt.FinInstnId.ClrSysMmbId == nil ||
+		txs[2].CdtrAgt.FinInstnId.ClrSysMmbId.MmbId != "021000021" || txs[2].CdtrAcct.Id.IBAN != "" ||
+		txs[2].CdtrAcct.Id.Othr == nil || txs[2].CdtrAcct.Id.Othr.Id != "000123456789" {
+		t.Errorf("unexpected non IBAN transaction %+v", txs[2])
+	}
+	if txs[0].PmtId.EndToEndId != "e1a7c0a211114c8ea1c58b0f6e6a1d11" || txs[0].Amt.InstdAmt.Value != "100.00" ||
+		txs[0].Amt.InstdAmt.Ccy != "EUR" {
+		t.Errorf("unexpected transaction %+v", txs[0])
+	}
+}
+
+func TestParseBankReport(t *testing.T) {
+	csvReport := "entry_id,status,reason\n" +
+		"e1,completed,\n" +
+		"e2,payout_failed,Account closed\n" +
+		"e3,rejected,\n"
+	lines, err := ParseBankReport([]byte(csvReport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BankReportLine{
+		{Reference: "e1", Status: models.PayoutSendStatusCompleted},
+		{Reference: "e2", Status: models.PayoutSendStatusFailed, Reason: "Account closed"},
+		{Reference: "e3", Status: models.PayoutSendStatusFailed, Reason: "Rejected by bank"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, expected[i], lines[i])
+		}
+	}
+
+	if _, err := ParseBankReport([]byte("entry_id,status\ne1,unknown\n")); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+
+	pain002 := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03">
+  <CstmrPmtStsRpt>
+    <OrgnlPmtInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e1</OrgnlEndToEndId>
+        <TxSts>ACSC</TxSts>
+      </TxInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e2</OrgnlEndToEndId>
+        <TxSts>RJCT</TxSts>
+        <StsRsnInf><Rsn><Cd>AC04</Cd></Rsn><AddtlInf>Closed account number</AddtlInf></StsRsnInf>
+      </TxInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e3</OrgnlEndToEndId>
+        <TxSts>PDNG</TxSts>
+      </TxInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e4</OrgnlEndToEndId>
+        <TxSts>ACSP</TxSts>
+      </TxInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e5</OrgnlEndToEndId>
+        <TxSts>ACCC</TxSts>
+      </TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <OrgnlPmtInfId>b2</OrgnlPmtInfId>
+      <PmtInfSts>RJCT</PmtInfSts>
+      <StsRsnInf><Rsn><Cd>AM04</Cd></Rsn></StsRsnInf>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e6</OrgnlEndToEndId>
+        <TxSts>ACSC</TxSts>
+      </TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+  </CstmrPmtStsRpt>
+</Document>`
+	lines, err = ParseBankReport([]byte(pain002))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []BankReportLine{
+		{Reference: "e1", Status: models.PayoutSendStatusCompleted},
+		{Reference: "e2", Status: models.PayoutSendStatusFailed, Reason: "AC04: Closed account number"},
+		{Reference: "e5", Status: models.PayoutSendStatusCompleted},
+		{Reference: "b2", Status: models.PayoutSendStatusFailed, Reason: "AM04"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected lines %+v, got %+v", expected, lines)
+	}
+
+	rejected := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.002.001.03">
+  <CstmrPmtStsRpt>
+    <OrgnlGrpInfAndSts>
+      <OrgnlMsgId>b1</OrgnlMsgId>
+      <GrpSts>RJCT</GrpSts>
+    </OrgnlGrpInfAndSts>
+    <OrgnlPmtInfAndSts>
+      <TxInfAndSts>
+        <OrgnlEndToEndId>e1</OrgnlEndToEndId>
+        <TxSts>ACSC</TxSts>
+      </TxInfAndSts>
+    </OrgnlPmtInfAndSts>
+  </CstmrPmtStsRpt>
+</Document>`
+	lines, err = ParseBankReport([]byte(rejected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []BankReportLine{
+		{Reference: "b1", Status: models.PayoutSendStatusFailed, Reason: "Rejected by bank"},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected lines %+v, got %+v", expected, lines)
+	}
+}
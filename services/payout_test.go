@@ -0,0 +1,43 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"testing"
+)
+
+func TestCheckPayoutTransition(t *testing.T) {
+	cases := []struct {
+		from, to models.PayoutSendStatus
+		allowed  bool
+	}{
+		{models.PayoutSendStatusPending, models.PayoutSendStatusConfirmed, true},
+		{models.PayoutSendStatusPending, models.PayoutSendStatusFailed, true},
+		{models.PayoutSendStatusPending, models.PayoutSendStatusCompleted, false},
+		{models.PayoutSendStatusConfirmed, models.PayoutSendStatusProcessing, true},
+		{models.PayoutSendStatusConfirmed, models.PayoutSendStatusPending, false},
+		{models.PayoutSendStatusProcessing, models.PayoutSendStatusCompleted, true},
+		{models.PayoutSendStatusProcessing, models.PayoutSendStatusConfirmed, false},
+		{models.PayoutSendStatusCompleted, models.PayoutSendStatusPending, false},
+		{models.PayoutSendStatusCompleted, models.PayoutSendStatusFailed, false},
+		{models.PayoutSendStatusFailed, models.PayoutSendStatusConfirmed, false},
+	}
+
+	for _, c := range cases {
+		err := CheckPayoutTransition(c.from, c.to)
+		if c.allowed && err != nil {
+			t.Errorf("%s -> %s: expected to be allowed, got %v", c.from, c.to, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("%s -> %s: expected to be rejected", c.from, c.to)
+		}
+	}
+}
+
+func TestPayoutStatusName(t *testing.T) {
+	if got := payoutStatusName(models.PayoutSendStatusProcessing); got != "Processing" {
+		t.Errorf("expected Processing, got %s", got)
+	}
+	if got := payoutStatusName(""); got != "-" {
+		t.Errorf("expected -, got %s", got)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+)
+
+// CheckProductAlerts records an alert for every wishlisted or subscribed product that dropped in price
+// or came back in stock since last seen, then emails the alerts not sent yet, one email per user
+func CheckProductAlerts() error {
+	if err := recordProductAlerts(app.DB()); err != nil {
+		return err
+	}
+	return SendProductAlerts(app.DB())
+}
+
+func recordProductAlerts(db *gorm.DB) error {
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	au := data.NewProductAlertRepository()
+
+	watched, err := au.ListWatchedChanges(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range DetectProductAlerts(watched, time.Now().UTC()) {
+		a := a
+		if err := au.CreateAlert(tx, &a); err != nil {
+			return err
+		}
+	}
+
+	// Unavailable products are seen once available again, so the changes made meanwhile still alert
+	seen := map[string]bool{}
+	for _, w := range watched {
+		if seen[w.ProductID] || !w.IsAvailable {
+			continue
+		}
+		seen[w.ProductID] = true
+
+		if err := au.MarkSeen(tx, w.ProductID, w.Price, w.Stock); err != nil {
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// DetectProductAlerts turns the changes of the watched products into alerts, once per user, product and kind.
+// Unavailable products don't alert, nor do digital products about their stock.
+func DetectProductAlerts(watched []models.WatchedProduct, now time.Time) []models.ProductAlert {
+	var alerts []models.ProductAlert
+
+	detected := map[string]bool{}
+	add := func(w models.WatchedProduct, kind models.ProductAlertKind) {
+		key := w.UserID + "/" + w.ProductID + "/" + string(kind)
+		if detected[key] {
+			return
+		}
+		detected[key] = true
+
+		alerts = append(alerts, models.ProductAlert{
+			ID:        utils.NewUUID(),
+			UserID:    w.UserID,
+			ProductID: w.ProductID,
+			Kind:      kind,
+			OldPrice:  w.SeenPrice,
+			NewPrice:  w.Price,
+			CreatedAt: now,
+		})
+	}
+
+	for _, w := range watched {
+		if !w.IsAvailable {
+			continue
+		}
+		if w.Price < w.SeenPrice {
+			add(w, models.AlertPriceDrop)
+		}
+		if !w.IsDigital && w.SeenStock <= 0 && w.Stock > 0 {
+			add(w, models.AlertBackInStock)
+		}
+	}
+	return alerts
+}
+
+// SendProductAlerts emails the unsent alerts, a user failing to be emailed doesn't hold back the others
+func SendProductAlerts(db *gorm.DB) error {
+	au := data.NewProductAlertRepository()
+
+	alerts, err := au.ListUnsentAlerts(db)
+	if err != nil {
+		return err
+	}
+
+	for from := 0; from < len(alerts); {
+		to := from
+		for to < len(alerts) && alerts[to].UserID == alerts[from].UserID {
+			to++
+		}
+
+		userAlerts := alerts[from:to]
+		from = to
+
+		if err := sendProductAlertsEmail(userAlerts); err != nil {
+			log.Log().Errorln("Failed to send product alerts email to user ", userAlerts[0].UserID, " : ", err)
+			continue
+		}
+
+		var ids []string
+		for _, a := range userAlerts {
+			ids = append(ids, a.ID)
+		}
+		if err := au.MarkAlertsSent(db, ids, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sendProductAlertsEmail(alerts []models.ProductAlertDetails) error {
+	var details []NotificationDetail
+	for _, a := range alerts {
+		value := "Back in stock"
+		if a.Kind == models.AlertPriceDrop {
+			value = fmt.Sprintf("Price dropped from %s to %s", formatAmount(a.OldPrice), formatAmount(a.NewPrice))
+		}
+		details = append(details, NotificationDetail{Label: a.ProductName, Value: value})
+	}
+
+	n := &Notification{
+		Title:     "Good News From Your Wishlist",
+		Greetings: fmt.Sprintf("Hi %s,", alerts[0].UserName),
+		Intros:    "Some of the products you are watching dropped in price or are back in stock.",
+		Details:   details,
+	}
+	// A single product is linked, several are listed
+	if path := config.PathMappingCfg()["product_details"]; path != "" && len(alerts) == 1 {
+		n.ActionURL = config.App().FrontStoreUrl + fmt.Sprintf(path, alerts[0].ProductSlug)
+		n.ActionText = "View Product"
+	}
+
+	return SendNotificationEmail(alerts[0].UserEmail, "Products you are watching have changed", n)
+}
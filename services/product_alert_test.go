@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func TestDetectProductAlerts(t *testing.T) {
+	now := time.Now().UTC()
+
+	watched := []models.WatchedProduct{
+		// wishlisted twice and subscribed to by the same user
+		{UserID: "u1", ProductID: "jacket", SeenPrice: 12000, SeenStock: 0, Price: 9000, Stock: 3, IsAvailable: true},
+		{UserID: "u1", ProductID: "jacket", SeenPrice: 12000, SeenStock: 0, Price: 9000, Stock: 3, IsAvailable: true},
+		{UserID: "u2", ProductID: "jacket", SeenPrice: 9500, SeenStock: 2, Price: 9000, Stock: 3, IsAvailable: true},
+		{UserID: "u1", ProductID: "boots", SeenPrice: 5000, SeenStock: 4, Price: 6000, Stock: 0, IsAvailable: true},
+		{UserID: "u1", ProductID: "ebook", SeenPrice: 1000, SeenStock: 0, Price: 1000, Stock: 5, IsDigital: true, IsAvailable: true},
+		{UserID: "u1", ProductID: "hidden", SeenPrice: 1000, SeenStock: 0, Price: 500, Stock: 5},
+	}
+
+	alerts := DetectProductAlerts(watched, now)
+
+	expected := []struct {
+		userID    string
+		productID string
+		kind      models.ProductAlertKind
+	}{
+		{"u1", "jacket", models.AlertPriceDrop},
+		{"u1", "jacket", models.AlertBackInStock},
+		{"u2", "jacket", models.AlertPriceDrop},
+	}
+	if len(alerts) != len(expected) {
+		t.Fatalf("expected %d alerts, got %+v", len(expected), alerts)
+	}
+	for i, e := range expected {
+		a := alerts[i]
+		if a.UserID != e.userID || a.ProductID != e.productID || a.Kind != e.kind {
+			t.Errorf("expected %s alert of %s for %s, got %+v", e.kind, e.productID, e.userID, a)
+		}
+		if a.ID == "" || !a.CreatedAt.Equal(now) {
+			t.Errorf("expected alert to be identified and dated, got %+v", a)
+		}
+	}
+	if alerts[0].OldPrice != 12000 || alerts[0].NewPrice != 9000 {
+		t.Errorf("expected prices 12000 to 9000, got %d to %d", alerts[0].OldPrice, alerts[0].NewPrice)
+	}
+}
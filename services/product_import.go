@@ -0,0 +1,558 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/validators"
+	"github.com/shopicano/shopicano-backend/values"
+)
+
+const (
+	// ProductCSVAttributePrefix starts the attribute columns, e.g. attribute:Color
+	ProductCSVAttributePrefix = "attribute:"
+	// ProductCSVListSeparator separates the additional images and the values of an attribute in a cell
+	ProductCSVListSeparator = "|"
+
+	productImportMaxRows = 10000
+
+	ProductImportRowCreated = "created"
+	ProductImportRowUpdated = "updated"
+	ProductImportRowFailed  = "failed"
+)
+
+// ProductCSVColumns are the columns every product CSV has, before the attribute columns
+var ProductCSVColumns = []string{"sku", "name", "description", "category", "price", "product_cost", "stock", "unit",
+	"max_quantity_count", "is_published", "is_shippable", "is_digital", "image", "additional_images"}
+
+// ProductCSVRow is a product as laid out in a row of the product CSV
+type ProductCSVRow struct {
+	Product    validators.ReqProductCreate
+	Category   string
+	Attributes map[string][]string
+}
+
+// ProductCSVHeader locates the columns of a product CSV
+type ProductCSVHeader struct {
+	columns       map[string]int
+	AttributeKeys []string
+}
+
+// ProductImportRowResult is a line of the import report, Line being the line of the row in the file
+type ProductImportRowResult struct {
+	Line   int
+	SKU    string
+	Status string
+	Errors []string
+}
+
+// ParseProductCSVHeader checks that the header has every product column once, in any order.
+// The attribute columns are optional.
+func ParseProductCSVHeader(header []string) (*ProductCSVHeader, error) {
+	h := &ProductCSVHeader{columns: map[string]int{}}
+
+	for i, c := range header {
+		c = strings.TrimSpace(c)
+		if i == 0 {
+			c = strings.TrimPrefix(c, "\ufeff")
+		}
+
+		name := strings.ToLower(c)
+		if strings.HasPrefix(name, ProductCSVAttributePrefix) {
+			key := strings.TrimSpace(c[len(ProductCSVAttributePrefix):])
+			if key == "" {
+				return nil, fmt.Errorf("column %d has an attribute without key", i+1)
+			}
+			name = ProductCSVAttributePrefix + key
+			h.AttributeKeys = append(h.AttributeKeys, key)
+		}
+
+		if _, ok := h.columns[name]; ok {
+			return nil, fmt.Errorf("column %s is repeated", c)
+		}
+		h.columns[name] = i
+	}
+
+	var missing []string
+	for _, c := range ProductCSVColumns {
+		if _, ok := h.columns[c]; !ok {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns %s", strings.Join(missing, ", "))
+	}
+
+	return h, nil
+}
+
+func (h *ProductCSVHeader) cell(record []string, column string) string {
+	i, ok := h.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// ParseRow reads the product of the record, the errors tell the cells that aren't numbers or booleans.
+// The product itself still has to be validated.
+func (h *ProductCSVHeader) ParseRow(record []string) (*ProductCSVRow, []string) {
+	var errs []string
+
+	parseInt := func(column string, def int64) int64 {
+		v := h.cell(record, column)
+		if v == "" {
+			return def
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s must be a whole number", column))
+		}
+		return n
+	}
+	parseBool := func(column string) bool {
+		v := h.cell(record, column)
+		if v == "" {
+			return false
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s must be true or false", column))
+		}
+		return b
+	}
+
+	r := &ProductCSVRow{
+		Product: validators.ReqProductCreate{
+			SKU:              h.cell(record, "sku"),
+			Name:             h.cell(record, "name"),
+			Description:      h.cell(record, "description"),
+			Price:            parseInt("price", 0),
+			ProductCost:      parseInt("product_cost", 0),
+			Stock:            int(parseInt("stock", 0)),
+			Unit:             h.cell(record, "unit"),
+			MaxQuantityCount: int(parseInt("max_quantity_count", 10)),
+			IsPublished:      parseBool("is_published"),
+			IsShippable:      parseBool("is_shippable"),
+			IsDigital:        parseBool("is_digital"),
+			Image:            h.cell(record, "image"),
+			AdditionalImages: splitProductCSVList(h.cell(record, "additional_images")),
+		},
+		Category:   h.cell(record, "category"),
+		Attributes: map[string][]string{},
+	}
+
+	for _, k := range h.AttributeKeys {
+		r.Attributes[k] = splitProductCSVList(h.cell(record, ProductCSVAttributePrefix+k))
+	}
+
+	return r, errs
+}
+
+func splitProductCSVList(v string) []string {
+	var values []string
+	for _, s := range strings.Split(v, ProductCSVListSeparator) {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// BuildProductCSV writes the products in the format read by the import, with a column for every attribute key
+func BuildProductCSV(rows []ProductCSVRow) ([]byte, error) {
+	keys := map[string]bool{}
+	for _, r := range rows {
+		for k := range r.Attributes {
+			keys[k] = true
+		}
+	}
+	var attributeKeys []string
+	for k := range keys {
+		attributeKeys = append(attributeKeys, k)
+	}
+	sort.Strings(attributeKeys)
+
+	header := append([]string{}, ProductCSVColumns...)
+	for _, k := range attributeKeys {
+		header = append(header, ProductCSVAttributePrefix+k)
+	}
+
+	records := [][]string{header}
+	for _, r := range rows {
+		p := r.Product
+		record := []string{p.SKU, p.Name, p.Description, r.Category, strconv.FormatInt(p.Price, 10),
+			strconv.FormatInt(p.ProductCost, 10), strconv.Itoa(p.Stock), p.Unit, strconv.Itoa(p.MaxQuantityCount),
+			strconv.FormatBool(p.IsPublished), strconv.FormatBool(p.IsShippable), strconv.FormatBool(p.IsDigital),
+			p.Image, strings.Join(p.AdditionalImages, ProductCSVListSeparator)}
+		for _, k := range attributeKeys {
+			record = append(record, strings.Join(r.Attributes[k], ProductCSVListSeparator))
+		}
+		records = append(records, record)
+	}
+
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateProductCSV exports every product of the store with its attributes and additional images
+func GenerateProductCSV(db *gorm.DB, storeID string) ([]byte, error) {
+	pu := data.NewProductRepository()
+
+	products, err := pu.ListForExport(db, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []ProductCSVRow
+	for _, p := range products {
+		attributes, err := pu.ListAttributes(db, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		images, err := pu.GetImages(db, p.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		r := ProductCSVRow{
+			Product: validators.ReqProductCreate{
+				SKU:              p.SKU,
+				Name:             p.Name,
+				Description:      p.Description,
+				Price:            int64(p.Price),
+				ProductCost:      int64(p.ProductCost),
+				Stock:            p.Stock,
+				Unit:             p.Unit,
+				MaxQuantityCount: p.MaxQuantityCount,
+				IsPublished:      p.IsPublished,
+				IsShippable:      p.IsShippable,
+				IsDigital:        p.IsDigital,
+				Image:            p.Image,
+				AdditionalImages: images,
+			},
+			Category:   p.CategoryName,
+			Attributes: map[string][]string{},
+		}
+		for k, values := range attributes {
+			for _, v := range values {
+				r.Attributes[k] = append(r.Attributes[k], v.Value)
+			}
+			sort.Strings(r.Attributes[k])
+		}
+		rows = append(rows, r)
+	}
+
+	return BuildProductCSV(rows)
+}
+
+// BuildProductImportReport writes the outcome of every row of the import
+func BuildProductImportReport(results []ProductImportRowResult) ([]byte, error) {
+	records := [][]string{{"line", "sku", "status", "errors"}}
+	for _, r := range results {
+		records = append(records, []string{strconv.Itoa(r.Line), r.SKU, r.Status, strings.Join(r.Errors, "; ")})
+	}
+
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportProducts processes the uploaded CSV of the import. Every row is saved on its own, creating the
+// product or updating the product of the store with the same SKU, so invalid rows don't hold back the
+// others. Files that can't be read at all fail the import without a report.
+func ImportProducts(importID string) error {
+	db := app.DB()
+	iu := data.NewProductImportRepository()
+
+	pi, err := iu.Get(db, importID)
+	if err != nil {
+		return err
+	}
+	if pi.Status.IsFinished() {
+		return nil
+	}
+
+	pi.Status = models.ProductImportProcessing
+	pi.UpdatedAt = time.Now().UTC()
+	if err := iu.Update(db, pi); err != nil {
+		return err
+	}
+
+	f, err := ServeAsStreamFromMinio(pi.FilePath)
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	results, err := importProductCSV(db, pi.StoreID, pi.UserID, body)
+	if err != nil {
+		now := time.Now().UTC()
+		pi.Status = models.ProductImportFailed
+		pi.Error = err.Error()
+		pi.UpdatedAt = now
+		pi.CompletedAt = &now
+		return iu.Update(db, pi)
+	}
+
+	report, err := BuildProductImportReport(results)
+	if err != nil {
+		return err
+	}
+
+	pi.ReportPath = fmt.Sprintf("%s/imports/%s/%s-report.csv", values.ReservedBucketName, pi.StoreID, pi.ID)
+	if err := UploadToMinio(pi.ReportPath, "text/csv", bytes.NewReader(report), int64(len(report))); err != nil {
+		return err
+	}
+
+	pi.TotalRows = len(results)
+	pi.CreatedRows, pi.UpdatedRows, pi.FailedRows = 0, 0, 0
+	for _, r := range results {
+		switch r.Status {
+		case ProductImportRowCreated:
+			pi.CreatedRows++
+		case ProductImportRowUpdated:
+			pi.UpdatedRows++
+		default:
+			pi.FailedRows++
+		}
+	}
+
+	now := time.Now().UTC()
+	pi.Status = models.ProductImportCompleted
+	pi.UpdatedAt = now
+	pi.CompletedAt = &now
+	return iu.Update(db, pi)
+}
+
+// importProductCSV only fails when the file isn't a product CSV, row errors are part of the results
+func importProductCSV(db *gorm.DB, storeID, userID string, body []byte) ([]ProductImportRowResult, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	if len(records)-1 > productImportMaxRows {
+		return nil, fmt.Errorf("the file has more than %d rows", productImportMaxRows)
+	}
+
+	header, err := ParseProductCSVHeader(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var results []ProductImportRowResult
+	for i, record := range records[1:] {
+		res := ProductImportRowResult{Line: i + 2}
+
+		row, errs := header.ParseRow(record)
+		res.SKU = row.Product.SKU
+
+		if len(errs) == 0 {
+			res.Status, errs = importProductRow(db, storeID, userID, row)
+		}
+		if len(errs) > 0 {
+			res.Status = ProductImportRowFailed
+			res.Errors = errs
+		}
+
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func importProductRow(db *gorm.DB, storeID, userID string, row *ProductCSVRow) (string, []string) {
+	if err := validators.ValidateProductRow(&row.Product); err != nil {
+		var errs []string
+		if ve, ok := err.(*errors.ValidationError); ok {
+			for k, msgs := range *ve {
+				for _, m := range msgs {
+					errs = append(errs, fmt.Sprintf("%s: %s", k, m))
+				}
+			}
+			sort.Strings(errs)
+		} else {
+			errs = append(errs, err.Error())
+		}
+		return "", errs
+	}
+
+	var errs []string
+
+	var categoryID *string
+	if row.Category != "" {
+		cu := data.NewCategoryRepository()
+		c, err := cu.GetByNameAsStoreOwner(db, storeID, row.Category)
+		if err != nil {
+			if !errors.IsRecordNotFoundError(err) {
+				log.Log().Errorln(err)
+			}
+			errs = append(errs, fmt.Sprintf("category %s not found", row.Category))
+		} else {
+			categoryID = &c.ID
+		}
+	}
+
+	for _, image := range append([]string{row.Product.Image}, row.Product.AdditionalImages...) {
+		if image == "" {
+			continue
+		}
+		if strings.HasPrefix(image, values.ReservedBucketName+"/") {
+			errs = append(errs, fmt.Sprintf("image %s is in a private bucket", image))
+			continue
+		}
+		if err := StatMinioObject(image); err != nil {
+			errs = append(errs, fmt.Sprintf("image %s not found", image))
+		}
+	}
+
+	if len(errs) > 0 {
+		return "", errs
+	}
+
+	tx := db.Begin()
+
+	status, err := saveImportedProduct(tx, storeID, userID, categoryID, row)
+	if err != nil {
+		tx.Rollback()
+
+		if status != "" {
+			return "", []string{status}
+		}
+		if msg, ok := errors.IsDuplicateKeyError(err); ok {
+			if msg == "" {
+				msg = "SKU already exists"
+			}
+			return "", []string{msg}
+		}
+		log.Log().Errorln(err)
+		return "", []string{"couldn't be saved"}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Log().Errorln(err)
+		return "", []string{"couldn't be saved"}
+	}
+	return status, nil
+}
+
+// saveImportedProduct creates or updates the product and replaces its additional images and the values of the
+// attributes in the file. On error, the status is the reason to report when it isn't a database error.
+func saveImportedProduct(db *gorm.DB, storeID, userID string, categoryID *string, row *ProductCSVRow) (string, error) {
+	pu := data.NewProductRepository()
+	req := row.Product
+
+	p, err := pu.GetBySKU(db, req.SKU)
+	if err != nil && !errors.IsRecordNotFoundError(err) {
+		return "", err
+	}
+
+	status := ProductImportRowUpdated
+	if p == nil {
+		status = ProductImportRowCreated
+		p = &models.Product{
+			ID:        utils.NewUUID(),
+			StoreID:   storeID,
+			CreatedAt: time.Now().UTC(),
+		}
+	} else if p.StoreID != storeID {
+		return "SKU is used by another store", fmt.Errorf("sku %s belongs to store %s", req.SKU, p.StoreID)
+	}
+
+	p.Name = req.Name
+	p.Slug = slug.Make(req.Name)
+	p.Description = req.Description
+	p.CategoryID = categoryID
+	p.SKU = req.SKU
+	p.Price = req.Price
+	p.ProductCost = req.ProductCost
+	p.Unit = req.Unit
+	p.MaxQuantityCount = req.MaxQuantityCount
+	p.IsPublished = req.IsPublished
+	p.IsShippable = req.IsShippable
+	p.IsDigital = req.IsDigital
+	p.Image = req.Image
+	p.UpdatedAt = time.Now().UTC()
+
+	if status == ProductImportRowCreated {
+		err = pu.Create(db, p)
+	} else {
+		err = pu.Update(db, p)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	reason := models.StockCorrection
+	if status == ProductImportRowCreated {
+		reason = models.StockReceived
+	}
+	if err := SetProductStock(db, p, nil, p.Stock, req.Stock, reason, &userID); err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			return "stock is below what the other locations hold", err
+		}
+		return "", err
+	}
+	p.Stock = req.Stock
+
+	if err := pu.RemoveImage(db, p.ID); err != nil {
+		return "", err
+	}
+	for _, i := range req.AdditionalImages {
+		if err := pu.AddImage(db, p.ID, i); err != nil {
+			return "", err
+		}
+	}
+
+	for key, values := range row.Attributes {
+		if err := pu.RemoveAttributesByKey(db, p.ID, key); err != nil {
+			return "", err
+		}
+		for _, v := range values {
+			if err := pu.AddAttribute(db, &models.ProductAttribute{
+				ID:        utils.NewUUID(),
+				ProductID: p.ID,
+				Key:       key,
+				Value:     v,
+			}); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// Imported content is part of the revision history, so publishing an older draft can't silently undo it
+	if _, err := RecordProductRevision(db, p, &userID); err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
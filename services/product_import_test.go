@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/csv"
+	"github.com/shopicano/shopicano-backend/validators"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProductCSVRoundTrip(t *testing.T) {
+	rows := []ProductCSVRow{
+		{
+			Product: validators.ReqProductCreate{
+				SKU:              "TS-1",
+				Name:             "T-Shirt, cotton",
+				Description:      "Soft \"premium\" cotton\nMachine washable",
+				Price:            2500,
+				ProductCost:      900,
+				Stock:            40,
+				Unit:             "piece",
+				MaxQuantityCount: 5,
+				IsPublished:      true,
+				IsShippable:      true,
+				Image:            "products/ts-1.png",
+				AdditionalImages: []string{"products/ts-1-back.png", "products/ts-1-side.png"},
+			},
+			Category: "Apparel",
+			Attributes: map[string][]string{
+				"Color": {"Blue", "Red"},
+				"Size":  {"M"},
+			},
+		},
+		{
+			Product: validators.ReqProductCreate{
+				SKU:              "EB-1",
+				Name:             "E-Book",
+				Description:      "A digital book",
+				Price:            1000,
+				Unit:             "copy",
+				MaxQuantityCount: 1,
+				IsDigital:        true,
+			},
+			Attributes: map[string][]string{},
+		},
+	}
+
+	b, err := BuildProductCSV(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(b))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	header, err := ParseProductCSVHeader(records[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header.AttributeKeys, []string{"Color", "Size"}) {
+		t.Errorf("unexpected attribute keys %v", header.AttributeKeys)
+	}
+
+	for i, record := range records[1:] {
+		r, errs := header.ParseRow(record)
+		if len(errs) > 0 {
+			t.Fatalf("row %d: unexpected errors %v", i, errs)
+		}
+
+		expected := rows[i]
+		for _, k := range header.AttributeKeys {
+			if _, ok := expected.Attributes[k]; !ok {
+				expected.Attributes[k] = nil
+			}
+		}
+		if !reflect.DeepEqual(*r, expected) {
+			t.Errorf("row %d: expected %+v, got %+v", i, expected, *r)
+		}
+	}
+}
+
+func TestParseProductCSVHeader(t *testing.T) {
+	header := append([]string{}, ProductCSVColumns...)
+	header[0] = "\ufeffSKU"
+	header = append(header, " Attribute:Material ")
+
+	h, err := ParseProductCSVHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h.AttributeKeys, []string{"Material"}) {
+		t.Errorf("unexpected attribute keys %v", h.AttributeKeys)
+	}
+
+	if _, err := ParseProductCSVHeader(ProductCSVColumns[1:]); err == nil {
+		t.Error("expected an error for the missing sku column")
+	}
+	if _, err := ParseProductCSVHeader(append(append([]string{}, ProductCSVColumns...), "name")); err == nil {
+		t.Error("expected an error for the repeated name column")
+	}
+	if _, err := ParseProductCSVHeader(append(append([]string{}, ProductCSVColumns...), "attribute: ")); err == nil {
+		t.Error("expected an error for the attribute column without key")
+	}
+}
+
+func TestProductCSVParseRowErrors(t *testing.T) {
+	h, err := ParseProductCSVHeader(ProductCSVColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, errs := h.ParseRow([]string{"SKU-1", "Mug", "A mug", "", "12.5", "", "", "piece", "", "yes", "", "", "", ""})
+	expected := []string{"price must be a whole number", "is_published must be true or false"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected errors %v, got %v", expected, errs)
+	}
+	if r.Product.MaxQuantityCount != 10 {
+		t.Errorf("expected the default max quantity count, got %d", r.Product.MaxQuantityCount)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+const (
+	// ProductRecommendationLimit is the number of recommendations kept for every product and kind
+	ProductRecommendationLimit = 20
+	// BoughtTogetherMinOrders leaves out the products bought together by chance
+	BoughtTogetherMinOrders = 2
+)
+
+// Weights of the similarities of related products
+const (
+	relatedCategoryWeight   = 1.0
+	relatedCollectionWeight = 0.5
+)
+
+// ComputeProductRecommendations computes the products bought together from the purchased orders and
+// the related products from their categories and collections, replacing the ones computed before
+func ComputeProductRecommendations() error {
+	ru := data.NewProductRecommendationRepository()
+
+	orders, err := ru.CountOrdersByProduct(app.DB())
+	if err != nil {
+		return err
+	}
+	coPurchases, err := ru.ListCoPurchases(app.DB(), BoughtTogetherMinOrders)
+	if err != nil {
+		return err
+	}
+	categoryPeers, err := ru.ListCategoryPeers(app.DB(), ProductRecommendationLimit)
+	if err != nil {
+		return err
+	}
+	collectionPeers, err := ru.ListCollectionPeers(app.DB(), ProductRecommendationLimit)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	boughtTogether := ScoreBoughtTogether(coPurchases, orders, ProductRecommendationLimit, now)
+	related := ScoreRelated(categoryPeers, collectionPeers, ProductRecommendationLimit, now)
+
+	tx := app.DB().Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	if err := ru.Replace(tx, models.RecommendationBoughtTogether, boughtTogether); err != nil {
+		return err
+	}
+	if err := ru.Replace(tx, models.RecommendationRelated, related); err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// ScoreBoughtTogether scores the products bought together by the cosine similarity of their orders,
+// so that best sellers present in many orders don't end up recommended along with everything
+func ScoreBoughtTogether(pairs []models.ProductPairCount, orders []models.ProductCount, limit int, now time.Time) []models.ProductRecommendation {
+	ordersOf := map[string]int{}
+	for _, c := range orders {
+		ordersOf[c.ProductID] = c.Count
+	}
+
+	scores := map[string]map[string]float64{}
+	for _, p := range pairs {
+		n := ordersOf[p.ProductID] * ordersOf[p.OtherProductID]
+		if n == 0 {
+			continue
+		}
+		addRecommendationScore(scores, p.ProductID, p.OtherProductID, float64(p.Count)/math.Sqrt(float64(n)))
+	}
+	return topRecommendations(scores, models.RecommendationBoughtTogether, limit, now)
+}
+
+// ScoreRelated scores the related products by the category and the collections they share
+func ScoreRelated(categoryPeers, collectionPeers []models.ProductPairCount, limit int, now time.Time) []models.ProductRecommendation {
+	scores := map[string]map[string]float64{}
+	for _, p := range categoryPeers {
+		addRecommendationScore(scores, p.ProductID, p.OtherProductID, relatedCategoryWeight)
+	}
+	for _, p := range collectionPeers {
+		addRecommendationScore(scores, p.ProductID, p.OtherProductID, relatedCollectionWeight*float64(p.Count))
+	}
+	return topRecommendations(scores, models.RecommendationRelated, limit, now)
+}
+
+func addRecommendationScore(scores map[string]map[string]float64, productID, otherProductID string, score float64) {
+	if productID == otherProductID {
+		return
+	}
+	if scores[productID] == nil {
+		scores[productID] = map[string]float64{}
+	}
+	scores[productID][otherProductID] += score
+}
+
+// topRecommendations keeps the best scored recommendations of every product, ordered by product
+func topRecommendations(scores map[string]map[string]float64, kind models.RecommendationKind, limit int, now time.Time) []models.ProductRecommendation {
+	productIDs := make([]string, 0, len(scores))
+	for id := range scores {
+		productIDs = append(productIDs, id)
+	}
+	sort.Strings(productIDs)
+
+	var recommendations []models.ProductRecommendation
+	for _, id := range productIDs {
+		var candidates []models.ProductRecommendation
+		for other, score := range scores[id] {
+			candidates = append(candidates, models.ProductRecommendation{
+				ProductID:            id,
+				RecommendedProductID: other,
+				Kind:                 kind,
+				Score:                score,
+				ComputedAt:           now,
+			})
+		}
+
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].Score != candidates[j].Score {
+				return candidates[i].Score > candidates[j].Score
+			}
+			return candidates[i].RecommendedProductID < candidates[j].RecommendedProductID
+		})
+		if len(candidates) > limit {
+			candidates = candidates[:limit]
+		}
+		recommendations = append(recommendations, candidates...)
+	}
+	return recommendations
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func TestScoreBoughtTogether(t *testing.T) {
+	now := time.Now().UTC()
+
+	orders := []models.ProductCount{
+		{ProductID: "phone", Count: 4},
+		{ProductID: "case", Count: 4},
+		{ProductID: "cable", Count: 100},
+	}
+	pairs := []models.ProductPairCount{
+		{ProductID: "phone", OtherProductID: "case", Count: 4},
+		{ProductID: "phone", OtherProductID: "cable", Count: 4},
+		{ProductID: "case", OtherProductID: "phone", Count: 4},
+		{ProductID: "phone", OtherProductID: "unknown", Count: 3},
+	}
+
+	recs := ScoreBoughtTogether(pairs, orders, 10, now)
+	if len(recs) != 3 {
+		t.Fatalf("expected 3 recommendations, got %d : %+v", len(recs), recs)
+	}
+
+	// case, then phone's recommendations ordered by score
+	if recs[0].ProductID != "case" || recs[0].RecommendedProductID != "phone" || recs[0].Score != 1 {
+		t.Errorf("unexpected recommendation %+v", recs[0])
+	}
+	if recs[1].RecommendedProductID != "case" || recs[2].RecommendedProductID != "cable" {
+		t.Errorf("expected the best seller to rank below, got %+v", recs[1:])
+	}
+	if math.Abs(recs[2].Score-0.2) > 1e-9 {
+		t.Errorf("expected cosine score 0.2, got %f", recs[2].Score)
+	}
+	for _, r := range recs {
+		if r.Kind != models.RecommendationBoughtTogether || !r.ComputedAt.Equal(now) {
+			t.Errorf("unexpected kind or time %+v", r)
+		}
+	}
+
+	if recs := ScoreBoughtTogether(pairs, orders, 1, now); len(recs) != 2 {
+		t.Errorf("expected one recommendation per product, got %+v", recs)
+	}
+}
+
+func TestScoreRelated(t *testing.T) {
+	now := time.Now().UTC()
+
+	category := []models.ProductPairCount{
+		{ProductID: "a", OtherProductID: "b", Count: 1},
+		{ProductID: "a", OtherProductID: "c", Count: 1},
+	}
+	collections := []models.ProductPairCount{
+		{ProductID: "a", OtherProductID: "c", Count: 2},
+		{ProductID: "a", OtherProductID: "d", Count: 1},
+		{ProductID: "a", OtherProductID: "a", Count: 1},
+	}
+
+	recs := ScoreRelated(category, collections, 10, now)
+
+	expected := []struct {
+		id    string
+		score float64
+	}{
+		{"c", 2},
+		{"b", 1},
+		{"d", 0.5},
+	}
+	if len(recs) != len(expected) {
+		t.Fatalf("expected %d recommendations, got %+v", len(expected), recs)
+	}
+	for i, e := range expected {
+		if recs[i].RecommendedProductID != e.id || recs[i].Score != e.score || recs[i].Kind != models.RecommendationRelated {
+			t.Errorf("expected %s scored %f, got %+v", e.id, e.score, recs[i])
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"time"
+
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+// ErrProductReviewExists is returned for the items reviewed already, a buyer reviews every item once
+var ErrProductReviewExists = errors.NewError("item already reviewed")
+
+// CheckProductReviewEligibility returns an error unless the buyer can review the item: it must be an item of
+// their own order, the order must be paid and not cancelled, and the item must not be reviewed yet
+func CheckProductReviewEligibility(userID string, o *models.Order, oi *models.OrderedItem, reviewed bool) error {
+	if o.UserID != userID || oi.OrderID != o.ID {
+		return errors.NewError("only the buyer can review the items of the order")
+	}
+	if o.PaymentStatus != models.PaymentCompleted || o.Status == models.OrderCancelled {
+		return errors.NewError("only items of paid orders can be reviewed")
+	}
+	if reviewed {
+		return ErrProductReviewExists
+	}
+	return nil
+}
+
+// ModerateProductReview approves or rejects the review, a decision can be revisited but reviews
+// never go back to pending
+func ModerateProductReview(r *models.ProductReview, status models.ReviewStatus, note, userID string, now time.Time) error {
+	if status != models.ReviewApproved && status != models.ReviewRejected {
+		return errors.NewError("reviews can only be approved or rejected")
+	}
+
+	r.Status = status
+	r.ModerationNote = note
+	r.ModeratedAt = &now
+	r.ModeratedByUserID = &userID
+	r.UpdatedAt = now
+	return nil
+}
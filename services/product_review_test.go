@@ -0,0 +1,90 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"testing"
+	"time"
+)
+
+func TestCheckProductReviewEligibility(t *testing.T) {
+	paid := models.Order{ID: "o1", UserID: "u1", PaymentStatus: models.PaymentCompleted, Status: models.OrderDelivered}
+	unpaid := paid
+	unpaid.PaymentStatus = models.PaymentPending
+	reverted := paid
+	reverted.PaymentStatus = models.PaymentReverted
+	cancelled := paid
+	cancelled.Status = models.OrderCancelled
+
+	item := models.OrderedItem{ID: "i1", OrderID: "o1"}
+	otherItem := models.OrderedItem{ID: "i2", OrderID: "o2"}
+
+	cases := []struct {
+		name     string
+		userID   string
+		order    models.Order
+		item     models.OrderedItem
+		reviewed bool
+		allowed  bool
+		exists   bool
+	}{
+		{"paid item of the buyer", "u1", paid, item, false, true, false},
+		{"another user", "u2", paid, item, false, false, false},
+		{"item of another order", "u1", paid, otherItem, false, false, false},
+		{"unpaid order", "u1", unpaid, item, false, false, false},
+		{"reverted payment", "u1", reverted, item, false, false, false},
+		{"cancelled order", "u1", cancelled, item, false, false, false},
+		{"already reviewed", "u1", paid, item, true, false, true},
+	}
+
+	for _, c := range cases {
+		err := CheckProductReviewEligibility(c.userID, &c.order, &c.item, c.reviewed)
+		if c.allowed && err != nil {
+			t.Errorf("%s: expected to be allowed, got %v", c.name, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("%s: expected to be rejected", c.name)
+		}
+		if exists := err == ErrProductReviewExists; exists != c.exists {
+			t.Errorf("%s: expected already reviewed to be %v, got %v", c.name, c.exists, err)
+		}
+	}
+}
+
+func TestModerateProductReview(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		from, to models.ReviewStatus
+		allowed  bool
+	}{
+		{models.ReviewPending, models.ReviewApproved, true},
+		{models.ReviewPending, models.ReviewRejected, true},
+		{models.ReviewApproved, models.ReviewRejected, true},
+		{models.ReviewRejected, models.ReviewApproved, true},
+		{models.ReviewApproved, models.ReviewPending, false},
+		{models.ReviewPending, "review_unknown", false},
+	}
+
+	for _, c := range cases {
+		r := models.ProductReview{Status: c.from}
+		err := ModerateProductReview(&r, c.to, "note", "admin", now)
+		if !c.allowed {
+			if err == nil {
+				t.Errorf("%s -> %s: expected to be rejected", c.from, c.to)
+			}
+			if r.Status != c.from || r.ModeratedAt != nil {
+				t.Errorf("%s -> %s: rejected moderation changed the review", c.from, c.to)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s -> %s: expected to be allowed, got %v", c.from, c.to, err)
+			continue
+		}
+		if r.Status != c.to || r.ModerationNote != "note" || r.ModeratedAt == nil || !r.ModeratedAt.Equal(now) ||
+			r.ModeratedByUserID == nil || *r.ModeratedByUserID != "admin" || !r.UpdatedAt.Equal(now) {
+			t.Errorf("%s -> %s: moderation not recorded, got %+v", c.from, c.to, r)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+)
+
+// NewProductRevision snapshots the content of the product into a draft revision
+func NewProductRevision(p *models.Product, images []string) *models.ProductRevision {
+	return &models.ProductRevision{
+		ID:               utils.NewUUID(),
+		ProductID:        p.ID,
+		StoreID:          p.StoreID,
+		Status:           models.RevisionDraft,
+		Name:             p.Name,
+		Slug:             p.Slug,
+		Description:      p.Description,
+		CategoryID:       p.CategoryID,
+		Image:            p.Image,
+		Unit:             p.Unit,
+		Price:            p.Price,
+		ProductCost:      p.ProductCost,
+		MaxQuantityCount: p.MaxQuantityCount,
+		IsShippable:      p.IsShippable,
+		IsDigital:        p.IsDigital,
+		AdditionalImages: images,
+		CreatedAt:        time.Now().UTC(),
+		UpdatedAt:        time.Now().UTC(),
+	}
+}
+
+// CopyProductRevision copies the content of the revision into a new draft of the same product
+func CopyProductRevision(r *models.ProductRevision) *models.ProductRevision {
+	c := *r
+	c.ID = utils.NewUUID()
+	c.Number = 0
+	c.Status = models.RevisionDraft
+	c.Note = ""
+	c.PublishAt = nil
+	c.PublishedAt = nil
+	c.RevertedFromID = nil
+	c.CreatedByUserID = nil
+	c.PublishedByUserID = nil
+	c.AdditionalImages = append([]string(nil), r.AdditionalImages...)
+	c.CreatedAt = time.Now().UTC()
+	c.UpdatedAt = time.Now().UTC()
+	return &c
+}
+
+// ApplyProductRevision replaces the content of the product with the one of the revision.
+// Stock, SKU and the downloadable file aren't part of the revisions and are kept.
+func ApplyProductRevision(p *models.Product, r *models.ProductRevision) {
+	p.Name = r.Name
+	p.Slug = slug.Make(r.Name)
+	p.Description = r.Description
+	p.CategoryID = r.CategoryID
+	p.Image = r.Image
+	p.Unit = r.Unit
+	p.Price = r.Price
+	p.ProductCost = r.ProductCost
+	p.MaxQuantityCount = r.MaxQuantityCount
+	p.IsShippable = r.IsShippable
+	p.IsDigital = r.IsDigital
+}
+
+// PreviewProductRevision shows the product as it would be once the revision is published
+func PreviewProductRevision(d *models.ProductDetailsInternal, r *models.ProductRevision) {
+	if r.CategoryID == nil {
+		d.CategoryID = ""
+		d.CategoryName = ""
+	} else if *r.CategoryID != d.CategoryID {
+		d.CategoryID = *r.CategoryID
+		d.CategoryName = ""
+	}
+
+	d.Name = r.Name
+	d.Slug = slug.Make(r.Name)
+	d.Description = r.Description
+	d.Image = r.Image
+	d.Unit = r.Unit
+	d.Price = int(r.Price)
+	d.ProductCost = int(r.ProductCost)
+	d.MaxQuantityCount = r.MaxQuantityCount
+	d.IsShippable = r.IsShippable
+	d.IsDigital = r.IsDigital
+	d.AdditionalImages = r.AdditionalImages
+	d.IsPublished = true
+}
+
+// CreateProductRevision numbers and saves the revision as the latest one of its product
+func CreateProductRevision(db *gorm.DB, r *models.ProductRevision) error {
+	ru := data.NewProductRevisionRepository()
+
+	n, err := ru.NextNumber(db, r.ProductID)
+	if err != nil {
+		return err
+	}
+	r.Number = n
+
+	return ru.Create(db, r)
+}
+
+// RecordProductRevision saves the current content of the product as its published revision,
+// so that the changes made straight to the product can be reverted as well
+func RecordProductRevision(db *gorm.DB, p *models.Product, userID *string) (*models.ProductRevision, error) {
+	pu := data.NewProductRepository()
+	ru := data.NewProductRevisionRepository()
+
+	images, err := pu.GetImages(db, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ru.ArchivePublished(db, p.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	r := NewProductRevision(p, images)
+	r.Status = models.RevisionPublished
+	r.CreatedByUserID = userID
+	r.PublishedByUserID = userID
+	r.PublishedAt = &now
+
+	if err := CreateProductRevision(db, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// PublishProductRevision makes the content of the revision the content of the product and publishes
+// the product. The revision published before is archived. The user is nil when the worker publishes.
+func PublishProductRevision(db *gorm.DB, r *models.ProductRevision, userID *string) (*models.Product, error) {
+	pu := data.NewProductRepository()
+	ru := data.NewProductRevisionRepository()
+
+	p, err := pu.GetAsStoreStuff(db, r.StoreID, r.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+
+	ApplyProductRevision(p, r)
+	p.IsPublished = true
+	p.UpdatedAt = now
+
+	if err := pu.Update(db, p); err != nil {
+		return nil, err
+	}
+
+	if err := pu.RemoveImage(db, p.ID); err != nil {
+		return nil, err
+	}
+	for _, i := range r.AdditionalImages {
+		if err := pu.AddImage(db, p.ID, i); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ru.ArchivePublished(db, p.ID); err != nil {
+		return nil, err
+	}
+
+	r.Status = models.RevisionPublished
+	r.PublishedAt = &now
+	r.PublishedByUserID = userID
+	r.UpdatedAt = now
+
+	if err := ru.Update(db, r); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PublishScheduledProducts publishes the scheduled revisions that are due and unpublishes the products
+// whose unpublish date has passed. A revision failing to publish doesn't hold back the others.
+func PublishScheduledProducts() error {
+	now := time.Now().UTC()
+
+	ru := data.NewProductRevisionRepository()
+	revisions, err := ru.ListDue(app.DB(), now)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range revisions {
+		if err := publishScheduledRevision(app.DB(), r.ProductID, r.ID, now); err != nil {
+			log.Log().Errorln("Failed to publish revision ", r.ID, " of product ", r.ProductID, " : ", err)
+		}
+	}
+
+	pu := data.NewProductRepository()
+	return pu.UnpublishDue(app.DB(), now)
+}
+
+// publishScheduledRevision publishes the revision unless the staff cancelled, rescheduled or published it
+// since it was listed
+func publishScheduledRevision(db *gorm.DB, productID, revisionID string, now time.Time) error {
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	ru := data.NewProductRevisionRepository()
+	r, err := ru.GetForUpdate(tx, productID, revisionID)
+	if err != nil {
+		if errors.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	if r.Status != models.RevisionScheduled || r.PublishAt == nil || r.PublishAt.After(now) {
+		return nil
+	}
+
+	if _, err := PublishProductRevision(tx, r, nil); err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func TestProductRevisionRoundTrip(t *testing.T) {
+	category := "category-id"
+	p := &models.Product{
+		ID:               "product-id",
+		StoreID:          "store-id",
+		Name:             "Winter Jacket",
+		Slug:             "winter-jacket",
+		Description:      "Warm",
+		CategoryID:       &category,
+		Image:            "images/jacket.png",
+		Unit:             "pcs",
+		Price:            12000,
+		ProductCost:      8000,
+		MaxQuantityCount: 5,
+		IsShippable:      true,
+		SKU:              "JKT-1",
+		Stock:            7,
+	}
+
+	r := NewProductRevision(p, []string{"images/back.png"})
+	if r.Status != models.RevisionDraft || r.ProductID != p.ID || r.StoreID != p.StoreID {
+		t.Fatalf("unexpected revision %+v", r)
+	}
+
+	r.Name = "Summer Jacket"
+	r.Price = 9000
+	r.CategoryID = nil
+
+	c := CopyProductRevision(r)
+	if c.ID == r.ID || c.Status != models.RevisionDraft {
+		t.Fatalf("expected a new draft, got %+v", c)
+	}
+	if !reflect.DeepEqual(c.AdditionalImages, r.AdditionalImages) {
+		t.Errorf("expected images %v, got %v", r.AdditionalImages, c.AdditionalImages)
+	}
+
+	ApplyProductRevision(p, c)
+	if p.Name != "Summer Jacket" || p.Slug != "summer-jacket" || p.Price != 9000 || p.CategoryID != nil {
+		t.Errorf("revision not applied, got %+v", p)
+	}
+	if p.SKU != "JKT-1" || p.Stock != 7 {
+		t.Errorf("expected SKU and stock to be kept, got %s and %d", p.SKU, p.Stock)
+	}
+}
+
+func TestPreviewProductRevision(t *testing.T) {
+	other := "other-category-id"
+
+	d := &models.ProductDetailsInternal{
+		Name:         "Winter Jacket",
+		CategoryID:   "category-id",
+		CategoryName: "Jackets",
+		Stock:        7,
+	}
+	r := &models.ProductRevision{
+		Name:             "Summer Jacket",
+		CategoryID:       &other,
+		Price:            9000,
+		AdditionalImages: []string{"images/back.png"},
+	}
+
+	PreviewProductRevision(d, r)
+	if d.Name != "Summer Jacket" || d.Slug != "summer-jacket" || d.Price != 9000 || !d.IsPublished {
+		t.Errorf("revision not previewed, got %+v", d)
+	}
+	if d.CategoryID != other || d.CategoryName != "" {
+		t.Errorf("expected category %s without the stale name, got %s %s", other, d.CategoryID, d.CategoryName)
+	}
+	if d.Stock != 7 {
+		t.Errorf("expected stock to be kept, got %d", d.Stock)
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+)
+
+// VariantCombination is one value of every option of a product
+type VariantCombination struct {
+	Key            string
+	Title          string
+	OptionValueIDs []string
+	Values         []string
+}
+
+// BuildVariantCombinations returns every combination of the option values, in option and value order.
+// There are no combinations when the product has no options or any of its options has no values.
+func BuildVariantCombinations(options []models.ProductOptionDetails) []VariantCombination {
+	if len(options) == 0 {
+		return nil
+	}
+
+	combinations := []VariantCombination{{}}
+	for _, o := range options {
+		var next []VariantCombination
+		for _, c := range combinations {
+			for _, v := range o.Values {
+				next = append(next, VariantCombination{
+					OptionValueIDs: append(append([]string{}, c.OptionValueIDs...), v.ID),
+					Values:         append(append([]string{}, c.Values...), v.Value),
+				})
+			}
+		}
+		combinations = next
+	}
+
+	for i := range combinations {
+		combinations[i].Key = strings.Join(combinations[i].OptionValueIDs, ",")
+		combinations[i].Title = strings.Join(combinations[i].Values, " / ")
+	}
+	return combinations
+}
+
+// VariantSKU suggests the SKU of a generated variant from the product SKU and the option values,
+// e.g. TSHIRT-XL-RED. Taken SKUs, of any product, get a numeric suffix.
+func VariantSKU(productSKU string, values []string, taken map[string]bool) string {
+	sku := fmt.Sprintf("%s-%s", productSKU, strings.ToUpper(slug.Make(strings.Join(values, " "))))
+	if !taken[sku] {
+		return sku
+	}
+
+	for i := 2; ; i++ {
+		s := fmt.Sprintf("%s-%d", sku, i)
+		if !taken[s] {
+			return s
+		}
+	}
+}
+
+// GenerateProductVariants creates a variant for every combination of the product options that doesn't
+// have one yet, without stock and with the product price. Variants of combinations that no longer exist
+// are deactivated and those of combinations that exist again are reactivated.
+func GenerateProductVariants(db *gorm.DB, p *models.Product) ([]models.ProductVariantDetails, error) {
+	vu := data.NewProductVariantRepository()
+
+	options, err := vu.ListOptions(db, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := vu.ListVariants(db, p.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The SKUs are unique across the products, the variants of another product may have taken a suggested one
+	skus, err := vu.ListSKUsByPrefix(db, p.SKU+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	takenSKUs := map[string]bool{}
+	for _, sku := range skus {
+		takenSKUs[sku] = true
+	}
+
+	existing := map[string]models.ProductVariant{}
+	for _, v := range variants {
+		existing[v.Combination] = v.ProductVariant
+		takenSKUs[v.SKU] = true
+	}
+
+	combinations := BuildVariantCombinations(options)
+	current := map[string]bool{}
+
+	for _, c := range combinations {
+		current[c.Key] = true
+
+		if v, ok := existing[c.Key]; ok {
+			if !v.IsActive {
+				if err := vu.SetVariantActive(db, v.ID, true); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		v := models.ProductVariant{
+			ID:          utils.NewUUID(),
+			ProductID:   p.ID,
+			Combination: c.Key,
+			Title:       c.Title,
+			SKU:         VariantSKU(p.SKU, c.Values, takenSKUs),
+			IsActive:    true,
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
+		}
+		if err := vu.CreateVariant(db, &v, c.OptionValueIDs); err != nil {
+			return nil, err
+		}
+		takenSKUs[v.SKU] = true
+	}
+
+	for _, v := range variants {
+		if v.IsActive && !current[v.Combination] {
+			if err := vu.SetVariantActive(db, v.ID, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return vu.ListVariants(db, p.ID, false)
+}
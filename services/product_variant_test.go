@@ -0,0 +1,74 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"reflect"
+	"testing"
+)
+
+func TestBuildVariantCombinations(t *testing.T) {
+	options := []models.ProductOptionDetails{
+		{
+			ProductOption: models.ProductOption{ID: "size", Name: "Size"},
+			Values: []models.ProductOptionValue{
+				{ID: "s", Value: "S"},
+				{ID: "xl", Value: "XL"},
+			},
+		},
+		{
+			ProductOption: models.ProductOption{ID: "color", Name: "Color"},
+			Values: []models.ProductOptionValue{
+				{ID: "red", Value: "Red"},
+				{ID: "blue", Value: "Blue"},
+				{ID: "green", Value: "Green"},
+			},
+		},
+	}
+
+	combinations := BuildVariantCombinations(options)
+	if len(combinations) != 6 {
+		t.Fatalf("expected 6 combinations, got %d", len(combinations))
+	}
+
+	var keys, titles []string
+	for _, c := range combinations {
+		keys = append(keys, c.Key)
+		titles = append(titles, c.Title)
+	}
+
+	expectedKeys := []string{"s,red", "s,blue", "s,green", "xl,red", "xl,blue", "xl,green"}
+	if !reflect.DeepEqual(keys, expectedKeys) {
+		t.Errorf("expected keys %v, got %v", expectedKeys, keys)
+	}
+	expectedTitles := []string{"S / Red", "S / Blue", "S / Green", "XL / Red", "XL / Blue", "XL / Green"}
+	if !reflect.DeepEqual(titles, expectedTitles) {
+		t.Errorf("expected titles %v, got %v", expectedTitles, titles)
+	}
+	if !reflect.DeepEqual(combinations[3].OptionValueIDs, []string{"xl", "red"}) {
+		t.Errorf("expected option values [xl red], got %v", combinations[3].OptionValueIDs)
+	}
+
+	if c := BuildVariantCombinations(nil); len(c) != 0 {
+		t.Errorf("expected no combinations without options, got %d", len(c))
+	}
+
+	options = append(options, models.ProductOptionDetails{ProductOption: models.ProductOption{ID: "fit", Name: "Fit"}})
+	if c := BuildVariantCombinations(options); len(c) != 0 {
+		t.Errorf("expected no combinations with an option without values, got %d", len(c))
+	}
+}
+
+func TestVariantSKU(t *testing.T) {
+	taken := map[string]bool{}
+
+	sku := VariantSKU("TSHIRT", []string{"XL", "Navy Blue"}, taken)
+	if sku != "TSHIRT-XL-NAVY-BLUE" {
+		t.Errorf("expected TSHIRT-XL-NAVY-BLUE, got %s", sku)
+	}
+
+	taken[sku] = true
+	taken[sku+"-2"] = true
+	if sku := VariantSKU("TSHIRT", []string{"XL", "Navy Blue"}, taken); sku != "TSHIRT-XL-NAVY-BLUE-3" {
+		t.Errorf("expected TSHIRT-XL-NAVY-BLUE-3, got %s", sku)
+	}
+}
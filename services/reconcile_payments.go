@@ -0,0 +1,291 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	payment_gateways "github.com/shopicano/shopicano-backend/payment-gateways"
+	"github.com/shopicano/shopicano-backend/utils"
+	"time"
+)
+
+// Orders paid shortly before the end of the window may not be settled yet,
+// so they are only reported as missing in the gateway by a later run.
+const missingInGatewayGracePeriod = time.Hour * 24
+
+type paymentReconciler struct {
+	db     *gorm.DB
+	report *models.ReconciliationReport
+}
+
+// ReconcilePayments matches the transactions settled by each payment gateway between from and to
+// against the orders and records the discrepancies found in a new reconciliation report
+func ReconcilePayments(from, to time.Time) (*models.ReconciliationReport, error) {
+	db := app.DB()
+	ru := data.NewReconciliationRepository()
+
+	r := &models.ReconciliationReport{
+		ID:        utils.NewUUID(),
+		From:      from.UTC(),
+		To:        to.UTC(),
+		Status:    models.ReconciliationRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	if err := ru.CreateReport(db, r); err != nil {
+		return nil, err
+	}
+
+	pr := paymentReconciler{db: db, report: r}
+
+	err := pr.run()
+	if err != nil {
+		log.Log().Errorln(err)
+
+		r.Status = models.ReconciliationFailed
+		r.FailureReason = err.Error()
+	} else {
+		r.Status = models.ReconciliationCompleted
+	}
+
+	finishedAt := time.Now().UTC()
+	r.FinishedAt = &finishedAt
+
+	if err := ru.UpdateReport(db, r); err != nil {
+		return nil, err
+	}
+	return r, err
+}
+
+func (pr *paymentReconciler) run() error {
+	ou := data.NewOrderRepository()
+
+	gateways, err := ou.ListPaymentGateways(pr.db, pr.report.From, pr.report.To)
+	if err != nil {
+		return err
+	}
+
+	if active := payment_gateways.GetActivePaymentGateway(); active != nil {
+		gateways = append(gateways, active.GetName())
+	}
+
+	visited := map[string]bool{}
+	for _, name := range gateways {
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		if _, ok := config.PaymentGateway().Configs[name].(map[string]interface{}); !ok {
+			log.Log().Warnln("Skipping reconciliation of unconfigured payment gateway : ", name)
+			continue
+		}
+
+		pg, err := payment_gateways.GetPaymentGatewayByName(name)
+		if err != nil {
+			return err
+		}
+
+		if err := pr.reconcile(pg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pr *paymentReconciler) reconcile(pg payment_gateways.PaymentGateway) error {
+	settled, err := pg.ListSettledTransactions(pr.report.From, pr.report.To)
+	if err == payment_gateways.ErrSettlementListingNotSupported {
+		return pr.validatePendingOrders(pg)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Refunds are listed separately by some gateways, merge them into the refunded transaction
+	var transactions []*payment_gateways.SettledTransaction
+	byID := map[string]*payment_gateways.SettledTransaction{}
+	for i := range settled {
+		st := settled[i]
+		if v, ok := byID[st.TransactionID]; ok {
+			v.Amount += st.Amount
+			v.RefundedAmount += st.RefundedAmount
+			if v.OrderID == "" {
+				v.OrderID = st.OrderID
+			}
+			continue
+		}
+		byID[st.TransactionID] = &st
+		transactions = append(transactions, &st)
+	}
+
+	ou := data.NewOrderRepository()
+
+	for _, st := range transactions {
+		pr.report.TotalTransactions++
+
+		orders, err := ou.ListByTransactionID(pr.db, pg.GetName(), st.TransactionID)
+		if err != nil {
+			return err
+		}
+
+		if len(orders) == 0 {
+			if err := pr.reportUnmatchedTransaction(pg, st); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// A transaction recorded against several orders can't be matched, every order of it is reported
+		if len(orders) > 1 {
+			for i := range orders {
+				if err := pr.addDiscrepancy(pg, st, &orders[i], models.DiscrepancyDuplicateTransaction, nil); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		o := &orders[0]
+
+		if st.Amount != 0 && st.Amount != o.GrandTotal {
+			if err := pr.addDiscrepancy(pg, st, o, models.DiscrepancyAmountMismatch, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if st.RefundedAmount > 0 && o.PaymentStatus != models.PaymentReverted {
+			suggested := models.PaymentReverted
+			if err := pr.addDiscrepancy(pg, st, o, models.DiscrepancyRefundedInGateway, &suggested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if st.RefundedAmount == 0 && (o.PaymentStatus == models.PaymentPending || o.PaymentStatus == models.PaymentFailed) {
+			suggested := models.PaymentCompleted
+			if err := pr.addDiscrepancy(pg, st, o, models.DiscrepancyPaidButPending, &suggested); err != nil {
+				return err
+			}
+		}
+	}
+
+	orders, err := ou.ListByPaymentGateway(pr.db, pg.GetName(), []models.PaymentStatus{models.PaymentCompleted},
+		pr.report.From, pr.report.To.Add(-missingInGatewayGracePeriod))
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		o := &orders[i]
+		if _, ok := byID[*o.TransactionID]; ok {
+			continue
+		}
+
+		st := &payment_gateways.SettledTransaction{TransactionID: *o.TransactionID}
+		if err := pr.addDiscrepancy(pg, st, o, models.DiscrepancyMissingInGateway, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportUnmatchedTransaction reports a settled transaction that isn't recorded against any order.
+// When the gateway knows the order and the order was paid by another transaction, the buyer was charged twice.
+func (pr *paymentReconciler) reportUnmatchedTransaction(pg payment_gateways.PaymentGateway, st *payment_gateways.SettledTransaction) error {
+	if st.OrderID != "" {
+		ou := data.NewOrderRepository()
+		o, err := ou.Get(pr.db, st.OrderID)
+		if err != nil && !errors.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		if o != nil {
+			if o.TransactionID != nil && *o.TransactionID != st.TransactionID {
+				return pr.addDiscrepancy(pg, st, o, models.DiscrepancyDuplicateTransaction, nil)
+			}
+			return pr.addDiscrepancy(pg, st, o, models.DiscrepancyUnknownTransaction, nil)
+		}
+	}
+	return pr.addDiscrepancy(pg, st, nil, models.DiscrepancyUnknownTransaction, nil)
+}
+
+// validatePendingOrders is used for the gateways that can't list settled transactions,
+// the unpaid orders with a transaction are validated one by one instead
+func (pr *paymentReconciler) validatePendingOrders(pg payment_gateways.PaymentGateway) error {
+	ou := data.NewOrderRepository()
+
+	orders, err := ou.ListByPaymentGateway(pr.db, pg.GetName(),
+		[]models.PaymentStatus{models.PaymentPending, models.PaymentFailed}, pr.report.From, pr.report.To)
+	if err != nil {
+		return err
+	}
+
+	for i := range orders {
+		o := &orders[i]
+		pr.report.TotalTransactions++
+
+		od, err := ou.GetDetails(pr.db, o.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := pg.ValidateTransaction(od); err != nil {
+			continue
+		}
+
+		st := &payment_gateways.SettledTransaction{
+			TransactionID: *o.TransactionID,
+			OrderID:       o.ID,
+			Amount:        o.GrandTotal,
+		}
+		suggested := models.PaymentCompleted
+		if err := pr.addDiscrepancy(pg, st, o, models.DiscrepancyPaidButPending, &suggested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addDiscrepancy records the discrepancy in the report. Discrepancies already reported by an earlier report,
+// e.g. of an overlapping window, aren't reported again, whether they were resolved or not.
+func (pr *paymentReconciler) addDiscrepancy(pg payment_gateways.PaymentGateway, st *payment_gateways.SettledTransaction,
+	o *models.Order, t models.DiscrepancyType, suggested *models.PaymentStatus) error {
+	d := &models.PaymentDiscrepancy{
+		ID:                     utils.NewUUID(),
+		ReportID:               pr.report.ID,
+		PaymentGateway:         pg.GetName(),
+		TransactionID:          st.TransactionID,
+		Type:                   t,
+		GatewayAmount:          st.Amount,
+		GatewayRefundedAmount:  st.RefundedAmount,
+		SuggestedPaymentStatus: suggested,
+		CreatedAt:              time.Now().UTC(),
+	}
+	if o != nil {
+		d.OrderID = &o.ID
+		d.OrderAmount = o.GrandTotal
+		d.OrderPaymentStatus = &o.PaymentStatus
+	}
+
+	ru := data.NewReconciliationRepository()
+
+	reported, err := ru.HasDiscrepancy(pr.db, d)
+	if err != nil {
+		return err
+	}
+	if reported {
+		return nil
+	}
+
+	if err := ru.CreateDiscrepancy(pr.db, d); err != nil {
+		return err
+	}
+
+	pr.report.TotalDiscrepancies++
+	return nil
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+	"time"
+)
+
+// CreateScheduledPayouts creates a pending payout entry for every active store whose available
+// balance reached its minimum payout threshold. Stores and the finance team are notified by email.
+func CreateScheduledPayouts() ([]models.PayoutSend, error) {
+	au := data.NewMarketplaceRepository()
+
+	settings, err := au.ListPayoutSettingsOfActiveStores(app.DB())
+	if err != nil {
+		return nil, err
+	}
+
+	var payouts []models.PayoutSend
+	var details []NotificationDetail
+	for _, ps := range settings {
+		p, creator, err := createScheduledPayout(app.DB(), &ps)
+		if err != nil {
+			log.Log().Errorln("Failed to create scheduled payout of store ", ps.StoreID, " : ", err)
+			continue
+		}
+		if p == nil {
+			continue
+		}
+
+		payouts = append(payouts, *p)
+		details = append(details, NotificationDetail{Label: creator.StoreName, Value: formatAmount(p.Amount)})
+
+		if err := sendScheduledPayoutStoreEmail(p, creator); err != nil {
+			log.Log().Errorln("Failed to send scheduled payout email of store ", p.StoreID, " : ", err)
+		}
+	}
+
+	if len(payouts) > 0 {
+		if err := sendScheduledPayoutsFinanceEmail(payouts, details); err != nil {
+			log.Log().Errorln("Failed to send scheduled payouts email to finance team : ", err)
+		}
+	}
+	return payouts, nil
+}
+
+// createScheduledPayout returns nil when the store has nothing to be paid out
+func createScheduledPayout(db *gorm.DB, ps *models.PayoutSettings) (*models.PayoutSend, *models.StaffProfile, error) {
+	tx := db.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	available, err := LockStoreAvailableBalance(tx, ps.StoreID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if available <= 0 || available < ps.PayoutMinimumThreshold {
+		return nil, nil, nil
+	}
+
+	su := data.NewStoreRepository()
+	creator, err := su.GetStoreCreator(tx, ps.StoreID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := &models.PayoutSend{
+		ID:                     utils.NewUUID(),
+		StoreID:                ps.StoreID,
+		InitiatedByUserID:      creator.StaffID,
+		Amount:                 available,
+		Note:                   fmt.Sprintf("Scheduled %s payout", config.Scheduler().PayoutSchedule),
+		Status:                 models.PayoutSendStatusPending,
+		IsMarketplaceInitiated: true,
+		FailureReason:          "",
+		Highlights:             "",
+		PayoutMethodID:         ps.PayoutMethodID,
+		PayoutMethodDetails:    ps.PayoutMethodDetails,
+		CreatedAt:              time.Now().UTC(),
+		UpdatedAt:              time.Now().UTC(),
+	}
+
+	au := data.NewMarketplaceRepository()
+	if err := au.CreatePayoutEntry(tx, m); err != nil {
+		return nil, nil, err
+	}
+
+	if err := PostPayoutLedgerEntries(tx, m); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := RecordPayoutCreated(tx, m, nil, m.Note); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, err
+	}
+	return m, creator, nil
+}
+
+func sendScheduledPayoutStoreEmail(p *models.PayoutSend, creator *models.StaffProfile) error {
+	return SendNotificationEmail(creator.StaffEmail, "A payout has been scheduled for your store", &Notification{
+		Title:     "Payout Scheduled",
+		Greetings: fmt.Sprintf("Hi %s,", creator.StaffName),
+		Intros:    fmt.Sprintf("A payout of your available earnings has been scheduled for %s.", creator.StoreName),
+		Details: []NotificationDetail{
+			{Label: "Payout ID", Value: p.ID},
+			{Label: "Amount", Value: formatAmount(p.Amount)},
+			{Label: "Status", Value: "Pending"},
+			{Label: "Date", Value: p.CreatedAt.Format(utils.DateTimeFormatForDistribution)},
+		},
+	})
+}
+
+func sendScheduledPayoutsFinanceEmail(payouts []models.PayoutSend, details []NotificationDetail) error {
+	var total int64
+	for _, p := range payouts {
+		total += p.Amount
+	}
+	details = append(details, NotificationDetail{Label: "Total", Value: formatAmount(total)})
+
+	for _, email := range config.Payout().FinanceTeamEmails {
+		if err := SendNotificationEmail(email, "Scheduled payouts are pending", &Notification{
+			Title:     "Scheduled Payouts",
+			Greetings: "Hi,",
+			Intros:    fmt.Sprintf("%d scheduled payouts are pending for processing.", len(payouts)),
+			Details:   details,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
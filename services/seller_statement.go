@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/templates"
+	"github.com/shopicano/shopicano-backend/utils"
+	"github.com/shopicano/shopicano-backend/values"
+	"time"
+)
+
+const (
+	SellerStatementLineOrder  = "order"
+	SellerStatementLineRefund = "refund"
+	SellerStatementLinePayout = "payout"
+)
+
+// SellerStatementLine is a movement of the store balance within the statement period
+type SellerStatementLine struct {
+	Date        time.Time
+	Type        string
+	Reference   string
+	Description string
+	Sales       int64
+	Commission  int64
+	Amount      int64
+	Balance     int64
+}
+
+// GenerateMonthlySellerStatements generates the statement of the month of the given time for every store
+// with a balance or some activity in the month. Stores that already have the statement are skipped,
+// so the task can be retried safely.
+func GenerateMonthlySellerStatements(month time.Time) ([]models.SellerStatement, error) {
+	month = month.UTC()
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	su := data.NewStoreRepository()
+	storeIDs, err := su.ListIDs(app.DB())
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []models.SellerStatement
+	for _, storeID := range storeIDs {
+		s, err := generateSellerStatement(app.DB(), storeID, start, end)
+		if err != nil {
+			log.Log().Errorln("Failed to generate statement of store ", storeID, " : ", err)
+			continue
+		}
+		if s == nil {
+			continue
+		}
+
+		statements = append(statements, *s)
+
+		if err := sendSellerStatementEmail(app.DB(), s); err != nil {
+			log.Log().Errorln("Failed to send statement email of store ", storeID, " : ", err)
+		}
+	}
+	return statements, nil
+}
+
+// generateSellerStatement returns nil when the statement already exists or there is nothing to report
+func generateSellerStatement(db *gorm.DB, storeID string, start, end time.Time) (*models.SellerStatement, error) {
+	sr := data.NewSellerStatementRepository()
+	exists, err := sr.Exists(db, storeID, start)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, nil
+	}
+
+	lu := data.NewLedgerRepository()
+	opening, err := lu.GetStoreBalanceAt(db, storeID, start)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := lu.ListStoreMovements(db, storeID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	s, lines := BuildSellerStatement(storeID, start, end, opening, movements)
+	if len(lines) == 0 && s.OpeningBalance == 0 {
+		return nil, nil
+	}
+
+	su := data.NewStoreRepository()
+	store, err := su.FindStoreByID(db, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	au := data.NewMarketplaceRepository()
+	settings, err := au.GetSettings(db)
+	if err != nil {
+		return nil, err
+	}
+
+	csvBody, err := GenerateSellerStatementCSV(s, lines)
+	if err != nil {
+		return nil, err
+	}
+	pdfBody := GenerateSellerStatementPDF(settings.Name, store, s, lines)
+
+	path := fmt.Sprintf("%s/statements/%s/%s-%s", values.ReservedBucketName, storeID, start.Format("2006-01"), s.ID)
+	s.CSVPath = path + ".csv"
+	s.PDFPath = path + ".pdf"
+
+	if err := UploadToMinio(s.CSVPath, "text/csv", bytes.NewReader(csvBody), int64(len(csvBody))); err != nil {
+		return nil, err
+	}
+	if err := UploadToMinio(s.PDFPath, "application/pdf", bytes.NewReader(pdfBody), int64(len(pdfBody))); err != nil {
+		return nil, err
+	}
+
+	if err := sr.Create(db, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// BuildSellerStatement sums up the movements of the store balance in the period. Paid orders add their
+// sales less the commission, reverted orders take the earnings back and payouts are deducted until they fail.
+func BuildSellerStatement(storeID string, start, end time.Time, opening int64, movements []models.StoreLedgerMovement) (*models.SellerStatement, []SellerStatementLine) {
+	s := &models.SellerStatement{
+		ID:             utils.NewUUID(),
+		StoreID:        storeID,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		OpeningBalance: opening,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	var lines []SellerStatementLine
+	balance := opening
+
+	for _, m := range movements {
+		if m.StoreAmount == 0 {
+			continue
+		}
+
+		l := SellerStatementLine{
+			Date:        m.CreatedAt,
+			Reference:   m.ReferenceID,
+			Description: m.Description,
+			Amount:      m.StoreAmount,
+		}
+
+		switch {
+		case m.ReferenceType == models.JournalReferencePayout:
+			l.Type = SellerStatementLinePayout
+			s.TotalPayouts -= m.StoreAmount
+		case m.StoreAmount > 0:
+			l.Type = SellerStatementLineOrder
+			l.Sales = m.StoreAmount + m.CommissionAmount
+			l.Commission = m.CommissionAmount
+			s.TotalSales += l.Sales
+			s.TotalCommission += l.Commission
+			s.TotalEarnings += m.StoreAmount
+			if m.Event == string(models.PaymentCompleted) {
+				s.TotalOrders++
+			}
+		default:
+			l.Type = SellerStatementLineRefund
+			s.TotalRefunds -= m.StoreAmount
+		}
+
+		balance += m.StoreAmount
+		l.Balance = balance
+		lines = append(lines, l)
+	}
+
+	s.ClosingBalance = balance
+	return s, lines
+}
+
+// GenerateSellerStatementCSV writes the statement lines between the opening and the closing balance
+func GenerateSellerStatementCSV(s *models.SellerStatement, lines []SellerStatementLine) ([]byte, error) {
+	buf := bytes.Buffer{}
+	w := csv.NewWriter(&buf)
+
+	records := [][]string{
+		{"date", "type", "reference", "description", "sales", "commission", "amount", "balance"},
+		{s.PeriodStart.Format(utils.DateFormat), "opening_balance", "", "Opening balance", "", "", "", formatDecimalAmount(s.OpeningBalance)},
+	}
+	for _, l := range lines {
+		records = append(records, []string{l.Date.Format(utils.DateFormat), l.Type, l.Reference, l.Description,
+			formatDecimalAmount(l.Sales), formatDecimalAmount(l.Commission), formatDecimalAmount(l.Amount),
+			formatDecimalAmount(l.Balance)})
+	}
+	records = append(records, []string{s.PeriodEnd.AddDate(0, 0, -1).Format(utils.DateFormat), "closing_balance", "",
+		"Closing balance", formatDecimalAmount(s.TotalSales), formatDecimalAmount(s.TotalCommission), "",
+		formatDecimalAmount(s.ClosingBalance)})
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateSellerStatementPDF writes the statement summary followed by its lines
+func GenerateSellerStatementPDF(platformName string, store *models.Store, s *models.SellerStatement, lines []SellerStatementLine) []byte {
+	d := templates.NewPDFDocument(fmt.Sprintf("%s | Statement %s", platformName, s.ID))
+
+	d.Title(fmt.Sprintf("Statement of %s", store.Name))
+	d.Text(fmt.Sprintf("Period: %s to %s", s.PeriodStart.Format(utils.DateFormat),
+		s.PeriodEnd.AddDate(0, 0, -1).Format(utils.DateFormat)))
+
+	d.Heading("Summary")
+	d.KeyValues([][2]string{
+		{"Opening balance", formatAmount(s.OpeningBalance)},
+		{"Paid orders", fmt.Sprintf("%d", s.TotalOrders)},
+		{"Sales", formatAmount(s.TotalSales)},
+		{"Commission", formatAmount(-s.TotalCommission)},
+		{"Earnings", formatAmount(s.TotalEarnings)},
+		{"Refunds", formatAmount(-s.TotalRefunds)},
+		{"Payouts", formatAmount(-s.TotalPayouts)},
+		{"Closing balance", formatAmount(s.ClosingBalance)},
+	})
+
+	d.Heading("Transactions")
+	if len(lines) == 0 {
+		d.Text("No transactions in this period.")
+		return d.Bytes()
+	}
+
+	var rows [][]string
+	for _, l := range lines {
+		rows = append(rows, []string{l.Date.Format(utils.DateFormat), l.Type, l.Description,
+			formatDecimalAmount(l.Sales), formatDecimalAmount(l.Commission), formatDecimalAmount(l.Amount),
+			formatDecimalAmount(l.Balance)})
+	}
+	d.Table([]templates.PDFColumn{
+		{Title: "Date", Width: 60},
+		{Title: "Type", Width: 45},
+		{Title: "Description", Width: 150},
+		{Title: "Sales", Width: 60, AlignRight: true},
+		{Title: "Commission", Width: 60, AlignRight: true},
+		{Title: "Amount", Width: 60, AlignRight: true},
+		{Title: "Balance", Width: d.ContentWidth() - 435, AlignRight: true},
+	}, rows)
+
+	return d.Bytes()
+}
+
+func sendSellerStatementEmail(db *gorm.DB, s *models.SellerStatement) error {
+	su := data.NewStoreRepository()
+	creator, err := su.GetStoreCreator(db, s.StoreID)
+	if err != nil {
+		return err
+	}
+
+	period := s.PeriodStart.Format("January 2006")
+	return SendNotificationEmail(creator.StaffEmail, fmt.Sprintf("Your statement for %s is ready", period), &Notification{
+		Title:     "Monthly Statement",
+		Greetings: fmt.Sprintf("Hi %s,", creator.StaffName),
+		Intros:    fmt.Sprintf("The statement of %s for %s is ready to download from your dashboard.", creator.StoreName, period),
+		Details: []NotificationDetail{
+			{Label: "Opening balance", Value: formatAmount(s.OpeningBalance)},
+			{Label: "Sales", Value: formatAmount(s.TotalSales)},
+			{Label: "Commission", Value: formatAmount(s.TotalCommission)},
+			{Label: "Refunds", Value: formatAmount(s.TotalRefunds)},
+			{Label: "Payouts", Value: formatAmount(s.TotalPayouts)},
+			{Label: "Closing balance", Value: formatAmount(s.ClosingBalance)},
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSellerStatement(t *testing.T) {
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	movements := []models.StoreLedgerMovement{
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o1", Event: string(models.PaymentCompleted),
+			StoreAmount: 9000, CommissionAmount: 1000, CreatedAt: start.Add(time.Hour)},
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o2", Event: string(models.PaymentCompleted),
+			StoreAmount: 4500, CommissionAmount: 500, CreatedAt: start.Add(2 * time.Hour)},
+		// Only the gateway fee moves when a payment is reverted before it was completed
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o3", Event: string(models.PaymentReverted),
+			CreatedAt: start.Add(3 * time.Hour)},
+		{ReferenceType: models.JournalReferenceOrder, ReferenceID: "o2", Event: string(models.PaymentReverted),
+			StoreAmount: -4500, CommissionAmount: -500, CreatedAt: start.Add(4 * time.Hour)},
+		{ReferenceType: models.JournalReferencePayout, ReferenceID: "p1", Event: string(models.PayoutSendStatusPending),
+			StoreAmount: -6000, CreatedAt: start.Add(5 * time.Hour)},
+		{ReferenceType: models.JournalReferencePayout, ReferenceID: "p2", Event: string(models.PayoutSendStatusFailed),
+			StoreAmount: 2000, CreatedAt: start.Add(6 * time.Hour)},
+	}
+
+	s, lines := BuildSellerStatement("s1", start, end, 1000, movements)
+
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 lines, got %d", len(lines))
+	}
+	if s.TotalOrders != 2 || s.TotalSales != 15000 || s.TotalCommission != 1500 || s.TotalEarnings != 13500 {
+		t.Errorf("unexpected order totals %+v", s)
+	}
+	if s.TotalRefunds != 4500 || s.TotalPayouts != 4000 {
+		t.Errorf("unexpected refunds %d or payouts %d", s.TotalRefunds, s.TotalPayouts)
+	}
+	if s.ClosingBalance != 1000+13500-4500-4000 || lines[len(lines)-1].Balance != s.ClosingBalance {
+		t.Errorf("unexpected closing balance %d", s.ClosingBalance)
+	}
+	if lines[2].Type != SellerStatementLineRefund || lines[3].Type != SellerStatementLinePayout {
+		t.Errorf("unexpected line types %s and %s", lines[2].Type, lines[3].Type)
+	}
+
+	body, err := GenerateSellerStatementCSV(s, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(rows) != 8 {
+		t.Fatalf("expected 8 csv rows, got %d", len(rows))
+	}
+	if rows[1] != "01-09-2020,opening_balance,,Opening balance,,,,10.00" {
+		t.Errorf("unexpected opening row %s", rows[1])
+	}
+	if !strings.HasPrefix(rows[7], "30-09-2020,closing_balance,") || !strings.HasSuffix(rows[7], ",60.00") {
+		t.Errorf("unexpected closing row %s", rows[7])
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/templates"
+)
+
+// NotificationDetail is a label/value row rendered in a notification email
+type NotificationDetail struct {
+	Label string
+	Value string
+}
+
+// Notification is the content of a generic notification email
+type Notification struct {
+	Title      string
+	Greetings  string
+	Intros     string
+	Details    []NotificationDetail
+	ActionURL  string
+	ActionText string
+}
+
+func SendNotificationEmail(email, subject string, n *Notification) error {
+	pu := data.NewMarketplaceRepository()
+	settings, err := pu.GetSettings(app.DB())
+	if err != nil {
+		return err
+	}
+
+	var details []map[string]interface{}
+	for _, d := range n.Details {
+		details = append(details, map[string]interface{}{
+			"label": d.Label,
+			"value": d.Value,
+		})
+	}
+
+	params := map[string]interface{}{
+		"title":           n.Title,
+		"greetings":       n.Greetings,
+		"intros":          n.Intros,
+		"details":         details,
+		"actionUrl":       n.ActionURL,
+		"actionText":      n.ActionText,
+		"assetsUrl":       fmt.Sprintf("%s/assets/", settings.Website),
+		"platformWebsite": settings.Website,
+		"platformName":    settings.Name,
+	}
+
+	body, err := templates.GenerateNotificationEmailHTML(params)
+	if err != nil {
+		return err
+	}
+	return SendEmail(subject, email, body)
+}
+
+func formatAmount(amount int64) string {
+	return fmt.Sprintf("%.2f", float64(amount)/100)
+}
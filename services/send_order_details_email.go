@@ -91,10 +91,11 @@ func SendOrderDetailsEmail(email, subject string, order *models.OrderDetailsView
 
 	for _, v := range order.Items {
 		items = append(items, map[string]interface{}{
-			"name":     v.Name,
-			"quantity": v.Quantity,
-			"price":    fmt.Sprintf("%.2f", float64(v.Price)/100),
-			"subTotal": fmt.Sprintf("%.2f", float64(v.SubTotal)/100),
+			"name":        v.Name,
+			"quantity":    v.Quantity,
+			"price":       fmt.Sprintf("%.2f", float64(v.Price)/100),
+			"subTotal":    fmt.Sprintf("%.2f", float64(v.SubTotal)/100),
+			"licenseKeys": v.LicenseKeys,
 		})
 	}
 
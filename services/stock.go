@@ -0,0 +1,218 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/utils"
+)
+
+const (
+	DefaultStockLocationName = "Main warehouse"
+	DefaultStockLocationCode = "main"
+)
+
+// CreateStockLocation saves the location. The first location of the store becomes its default one and
+// takes over the stock its products had so far.
+func CreateStockLocation(db *gorm.DB, l *models.StockLocation) error {
+	su := data.NewStockRepository()
+
+	locations, err := su.ListLocations(db, l.StoreID)
+	if err != nil {
+		return err
+	}
+
+	l.IsDefault = len(locations) == 0
+	if l.IsDefault {
+		l.IsActive = true
+	}
+
+	if err := su.CreateLocation(db, l); err != nil {
+		return err
+	}
+
+	if l.IsDefault {
+		return su.SeedLevels(db, l.StoreID, l.ID)
+	}
+	return nil
+}
+
+// DefaultStockLocation returns the default location of the store, stores without any location get a main warehouse
+func DefaultStockLocation(db *gorm.DB, storeID string) (*models.StockLocation, error) {
+	su := data.NewStockRepository()
+
+	l, err := su.GetDefaultLocation(db, storeID)
+	if err == nil {
+		return l, nil
+	}
+	if !errors.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	locations, err := su.ListLocations(db, storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent first orders of the store create the location once, the others use it
+	l = &models.StockLocation{
+		ID:        utils.NewUUID(),
+		StoreID:   storeID,
+		Name:      DefaultStockLocationName,
+		Code:      DefaultStockLocationCode,
+		IsDefault: len(locations) == 0,
+		IsActive:  true,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	created, err := su.CreateLocationIfMissing(db, l)
+	if err != nil {
+		return nil, err
+	}
+	if created && l.IsDefault {
+		if err := su.SeedLevels(db, storeID, l.ID); err != nil {
+			return nil, err
+		}
+	}
+	return su.GetLocationByCode(db, storeID, DefaultStockLocationCode)
+}
+
+// CheckStockAdjustment tells whether the quantity goes the way the reason does, corrections go either way
+func CheckStockAdjustment(reason models.StockAdjustmentReason, quantity int) error {
+	sign := reason.Sign()
+	if sign > 0 && quantity < 0 {
+		return errors.NewError(fmt.Sprintf("%s adjustments must add stock", reason))
+	}
+	if sign < 0 && quantity > 0 {
+		return errors.NewError(fmt.Sprintf("%s adjustments must remove stock", reason))
+	}
+	return nil
+}
+
+// AdjustStock applies the adjustment to the level at its location and to the stock of the product or the
+// variant, then records it. Taking more than the location holds fails as not found.
+func AdjustStock(db *gorm.DB, a *models.StockAdjustment) error {
+	su := data.NewStockRepository()
+
+	quantity, err := su.AdjustLevel(db, a.LocationID, a.ProductID, a.VariantID, a.Quantity)
+	if err != nil {
+		return err
+	}
+	return recordStockAdjustment(db, a, quantity)
+}
+
+// recordStockAdjustment applies the adjustment to the stock of the product or the variant and records it
+// with the quantity left at the location
+func recordStockAdjustment(db *gorm.DB, a *models.StockAdjustment, quantity int) error {
+	su := data.NewStockRepository()
+
+	if err := su.AddToTotal(db, a.ProductID, a.VariantID, a.Quantity); err != nil {
+		return err
+	}
+
+	if a.ID == "" {
+		a.ID = utils.NewUUID()
+	}
+	a.QuantityAfter = quantity
+	a.CreatedAt = time.Now().UTC()
+	return su.CreateAdjustment(db, a)
+}
+
+// SetStockLevel sets the quantity at the location of the adjustment, the difference is adjusted for the reason
+// of the adjustment. It tells whether the quantity changed, nothing is recorded when it didn't.
+func SetStockLevel(db *gorm.DB, a *models.StockAdjustment, quantity int) (bool, error) {
+	su := data.NewStockRepository()
+
+	diff, err := su.SetLevel(db, a.LocationID, a.ProductID, a.VariantID, quantity)
+	if err != nil {
+		return false, err
+	}
+
+	a.Quantity = diff
+	if a.Quantity == 0 {
+		return false, nil
+	}
+	return true, recordStockAdjustment(db, a, quantity)
+}
+
+// SetProductStock brings the stock of the product, or of its variant, from current to quantity at the default
+// location of the store, the other locations are left as they are. Digital products have unlimited stock,
+// theirs is only informative and kept out of the locations.
+func SetProductStock(db *gorm.DB, p *models.Product, variantID *string, current, quantity int, reason models.StockAdjustmentReason, userID *string) error {
+	diff := quantity - current
+	if diff == 0 {
+		return nil
+	}
+
+	if p.IsDigital {
+		su := data.NewStockRepository()
+		return su.AddToTotal(db, p.ID, variantID, diff)
+	}
+
+	l, err := DefaultStockLocation(db, p.StoreID)
+	if err != nil {
+		return err
+	}
+
+	return AdjustStock(db, &models.StockAdjustment{
+		StoreID:    p.StoreID,
+		LocationID: l.ID,
+		ProductID:  p.ID,
+		VariantID:  variantID,
+		Reason:     reason,
+		Quantity:   diff,
+		UserID:     userID,
+	})
+}
+
+// AllocateOrderStock takes the quantity of every item from a single location, the default location when it
+// holds enough. Items no location holds enough of fail as not found. Digital orders have nothing to allocate.
+func AllocateOrderStock(db *gorm.DB, o *models.Order, items []*models.OrderedItem) error {
+	if o.IsAllDigitalProducts {
+		return nil
+	}
+
+	// Stores ordered from before having locations have their stock moved to the default one first
+	if _, err := DefaultStockLocation(db, o.StoreID); err != nil {
+		return err
+	}
+
+	su := data.NewStockRepository()
+
+	for _, oi := range items {
+		locations, err := su.ListAllocationLocations(db, o.StoreID, oi.ProductID, oi.VariantID, oi.Quantity)
+		if err != nil {
+			return err
+		}
+
+		for _, l := range locations {
+			err := AdjustStock(db, &models.StockAdjustment{
+				StoreID:    o.StoreID,
+				LocationID: l.ID,
+				ProductID:  oi.ProductID,
+				VariantID:  oi.VariantID,
+				Reason:     models.StockSale,
+				Quantity:   -oi.Quantity,
+				OrderID:    &o.ID,
+			})
+			if err == nil {
+				locationID := l.ID
+				oi.StockLocationID = &locationID
+				break
+			}
+			// Another order took the quantity in the meantime
+			if !errors.IsRecordNotFoundError(err) {
+				return err
+			}
+		}
+
+		if oi.StockLocationID == nil {
+			return gorm.ErrRecordNotFound
+		}
+	}
+	return nil
+}
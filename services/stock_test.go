@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func TestCheckStockAdjustment(t *testing.T) {
+	cases := []struct {
+		reason   models.StockAdjustmentReason
+		quantity int
+		allowed  bool
+	}{
+		{models.StockReceived, 10, true},
+		{models.StockReceived, -10, false},
+		{models.StockReturn, 1, true},
+		{models.StockReturn, -1, false},
+		{models.StockDamaged, -2, true},
+		{models.StockDamaged, 2, false},
+		{models.StockSale, -3, true},
+		{models.StockSale, 3, false},
+		{models.StockCorrection, 5, true},
+		{models.StockCorrection, -5, true},
+	}
+
+	for _, c := range cases {
+		err := CheckStockAdjustment(c.reason, c.quantity)
+		if c.allowed && err != nil {
+			t.Errorf("expected %s of %d to be allowed, got %v", c.reason, c.quantity, err)
+		}
+		if !c.allowed && err == nil {
+			t.Errorf("expected %s of %d to be rejected", c.reason, c.quantity)
+		}
+	}
+}
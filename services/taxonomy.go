@@ -0,0 +1,75 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/shopicano/shopicano-backend/data"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+// PlaceTaxonomyNode puts the node below the parent, or at the root without parent, within the given
+// transaction. Moving an existing node moves its descendants along.
+func PlaceTaxonomyNode(db *gorm.DB, n *models.TaxonomyNode, parent *models.TaxonomyNode) error {
+	tu := data.NewTaxonomyRepository()
+
+	height := 0
+	if n.Path != "" {
+		h, err := tu.SubtreeHeight(db, n)
+		if err != nil {
+			return err
+		}
+		height = h
+	}
+
+	var parentID *string
+	parentPath, parentDepth := "", 0
+	if parent != nil {
+		parentID, parentPath, parentDepth = &parent.ID, parent.Path, parent.Depth
+	}
+
+	if err := CheckTreeMove(n.Path, height, parentPath, parentDepth); err != nil {
+		return err
+	}
+
+	oldPath, oldDepth := n.Path, n.Depth
+
+	n.ParentID = parentID
+	n.Path = models.MaterializedPath(parentPath, n.ID)
+	n.Depth = 0
+	if parent != nil {
+		n.Depth = parent.Depth + 1
+	}
+
+	if oldPath != "" && oldPath != n.Path {
+		return tu.MoveSubtree(db, oldPath, n.Path, n.Depth-oldDepth)
+	}
+	return nil
+}
+
+// BuildTaxonomyTree nests the nodes below their parents, keeping the order of the siblings.
+// Nodes whose parent isn't in the list are left out.
+func BuildTaxonomyTree(nodes []models.TaxonomyNode) []models.TaxonomyTree {
+	children := map[string][]models.TaxonomyNode{}
+	var roots []models.TaxonomyNode
+
+	for _, n := range nodes {
+		if n.ParentID == nil {
+			roots = append(roots, n)
+			continue
+		}
+		children[*n.ParentID] = append(children[*n.ParentID], n)
+	}
+
+	var build func(nodes []models.TaxonomyNode) []models.TaxonomyTree
+	build = func(nodes []models.TaxonomyNode) []models.TaxonomyTree {
+		trees := []models.TaxonomyTree{}
+		for _, n := range nodes {
+			trees = append(trees, models.TaxonomyTree{
+				TaxonomyNode: n,
+				Children:     build(children[n.ID]),
+			})
+		}
+		return trees
+	}
+
+	return build(roots)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"github.com/shopicano/shopicano-backend/models"
+	"testing"
+)
+
+func TestBuildTaxonomyTree(t *testing.T) {
+	electronics := "electronics"
+	phones := "phones"
+	orphan := "missing"
+
+	nodes := []models.TaxonomyNode{
+		{ID: "electronics", Name: "Electronics", Path: "/electronics/"},
+		{ID: "home", Name: "Home", Path: "/home/"},
+		{ID: "phones", ParentID: &electronics, Name: "Phones", Path: "/electronics/phones/", Depth: 1},
+		{ID: "laptops", ParentID: &electronics, Name: "Laptops", Path: "/electronics/laptops/", Depth: 1},
+		{ID: "accessories", ParentID: &phones, Name: "Accessories", Path: "/electronics/phones/accessories/", Depth: 2},
+		{ID: "lost", ParentID: &orphan, Name: "Lost", Path: "/missing/lost/", Depth: 1},
+	}
+
+	tree := BuildTaxonomyTree(nodes)
+	if len(tree) != 2 || tree[0].ID != "electronics" || tree[1].ID != "home" {
+		t.Fatalf("unexpected roots %+v", tree)
+	}
+
+	e := tree[0]
+	if len(e.Children) != 2 || e.Children[0].ID != "phones" || e.Children[1].ID != "laptops" {
+		t.Fatalf("unexpected children of electronics %+v", e.Children)
+	}
+	if len(e.Children[0].Children) != 1 || e.Children[0].Children[0].ID != "accessories" {
+		t.Errorf("unexpected children of phones %+v", e.Children[0].Children)
+	}
+	if tree[1].Children == nil || len(tree[1].Children) != 0 {
+		t.Errorf("expected no children for home, got %+v", tree[1].Children)
+	}
+}
+
+func TestMaterializedPath(t *testing.T) {
+	root := models.MaterializedPath("", "a")
+	if root != "/a/" {
+		t.Errorf("unexpected root path %s", root)
+	}
+	child := models.MaterializedPath(root, "b")
+	if child != "/a/b/" {
+		t.Errorf("unexpected child path %s", child)
+	}
+	if ids := models.PathIDs(child); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("unexpected path ids %v", ids)
+	}
+	if !models.IsPathWithin(child, root) || models.IsPathWithin(root, child) || models.IsPathWithin("/ab/", "/a/") {
+		t.Error("unexpected ancestry")
+	}
+}
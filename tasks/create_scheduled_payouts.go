@@ -0,0 +1,22 @@
+package tasks
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/services"
+)
+
+const (
+	CreateScheduledPayoutsTaskName = "create_scheduled_payouts"
+)
+
+func CreateScheduledPayoutsFn() error {
+	payouts, err := services.CreateScheduledPayouts()
+	if err != nil {
+		log.Log().Errorln(err)
+		return tasks.NewErrRetryTaskLater(err.Error(), services.TaskRetryDelay)
+	}
+
+	log.Log().Infoln("Scheduled payouts created for ", len(payouts), " stores")
+	return nil
+}
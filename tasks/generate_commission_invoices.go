@@ -0,0 +1,27 @@
+package tasks
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/services"
+	"time"
+)
+
+const (
+	GenerateCommissionInvoicesTaskName = "generate_commission_invoices"
+)
+
+// GenerateCommissionInvoicesFn issues the commission invoices of the previous month
+func GenerateCommissionInvoicesFn() error {
+	now := time.Now().UTC()
+	previousMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	invoices, err := services.GenerateMonthlyCommissionInvoices(previousMonth)
+	if err != nil {
+		log.Log().Errorln(err)
+		return tasks.NewErrRetryTaskLater(err.Error(), services.TaskRetryDelay)
+	}
+
+	log.Log().Infoln("Commission invoices issued to ", len(invoices), " stores")
+	return nil
+}
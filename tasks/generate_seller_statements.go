@@ -0,0 +1,27 @@
+package tasks
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/services"
+	"time"
+)
+
+const (
+	GenerateSellerStatementsTaskName = "generate_seller_statements"
+)
+
+// GenerateSellerStatementsFn generates the statements of the previous month
+func GenerateSellerStatementsFn() error {
+	now := time.Now().UTC()
+	previousMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	statements, err := services.GenerateMonthlySellerStatements(previousMonth)
+	if err != nil {
+		log.Log().Errorln(err)
+		return tasks.NewErrRetryTaskLater(err.Error(), services.TaskRetryDelay)
+	}
+
+	log.Log().Infoln("Seller statements generated for ", len(statements), " stores")
+	return nil
+}
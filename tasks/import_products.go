@@ -0,0 +1,19 @@
+package tasks
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/services"
+)
+
+const (
+	ImportProductsTaskName = "import_products"
+)
+
+func ImportProductsFn(importID string) error {
+	if err := services.ImportProducts(importID); err != nil {
+		log.Log().Errorln(err)
+		return tasks.NewErrRetryTaskLater(err.Error(), services.TaskRetryDelay)
+	}
+	return nil
+}
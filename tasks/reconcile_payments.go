@@ -0,0 +1,34 @@
+package tasks
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/config"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/services"
+	"time"
+)
+
+const (
+	ReconcilePaymentsTaskName = "reconcile_payments"
+)
+
+const defaultPaymentReconciliationWindow = time.Hour * 48
+
+func ReconcilePaymentsFn() error {
+	window := time.Duration(config.Scheduler().PaymentReconciliationWindowHrs) * time.Hour
+	if window <= 0 {
+		window = defaultPaymentReconciliationWindow
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-window)
+
+	r, err := services.ReconcilePayments(from, to)
+	if err != nil {
+		log.Log().Errorln(err)
+		return tasks.NewErrRetryTaskLater(err.Error(), services.TaskRetryDelay)
+	}
+
+	log.Log().Infoln("Payment reconciliation ", r.ID, " found ", r.TotalDiscrepancies, " discrepancies")
+	return nil
+}
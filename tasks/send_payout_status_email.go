@@ -0,0 +1,20 @@
+package tasks
+
+import (
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/shopicano/shopicano-backend/app"
+	"github.com/shopicano/shopicano-backend/log"
+	"github.com/shopicano/shopicano-backend/services"
+)
+
+const (
+	SendPayoutStatusEmailTaskName = "send_payout_status_email"
+)
+
+func SendPayoutStatusEmailFn(eventID string) error {
+	if err := services.SendPayoutStatusEmail(app.DB(), eventID); err != nil {
+		log.Log().Errorln(err)
+		return tasks.NewErrRetryTaskLater(err.Error(), services.TaskRetryDelay)
+	}
+	return nil
+}
@@ -205,7 +205,11 @@ var invoiceTemplate = `
                                     </tr>
                                     {{ range $item := .orderedItems }}
                                         <tr class="tbl-data">
-                                            <td class="td-border2nd" style="padding: 7px 0;">{{ $item.name }}</td>
+                                            <td class="td-border2nd" style="padding: 7px 0;">{{ $item.name }}
+                                                {{ range $key := $item.licenseKeys }}
+                                                    <br/><span style="font-family: monospace;">{{ $key }}</span>
+                                                {{end}}
+                                            </td>
                                             <td class="td-border2nd" style="text-align: center; padding: 7px 0;">{{ $item.price }}</td>
                                             <td class="td-border2nd" style="text-align: center; padding: 7px 0;">{{ $item.quantity }}</td>
                                             <td class="td-border2nd" style="text-align: right; padding: 7px 0;">{{ $item.subTotal }}</td>
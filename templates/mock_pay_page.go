@@ -0,0 +1,52 @@
+package templates
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var mockPayPageTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0, maximum-scale=1">
+
+    <title>Mock Payment Gateway | Order #{{ .orderHash }}</title>
+
+    <style type="text/css" media="screen">
+    body { padding: 40px 0; margin: 0 auto; font-family: sans-serif; background: #f6f8fc; color: #5a637c; text-align: center }
+    .card { display: inline-block; padding: 32px 48px; background: #ffffff; border-radius: 8px }
+    button { margin: 6px; padding: 10px 24px; font-size: 14px; border: 0; border-radius: 4px; cursor: pointer }
+    .success { background: #27ae60; color: #ffffff }
+    .decline { background: #c0392b; color: #ffffff }
+    .pending { background: #f39c12; color: #ffffff }
+    .timeout { background: #7f8c8d; color: #ffffff }
+    </style>
+</head>
+<body>
+    <div class="card">
+        <h2>Mock Payment Gateway</h2>
+        <p>This page is only available in development and test environments.</p>
+        <p>Order <b>#{{ .orderHash }}</b></p>
+        <p>Amount <b>{{ .amount }}</b></p>
+        <p>Transaction <code>{{ .transactionID }}</code></p>
+
+        <form method="POST" action="{{ .action }}">
+            <button class="success" type="submit" name="outcome" value="success">Pay</button>
+            <button class="decline" type="submit" name="outcome" value="decline">Decline</button>
+            <button class="pending" type="submit" name="outcome" value="pending">Leave Pending</button>
+            <button class="timeout" type="submit" name="outcome" value="timeout">Time Out</button>
+        </form>
+    </div>
+</body>
+</html>
+`
+
+func GenerateMockPayPageHTML(params map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("MockPayPageTemplate").Parse(mockPayPageTemplate))
+	if err := t.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
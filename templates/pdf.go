@@ -0,0 +1,244 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfPageWidth  = 595.28 // A4
+	pdfPageHeight = 841.89
+	pdfMargin     = 50.0
+)
+
+// PDFDocument is a minimal text only PDF writer using the standard Helvetica fonts.
+// It's enough for statements and invoices, which are made of headings, key values and tables.
+type PDFDocument struct {
+	pages  []*bytes.Buffer
+	y      float64
+	footer string
+}
+
+// PDFColumn describes a table column, the widths of all columns should add up to the content width
+type PDFColumn struct {
+	Title      string
+	Width      float64
+	AlignRight bool
+}
+
+func NewPDFDocument(footer string) *PDFDocument {
+	d := &PDFDocument{footer: footer}
+	d.newPage()
+	return d
+}
+
+// ContentWidth is the usable width of a page
+func (d *PDFDocument) ContentWidth() float64 {
+	return pdfPageWidth - 2*pdfMargin
+}
+
+func (d *PDFDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+func (d *PDFDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// ensureSpace starts a new page unless the given height fits in the current one
+func (d *PDFDocument) ensureSpace(height float64) bool {
+	if d.y-height < pdfMargin+20 {
+		d.newPage()
+		return true
+	}
+	return false
+}
+
+func (d *PDFDocument) write(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, pdfEscape(text))
+}
+
+func (d *PDFDocument) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.8 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", x1, y1, x2, y2)
+}
+
+func (d *PDFDocument) Title(text string) {
+	d.ensureSpace(30)
+	d.y -= 18
+	d.write(pdfMargin, d.y, 18, true, text)
+	d.y -= 12
+}
+
+func (d *PDFDocument) Heading(text string) {
+	d.ensureSpace(40)
+	d.y -= 20
+	d.write(pdfMargin, d.y, 12, true, text)
+	d.y -= 6
+	d.line(pdfMargin, d.y, pdfPageWidth-pdfMargin, d.y)
+	d.y -= 4
+}
+
+func (d *PDFDocument) Text(text string) {
+	for _, l := range strings.Split(text, "\n") {
+		d.ensureSpace(14)
+		d.y -= 14
+		d.write(pdfMargin, d.y, 10, false, l)
+	}
+}
+
+// KeyValues writes each pair in a row, with the key in bold and the value right aligned
+func (d *PDFDocument) KeyValues(pairs [][2]string) {
+	for _, p := range pairs {
+		d.ensureSpace(16)
+		d.y -= 16
+		d.write(pdfMargin, d.y, 10, true, p[0])
+		d.write(pdfPageWidth-pdfMargin-pdfTextWidth(p[1], 10), d.y, 10, false, p[1])
+	}
+}
+
+// Table writes the rows under the column titles, repeating the titles on every new page.
+// Cell texts that don't fit their column are truncated.
+func (d *PDFDocument) Table(columns []PDFColumn, rows [][]string) {
+	header := func() {
+		d.y -= 16
+		d.writeRow(columns, nil, true)
+		d.y -= 5
+		d.line(pdfMargin, d.y, pdfPageWidth-pdfMargin, d.y)
+	}
+
+	d.ensureSpace(40)
+	header()
+
+	for _, r := range rows {
+		if d.ensureSpace(15) {
+			header()
+		}
+		d.y -= 15
+		d.writeRow(columns, r, false)
+	}
+	d.y -= 4
+}
+
+func (d *PDFDocument) writeRow(columns []PDFColumn, cells []string, bold bool) {
+	x := pdfMargin
+	for i, c := range columns {
+		text := c.Title
+		if cells != nil {
+			text = ""
+			if i < len(cells) {
+				text = cells[i]
+			}
+		}
+		text = pdfTruncate(text, c.Width-6, 9)
+
+		if c.AlignRight {
+			d.write(x+c.Width-pdfTextWidth(text, 9), d.y, 9, bold, text)
+		} else {
+			d.write(x, d.y, 9, bold, text)
+		}
+		x += c.Width
+	}
+}
+
+// Bytes renders the document with the footer and page numbers on every page
+func (d *PDFDocument) Bytes() []byte {
+	buf := bytes.Buffer{}
+	var offsets []int
+
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and the fonts, followed by a page and its content per page
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, p := range d.pages {
+		content := bytes.Buffer{}
+		content.Write(p.Bytes())
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		fmt.Fprintf(&content, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n", pdfMargin, pdfMargin-20, pdfEscape(d.footer))
+		fmt.Fprintf(&content, "BT /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n",
+			pdfPageWidth-pdfMargin-pdfTextWidth(footer, 8), pdfMargin-20, footer)
+
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+i*2))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfEscape encodes the text in WinAnsi, which matches Latin-1 for the printable characters,
+// replacing anything else with a question mark
+func pdfEscape(text string) string {
+	b := strings.Builder{}
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 32 && r < 127:
+			b.WriteByte(byte(r))
+		case r >= 160 && r <= 255:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the width of the text in Helvetica, exact for digits which matters
+// for right aligned amounts
+func pdfTextWidth(text string, size float64) float64 {
+	w := 0.0
+	for _, r := range text {
+		switch {
+		case r == ' ' || r == '.' || r == ',' || r == ':' || r == 'i' || r == 'l' || r == '|':
+			w += 0.278
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+			w += 0.833
+		case r >= 'A' && r <= 'Z':
+			w += 0.667
+		default:
+			w += 0.556
+		}
+	}
+	return w * size
+}
+
+func pdfTruncate(text string, width, size float64) string {
+	if pdfTextWidth(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdfTextWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
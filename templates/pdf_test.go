@@ -0,0 +1,57 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPDFDocument(t *testing.T) {
+	d := NewPDFDocument("Shopicano (test)")
+	d.Title("Statement for Café")
+	d.KeyValues([][2]string{{"Closing balance", "1,234.56"}})
+	d.Heading("Orders")
+
+	var rows [][]string
+	for i := 0; i < 120; i++ {
+		rows = append(rows, []string{fmt.Sprintf("order-%d with a very long reference that does not fit", i), "12.00"})
+	}
+	d.Table([]PDFColumn{
+		{Title: "Reference", Width: 300},
+		{Title: "Amount", Width: d.ContentWidth() - 300, AlignRight: true},
+	}, rows)
+
+	body := d.Bytes()
+
+	if !bytes.HasPrefix(body, []byte("%PDF-1.4")) || !bytes.HasSuffix(body, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	if len(d.pages) < 2 {
+		t.Fatalf("expected the table to span pages, got %d", len(d.pages))
+	}
+	if !bytes.Contains(body, []byte(`(Shopicano \(test\))`)) || !bytes.Contains(body, []byte("Caf\xe9")) {
+		t.Error("text is not escaped or encoded")
+	}
+
+	// Every cross reference entry must point to the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(body)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(body[xref:], []byte("xref\n")) {
+		t.Fatal("startxref doesn't point to the cross reference table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(body[xref:], -1)
+	if len(entries) != 4+2*len(d.pages) {
+		t.Fatalf("expected %d objects, got %d", 4+2*len(d.pages), len(entries))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if !bytes.HasPrefix(body[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TSQueryTerm is a word, a prefix or a phrase of a search box query in to_tsquery syntax.
+// Negated terms must not match.
+type TSQueryTerm struct {
+	Query  string
+	Negate bool
+}
+
+// ParseTSQuery splits a search box query into text search terms, which must all match. "Quoted words"
+// must appear as a phrase, a trailing * matches by prefix and a leading - excludes the word. Anything
+// but letters and digits is dropped, so every term is a valid query.
+func ParseTSQuery(query string) []TSQueryTerm {
+	var terms []TSQueryTerm
+
+	for i, part := range strings.Split(query, `"`) {
+		// Odd parts are between quotes
+		if i%2 == 1 {
+			prefix := strings.HasSuffix(strings.TrimSpace(part), "*")
+			if phrase := buildTSPhrase(strings.Fields(part), prefix); phrase != "" {
+				terms = append(terms, TSQueryTerm{Query: phrase})
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			negate := strings.HasPrefix(word, "-")
+			prefix := strings.HasSuffix(word, "*")
+
+			// A word like t-shirt is searched as the phrase t <-> shirt, like the index splits it
+			term := buildTSPhrase(splitTSWords(word), prefix)
+			if term == "" {
+				continue
+			}
+			terms = append(terms, TSQueryTerm{Query: term, Negate: negate})
+		}
+	}
+
+	return terms
+}
+
+// BuildTSQuery turns a search box query into a text search query for to_tsquery, the terms of
+// ParseTSQuery and-ed. It's empty when nothing searchable is left.
+func BuildTSQuery(query string) string {
+	var terms []string
+	for _, t := range ParseTSQuery(query) {
+		if t.Negate {
+			terms = append(terms, "!"+t.Query)
+		} else {
+			terms = append(terms, t.Query)
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+func buildTSPhrase(words []string, prefix bool) string {
+	var lexemes []string
+	for _, w := range words {
+		lexemes = append(lexemes, splitTSWords(w)...)
+	}
+
+	if len(lexemes) == 0 {
+		return ""
+	}
+	if prefix {
+		lexemes[len(lexemes)-1] += ":*"
+	}
+	if len(lexemes) == 1 {
+		return lexemes[0]
+	}
+	return "(" + strings.Join(lexemes, " <-> ") + ")"
+}
+
+func splitTSWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
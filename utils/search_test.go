@@ -0,0 +1,50 @@
+package utils
+
+import "testing"
+
+func TestBuildTSQuery(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"Shirt", "shirt"},
+		{"red shirt", "red & shirt"},
+		{"shi*", "shi:*"},
+		{`"red cotton shirt"`, "(red <-> cotton <-> shirt)"},
+		{`"red cott*" -wool`, "(red <-> cott:*) & !wool"},
+		{"t-shirt", "(t <-> shirt)"},
+		{"shirt's & (xl) | !", "(shirt <-> s) & xl"},
+		{`unclosed "quote here`, "unclosed & (quote <-> here)"},
+		{"café 42", "café & 42"},
+	}
+
+	for _, c := range cases {
+		if got := BuildTSQuery(c.query); got != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.query, c.expected, got)
+		}
+	}
+}
+
+func TestParseTSQuery(t *testing.T) {
+	terms := ParseTSQuery(`red -shirts "cotton t-shirt" sku-42*`)
+	expected := []TSQueryTerm{
+		{Query: "red"},
+		{Query: "shirts", Negate: true},
+		{Query: "(cotton <-> t <-> shirt)"},
+		{Query: "(sku <-> 42:*)"},
+	}
+	if len(terms) != len(expected) {
+		t.Fatalf("expected %d terms, got %+v", len(expected), terms)
+	}
+	for i := range expected {
+		if terms[i] != expected[i] {
+			t.Errorf("term %d: expected %+v, got %+v", i, expected[i], terms[i])
+		}
+	}
+
+	if terms := ParseTSQuery(" - * "); len(terms) != 0 {
+		t.Errorf("expected no terms, got %+v", terms)
+	}
+}
@@ -11,10 +11,12 @@ import (
 
 func ValidateCreateCategory(ctx echo.Context) (*models.Category, error) {
 	pld := struct {
-		Name        string `json:"name" valid:"required,stringlength(1|20)"`
-		Description string `json:"description" valid:"required,stringlength(1|50)"`
-		Image       string `json:"image"`
-		IsPublished bool   `json:"is_published"`
+		Name        string  `json:"name" valid:"required,stringlength(1|20)"`
+		Description string  `json:"description" valid:"required,stringlength(1|50)"`
+		Image       string  `json:"image"`
+		IsPublished bool    `json:"is_published"`
+		ParentID    *string `json:"parent_id"`
+		TaxonomyID  *string `json:"taxonomy_id"`
 	}{}
 
 	if err := ctx.Bind(&pld); err != nil {
@@ -29,6 +31,8 @@ func ValidateCreateCategory(ctx echo.Context) (*models.Category, error) {
 			Description: pld.Description,
 			Image:       pld.Image,
 			IsPublished: pld.IsPublished,
+			ParentID:    nonEmptyID(pld.ParentID),
+			TaxonomyID:  nonEmptyID(pld.TaxonomyID),
 			CreatedAt:   time.Now().UTC(),
 			UpdatedAt:   time.Now().UTC(),
 		}, nil
@@ -43,11 +47,14 @@ func ValidateCreateCategory(ctx echo.Context) (*models.Category, error) {
 	return nil, &ve
 }
 
+// ReqCategoryUpdate moves the category to the root with an empty parent_id and unmaps it with an empty taxonomy_id
 type ReqCategoryUpdate struct {
 	Name        *string `json:"name"`
 	Description *string `json:"description"`
 	Image       *string `json:"image"`
 	IsPublished *bool   `json:"is_published"`
+	ParentID    *string `json:"parent_id"`
+	TaxonomyID  *string `json:"taxonomy_id"`
 }
 
 func ValidateUpdateCategory(ctx echo.Context) (*ReqCategoryUpdate, error) {
@@ -65,7 +72,7 @@ func ValidateUpdateCategory(ctx echo.Context) (*ReqCategoryUpdate, error) {
 		}
 	}
 	if pld.Description != nil {
-		ok := len(*pld.Name) >= 1 && len(*pld.Name) <= 500
+		ok := len(*pld.Description) >= 1 && len(*pld.Description) <= 500
 		if !ok {
 			ve.Add("description", "must be between 1 to 500 characters")
 		}
@@ -77,3 +84,11 @@ func ValidateUpdateCategory(ctx echo.Context) (*ReqCategoryUpdate, error) {
 
 	return nil, &ve
 }
+
+// nonEmptyID treats an empty ID as no ID
+func nonEmptyID(id *string) *string {
+	if id == nil || *id == "" {
+		return nil
+	}
+	return id
+}
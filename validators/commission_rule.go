@@ -0,0 +1,103 @@
+package validators
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+	"time"
+)
+
+type ReqCreateCommissionRule struct {
+	Name            string     `json:"name" valid:"required,stringlength(1|100)"`
+	ProductID       *string    `json:"product_id"`
+	CategoryID      *string    `json:"category_id"`
+	StoreTier       *string    `json:"store_tier"`
+	MinMonthlySales *int64     `json:"min_monthly_sales"`
+	MaxMonthlySales *int64     `json:"max_monthly_sales"`
+	Rate            int64      `json:"rate"`
+	FixedFee        int64      `json:"fixed_fee"`
+	Priority        int        `json:"priority"`
+	IsActive        bool       `json:"is_active"`
+	EffectiveFrom   *time.Time `json:"effective_from"`
+	EffectiveTo     *time.Time `json:"effective_to"`
+}
+
+func ValidateCreateCommissionRule(ctx echo.Context) (*ReqCreateCommissionRule, error) {
+	pld := ReqCreateCommissionRule{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	_, err := govalidator.ValidateStruct(&pld)
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	validateCommissionRule(ve, &pld.Rate, &pld.FixedFee, pld.MinMonthlySales, pld.MaxMonthlySales, pld.EffectiveFrom, pld.EffectiveTo)
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+// ReqUpdateCommissionRule updates the given fields of the rule, the criteria are cleared with an empty string
+// or with the respective clear flag
+type ReqUpdateCommissionRule struct {
+	Name              *string    `json:"name"`
+	ProductID         *string    `json:"product_id"`
+	CategoryID        *string    `json:"category_id"`
+	StoreTier         *string    `json:"store_tier"`
+	MinMonthlySales   *int64     `json:"min_monthly_sales"`
+	MaxMonthlySales   *int64     `json:"max_monthly_sales"`
+	ClearMonthlySales bool       `json:"clear_monthly_sales"`
+	Rate              *int64     `json:"rate"`
+	FixedFee          *int64     `json:"fixed_fee"`
+	Priority          *int       `json:"priority"`
+	IsActive          *bool      `json:"is_active"`
+	EffectiveFrom     *time.Time `json:"effective_from"`
+	EffectiveTo       *time.Time `json:"effective_to"`
+	ClearEffectiveTo  bool       `json:"clear_effective_to"`
+}
+
+func ValidateUpdateCommissionRule(ctx echo.Context) (*ReqUpdateCommissionRule, error) {
+	pld := ReqUpdateCommissionRule{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	if pld.Name != nil && (len(*pld.Name) == 0 || len(*pld.Name) > 100) {
+		ve.Add("name", "is invalid")
+	}
+
+	validateCommissionRule(ve, pld.Rate, pld.FixedFee, pld.MinMonthlySales, pld.MaxMonthlySales, pld.EffectiveFrom, pld.EffectiveTo)
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+func validateCommissionRule(ve errors.ValidationError, rate, fixedFee, minSales, maxSales *int64, from, to *time.Time) {
+	if rate != nil && (*rate < 0 || *rate > 100) {
+		ve.Add("rate", "is invalid")
+	}
+	if fixedFee != nil && *fixedFee < 0 {
+		ve.Add("fixed_fee", "is invalid")
+	}
+	if minSales != nil && *minSales < 0 {
+		ve.Add("min_monthly_sales", "is invalid")
+	}
+	if maxSales != nil && (*maxSales <= 0 || (minSales != nil && *maxSales <= *minSales)) {
+		ve.Add("max_monthly_sales", "must be greater than min_monthly_sales")
+	}
+	if from != nil && to != nil && !to.After(*from) {
+		ve.Add("effective_to", "must be after effective_from")
+	}
+}
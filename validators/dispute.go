@@ -0,0 +1,83 @@
+package validators
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"time"
+)
+
+type ReqCreateDispute struct {
+	OrderID          string     `json:"order_id" valid:"required"`
+	GatewayDisputeID *string    `json:"gateway_dispute_id"`
+	Amount           int64      `json:"amount" valid:"required"`
+	Fee              int64      `json:"fee"`
+	Reason           string     `json:"reason" valid:"required,stringlength(1|255)"`
+	EvidenceDueBy    *time.Time `json:"evidence_due_by"`
+	Note             string     `json:"note"`
+}
+
+func ValidateCreateDispute(ctx echo.Context) (*ReqCreateDispute, error) {
+	pld := ReqCreateDispute{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	_, err := govalidator.ValidateStruct(&pld)
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	validateDisputeAmounts(ve, &pld.Amount, &pld.Fee)
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+type ReqUpdateDispute struct {
+	Status        *models.DisputeStatus `json:"status"`
+	Amount        *int64                `json:"amount"`
+	Fee           *int64                `json:"fee"`
+	Reason        *string               `json:"reason"`
+	EvidenceDueBy *time.Time            `json:"evidence_due_by"`
+	Note          *string               `json:"note"`
+}
+
+func ValidateUpdateDispute(ctx echo.Context) (*ReqUpdateDispute, error) {
+	pld := ReqUpdateDispute{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	if pld.Status != nil && !pld.Status.IsValid() {
+		ve.Add("status", "is invalid")
+	}
+	if pld.Reason != nil && *pld.Reason == "" {
+		ve.Add("reason", "is required")
+	}
+
+	validateDisputeAmounts(ve, pld.Amount, pld.Fee)
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+func validateDisputeAmounts(ve errors.ValidationError, amount, fee *int64) {
+	if amount != nil && *amount <= 0 {
+		ve.Add("amount", "must be positive")
+	}
+	if fee != nil && *fee < 0 {
+		ve.Add("fee", "can't be negative")
+	}
+}
@@ -0,0 +1,102 @@
+package validators
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+)
+
+const (
+	licenseKeyUploadMaxKeys    = 10000
+	licenseKeyMaxLength        = 500
+	licenseKeyUploadMaxBytes   = 8 << 20
+	licenseKeyPoolMaxThreshold = 1000000
+)
+
+// ReqLicenseKeyUpload holds the keys to add to the pool of a product. They are given as a JSON list,
+// or as a text file with a key per line in a multipart form.
+type ReqLicenseKeyUpload struct {
+	Keys []string `json:"keys"`
+}
+
+func ValidateLicenseKeyUpload(ctx echo.Context) (*ReqLicenseKeyUpload, error) {
+	pld := ReqLicenseKeyUpload{}
+
+	ve := errors.ValidationError{}
+
+	if strings.HasPrefix(ctx.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fh, err := ctx.FormFile("file")
+		if err != nil {
+			ve.Add("file", "is required")
+			return nil, &ve
+		}
+		if fh.Size > licenseKeyUploadMaxBytes {
+			ve.Add("file", fmt.Sprintf("must be at most %d bytes", licenseKeyUploadMaxBytes))
+			return nil, &ve
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		body, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		pld.Keys = strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	} else if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for i, k := range pld.Keys {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		count++
+
+		if len(k) > licenseKeyMaxLength {
+			ve.Add(fmt.Sprintf("keys.%d", i), fmt.Sprintf("must be at most %d characters", licenseKeyMaxLength))
+		}
+	}
+
+	if count == 0 {
+		ve.Add("keys", "is required")
+	}
+	if count > licenseKeyUploadMaxKeys {
+		ve.Add("keys", fmt.Sprintf("must have at most %d keys", licenseKeyUploadMaxKeys))
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+	return &pld, nil
+}
+
+type ReqLicenseKeyPool struct {
+	LowThreshold int `json:"low_threshold"`
+}
+
+func ValidateLicenseKeyPool(ctx echo.Context) (*ReqLicenseKeyPool, error) {
+	pld := ReqLicenseKeyPool{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	if pld.LowThreshold < 0 || pld.LowThreshold > licenseKeyPoolMaxThreshold {
+		ve.Add("low_threshold", fmt.Sprintf("must be between 0 and %d", licenseKeyPoolMaxThreshold))
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+	return &pld, nil
+}
@@ -9,6 +9,7 @@ import (
 
 type ReqOrderItem struct {
 	ID         string   `json:"id" valid:"required"`
+	VariantID  *string  `json:"variant_id"`
 	Quantity   int      `json:"quantity" valid:"range(1|10000000)"`
 	Attributes []string `json:"attributes"`
 }
@@ -88,3 +89,27 @@ func ValidateUpdatePaymentStatus(ctx echo.Context) (*ReqPaymentStatusUpdate, err
 
 	return &pld, nil
 }
+
+type ReqChangeOrderPaymentMethod struct {
+	PaymentMethodID string `json:"payment_method_id" valid:"required"`
+}
+
+func ValidateChangeOrderPaymentMethod(ctx echo.Context) (*ReqChangeOrderPaymentMethod, error) {
+	pld := ReqChangeOrderPaymentMethod{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
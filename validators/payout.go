@@ -37,6 +37,7 @@ type ReqUpdatePayoutEntry struct {
 	Status        *models.PayoutSendStatus `json:"status"`
 	Highlights    *string                  `json:"highlights"`
 	FailureReason *string                  `json:"failure_reason"`
+	Note          *string                  `json:"note"`
 }
 
 func ValidateUpdatePayoutEntry(ctx echo.Context) (*ReqUpdatePayoutEntry, error) {
@@ -60,3 +61,39 @@ func ValidateUpdatePayoutEntry(ctx echo.Context) (*ReqUpdatePayoutEntry, error)
 
 	return &pld, nil
 }
+
+type ReqCreatePayoutBatch struct {
+	Format   models.PayoutBatchFormat `json:"format"`
+	EntryIDs []string                 `json:"entry_ids"`
+}
+
+func ValidateCreatePayoutBatch(ctx echo.Context) (*ReqCreatePayoutBatch, error) {
+	pld := ReqCreatePayoutBatch{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	if !pld.Format.IsValid() {
+		ve.Add("format", "is invalid")
+	}
+	if len(pld.EntryIDs) == 0 {
+		ve.Add("entry_ids", "is required")
+	}
+
+	seen := map[string]bool{}
+	for _, id := range pld.EntryIDs {
+		if seen[id] {
+			ve.Add("entry_ids", "must be unique")
+			break
+		}
+		seen[id] = true
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
@@ -81,6 +81,7 @@ type ReqSettingsUpdate struct {
 	IsSignUpEnabled              *bool                  `json:"is_sign_up_enabled"`
 	IsStoreCreationEnabled       *bool                  `json:"is_store_creation_enabled"`
 	DefaultCommissionRate        *int64                 `json:"default_commission_rate"`
+	DefaultReservePeriodDays     *int                   `json:"default_reserve_period_days"`
 	TagLine                      *string                `json:"tag_line"`
 }
 
@@ -90,16 +91,21 @@ func ValidateUpdateSettings(ctx echo.Context) (*ReqSettingsUpdate, error) {
 		return nil, err
 	}
 
+	ve := errors.ValidationError{}
+
 	ok, err := govalidator.ValidateStruct(&pld)
-	if ok {
-		return &pld, nil
+	if !ok {
+		for k, v := range govalidator.ErrorsByField(err) {
+			ve.Add(k, v)
+		}
 	}
 
-	ve := errors.ValidationError{}
-
-	for k, v := range govalidator.ErrorsByField(err) {
-		ve.Add(k, v)
+	if pld.DefaultReservePeriodDays != nil && *pld.DefaultReservePeriodDays < 0 {
+		ve.Add("default_reserve_period_days", "is invalid")
 	}
 
-	return nil, &ve
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+	return &pld, nil
 }
@@ -1,26 +1,29 @@
 package validators
 
 import (
+	"time"
+
 	"github.com/asaskevich/govalidator"
 	"github.com/labstack/echo/v4"
 	"github.com/shopicano/shopicano-backend/errors"
 )
 
 type ReqProductCreate struct {
-	Name             string   `json:"name" valid:"required,stringlength(3|100)"`
-	Description      string   `json:"description" valid:"required,stringlength(3|100000)"`
-	IsPublished      bool     `json:"is_published"`
-	CategoryID       *string  `json:"category_id"`
-	Image            string   `json:"image"`
-	IsShippable      bool     `json:"is_shippable"`
-	IsDigital        bool     `json:"is_digital"`
-	SKU              string   `json:"sku" valid:"required,stringlength(1|100)"`
-	Stock            int      `json:"stock" valid:"range(0|100000)"`
-	Unit             string   `json:"unit" valid:"required,stringlength(1|20)"`
-	Price            int64    `json:"price" valid:"range(0|10000000)"`
-	MaxQuantityCount int      `json:"max_quantity_count"`
-	ProductCost      int64    `json:"product_cost" valid:"range(0|10000000)"`
-	AdditionalImages []string `json:"additional_images"`
+	Name             string     `json:"name" valid:"required,stringlength(3|100)"`
+	Description      string     `json:"description" valid:"required,stringlength(3|100000)"`
+	IsPublished      bool       `json:"is_published"`
+	UnpublishAt      *time.Time `json:"unpublish_at"`
+	CategoryID       *string    `json:"category_id"`
+	Image            string     `json:"image"`
+	IsShippable      bool       `json:"is_shippable"`
+	IsDigital        bool       `json:"is_digital"`
+	SKU              string     `json:"sku" valid:"required,stringlength(1|100)"`
+	Stock            int        `json:"stock" valid:"range(0|100000)"`
+	Unit             string     `json:"unit" valid:"required,stringlength(1|20)"`
+	Price            int64      `json:"price" valid:"range(0|10000000)"`
+	MaxQuantityCount int        `json:"max_quantity_count"`
+	ProductCost      int64      `json:"product_cost" valid:"range(0|10000000)"`
+	AdditionalImages []string   `json:"additional_images"`
 }
 
 func ValidateCreateProduct(ctx echo.Context) (*ReqProductCreate, error) {
@@ -30,9 +33,24 @@ func ValidateCreateProduct(ctx echo.Context) (*ReqProductCreate, error) {
 		return nil, err
 	}
 
-	ok, err := govalidator.ValidateStruct(&pld)
+	if err := ValidateProductRow(&pld); err != nil {
+		return nil, err
+	}
+
+	if pld.UnpublishAt != nil && !pld.UnpublishAt.After(time.Now()) {
+		ve := errors.ValidationError{}
+		ve.Add("unpublish_at", "must be in the future")
+		return nil, &ve
+	}
+	return &pld, nil
+}
+
+// ValidateProductRow applies the product creation rules to a product read from somewhere else
+// than a request body, like a row of an imported file
+func ValidateProductRow(pld *ReqProductCreate) error {
+	ok, err := govalidator.ValidateStruct(pld)
 	if ok {
-		return &pld, nil
+		return nil
 	}
 
 	ve := errors.ValidationError{}
@@ -41,25 +59,27 @@ func ValidateCreateProduct(ctx echo.Context) (*ReqProductCreate, error) {
 		ve.Add(k, v)
 	}
 
-	return nil, &ve
+	return &ve
 }
 
 type ReqProductUpdate struct {
-	Name                *string  `json:"name" valid:"required,stringlength(3|100)"`
-	Description         *string  `json:"description" valid:"required,stringlength(3|100000)"`
-	IsPublished         *bool    `json:"is_published"`
-	CategoryID          *string  `json:"category_id"`
-	Image               *string  `json:"image"`
-	IsShippable         *bool    `json:"is_shippable"`
-	IsDigital           *bool    `json:"is_digital"`
-	SKU                 *string  `json:"sku" valid:"required,stringlength(1|100)"`
-	Stock               *int     `json:"stock" valid:"range(0|100000)"`
-	Unit                *string  `json:"unit" valid:"required,stringlength(1|20)"`
-	Price               *int64   `json:"price" valid:"range(0|10000000)"`
-	ProductCost         *int64   `json:"product_cost" valid:"range(0|10000000)"`
-	MaxQuantityCount    *int     `json:"max_quantity_count" valid:"range(0,10000)"`
-	DigitalDownloadLink *string  `json:"digital_download_link" valid:"stringlength(1|1000000)"`
-	AdditionalImages    []string `json:"additional_images"`
+	Name                *string    `json:"name" valid:"required,stringlength(3|100)"`
+	Description         *string    `json:"description" valid:"required,stringlength(3|100000)"`
+	IsPublished         *bool      `json:"is_published"`
+	UnpublishAt         *time.Time `json:"unpublish_at"`
+	ClearUnpublishAt    bool       `json:"clear_unpublish_at"`
+	CategoryID          *string    `json:"category_id"`
+	Image               *string    `json:"image"`
+	IsShippable         *bool      `json:"is_shippable"`
+	IsDigital           *bool      `json:"is_digital"`
+	SKU                 *string    `json:"sku" valid:"required,stringlength(1|100)"`
+	Stock               *int       `json:"stock" valid:"range(0|100000)"`
+	Unit                *string    `json:"unit" valid:"required,stringlength(1|20)"`
+	Price               *int64     `json:"price" valid:"range(0|10000000)"`
+	ProductCost         *int64     `json:"product_cost" valid:"range(0|10000000)"`
+	MaxQuantityCount    *int       `json:"max_quantity_count" valid:"range(0,10000)"`
+	DigitalDownloadLink *string    `json:"digital_download_link" valid:"stringlength(1|1000000)"`
+	AdditionalImages    []string   `json:"additional_images"`
 }
 
 func ValidateUpdateProduct(ctx echo.Context) (*ReqProductUpdate, error) {
@@ -69,18 +89,22 @@ func ValidateUpdateProduct(ctx echo.Context) (*ReqProductUpdate, error) {
 		return nil, err
 	}
 
-	ok, err := govalidator.ValidateStruct(&pld)
-	if ok {
-		return &pld, nil
-	}
-
 	ve := errors.ValidationError{}
 
+	_, err := govalidator.ValidateStruct(&pld)
 	for k, v := range govalidator.ErrorsByField(err) {
 		ve.Add(k, v)
 	}
 
-	return nil, &ve
+	if pld.UnpublishAt != nil && !pld.UnpublishAt.After(time.Now()) {
+		ve.Add("unpublish_at", "must be in the future")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
 }
 
 type ReqAddProductAttribute struct {
@@ -0,0 +1,101 @@
+package validators
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+// ValidateProductFilter reads the product filter from the query string. Categories, taxonomy nodes, stores
+// and attributes repeat their parameter for every value, attributes as attribute=key:value.
+func ValidateProductFilter(ctx echo.Context) (*models.ProductFilter, error) {
+	q := ctx.Request().URL.Query()
+
+	f := models.ProductFilter{
+		Query:       strings.TrimSpace(q.Get("query")),
+		CategoryIDs: nonEmptyValues(q["category_id"]),
+		TaxonomyIDs: nonEmptyValues(q["taxonomy_id"]),
+		StoreIDs:    nonEmptyValues(q["store_id"]),
+		Attributes:  map[string][]string{},
+		Sort:        models.ProductSort(q.Get("sort")),
+	}
+
+	ve := errors.ValidationError{}
+
+	parsePrice := func(key string) *int64 {
+		v := q.Get(key)
+		if v == "" {
+			return nil
+		}
+		price, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || price < 0 {
+			ve.Add(key, "must be a non negative amount")
+			return nil
+		}
+		return &price
+	}
+	f.MinPrice = parsePrice("min_price")
+	f.MaxPrice = parsePrice("max_price")
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		ve.Add("max_price", "must not be less than min_price")
+	}
+
+	for _, a := range q["attribute"] {
+		kv := strings.SplitN(a, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			ve.Add("attribute", "must be formatted as key:value")
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		f.Attributes[key] = append(f.Attributes[key], strings.TrimSpace(kv[1]))
+	}
+
+	if v := q.Get("is_digital"); v != "" {
+		isDigital, err := strconv.ParseBool(v)
+		if err != nil {
+			ve.Add("is_digital", "must be true or false")
+		} else {
+			f.IsDigital = &isDigital
+		}
+	}
+
+	if v := q.Get("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			ve.Add("in_stock", "must be true or false")
+		}
+		f.InStock = inStock
+	}
+
+	if v := q.Get("min_rating"); v != "" {
+		rating, err := strconv.Atoi(v)
+		if err != nil || rating < 1 || rating > 5 {
+			ve.Add("min_rating", "must be between 1 and 5")
+		} else {
+			f.MinRating = &rating
+		}
+	}
+
+	if f.Sort != "" && !f.Sort.IsValid() {
+		ve.Add("sort", "is invalid")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &f, nil
+}
+
+func nonEmptyValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package validators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+func validateProductFilterQuery(query string) (*models.ProductFilter, error) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/products?"+query, nil)
+	return ValidateProductFilter(echo.New().NewContext(req, httptest.NewRecorder()))
+}
+
+func TestValidateProductFilter(t *testing.T) {
+	minPrice, maxPrice := int64(100), int64(500)
+	zero := int64(0)
+	isDigital := true
+	rating := 4
+
+	cases := []struct {
+		query    string
+		expected models.ProductFilter
+	}{
+		{"", models.ProductFilter{Attributes: map[string][]string{}}},
+		{"query=+red+shirt+&category_id=c1&category_id=+&category_id=c2&taxonomy_id=t1&store_id=s1",
+			models.ProductFilter{
+				Query:       "red shirt",
+				CategoryIDs: []string{"c1", "c2"},
+				TaxonomyIDs: []string{"t1"},
+				StoreIDs:    []string{"s1"},
+				Attributes:  map[string][]string{},
+			}},
+		{"min_price=100&max_price=500", models.ProductFilter{MinPrice: &minPrice, MaxPrice: &maxPrice, Attributes: map[string][]string{}}},
+		{"min_price=100&max_price=100", models.ProductFilter{MinPrice: &minPrice, MaxPrice: &minPrice, Attributes: map[string][]string{}}},
+		{"min_price=0", models.ProductFilter{MinPrice: &zero, Attributes: map[string][]string{}}},
+		{"attribute=color:red&attribute=+color+:+blue&attribute=size:XL:tall",
+			models.ProductFilter{Attributes: map[string][]string{
+				"color": {"red", "blue"},
+				"size":  {"XL:tall"},
+			}}},
+		{"is_digital=true&in_stock=1&min_rating=4&sort=price_asc",
+			models.ProductFilter{
+				Attributes: map[string][]string{},
+				IsDigital:  &isDigital,
+				InStock:    true,
+				MinRating:  &rating,
+				Sort:       models.ProductSortPriceAsc,
+			}},
+		{"sort=best_selling", models.ProductFilter{Attributes: map[string][]string{}, Sort: models.ProductSortBestSelling}},
+	}
+
+	for _, c := range cases {
+		f, err := validateProductFilterQuery(c.query)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", c.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(*f, c.expected) {
+			t.Errorf("%q: expected %+v, got %+v", c.query, c.expected, *f)
+		}
+	}
+}
+
+func TestValidateProductFilterInvalid(t *testing.T) {
+	cases := []struct {
+		query  string
+		fields []string
+	}{
+		{"min_price=-1", []string{"min_price"}},
+		{"max_price=ten", []string{"max_price"}},
+		{"min_price=1.5&max_price=-2", []string{"min_price", "max_price"}},
+		{"min_price=500&max_price=100", []string{"max_price"}},
+		{"attribute=color", []string{"attribute"}},
+		{"attribute=:red&attribute=color:+", []string{"attribute"}},
+		{"is_digital=maybe", []string{"is_digital"}},
+		{"in_stock=yes", []string{"in_stock"}},
+		{"min_rating=0", []string{"min_rating"}},
+		{"min_rating=6", []string{"min_rating"}},
+		{"min_rating=4.5", []string{"min_rating"}},
+		{"sort=cheapest", []string{"sort"}},
+		{"sort=price_asc&min_price=-5&attribute=size", []string{"min_price", "attribute"}},
+	}
+
+	for _, c := range cases {
+		f, err := validateProductFilterQuery(c.query)
+		if err == nil {
+			t.Errorf("%q: expected an error, got %+v", c.query, *f)
+			continue
+		}
+
+		ve, ok := err.(*errors.ValidationError)
+		if !ok {
+			t.Errorf("%q: expected a validation error, got %v", c.query, err)
+			continue
+		}
+		if len(*ve) != len(c.fields) {
+			t.Errorf("%q: expected errors on %v, got %v", c.query, c.fields, *ve)
+		}
+		for _, field := range c.fields {
+			if _, ok := (*ve)[field]; !ok {
+				t.Errorf("%q: expected an error on %s, got %v", c.query, field, *ve)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+	"github.com/shopicano/shopicano-backend/values"
+)
+
+type ReqProductReviewCreate struct {
+	Rating      int      `json:"rating" valid:"required,range(1|5)"`
+	Title       string   `json:"title" valid:"stringlength(0|200)"`
+	Description string   `json:"description" valid:"required,stringlength(2|100000)"`
+	Photos      []string `json:"photos"`
+}
+
+func ValidateCreateProductReview(ctx echo.Context) (*ReqProductReviewCreate, error) {
+	pld := ReqProductReviewCreate{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	_, err := govalidator.ValidateStruct(&pld)
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	if len(pld.Photos) > models.ProductReviewMaxPhotos {
+		ve.Add("photos", fmt.Sprintf("must not be more than %d", models.ProductReviewMaxPhotos))
+	}
+	for _, p := range pld.Photos {
+		// Photos are uploaded beforehand to the public bucket, like the product images
+		if strings.TrimSpace(p) == "" || strings.HasPrefix(p, values.ReservedBucketName+"/") {
+			ve.Add("photos", "is invalid")
+			break
+		}
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+type ReqProductReviewReply struct {
+	Reply string `json:"reply" valid:"required,stringlength(2|100000)"`
+}
+
+func ValidateProductReviewReply(ctx echo.Context) (*ReqProductReviewReply, error) {
+	pld := ReqProductReviewReply{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
+
+type ReqProductReviewModerate struct {
+	Status models.ReviewStatus `json:"status"`
+	Note   string              `json:"note"`
+}
+
+func ValidateModerateProductReview(ctx echo.Context) (*ReqProductReviewModerate, error) {
+	pld := ReqProductReviewModerate{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	if pld.Status != models.ReviewApproved && pld.Status != models.ReviewRejected {
+		ve.Add("status", "must be review_approved or review_rejected")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
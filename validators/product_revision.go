@@ -0,0 +1,63 @@
+package validators
+
+import (
+	"time"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+)
+
+// ReqProductRevision holds the changes of a draft, the fields left out keep their value.
+// Additional images replace the ones of the draft when given, an empty list removes them.
+type ReqProductRevision struct {
+	Note             *string  `json:"note" valid:"stringlength(0|1000)"`
+	Name             *string  `json:"name" valid:"stringlength(3|100)"`
+	Description      *string  `json:"description" valid:"stringlength(3|100000)"`
+	CategoryID       *string  `json:"category_id"`
+	Image            *string  `json:"image"`
+	Unit             *string  `json:"unit" valid:"stringlength(1|20)"`
+	Price            *int64   `json:"price" valid:"range(0|10000000)"`
+	ProductCost      *int64   `json:"product_cost" valid:"range(0|10000000)"`
+	MaxQuantityCount *int     `json:"max_quantity_count" valid:"range(0|10000)"`
+	IsShippable      *bool    `json:"is_shippable"`
+	IsDigital        *bool    `json:"is_digital"`
+	AdditionalImages []string `json:"additional_images"`
+}
+
+func ValidateProductRevision(ctx echo.Context) (*ReqProductRevision, error) {
+	pld := ReqProductRevision{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
+
+// ReqPublishProductRevision publishes the revision right away unless a future publish date is given
+type ReqPublishProductRevision struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+func ValidatePublishProductRevision(ctx echo.Context) (*ReqPublishProductRevision, error) {
+	pld := ReqPublishProductRevision{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	if pld.PublishAt != nil && !pld.PublishAt.After(time.Now()) {
+		pld.PublishAt = nil
+	}
+	return &pld, nil
+}
@@ -0,0 +1,121 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+)
+
+type ReqCreateProductOption struct {
+	Name   string   `json:"name" valid:"required,stringlength(1|50)"`
+	Values []string `json:"values"`
+}
+
+func ValidateCreateProductOption(ctx echo.Context) (*ReqCreateProductOption, error) {
+	pld := ReqCreateProductOption{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	pld.Name = strings.TrimSpace(pld.Name)
+
+	ve := errors.ValidationError{}
+
+	_, err := govalidator.ValidateStruct(&pld)
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	pld.Values = validateProductOptionValues(ve, pld.Values)
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+type ReqAddProductOptionValues struct {
+	Values []string `json:"values"`
+}
+
+func ValidateAddProductOptionValues(ctx echo.Context) (*ReqAddProductOptionValues, error) {
+	pld := ReqAddProductOptionValues{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	pld.Values = validateProductOptionValues(ve, pld.Values)
+	if len(pld.Values) == 0 {
+		ve.Add("values", "is required")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+// validateProductOptionValues trims the values and rejects empty, too long and repeated ones
+func validateProductOptionValues(ve errors.ValidationError, values []string) []string {
+	seen := map[string]bool{}
+	var trimmed []string
+
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		key := fmt.Sprintf("values[%d]", i)
+
+		switch {
+		case v == "":
+			ve.Add(key, "is required")
+		case len(v) > 50:
+			ve.Add(key, "must be at most 50 characters")
+		case seen[strings.ToLower(v)]:
+			ve.Add(key, "is repeated")
+		}
+
+		seen[strings.ToLower(v)] = true
+		trimmed = append(trimmed, v)
+	}
+	return trimmed
+}
+
+type ReqUpdateProductVariant struct {
+	SKU   *string `json:"sku" valid:"stringlength(1|100)"`
+	Price *int64  `json:"price" valid:"range(0|10000000)"`
+	// UseProductPrice removes the price override of the variant
+	UseProductPrice bool    `json:"use_product_price"`
+	Stock           *int    `json:"stock" valid:"range(0|100000)"`
+	Weight          *int    `json:"weight" valid:"range(0|10000000)"`
+	Image           *string `json:"image"`
+}
+
+func ValidateUpdateProductVariant(ctx echo.Context) (*ReqUpdateProductVariant, error) {
+	pld := ReqUpdateProductVariant{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	_, err := govalidator.ValidateStruct(&pld)
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	if pld.UseProductPrice && pld.Price != nil {
+		ve.Add("price", "can't be set along with use_product_price")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
@@ -0,0 +1,57 @@
+package validators
+
+import (
+	"net/http"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+)
+
+type ReqSavePaymentMethod struct {
+	Token string `json:"token" valid:"required"`
+}
+
+func ValidateSavePaymentMethod(ctx echo.Context) (*ReqSavePaymentMethod, error) {
+	pld := ReqSavePaymentMethod{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
+
+// ReqPayNonce asks for the nonce or the pay url of the order, or to charge a payment method saved by its owner.
+// The saved payment method is only read from the body of a POST, a GET never charges the order.
+type ReqPayNonce struct {
+	SavedPaymentMethodID *string `json:"saved_payment_method_id"`
+}
+
+func ValidatePayNonce(ctx echo.Context) (*ReqPayNonce, error) {
+	pld := ReqPayNonce{}
+	if ctx.Request().Method != http.MethodPost || ctx.Request().ContentLength == 0 {
+		return &pld, nil
+	}
+
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	if pld.SavedPaymentMethodID != nil && *pld.SavedPaymentMethodID == "" {
+		ve := errors.ValidationError{}
+		ve.Add("saved_payment_method_id", "must not be empty")
+		return nil, &ve
+	}
+	return &pld, nil
+}
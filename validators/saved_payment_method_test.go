@@ -0,0 +1,45 @@
+package validators
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestValidatePayNonce(t *testing.T) {
+	cases := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		expected string
+	}{
+		{"post without a body", http.MethodPost, "/v1/orders/o1/nonce/", "", ""},
+		{"post with a saved payment method", http.MethodPost, "/v1/orders/o1/nonce/", `{"saved_payment_method_id":"pm1"}`, "pm1"},
+		{"post with a saved payment method in the query", http.MethodPost, "/v1/orders/o1/nonce/?saved_payment_method_id=pm1", "", ""},
+		{"get with a saved payment method in the query", http.MethodGet, "/v1/orders/o1/nonce/?saved_payment_method_id=pm1", "", ""},
+		{"get with a saved payment method in the body", http.MethodGet, "/v1/orders/o1/nonce/", `{"saved_payment_method_id":"pm1"}`, ""},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		pld, err := ValidatePayNonce(echo.New().NewContext(req, httptest.NewRecorder()))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+
+		got := ""
+		if pld.SavedPaymentMethodID != nil {
+			got = *pld.SavedPaymentMethodID
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected saved payment method %q, got %q", c.name, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package validators
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+	"github.com/shopicano/shopicano-backend/models"
+)
+
+const bulkStockUpdateMaxItems = 1000
+
+type ReqStockLocation struct {
+	Name    string `json:"name" valid:"required,stringlength(1|100)"`
+	Code    string `json:"code" valid:"required,stringlength(1|50)"`
+	Address string `json:"address" valid:"stringlength(0|500)"`
+}
+
+func ValidateCreateStockLocation(ctx echo.Context) (*ReqStockLocation, error) {
+	pld := ReqStockLocation{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	pld.Code = strings.TrimSpace(pld.Code)
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
+
+// ReqUpdateStockLocation holds the changes of a location, setting is_default makes it the default one
+type ReqUpdateStockLocation struct {
+	Name      *string `json:"name" valid:"stringlength(1|100)"`
+	Code      *string `json:"code" valid:"stringlength(1|50)"`
+	Address   *string `json:"address" valid:"stringlength(0|500)"`
+	IsActive  *bool   `json:"is_active"`
+	IsDefault bool    `json:"is_default"`
+}
+
+func ValidateUpdateStockLocation(ctx echo.Context) (*ReqUpdateStockLocation, error) {
+	pld := ReqUpdateStockLocation{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	if pld.Code != nil {
+		code := strings.TrimSpace(*pld.Code)
+		pld.Code = &code
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
+
+// ReqStockAdjustment adds the quantity at the location, negative quantities remove stock
+type ReqStockAdjustment struct {
+	LocationID string                       `json:"location_id" valid:"required"`
+	VariantID  *string                      `json:"variant_id"`
+	Reason     models.StockAdjustmentReason `json:"reason" valid:"required"`
+	Quantity   int                          `json:"quantity"`
+	Note       string                       `json:"note" valid:"stringlength(0|500)"`
+	OrderID    *string                      `json:"order_id"`
+}
+
+func ValidateStockAdjustment(ctx echo.Context) (*ReqStockAdjustment, error) {
+	pld := ReqStockAdjustment{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if !ok {
+		for k, v := range govalidator.ErrorsByField(err) {
+			ve.Add(k, v)
+		}
+	}
+
+	if pld.Reason != "" && !pld.Reason.IsValid() {
+		ve.Add("reason", "is invalid")
+	}
+	if pld.Quantity == 0 {
+		ve.Add("quantity", "must not be zero")
+	}
+	if pld.Quantity < -1000000 || pld.Quantity > 1000000 {
+		ve.Add("quantity", "must be between -1000000 and 1000000")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+	return &pld, nil
+}
+
+type ReqStockLevel struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
+
+// ReqBulkStockUpdate sets the quantities of the SKUs at a location, the default location when none is given.
+// The location is given by ID or by code.
+type ReqBulkStockUpdate struct {
+	LocationID   *string         `json:"location_id"`
+	LocationCode *string         `json:"location_code"`
+	Note         string          `json:"note" valid:"stringlength(0|500)"`
+	Items        []ReqStockLevel `json:"items"`
+}
+
+func ValidateBulkStockUpdate(ctx echo.Context) (*ReqBulkStockUpdate, error) {
+	pld := ReqBulkStockUpdate{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if !ok {
+		for k, v := range govalidator.ErrorsByField(err) {
+			ve.Add(k, v)
+		}
+	}
+
+	if pld.LocationID != nil && pld.LocationCode != nil {
+		ve.Add("location_code", "can't be set along with location_id")
+	}
+
+	if len(pld.Items) == 0 {
+		ve.Add("items", "is required")
+	}
+	if len(pld.Items) > bulkStockUpdateMaxItems {
+		ve.Add("items", fmt.Sprintf("must have at most %d items", bulkStockUpdateMaxItems))
+	}
+
+	skus := map[string]bool{}
+	for i, item := range pld.Items {
+		key := fmt.Sprintf("items.%d", i)
+
+		if strings.TrimSpace(item.SKU) == "" {
+			ve.Add(key, "sku is required")
+		} else if skus[item.SKU] {
+			ve.Add(key, fmt.Sprintf("sku %s is repeated", item.SKU))
+		}
+		skus[item.SKU] = true
+
+		if item.Quantity < 0 || item.Quantity > 1000000 {
+			ve.Add(key, "quantity must be between 0 and 1000000")
+		}
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+	return &pld, nil
+}
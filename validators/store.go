@@ -142,14 +142,19 @@ func ValidateUpdateStoreStaff(ctx echo.Context) (*string, error) {
 	return nil, &ve
 }
 
-func ValidateUpdateStoreStatus(ctx echo.Context) (*models.StoreStatus, *int64, error) {
-	pld := struct {
-		Status         *models.StoreStatus `json:"status"`
-		CommissionRate *int64              `json:"commission_rate"`
-	}{}
+type ReqUpdateStoreStatus struct {
+	Status                  *models.StoreStatus `json:"status"`
+	CommissionRate          *int64              `json:"commission_rate"`
+	Tier                    *string             `json:"tier"`
+	ReservePeriodDays       *int                `json:"reserve_period_days"`
+	UseDefaultReservePeriod bool                `json:"use_default_reserve_period"`
+}
+
+func ValidateUpdateStoreStatus(ctx echo.Context) (*ReqUpdateStoreStatus, error) {
+	pld := ReqUpdateStoreStatus{}
 
 	if err := ctx.Bind(&pld); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	ve := errors.ValidationError{}
@@ -160,10 +165,13 @@ func ValidateUpdateStoreStatus(ctx echo.Context) (*models.StoreStatus, *int64, e
 	if pld.CommissionRate != nil && (*pld.CommissionRate < 0 || *pld.CommissionRate > 100) {
 		ve.Add("commission_rate", "is invalid")
 	}
+	if pld.ReservePeriodDays != nil && *pld.ReservePeriodDays < 0 {
+		ve.Add("reserve_period_days", "is invalid")
+	}
 
 	if len(ve) > 0 {
-		return nil, nil, &ve
+		return nil, &ve
 	}
 
-	return pld.Status, pld.CommissionRate, nil
+	return &pld, nil
 }
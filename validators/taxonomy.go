@@ -0,0 +1,79 @@
+package validators
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/gosimple/slug"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+)
+
+type ReqTaxonomyNodeCreate struct {
+	Name        string  `json:"name" valid:"required,stringlength(1|100)"`
+	Slug        string  `json:"slug" valid:"stringlength(0|120)"`
+	Description string  `json:"description" valid:"stringlength(0|500)"`
+	Image       string  `json:"image"`
+	ParentID    *string `json:"parent_id"`
+	Position    int     `json:"position"`
+}
+
+func ValidateCreateTaxonomyNode(ctx echo.Context) (*ReqTaxonomyNodeCreate, error) {
+	pld := ReqTaxonomyNodeCreate{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	_, err := govalidator.ValidateStruct(&pld)
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	if pld.Slug == "" {
+		pld.Slug = slug.Make(pld.Name)
+	} else if !slug.IsSlug(pld.Slug) {
+		ve.Add("slug", "is invalid")
+	}
+	pld.ParentID = nonEmptyID(pld.ParentID)
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
+
+// ReqTaxonomyNodeUpdate moves the node to the root with an empty parent_id
+type ReqTaxonomyNodeUpdate struct {
+	Name        *string `json:"name"`
+	Slug        *string `json:"slug"`
+	Description *string `json:"description"`
+	Image       *string `json:"image"`
+	ParentID    *string `json:"parent_id"`
+	Position    *int    `json:"position"`
+}
+
+func ValidateUpdateTaxonomyNode(ctx echo.Context) (*ReqTaxonomyNodeUpdate, error) {
+	pld := ReqTaxonomyNodeUpdate{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ve := errors.ValidationError{}
+
+	if pld.Name != nil && (len(*pld.Name) < 1 || len(*pld.Name) > 100) {
+		ve.Add("name", "must be between 1 to 100 characters")
+	}
+	if pld.Slug != nil && !slug.IsSlug(*pld.Slug) {
+		ve.Add("slug", "is invalid")
+	}
+	if pld.Description != nil && len(*pld.Description) > 500 {
+		ve.Add("description", "must not be more than 500 characters")
+	}
+
+	if len(ve) > 0 {
+		return nil, &ve
+	}
+
+	return &pld, nil
+}
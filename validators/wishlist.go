@@ -0,0 +1,56 @@
+package validators
+
+import (
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/shopicano/shopicano-backend/errors"
+)
+
+type ReqWishlist struct {
+	Name string `json:"name" valid:"required,stringlength(1|100)"`
+}
+
+func ValidateWishlist(ctx echo.Context) (*ReqWishlist, error) {
+	pld := ReqWishlist{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}
+
+// ReqSaveProduct adds a product to a wishlist or subscribes to its alerts
+type ReqSaveProduct struct {
+	ProductID string `json:"product_id" valid:"required"`
+}
+
+func ValidateSaveProduct(ctx echo.Context) (*ReqSaveProduct, error) {
+	pld := ReqSaveProduct{}
+	if err := ctx.Bind(&pld); err != nil {
+		return nil, err
+	}
+
+	ok, err := govalidator.ValidateStruct(&pld)
+	if ok {
+		return &pld, nil
+	}
+
+	ve := errors.ValidationError{}
+
+	for k, v := range govalidator.ErrorsByField(err) {
+		ve.Add(k, v)
+	}
+
+	return nil, &ve
+}